@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"context"
+
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+/*
+GetAuditLog Return a single page of the audit entries matching the filter, most recent first. The filter may be
+nil to return every entry. Entries are only recorded while auditing is enabled with 'mongo.audit.enabled'
+*/
+func GetAuditLog(ctx context.Context, filter *server.AuditFilter, opts *server.PageOptions) ([]*server.AuditEntry, *server.Page, error) {
+	database, err := mtgContext.GetMongoDatabase(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return database.AuditLog(ctx, filter, opts)
+}
+
+/*
+GetActorActivity Return a single page of the writes made by the actor passed (e.g. the email address of a user),
+most recent first
+*/
+func GetActorActivity(ctx context.Context, actor string, opts *server.PageOptions) ([]*server.AuditEntry, *server.Page, error) {
+	return GetAuditLog(ctx, &server.AuditFilter{Actor: actor}, opts)
+}
+
+/*
+GetDocumentHistory Return a single page of the writes made to a single document, identified by its collection
+and _id, most recent first
+*/
+func GetDocumentHistory(ctx context.Context, collection string, documentId interface{}, opts *server.PageOptions) ([]*server.AuditEntry, *server.Page, error) {
+	return GetAuditLog(ctx, &server.AuditFilter{Collection: collection, DocumentId: documentId}, opts)
+}
@@ -0,0 +1,144 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/stevezaluk/mtgjson-sdk/set"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+/*
+UserGrowth The number of registered users, along with the number of users created within the last
+7 and 30 days. Creation times are derived from the timestamp embedded in each user's ObjectID
+*/
+type UserGrowth struct {
+	Total   int64 `json:"total"`
+	Last7d  int64 `json:"last7d"`
+	Last30d int64 `json:"last30d"`
+}
+
+/*
+Dashboard A single aggregated view of the state of the SDK for an operations dashboard. Sections that
+could not be gathered are left empty and the reason is recorded in Errors, keyed by section name
+*/
+type Dashboard struct {
+	GeneratedAt    time.Time                            `json:"generatedAt"`
+	Profile        string                               `json:"profile"`
+	Components     map[string]mtgContext.ComponentState `json:"components"`
+	ImportStatus   *set.ImportStatus                    `json:"importStatus,omitempty"`
+	Users          *UserGrowth                          `json:"users,omitempty"`
+	Storage        []*server.CollectionStats            `json:"storage"`
+	MissingIndexes map[string][]string                  `json:"missingIndexes"`
+	Errors         map[string]string                    `json:"errors"`
+}
+
+/*
+userGrowth Count the users in the database, and the users created since each of the cut-off points
+*/
+func userGrowth(ctx context.Context, database *server.Database) (*UserGrowth, error) {
+	ret := &UserGrowth{}
+
+	total, err := database.Count(ctx, "user", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret.Total = total
+
+	now := time.Now()
+	for days, field := range map[int]*int64{7: &ret.Last7d, 30: &ret.Last30d} {
+		since := primitive.NewObjectIDFromTimestamp(now.AddDate(0, 0, -days))
+
+		count, err := database.Count(ctx, "user", bson.M{"_id": bson.M{"$gte": since}})
+		if err != nil {
+			return nil, err
+		}
+
+		*field = count
+	}
+
+	return ret, nil
+}
+
+/*
+AdminDashboard Gather the health of each server component, the version of the most recently imported
+MTGJSON data, user growth, the storage used by each collection and any missing indexes into a single
+response. A failure in one section does not prevent the others from being gathered. Returns the context
+error if the context is cancelled before the dashboard is complete
+*/
+func AdminDashboard(ctx context.Context) (*Dashboard, error) {
+	ret := &Dashboard{
+		GeneratedAt:    time.Now().UTC(),
+		Profile:        mtgContext.GetProfile(),
+		Storage:        []*server.CollectionStats{},
+		MissingIndexes: map[string][]string{},
+		Errors:         map[string]string{},
+	}
+
+	mtgContext.CheckDatabase()
+	ret.Components = mtgContext.GetComponentStates()
+
+	database, err := mtgContext.GetMongoDatabase(ctx)
+	if err != nil {
+		ret.Errors["database"] = err.Error()
+		return ret, nil
+	}
+
+	status, err := set.GetImportStatus(ctx)
+	if err != nil && !errors.Is(err, set.ErrNoImportStatus) {
+		ret.Errors["importStatus"] = err.Error()
+	}
+
+	ret.ImportStatus = status
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	users, err := userGrowth(ctx, database)
+	if err != nil {
+		ret.Errors["users"] = err.Error()
+	}
+
+	ret.Users = users
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	collections, err := database.ListCollections(ctx)
+	if err != nil {
+		ret.Errors["storage"] = err.Error()
+	}
+
+	for _, collection := range collections {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		stats, err := database.Stats(ctx, collection)
+		if err != nil {
+			ret.Errors["storage"] = err.Error()
+			continue
+		}
+
+		ret.Storage = append(ret.Storage, stats)
+	}
+
+	missing, err := database.MissingIndexes(ctx)
+	if err != nil {
+		ret.Errors["indexes"] = err.Error()
+	}
+
+	if missing != nil {
+		ret.MissingIndexes = missing
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,294 @@
+package card
+
+import (
+	"cmp"
+	stdContext "context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/query"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	ATOMIC_COLLECTION = "card_atomic"
+	ATOMIC_BATCH_SIZE = 1000
+)
+
+var ErrNoAtomicCard = errors.New("card: failed to find atomic card with specified name")
+var ErrAtomicBuildFailed = errors.New("card: Operation failed. Failed to build the atomic card catalog")
+
+/*
+AtomicFace The oracle data of a single face of an atomic card. Single faced cards have exactly one face
+*/
+type AtomicFace struct {
+	FaceName       string   `bson:"faceName,omitempty" json:"faceName,omitempty"`
+	Side           string   `bson:"side,omitempty" json:"side,omitempty"`
+	ManaCost       string   `bson:"manaCost" json:"manaCost"`
+	FaceManaValue  int64    `bson:"faceManaValue" json:"faceManaValue"`
+	Colors         []string `bson:"colors" json:"colors"`
+	ColorIndicator []string `bson:"colorIndicator,omitempty" json:"colorIndicator,omitempty"`
+	Type           string   `bson:"type" json:"type"`
+	Supertypes     []string `bson:"supertypes" json:"supertypes"`
+	Types          []string `bson:"types" json:"types"`
+	Subtypes       []string `bson:"subtypes" json:"subtypes"`
+	Text           string   `bson:"text" json:"text"`
+	Power          string   `bson:"power,omitempty" json:"power,omitempty"`
+	Toughness      string   `bson:"toughness,omitempty" json:"toughness,omitempty"`
+	Loyalty        string   `bson:"loyalty,omitempty" json:"loyalty,omitempty"`
+	Defense        string   `bson:"defense,omitempty" json:"defense,omitempty"`
+	Life           string   `bson:"life,omitempty" json:"life,omitempty"`
+	Hand           string   `bson:"hand,omitempty" json:"hand,omitempty"`
+	Keywords       []string `bson:"keywords" json:"keywords"`
+}
+
+/*
+AtomicCard The oracle data of a card that is shared across all of its printings, mirroring the entries of
+MTGJSON's AtomicCards file. Atomic cards are stored in the card_atomic collection keyed by name, and are
+derived from the printings in the card collection, see BuildAtomicCards
+*/
+type AtomicCard struct {
+	Name                    string                 `bson:"name" json:"name"`
+	AsciiName               string                 `bson:"asciiName,omitempty" json:"asciiName,omitempty"`
+	ScryfallOracleId        string                 `bson:"scryfallOracleId,omitempty" json:"scryfallOracleId,omitempty"`
+	Layout                  string                 `bson:"layout" json:"layout"`
+	ManaValue               int64                  `bson:"manaValue" json:"manaValue"`
+	ColorIdentity           []string               `bson:"colorIdentity" json:"colorIdentity"`
+	Faces                   []*AtomicFace          `bson:"faces" json:"faces"`
+	Legalities              *meta.CardLegalities   `bson:"legalities" json:"legalities"`
+	LeadershipSkills        *meta.LeadershipSkills `bson:"leadershipSkills,omitempty" json:"leadershipSkills,omitempty"`
+	Rulings                 []*meta.CardRulings    `bson:"rulings" json:"rulings"`
+	Printings               []string               `bson:"printings" json:"printings"`
+	FirstPrinting           string                 `bson:"firstPrinting,omitempty" json:"firstPrinting,omitempty"`
+	EdhrecRank              int64                  `bson:"edhrecRank,omitempty" json:"edhrecRank,omitempty"`
+	EdhrecSaltiness         float64                `bson:"edhrecSaltiness,omitempty" json:"edhrecSaltiness,omitempty"`
+	IsReserved              bool                   `bson:"isReserved" json:"isReserved"`
+	IsFunny                 bool                   `bson:"isFunny" json:"isFunny"`
+	HasAlternativeDeckLimit bool                   `bson:"hasAlternativeDeckLimit" json:"hasAlternativeDeckLimit"`
+}
+
+/*
+atomicRepository Returns a typed repository for the card_atomic collection. Missing atomic cards are reported
+as ErrNoAtomicCard
+*/
+func atomicRepository(ctx stdContext.Context) (*server.Repository[AtomicCard], error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.NewRepository[AtomicCard](database, ATOMIC_COLLECTION, ErrNoAtomicCard), nil
+}
+
+/*
+DeriveAtomicCard Build the atomic card of the faces passed in the parameter. Each face should be a single
+printing of the same card, with one entry per side for multi faced cards. Fields shared by every face, such
+as the legalities and rulings, are taken from the first face. Returns nil if no faces are passed
+*/
+func DeriveAtomicCard(faces []*card.CardSet) *AtomicCard {
+	if len(faces) == 0 {
+		return nil
+	}
+
+	faces = slices.Clone(faces)
+	slices.SortStableFunc(faces, func(a *card.CardSet, b *card.CardSet) int {
+		return cmp.Compare(a.Side, b.Side)
+	})
+
+	first := faces[0]
+	ret := &AtomicCard{
+		Name:                    first.Name,
+		AsciiName:               first.AsciiName,
+		Layout:                  first.Layout,
+		ManaValue:               first.ManaValue,
+		ColorIdentity:           first.ColorIdentity,
+		Faces:                   make([]*AtomicFace, 0, len(faces)),
+		Legalities:              first.Legalities,
+		LeadershipSkills:        first.LeadershipSkills,
+		Rulings:                 first.Rulings,
+		Printings:               first.Printings,
+		FirstPrinting:           first.FirstPrinting,
+		EdhrecRank:              first.EdhrecRank,
+		EdhrecSaltiness:         first.EdhrecSaltiness,
+		IsReserved:              first.IsReserved,
+		IsFunny:                 first.IsFunny,
+		HasAlternativeDeckLimit: first.HasAlternativeDeckLimit,
+	}
+
+	if first.Identifiers != nil {
+		ret.ScryfallOracleId = first.Identifiers.ScryfallOracleId
+	}
+
+	if ret.Rulings == nil {
+		ret.Rulings = []*meta.CardRulings{}
+	}
+
+	for _, face := range faces {
+		ret.Faces = append(ret.Faces, &AtomicFace{
+			FaceName:       face.FaceName,
+			Side:           face.Side,
+			ManaCost:       face.ManaCost,
+			FaceManaValue:  face.FaceManaValue,
+			Colors:         face.Colors,
+			ColorIndicator: face.ColorIndicator,
+			Type:           face.Type,
+			Supertypes:     face.Supertypes,
+			Types:          face.Types,
+			Subtypes:       face.Subtypes,
+			Text:           face.Text,
+			Power:          face.Power,
+			Toughness:      face.Toughness,
+			Loyalty:        face.Loyalty,
+			Defense:        face.Defense,
+			Life:           face.Life,
+			Hand:           face.Hand,
+			Keywords:       face.Keywords,
+		})
+	}
+
+	return ret
+}
+
+/*
+GetAtomicCard Returns the atomic card with the name passed in the parameter. Multi faced cards are named
+with both of their faces (e.g. "Fire // Ice"). Returns ErrNoAtomicCard if the card does not exist
+*/
+func GetAtomicCard(ctx stdContext.Context, name string) (*AtomicCard, error) {
+	repo, err := atomicRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.FindOne(ctx, bson.M{"name": name})
+}
+
+/*
+SearchAtomicCards Returns every atomic card matching the filter built by the query passed in the parameter,
+ordered by the sort fields of the query, if any. Fields within a face are matched with a dotted path (e.g.
+faces.text). If fields are passed, they are used as a projection. Returns query.ErrInvalidField if the
+filter is invalid
+*/
+func SearchAtomicCards(ctx stdContext.Context, filter *query.Query, fields ...string) ([]*AtomicCard, error) {
+	compiled, err := filter.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := atomicRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.FindManySorted(ctx, compiled, filter.SortFields(), fields...)
+}
+
+/*
+loadAtomicRulings Populate the rulings of the faces passed from the card_extra collection, in batches of
+ATOMIC_BATCH_SIZE, for when large fields are split. The other large fields are not loaded
+*/
+func loadAtomicRulings(ctx stdContext.Context, database server.DatabaseInterface, faces []*card.CardSet) error {
+	for batch := range slices.Chunk(faces, ATOMIC_BATCH_SIZE) {
+		var extras []*CardExtras
+
+		err := database.FindMultiple(ctx, "card_extra", "cardId", ExtractCardIds(batch), &extras, "cardId", "owner", "rulings")
+		if err != nil {
+			return err
+		}
+
+		byKey := map[extrasKey]*CardExtras{}
+		for _, value := range extras {
+			byKey[extrasKey{cardId: value.CardId, owner: value.Owner}] = value
+		}
+
+		for _, face := range batch {
+			key, ok := cardExtrasKey(face)
+			if !ok {
+				continue
+			}
+
+			if extra, ok := byKey[key]; ok {
+				face.Rulings = extra.Rulings
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+BuildAtomicCards Derive the atomic card of every card owned by the system user and write them to the
+card_atomic collection, replacing any atomic card with the same name. The card collection is streamed,
+keeping only the first printing of each face in memory, so this is safe to run against the full catalog
+after a sync. Returns the number of atomic cards written. Atomic cards whose printings have since been
+removed are left in place
+*/
+func BuildAtomicCards(ctx stdContext.Context) (int, error) {
+	repo, err := repository(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	faces := map[string]map[string]*card.CardSet{}
+	names := []string{}
+	firstFaces := []*card.CardSet{}
+
+	for printing, err := range repo.FindStream(ctx, bson.M{"mtgjsonApiMeta.owner": user.SystemUser}) {
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrAtomicBuildFailed, err)
+		}
+
+		if printing.Name == "" {
+			continue
+		}
+
+		sides, ok := faces[printing.Name]
+		if !ok {
+			sides = map[string]*card.CardSet{}
+			faces[printing.Name] = sides
+			names = append(names, printing.Name)
+		}
+
+		if _, ok := sides[printing.Side]; ok {
+			continue
+		}
+
+		// the large fields are not needed by the atomic card, and are loaded separately when split
+		printing.ForeignData, printing.PurchaseUrls = nil, nil
+		sides[printing.Side] = printing
+		firstFaces = append(firstFaces, printing)
+	}
+
+	if SplitLargeFields() {
+		err = loadAtomicRulings(ctx, repo.Database, firstFaces)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrAtomicBuildFailed, err)
+		}
+	}
+
+	slices.Sort(names)
+
+	written := 0
+	for _, name := range names {
+		sides := make([]*card.CardSet, 0, len(faces[name]))
+		for _, face := range faces[name] {
+			sides = append(sides, face)
+		}
+
+		_, err = repo.Database.Upsert(ctx, ATOMIC_COLLECTION, bson.M{"name": name}, DeriveAtomicCard(sides))
+		if err != nil {
+			return written, fmt.Errorf("%w: %w", ErrAtomicBuildFailed, err)
+		}
+
+		written++
+	}
+
+	context.GetLogger().Info("Built atomic card catalog", "cards", written)
+
+	return written, nil
+}
@@ -0,0 +1,211 @@
+package card
+
+import (
+	"cmp"
+	stdContext "context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	DEFAULT_AUTOCOMPLETE_LIMIT  = 10
+	AUTOCOMPLETE_MIN_SIMILARITY = 0.5
+)
+
+/*
+nameEntry A single card name, along with the trigrams used to compare it against the input of Autocomplete
+*/
+type nameEntry struct {
+	name     string
+	lower    string
+	trigrams map[string]struct{}
+}
+
+/*
+nameCache The distinct names of the system cards of the database they were loaded from. The cache is dropped whenever a card
+is invalidated, see invalidation.Subscribe
+*/
+var (
+	nameCache     []*nameEntry
+	nameCacheFrom server.DatabaseInterface
+	nameCacheLock sync.Mutex
+	nameCacheOnce sync.Once
+)
+
+/*
+trigrams Return the set of trigrams of the text passed. Each word is lowercased and padded with two spaces at
+the start and one at the end, so that the beginning of a word weighs more than its end. Characters other than
+letters and digits separate words
+*/
+func trigrams(text string) map[string]struct{} {
+	ret := map[string]struct{}{}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			ret[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+
+	return ret
+}
+
+/*
+similarity Return the fraction of the trigrams of the input that are also trigrams of the name, and the
+Jaccard similarity of the two sets. The first is used to rank names, as it is not reduced by the part of
+a name that has not been typed yet
+*/
+func similarity(input map[string]struct{}, name map[string]struct{}) (float64, float64) {
+	if len(input) == 0 {
+		return 0, 0
+	}
+
+	shared := 0
+	for trigram := range input {
+		if _, ok := name[trigram]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(input)), float64(shared) / float64(len(input)+len(name)-shared)
+}
+
+/*
+cardNames Return the distinct names of the cards owned by the system user, loading them on first use or after
+the cache has been invalidated. The names of cards created by other users are private to their owner, and the
+cache is shared by every caller, so they are never loaded
+*/
+func cardNames(ctx stdContext.Context) ([]*nameEntry, error) {
+	nameCacheOnce.Do(func() {
+		invalidation.Subscribe(func(event *invalidation.Event) {
+			if event.Kind != invalidation.KindCard {
+				return
+			}
+
+			nameCacheLock.Lock()
+			defer nameCacheLock.Unlock()
+
+			nameCache = nil
+		})
+	})
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nameCacheLock.Lock()
+	defer nameCacheLock.Unlock()
+
+	if nameCache != nil && nameCacheFrom == database {
+		return nameCache, nil
+	}
+
+	names, err := database.Distinct(ctx, "card", "name", bson.M{"mtgjsonApiMeta.owner": user.SystemUser})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*nameEntry, 0, len(names))
+	for _, value := range names {
+		name, ok := value.(string)
+		if !ok || name == "" {
+			continue
+		}
+
+		ret = append(ret, &nameEntry{name: name, lower: strings.ToLower(name), trigrams: trigrams(name)})
+	}
+
+	nameCache, nameCacheFrom = ret, database
+
+	return ret, nil
+}
+
+/*
+Autocomplete Returns up to 'limit' card names that best match the partial or misspelled input passed in the
+parameter, for use in search-as-you-type. Names are compared using trigrams, so "lightnin bolt" and
+"lightening bolt" both suggest "Lightning Bolt". Names starting with the input are ranked first, followed by
+the names sharing the largest fraction of the trigrams of the input, and names sharing less than
+AUTOCOMPLETE_MIN_SIMILARITY are left out. Only the names of system cards are suggested, see cardNames. The
+distinct card names are cached in memory, and the cache is
+dropped whenever a card is invalidated. If limit is 0, DEFAULT_AUTOCOMPLETE_LIMIT is used
+*/
+func Autocomplete(ctx stdContext.Context, input string, limit int) ([]string, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return []string{}, nil
+	}
+
+	if limit <= 0 {
+		limit = DEFAULT_AUTOCOMPLETE_LIMIT
+	}
+
+	names, err := cardNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("card: Failed to load card names: %w", err)
+	}
+
+	type suggestion struct {
+		entry       *nameEntry
+		prefix      bool
+		containment float64
+		jaccard     float64
+	}
+
+	inputTrigrams := trigrams(input)
+	suggestions := []suggestion{}
+
+	for _, entry := range names {
+		prefix := strings.HasPrefix(entry.lower, input)
+		containment, jaccard := similarity(inputTrigrams, entry.trigrams)
+		if !prefix && containment < AUTOCOMPLETE_MIN_SIMILARITY {
+			continue
+		}
+
+		suggestions = append(suggestions, suggestion{entry: entry, prefix: prefix, containment: containment, jaccard: jaccard})
+	}
+
+	slices.SortFunc(suggestions, func(a suggestion, b suggestion) int {
+		if a.prefix != b.prefix {
+			if a.prefix {
+				return -1
+			}
+
+			return 1
+		}
+
+		if a.containment != b.containment {
+			return cmp.Compare(b.containment, a.containment)
+		}
+
+		if a.jaccard != b.jaccard {
+			return cmp.Compare(b.jaccard, a.jaccard)
+		}
+
+		return cmp.Compare(a.entry.name, b.entry.name)
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	ret := make([]string, 0, len(suggestions))
+	for _, value := range suggestions {
+		ret = append(ret, value.entry.name)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,65 @@
+package card
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+/*
+trigramSet Returns a set of the trigrams passed
+*/
+func trigramSet(values ...string) map[string]struct{} {
+	ret := map[string]struct{}{}
+	for _, value := range values {
+		ret[value] = struct{}{}
+	}
+
+	return ret
+}
+
+func TestTrigrams(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want map[string]struct{}
+	}{
+		{"empty", "", trigramSet()},
+		{"single word", "Bolt", trigramSet("  b", " bo", "bol", "olt", "lt ")},
+		{"short word", "Ox", trigramSet("  o", " ox", "ox ")},
+		{"punctuation separates words", "Ob-Ox", trigramSet("  o", " ob", "ob ", " ox", "ox ")},
+		{"repeated trigrams are counted once", "aa aa", trigramSet("  a", " aa", "aa ")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := trigrams(test.text); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("trigrams() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		candidate   string
+		wantShared  float64
+		wantJaccard float64
+	}{
+		{"empty input", "", "Lightning Bolt", 0, 0},
+		{"identical", "Lightning Bolt", "lightning bolt", 1, 1},
+		{"prefix", "light", "Lightning Bolt", 5.0 / 6.0, 5.0 / 16.0},
+		{"unrelated", "zzz", "Lightning Bolt", 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shared, jaccard := similarity(trigrams(test.input), trigrams(test.candidate))
+			if math.Abs(shared-test.wantShared) > 1e-9 || math.Abs(jaccard-test.wantJaccard) > 1e-9 {
+				t.Errorf("similarity() = %v, %v, want %v, %v", shared, jaccard, test.wantShared, test.wantJaccard)
+			}
+		})
+	}
+}
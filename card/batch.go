@@ -0,0 +1,110 @@
+package card
+
+import (
+	stdContext "context"
+	"github.com/stevezaluk/mtgjson-models/card"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+NewCards Insert a batch of cards into the MongoDB database using a single InsertMany call, which is
+significantly faster than calling NewCard for each card when loading a full MTGJSON dump. Every card is
+validated the same way as NewCard, and the batch is rejected if any card is invalid, is duplicated within
+the batch, or already exists for the owner
+*/
+func NewCards(ctx stdContext.Context, cards []*card.CardSet, owner string) error {
+	if len(cards) == 0 {
+		return nil
+	}
+
+	if owner == "" {
+		owner = user.SystemUser
+	}
+
+	if owner != user.SystemUser {
+		_, err := user.GetUser(ctx, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, value := range cards {
+		if value.Identifiers == nil || value.Name == "" || value.Identifiers.MtgjsonV4Id == "" {
+			return sdkErrors.ErrCardMissingId
+		}
+
+		if seen[value.Identifiers.MtgjsonV4Id] {
+			return sdkErrors.ErrCardAlreadyExist
+		}
+
+		seen[value.Identifiers.MtgjsonV4Id] = true
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	var existing []*card.CardSet
+	query := bson.M{"identifiers.mtgjsonV4Id": bson.M{"$in": ExtractCardIds(cards)}, "mtgjsonApiMeta.owner": owner}
+	err = database.FindMany(ctx, "card", query, &existing)
+	if err != nil {
+		return err
+	}
+
+	if len(existing) != 0 {
+		return sdkErrors.ErrCardAlreadyExist
+	}
+
+	for _, value := range cards {
+		err = prepareCard(value, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	models := make([]interface{}, 0, len(cards))
+	for _, value := range cards {
+		models = append(models, value)
+	}
+
+	if !SplitLargeFields() {
+		_, err = database.InsertMany(ctx, "card", models)
+		return err
+	}
+
+	extras := make([]interface{}, 0, len(cards))
+	for _, value := range cards {
+		extras = append(extras, &CardExtras{
+			CardId:       value.Identifiers.MtgjsonV4Id,
+			Owner:        owner,
+			ForeignData:  value.ForeignData,
+			Rulings:      value.Rulings,
+			PurchaseUrls: value.PurchaseUrls,
+		})
+	}
+
+	_, err = database.InsertMany(ctx, "card_extra", extras)
+	if err != nil {
+		return err
+	}
+
+	stripped := make([]*CardExtras, len(cards))
+	for i, value := range cards {
+		stripped[i] = &CardExtras{ForeignData: value.ForeignData, Rulings: value.Rulings, PurchaseUrls: value.PurchaseUrls}
+		value.ForeignData, value.Rulings, value.PurchaseUrls = []*meta.ForeignData{}, []*meta.CardRulings{}, &meta.PurchaseUrls{}
+	}
+
+	_, err = database.InsertMany(ctx, "card", models)
+
+	for i, value := range cards {
+		value.ForeignData, value.Rulings, value.PurchaseUrls = stripped[i].ForeignData, stripped[i].Rulings, stripped[i].PurchaseUrls
+	}
+
+	return err
+}
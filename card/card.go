@@ -1,17 +1,27 @@
 package card
 
 import (
+	stdContext "context"
 	"errors"
+	"fmt"
 	"github.com/stevezaluk/mtgjson-models/meta"
 	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/metrics"
+	"github.com/stevezaluk/mtgjson-sdk/query"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"github.com/stevezaluk/mtgjson-sdk/server/tracing"
 	"github.com/stevezaluk/mtgjson-sdk/user"
 	"github.com/stevezaluk/mtgjson-sdk/util"
+	"iter"
 	"regexp"
 	"slices"
 
 	"github.com/stevezaluk/mtgjson-models/card"
 	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -22,6 +32,18 @@ var (
 	UUIDRegex = regexp.MustCompile(UUIDRegexPattern)
 )
 
+/*
+repository Returns a typed repository for the card collection. Missing cards are reported as ErrNoCard
+*/
+func repository(ctx stdContext.Context) (*server.Repository[card.CardSet], error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.NewRepository[card.CardSet](database, "card", sdkErrors.ErrNoCard), nil
+}
+
 /*
 ValidateUUID Validates that the string passed in the argument is a Version 4 UUID. Returns true
 if validation passes, false otherwise
@@ -40,16 +62,25 @@ func ValidateUUID(uuid string) bool {
 ValidateCards Takes a list of strings representing MTGJSONv4 UUID's and ensures that they are both
 valid and exist. Returns 3 variables: an error, and two lists of strings.
 */
-func ValidateCards(uuids []string) (error, []string, []string) {
+func ValidateCards(ctx stdContext.Context, uuids []string) (error, []string, []string) {
 	var invalidCards []string // cards that failed UUID validation
 	var noExistCards []string // cards that do not exist in Mongo
 
-	cards, err := GetCards(uuids)
+	ctx, span := tracing.Start(ctx, "card.ValidateCards", attribute.Int("cards", len(uuids)))
+
+	database, err := context.GetDatabase(ctx)
 	if err != nil {
+		tracing.End(span, err)
 		return err, invalidCards, noExistCards
 	}
 
-	cardUuids := ExtractCardIds(cards)
+	cardUuids, err := database.ExistsMany(ctx, "card", "identifiers.mtgjsonV4Id", uuids)
+	if err != nil {
+		tracing.End(span, err)
+		return err, invalidCards, noExistCards
+	}
+
+	metrics.Add(metrics.CardValidated, int64(len(uuids)))
 
 	for _, uuid := range uuids {
 		isValidUUID := ValidateUUID(uuid)
@@ -63,6 +94,12 @@ func ValidateCards(uuids []string) (error, []string, []string) {
 		}
 	}
 
+	metrics.Add(metrics.CardValidationInvalid, int64(len(invalidCards)))
+	metrics.Add(metrics.CardValidationMissing, int64(len(noExistCards)))
+
+	span.SetAttributes(attribute.Int("cards.invalid", len(invalidCards)), attribute.Int("cards.missing", len(noExistCards)))
+	tracing.End(span, nil)
+
 	return nil, invalidCards, noExistCards
 }
 
@@ -83,20 +120,58 @@ func ExtractCardIds(cards []*card.CardSet) []string {
 	return ret
 }
 
+/*
+ListingFields A projection that omits the large rulings, foreignData and purchaseUrls fields of a card. Pass
+it to GetCards when the cards are only being listed, for example in the contents of a deck
+*/
+var ListingFields = []string{"-rulings", "-foreignData", "-purchaseUrls"}
+
 /*
 GetCards Takes a list of strings representing MTGJSONv4 UUID's and returns a list of card models
-representing them. Change this to process all cards in a single database call
+representing them. If fields are passed, they are used as a projection so that only the fields
+needed are fetched (see server.Projection)
 */
-func GetCards(cards []string) ([]*card.CardSet, error) {
+func GetCards(ctx stdContext.Context, cards []string, fields ...string) ([]*card.CardSet, error) {
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := repo.FindMany(ctx, bson.M{"identifiers.mtgjsonV4Id": bson.M{"$in": cards}}, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, nil
+}
+
+/*
+ResolveCards Takes a list of card identifiers, each of which may be an MTGJSONv4 UUID, a Scryfall id or a card
+name, and returns every card matching any of them in a single database call. Cards that share a name are all
+returned
+*/
+func ResolveCards(ctx stdContext.Context, identifiers []string, fields ...string) ([]*card.CardSet, error) {
 	var ret []*card.CardSet
 
-	var database = context.GetDatabase()
+	if len(identifiers) == 0 {
+		return ret, nil
+	}
 
-	err := database.FindMultiple("card", "identifiers.mtgjsonV4Id", cards, &ret)
-	if !err {
-		return nil, sdkErrors.ErrNoCards
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	keys := []string{"identifiers.mtgjsonV4Id", "identifiers.scryfallId", "name"}
+	err = database.FindMultipleKeys(ctx, "card", keys, identifiers, &ret, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
 	return ret, nil
 }
 
@@ -104,33 +179,38 @@ func GetCards(cards []string) ([]*card.CardSet, error) {
 GetCard Takes a single string representing an MTGJSONv4 UUID and return a card model
 for it
 */
-func GetCard(uuid string, owner string) (*card.CardSet, error) {
-	var result card.CardSet
-
+func GetCard(ctx stdContext.Context, uuid string, owner string) (*card.CardSet, error) {
 	if !ValidateUUID(uuid) {
-		return &result, sdkErrors.ErrInvalidUUID
+		return &card.CardSet{}, sdkErrors.ErrInvalidUUID
 	}
 
-	var database = context.GetDatabase()
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	query := bson.M{"identifiers.mtgjsonV4Id": uuid}
 	if owner != "" {
 		query = bson.M{"identifiers.mtgjsonV4Id": uuid, "mtgjsonApiMeta.owner": owner}
 	}
 
-	err := database.Find("card", query, &result)
-	if !err {
-		return nil, sdkErrors.ErrNoCard
+	ret, err := repo.FindOne(ctx, query)
+	if err != nil {
+		return nil, err
 	}
 
-	return &result, nil
+	serverMetrics.AddCardsServed(1)
+
+	return ret, nil
 }
 
 /*
 NewCard Insert a new card in the form of a model into the MongoDB database. The card model must have a
-valid name and MTGJSONv4 ID, additionally, the card cannot already exist under the same ID
+valid name and MTGJSONv4 ID, additionally, the card cannot already exist under the same ID. This includes cards
+that were soft deleted, as restoring the deleted card would leave two cards under the same ID, in which case ErrCardAlreadyExist is
+returned wrapping ErrCardDeleted. Cards created by a user are validated with ValidateForeignData and ValidateCard
 */
-func NewCard(card *card.CardSet, owner string) error {
+func NewCard(ctx stdContext.Context, card *card.CardSet, owner string) error {
 	if card.Identifiers == nil {
 		return sdkErrors.ErrCardMissingId
 	}
@@ -145,17 +225,220 @@ func NewCard(card *card.CardSet, owner string) error {
 	}
 
 	if owner != user.SystemUser {
-		_, err := user.GetUser(owner)
+		_, err := user.GetUser(ctx, owner)
 		if err != nil {
 			return err
 		}
 	}
 
-	_, err := GetCard(cardId, owner)
-	if !errors.Is(err, sdkErrors.ErrNoCard) {
+	_, err := GetCard(ctx, cardId, owner)
+	if err == nil {
 		return sdkErrors.ErrCardAlreadyExist
 	}
 
+	if !errors.Is(err, sdkErrors.ErrNoCard) {
+		return err
+	}
+
+	deleted, err := deletedCardExists(ctx, cardId, owner)
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrCardAlreadyExist, ErrCardDeleted)
+	}
+
+	err = prepareCard(card, owner)
+	if err != nil {
+		return err
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !SplitLargeFields() {
+		_, err = database.Insert(ctx, "card", &card)
+		return err
+	}
+
+	err = newExtras(ctx, card)
+	if err != nil {
+		return err
+	}
+
+	foreignData, rulings, purchaseUrls := card.ForeignData, card.Rulings, card.PurchaseUrls
+	card.ForeignData, card.Rulings, card.PurchaseUrls = []*meta.ForeignData{}, []*meta.CardRulings{}, &meta.PurchaseUrls{}
+
+	_, err = database.Insert(ctx, "card", &card)
+
+	card.ForeignData, card.Rulings, card.PurchaseUrls = foreignData, rulings, purchaseUrls
+
+	return err
+}
+
+/*
+UpsertCard Create the card passed in the parameter, or replace it if a card with the same MTGJSONv4 ID
+already exists for the owner. This does not require the existence check performed by NewCard, so sync
+jobs can call it repeatedly with the same card. The API metadata of a replaced card is regenerated, and the
+version it replaces is recorded in the history of the card (see GetHistory). Returns ErrCardUpdateFailed
+wrapping ErrCardDeleted if the card was soft deleted
+*/
+func UpsertCard(ctx stdContext.Context, card *card.CardSet, owner string) error {
+	if card.Identifiers == nil || card.Name == "" || card.Identifiers.MtgjsonV4Id == "" {
+		return sdkErrors.ErrCardMissingId
+	}
+
+	if owner == "" {
+		owner = user.SystemUser
+	}
+
+	if owner != user.SystemUser {
+		_, err := user.GetUser(ctx, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := prepareCard(card, owner)
+	if err != nil {
+		return err
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	cardId := card.Identifiers.MtgjsonV4Id
+	query := bson.M{"identifiers.mtgjsonV4Id": cardId, "mtgjsonApiMeta.owner": owner}
+
+	err = withRevision(ctx, cardId, owner, RevisionUpsert, func(ctx stdContext.Context) error {
+		if !SplitLargeFields() {
+			_, err := database.Upsert(ctx, "card", query, card)
+
+			return err
+		}
+
+		extras := &CardExtras{
+			CardId:       cardId,
+			Owner:        owner,
+			ForeignData:  card.ForeignData,
+			Rulings:      card.Rulings,
+			PurchaseUrls: card.PurchaseUrls,
+		}
+
+		_, err := database.Upsert(ctx, "card_extra", extrasQuery(cardId, owner), extras)
+		if err != nil {
+			return err
+		}
+
+		foreignData, rulings, purchaseUrls := card.ForeignData, card.Rulings, card.PurchaseUrls
+		card.ForeignData, card.Rulings, card.PurchaseUrls = []*meta.ForeignData{}, []*meta.CardRulings{}, &meta.PurchaseUrls{}
+
+		_, err = database.Upsert(ctx, "card", query, card)
+
+		card.ForeignData, card.Rulings, card.PurchaseUrls = foreignData, rulings, purchaseUrls
+
+		return err
+	})
+	if errors.Is(err, server.ErrDeleted) {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, ErrCardDeleted)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindCard, cardId)
+
+	return nil
+}
+
+/*
+ReplaceCard Replace an existing card in the database with the card model passed in the parameter. The card
+is located using its MTGJSONv4 ID and the owner stored in its API metadata, and is only replaced if it has not
+been modified since the model was read, which is checked using the modified date of its API metadata. The
+modified date is updated on success, and the version replaced is recorded in the history of the card (see
+GetHistory). Returns ErrCardUpdateFailed if the card cannot be located, wrapping
+server.ErrConflict if another writer modified the card first
+*/
+func ReplaceCard(ctx stdContext.Context, card *card.CardSet) error {
+	return replaceCard(ctx, card, RevisionReplace)
+}
+
+/*
+replaceCard Replace an existing card as described by ReplaceCard, recording the version it replaces as a
+revision of the card with the reason passed
+*/
+func replaceCard(ctx stdContext.Context, card *card.CardSet, reason string) error {
+	if card.Identifiers == nil || card.Identifiers.MtgjsonV4Id == "" {
+		return sdkErrors.ErrCardMissingId
+	}
+
+	if card.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
+	}
+
+	repo, err := repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	cardId := card.Identifiers.MtgjsonV4Id
+	query := bson.M{"identifiers.mtgjsonV4Id": cardId, "mtgjsonApiMeta.owner": card.MtgjsonApiMeta.Owner}
+
+	expected := card.MtgjsonApiMeta.ModifiedDate
+	card.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr()
+
+	err = withRevision(ctx, cardId, card.MtgjsonApiMeta.Owner, reason, func(ctx stdContext.Context) error {
+		foreignData, rulings, purchaseUrls := card.ForeignData, card.Rulings, card.PurchaseUrls
+		if SplitLargeFields() {
+			card.ForeignData, card.Rulings, card.PurchaseUrls = []*meta.ForeignData{}, []*meta.CardRulings{}, &meta.PurchaseUrls{}
+		}
+
+		err := repo.ReplaceVersion(ctx, query, server.VersionField, expected, card)
+
+		card.ForeignData, card.Rulings, card.PurchaseUrls = foreignData, rulings, purchaseUrls
+
+		if err != nil {
+			return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+		}
+
+		if SplitLargeFields() {
+			extras := &CardExtras{
+				CardId:       cardId,
+				Owner:        card.MtgjsonApiMeta.Owner,
+				ForeignData:  foreignData,
+				Rulings:      rulings,
+				PurchaseUrls: purchaseUrls,
+			}
+
+			_, err = repo.Database.Upsert(ctx, "card_extra", extrasQuery(cardId, card.MtgjsonApiMeta.Owner), extras)
+			if err != nil {
+				return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		card.MtgjsonApiMeta.ModifiedDate = expected
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindCard, cardId)
+
+	return nil
+}
+
+/*
+prepareCard Fill the empty fields of a card model with their default values and assign its API metadata.
+The foreignData of cards created by a user is validated with ValidateForeignData
+*/
+func prepareCard(card *card.CardSet, owner string) error {
 	if card.LeadershipSkills == nil {
 		card.LeadershipSkills = &meta.LeadershipSkills{}
 	}
@@ -191,6 +474,20 @@ func NewCard(card *card.CardSet, owner string) error {
 		card.ForeignData = []*meta.ForeignData{}
 	}
 
+	if owner != user.SystemUser {
+		err := ValidateForeignData(card.ForeignData)
+		if err != nil {
+			metrics.Add(metrics.CardCreateRejected, 1)
+			return err
+		}
+
+		err = ValidateCard(card)
+		if err != nil {
+			metrics.Add(metrics.CardCreateRejected, 1)
+			return err
+		}
+	}
+
 	currentDate := util.CreateTimestampStr()
 	card.MtgjsonApiMeta = &meta.MTGJSONAPIMeta{
 		Owner:        owner,
@@ -200,9 +497,6 @@ func NewCard(card *card.CardSet, owner string) error {
 		ModifiedDate: currentDate,
 	}
 
-	var database = context.GetDatabase()
-	database.Insert("card", &card)
-
 	return nil
 }
 
@@ -211,39 +505,134 @@ DeleteCard Remove a card from the MongoDB database. The UUID passed in the param
 ErrNoCard will be returned if no card exists under the passed UUID, and ErrCardDeleteFailed will be returned
 if the deleted count does not equal 1
 */
-func DeleteCard(uuid string, owner string) error {
-	var database = context.GetDatabase()
+func DeleteCard(ctx stdContext.Context, uuid string, owner string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
 
-	query := bson.M{"identifiers.mtgjsonV4Id": uuid}
-	if owner != "" {
-		query = bson.M{"identifiers.mtgjsonV4Id": uuid, "mtgjsonApiMeta.owner": owner}
+	// the owner is resolved so that only the extras of the deleted card are removed with it
+	if owner == "" {
+		owner, err = cardOwner(ctx, database, cardQuery(uuid, ""))
+		if err != nil {
+			return err
+		}
 	}
-	result, err := database.Delete("card", query)
-	if !err {
+
+	_, err = database.Delete(ctx, "card", cardQuery(uuid, owner))
+	if errors.Is(err, server.ErrNotFound) {
 		return sdkErrors.ErrNoCard
 	}
 
-	if result.DeletedCount < 1 {
-		return sdkErrors.ErrCardDeleteFailed
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrCardDeleteFailed, err)
 	}
 
+	invalidation.Publish(ctx, invalidation.KindCard, uuid)
+
+	deleteExtras(ctx, uuid, owner)
+
 	return nil
 }
 
+/*
+SearchCards Returns every card matching the filter built by the query passed in the parameter. If fields
+are passed, they are used as a projection. The cards are ordered by the sort fields of the query, if any.
+Returns query.ErrInvalidField if the filter is invalid
+*/
+func SearchCards(ctx stdContext.Context, filter *query.Query, fields ...string) ([]*card.CardSet, error) {
+	compiled, err := filter.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := repo.FindManySorted(ctx, compiled, filter.SortFields(), fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, nil
+}
+
 /*
 IndexCards Returns all cards in the database unmarshalled as card models. The limit parameter
-will be passed directly to the database query to limit the number of models returned
+will be passed directly to the database query to limit the number of models returned. Sort fields
+can be passed to order the cards before the limit is applied (e.g. "name"), see server.SortBy
 */
-func IndexCards(limit int64) ([]*card.CardSet, error) {
+func IndexCards(ctx stdContext.Context, limit int64, sort ...string) ([]*card.CardSet, error) {
 	var result []*card.CardSet
 
-	var database = context.GetDatabase()
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	err := database.Index("card", limit, &result)
-	if !err {
-		return nil, sdkErrors.ErrNoCards
+	err = database.Index(ctx, "card", limit, &result, sort...)
+	if err != nil {
+		return nil, err
 	}
 
 	return result, nil
+}
+
+/*
+PageCards Returns a single page of cards from the database. Pass the NextCursor of the returned page in
+the options to fetch the next page. Large collections should be paged through this way rather than
+with IndexCards, which is limited to a single capped query
+*/
+func PageCards(ctx stdContext.Context, opts *server.PageOptions) ([]*card.CardSet, *server.Page, error) {
+	var result []*card.CardSet
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, err := database.Paginate(ctx, "card", bson.M{}, opts, &result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, page, nil
+}
+
+/*
+StreamCards Returns an iterator over every card in the database, read from a cursor as the iterator is consumed
+so that exporters and sync jobs can process the entire collection with constant memory. If fields are passed,
+they are used as a projection. Any error is yielded as the final value of the iterator
+*/
+func StreamCards(ctx stdContext.Context, fields ...string) iter.Seq2[*card.CardSet, error] {
+	repo, err := repository(ctx)
+	if err != nil {
+		return func(yield func(*card.CardSet, error) bool) {
+			yield(nil, err)
+		}
+	}
+
+	return repo.FindStream(ctx, bson.M{}, fields...)
+}
+
+/*
+CountCards Returns the number of cards in the database owned by the user passed in the parameter, for use in
+pagination metadata. If owner is an empty string, the estimated size of the whole collection is returned
+instead, which does not require a collection scan
+*/
+func CountCards(ctx stdContext.Context, owner string) (int64, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if owner == "" {
+		return database.EstimatedCount(ctx, "card")
+	}
 
+	return database.Count(ctx, "card", bson.M{"mtgjsonApiMeta.owner": owner})
 }
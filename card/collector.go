@@ -0,0 +1,117 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	COLLECTOR_STAR = "★"
+)
+
+/*
+promoSuffixRegex Matches collector numbers with a promo suffix, such as the prerelease (s) and promo pack (p)
+stamped printings that MTGJSON stores in the promo set of the set they were released with
+*/
+var promoSuffixRegex = regexp.MustCompile(`^[0-9]+[sp]$`)
+
+/*
+digitsRegex Matches collector numbers that are only made up of digits
+*/
+var digitsRegex = regexp.MustCompile(`^[0-9]+$`)
+
+/*
+collectorNumbers Return the spellings of the collector number passed that may be stored for it. Suffixes are
+stored in lowercase, and a star suffix is commonly typed as an asterisk, so both are tried
+*/
+func collectorNumbers(number string) []string {
+	ret := []string{number}
+
+	if lower := strings.ToLower(number); lower != number {
+		ret = append(ret, lower)
+	}
+
+	if strings.HasSuffix(number, "*") {
+		ret = append(ret, strings.TrimSuffix(number, "*")+COLLECTOR_STAR)
+	}
+
+	return ret
+}
+
+/*
+findCollectorNumber Return the first card matching the query, preferring the front face of multi faced
+cards. Returns ErrNoCard if no card matches
+*/
+func findCollectorNumber(ctx stdContext.Context, repo *server.Repository[card.CardSet], query bson.M) (*card.CardSet, error) {
+	ret, err := repo.FindManySorted(ctx, query, []string{"side", "number"})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ret) == 0 {
+		return nil, sdkErrors.ErrNoCard
+	}
+
+	return ret[0], nil
+}
+
+/*
+GetCardByCollectorNumber Returns the card printed in the set passed with the collector number passed, the
+natural key used by most paper collection tools. The set code is matched ignoring case. Promo suffixes are
+handled as follows: a star may be passed as either ★ or *, a number with a prerelease or promo pack suffix
+(e.g. 123s) is also looked up in the promo set of the set passed (e.g. PM19 for M19), and a number without a
+suffix matches the front face of a card whose faces are numbered separately (e.g. 45a). If database is nil,
+the database of the context passed is used. Returns ErrNoCard if no card matches
+*/
+func GetCardByCollectorNumber(ctx stdContext.Context, database server.DatabaseInterface, setCode string, number string) (*card.CardSet, error) {
+	setCode = strings.ToUpper(strings.TrimSpace(setCode))
+	number = strings.TrimSpace(number)
+	if setCode == "" || number == "" {
+		return nil, sdkErrors.ErrNoCard
+	}
+
+	if database == nil {
+		var err error
+
+		database, err = context.GetDatabase(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	repo := server.NewRepository[card.CardSet](database, "card", sdkErrors.ErrNoCard)
+
+	setCodes := []interface{}{setCode}
+	if promoSuffixRegex.MatchString(strings.ToLower(number)) {
+		setCodes = append(setCodes, "P"+setCode)
+	}
+
+	numbers := []interface{}{}
+	for _, value := range collectorNumbers(number) {
+		numbers = append(numbers, value)
+	}
+
+	ret, err := findCollectorNumber(ctx, repo, bson.M{"setCode": bson.M{"$in": setCodes}, "number": bson.M{"$in": numbers}})
+	if errors.Is(err, sdkErrors.ErrNoCard) && digitsRegex.MatchString(number) {
+		pattern := primitive.Regex{Pattern: "^" + number + "[a-z]$"}
+		ret, err = findCollectorNumber(ctx, repo, bson.M{"setCode": setCode, "number": pattern})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(1)
+
+	return ret, nil
+}
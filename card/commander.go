@@ -0,0 +1,125 @@
+package card
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+)
+
+const (
+	MAX_COMMANDERS = 2
+)
+
+/*
+hasKeyword Returns true if the card passed has the keyword passed, ignoring case. MTGJSON lists the keyword
+abilities of a card in its keywords, so the rules text is only checked if it has none
+*/
+func hasKeyword(card *card.CardSet, keyword string) bool {
+	if len(card.Keywords) == 0 {
+		return strings.Contains(strings.ToLower(card.Text), strings.ToLower(keyword))
+	}
+
+	return slices.ContainsFunc(card.Keywords, func(value string) bool {
+		return strings.EqualFold(value, keyword)
+	})
+}
+
+/*
+isBackground Returns true if the card passed is a Background, a legendary enchantment that may only be a
+commander alongside a creature that can choose a Background
+*/
+func isBackground(card *card.CardSet) bool {
+	return slices.Contains(card.Supertypes, "Legendary") && slices.Contains(card.Types, "Enchantment") &&
+		slices.Contains(card.Subtypes, "Background")
+}
+
+/*
+CanBeCommander Returns true if the card passed may be the commander of a Commander deck: a legendary creature,
+a card that reads "can be your commander", a card MTGJSON marks as able to lead a Commander deck in its
+leadership skills, or a Background. Backgrounds may only be a commander alongside a creature that can choose
+a Background, see CanBeCommanders
+*/
+func CanBeCommander(card *card.CardSet) bool {
+	if card.LeadershipSkills != nil && card.LeadershipSkills.Commander {
+		return true
+	}
+
+	if slices.Contains(card.Supertypes, "Legendary") && slices.Contains(card.Types, "Creature") {
+		return true
+	}
+
+	if strings.Contains(strings.ToLower(card.Text), "can be your commander") {
+		return true
+	}
+
+	return isBackground(card)
+}
+
+/*
+partners Returns true if the two cards passed may be commanders of the same deck. Both cards must have
+partner or friends forever, or each must be partnered with the other by name, or one must choose a Background
+that the other is, or one must be a Doctor's companion for the other, a Time Lord Doctor
+*/
+func partners(a *card.CardSet, b *card.CardSet) bool {
+	pair := func(first *card.CardSet, second *card.CardSet) bool {
+		if hasKeyword(first, "Partner with") {
+			return strings.Contains(first.Text, "Partner with "+second.Name)
+		}
+
+		if hasKeyword(first, "Partner") && hasKeyword(second, "Partner") && !hasKeyword(second, "Partner with") {
+			return true
+		}
+
+		if hasKeyword(first, "Friends forever") && hasKeyword(second, "Friends forever") {
+			return true
+		}
+
+		if hasKeyword(first, "Choose a Background") && isBackground(second) {
+			return true
+		}
+
+		return hasKeyword(first, "Doctor's companion") && slices.Contains(second.Subtypes, "Time Lord") &&
+			slices.Contains(second.Subtypes, "Doctor")
+	}
+
+	if hasKeyword(a, "Partner with") || hasKeyword(b, "Partner with") {
+		return pair(a, b) && pair(b, a)
+	}
+
+	return pair(a, b) || pair(b, a)
+}
+
+/*
+CanBeCommanders Returns true if the cards passed may together be the commanders of a Commander deck. A deck
+has either a single commander, which must not be a Background, or two commanders that can both be a commander
+and are allowed to be paired by partner, friends forever, choose a Background or Doctor's companion. Faces of
+the same card are counted as a single commander
+*/
+func CanBeCommanders(cards []*card.CardSet) bool {
+	var commanders []*card.CardSet
+	for _, value := range cards {
+		duplicate := slices.ContainsFunc(commanders, func(existing *card.CardSet) bool {
+			return existing.Name == value.Name
+		})
+
+		if !duplicate {
+			commanders = append(commanders, value)
+		}
+	}
+
+	for _, value := range commanders {
+		if !CanBeCommander(value) {
+			return false
+		}
+	}
+
+	switch len(commanders) {
+	case 1:
+		return !isBackground(commanders[0])
+	case MAX_COMMANDERS:
+		return partners(commanders[0], commanders[1])
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package card
+
+import (
+	"testing"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-models/meta"
+)
+
+/*
+legendaryCreature Returns a legendary creature with the name and keywords passed
+*/
+func legendaryCreature(name string, keywords ...string) *card.CardSet {
+	return &card.CardSet{
+		Name:       name,
+		Supertypes: []string{"Legendary"},
+		Types:      []string{"Creature"},
+		Keywords:   keywords,
+	}
+}
+
+func TestCanBeCommanders(t *testing.T) {
+	background := &card.CardSet{
+		Name:       "Raised by Giants",
+		Supertypes: []string{"Legendary"},
+		Types:      []string{"Enchantment"},
+		Subtypes:   []string{"Background"},
+	}
+
+	doctor := legendaryCreature("The Tenth Doctor")
+	doctor.Subtypes = []string{"Time Lord", "Doctor"}
+
+	pir := legendaryCreature("Pir, Imaginative Rascal", "Partner with")
+	pir.Text = "Partner with Toothy, Imaginary Friend"
+
+	toothy := legendaryCreature("Toothy, Imaginary Friend", "Partner with")
+	toothy.Text = "Partner with Pir, Imaginative Rascal"
+
+	tests := []struct {
+		name  string
+		cards []*card.CardSet
+		want  bool
+	}{
+		{"no commanders", nil, false},
+		{"legendary creature", []*card.CardSet{legendaryCreature("Atraxa, Praetors' Voice")}, true},
+		{"non-legendary creature", []*card.CardSet{{Name: "Llanowar Elves", Types: []string{"Creature"}}}, false},
+		{"can be your commander", []*card.CardSet{{Name: "Teferi, Temporal Archmage", Types: []string{"Planeswalker"}, Text: "Teferi, Temporal Archmage can be your commander."}}, true},
+		{"leadership skills", []*card.CardSet{{Name: "Grist, the Hunger Tide", LeadershipSkills: &meta.LeadershipSkills{Commander: true}}}, true},
+		{"background alone", []*card.CardSet{background}, false},
+		{"faces of the same card", []*card.CardSet{legendaryCreature("Esika, God of the Tree"), legendaryCreature("Esika, God of the Tree")}, true},
+		{"two without partner", []*card.CardSet{legendaryCreature("Atraxa, Praetors' Voice"), legendaryCreature("Edgar Markov")}, false},
+		{"partners", []*card.CardSet{legendaryCreature("Thrasios, Triton Hero", "Partner"), legendaryCreature("Tymna the Weaver", "Partner")}, true},
+		{"partner with a creature without partner", []*card.CardSet{legendaryCreature("Thrasios, Triton Hero", "Partner"), legendaryCreature("Edgar Markov")}, false},
+		{"friends forever", []*card.CardSet{legendaryCreature("Will the Wise", "Friends forever"), legendaryCreature("Lucas, the Sharpshooter", "Friends forever")}, true},
+		{"choose a background", []*card.CardSet{legendaryCreature("Wilson, Refined Grizzly", "Choose a Background"), background}, true},
+		{"doctor's companion", []*card.CardSet{legendaryCreature("Rose Tyler", "Doctor's companion"), doctor}, true},
+		{"partner with each other", []*card.CardSet{pir, toothy}, true},
+		{"partner with another card", []*card.CardSet{pir, legendaryCreature("Thrasios, Triton Hero", "Partner")}, false},
+		{"three commanders", []*card.CardSet{legendaryCreature("A", "Partner"), legendaryCreature("B", "Partner"), legendaryCreature("C", "Partner")}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CanBeCommanders(test.cards); got != test.want {
+				t.Errorf("CanBeCommanders() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
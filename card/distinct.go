@@ -0,0 +1,64 @@
+package card
+
+import (
+	stdContext "context"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+distinctStrings Return the sorted unique string values of a field across every card in the database. Values
+that are not strings, or are empty, are skipped
+*/
+func distinctStrings(ctx stdContext.Context, field string) ([]string, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := database.Distinct(ctx, "card", field, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok && str != "" {
+			ret = append(ret, str)
+		}
+	}
+
+	slices.Sort(ret)
+
+	return ret, nil
+}
+
+/*
+DistinctSetCodes Returns the set codes of every set that has at least one card in the database
+*/
+func DistinctSetCodes(ctx stdContext.Context) ([]string, error) {
+	return distinctStrings(ctx, "setCode")
+}
+
+/*
+DistinctArtists Returns the name of every artist credited on a card in the database
+*/
+func DistinctArtists(ctx stdContext.Context) ([]string, error) {
+	return distinctStrings(ctx, "artist")
+}
+
+/*
+DistinctTypes Returns every card type (Creature, Instant, etc) used by a card in the database
+*/
+func DistinctTypes(ctx stdContext.Context) ([]string, error) {
+	return distinctStrings(ctx, "types")
+}
+
+/*
+DistinctRarities Returns every rarity used by a card in the database
+*/
+func DistinctRarities(ctx stdContext.Context) ([]string, error) {
+	return distinctStrings(ctx, "rarity")
+}
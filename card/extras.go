@@ -0,0 +1,262 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/spf13/viper"
+	"github.com/stevezaluk/mtgjson-models/card"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+CardExtras The large, rarely used fields of a card. When 'card.split_large_fields' is enabled, these
+fields are stored in the card_extra collection instead of on the card document, keeping the documents
+returned by card lookups small. They can be loaded on demand with LoadExtras. Cards owned by different users
+may share an MTGJSONv4 ID, so extras are stored per card and owner
+*/
+type CardExtras struct {
+	CardId       string              `bson:"cardId" json:"cardId"`
+	Owner        string              `bson:"owner" json:"owner"`
+	ForeignData  []*meta.ForeignData `bson:"foreignData" json:"foreignData"`
+	Rulings      []*meta.CardRulings `bson:"rulings" json:"rulings"`
+	PurchaseUrls *meta.PurchaseUrls  `bson:"purchaseUrls" json:"purchaseUrls"`
+}
+
+/*
+SplitLargeFields Returns true if large card fields should be stored in the card_extra collection
+*/
+func SplitLargeFields() bool {
+	return viper.GetBool("card.split_large_fields")
+}
+
+/*
+extrasKey The card and owner that a CardExtras entry belongs to
+*/
+type extrasKey struct {
+	cardId string
+	owner  string
+}
+
+/*
+cardExtrasKey Returns the key of the extras of the card passed, and false if the card has no MTGJSONv4 ID
+*/
+func cardExtrasKey(card *card.CardSet) (extrasKey, bool) {
+	if card.Identifiers == nil {
+		return extrasKey{}, false
+	}
+
+	ret := extrasKey{cardId: card.Identifiers.MtgjsonV4Id}
+	if card.MtgjsonApiMeta != nil {
+		ret.owner = card.MtgjsonApiMeta.Owner
+	}
+
+	return ret, true
+}
+
+/*
+extrasQuery Returns the query locating the extras of the card with the uuid and owner passed. As with cardQuery,
+an empty owner matches the extras of any owner
+*/
+func extrasQuery(uuid string, owner string) bson.M {
+	ret := bson.M{"cardId": uuid}
+	if owner != "" {
+		ret["owner"] = owner
+	}
+
+	return ret
+}
+
+/*
+cardOwner Returns the owner of the first card matching the query passed, so that the extras of a card located
+without an owner can be found. Returns ErrNoCard if no card matches
+*/
+func cardOwner(ctx stdContext.Context, database server.DatabaseInterface, query bson.M) (string, error) {
+	var result struct {
+		MtgjsonApiMeta struct {
+			Owner string `bson:"owner"`
+		} `bson:"mtgjsonApiMeta"`
+	}
+
+	err := database.Find(ctx, "card", query, &result, "mtgjsonApiMeta.owner")
+	if errors.Is(err, server.ErrNotFound) {
+		return "", sdkErrors.ErrNoCard
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return result.MtgjsonApiMeta.Owner, nil
+}
+
+/*
+newExtras Store the large fields of the card passed in the card_extra collection. The card model is
+not modified
+*/
+func newExtras(ctx stdContext.Context, card *card.CardSet) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	extras := &CardExtras{
+		CardId:       card.Identifiers.MtgjsonV4Id,
+		Owner:        card.MtgjsonApiMeta.Owner,
+		ForeignData:  card.ForeignData,
+		Rulings:      card.Rulings,
+		PurchaseUrls: card.PurchaseUrls,
+	}
+
+	_, err = database.Insert(ctx, "card_extra", extras)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+/*
+deleteExtras Remove the large fields of a card from the card_extra collection. Cards that were inserted
+before large fields were split will not have an entry, so a failed delete is not treated as an error
+*/
+func deleteExtras(ctx stdContext.Context, uuid string, owner string) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return
+	}
+
+	database.Delete(ctx, "card_extra", extrasQuery(uuid, owner))
+}
+
+/*
+LoadExtras Populate the foreignData, rulings and purchaseUrls fields of the cards passed from the card_extra
+collection. Consumes a single database call regardless of the number of cards. Cards without an entry in
+card_extra are left unmodified
+*/
+func LoadExtras(ctx stdContext.Context, cards []*card.CardSet) error {
+	var extras []*CardExtras
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	uuids := ExtractCardIds(cards)
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	err = database.FindMultiple(ctx, "card_extra", "cardId", uuids, &extras)
+	if err != nil {
+		return err
+	}
+
+	mergeExtras(cards, extras)
+
+	return nil
+}
+
+/*
+mergeExtras Populate the foreignData, rulings and purchaseUrls fields of the cards passed from the extras passed.
+Extras are matched to cards by their MTGJSONv4 ID and owner. Cards without an entry in the extras are left
+unmodified
+*/
+func mergeExtras(cards []*card.CardSet, extras []*CardExtras) {
+	byKey := map[extrasKey]*CardExtras{}
+	for _, value := range extras {
+		byKey[extrasKey{cardId: value.CardId, owner: value.Owner}] = value
+	}
+
+	for _, value := range cards {
+		key, ok := cardExtrasKey(value)
+		if !ok {
+			continue
+		}
+
+		extra, ok := byKey[key]
+		if !ok {
+			continue
+		}
+
+		value.ForeignData = extra.ForeignData
+		value.Rulings = extra.Rulings
+		value.PurchaseUrls = extra.PurchaseUrls
+	}
+}
+
+/*
+extraFields Returns the projection of the card_extra collection that matches the projection of a card lookup
+passed, so that large fields excluded from the cards (e.g. with ListingFields) are not fetched from card_extra
+either. An empty projection is returned unmodified
+*/
+func extraFields(fields []string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	ret := []string{"cardId", "owner"}
+	for _, field := range []string{"foreignData", "rulings", "purchaseUrls"} {
+		if !slices.Contains(fields, "-"+field) {
+			ret = append(ret, field)
+		}
+	}
+
+	return ret
+}
+
+/*
+BackfillExtraOwners Set the owner of the card_extra entries that were stored before extras were kept per owner,
+using the owner of the card they belong to. Entries whose MTGJSONv4 ID is shared by the cards of several owners
+cannot be attributed, and are logged and left unmodified. Returns the number of entries updated
+*/
+func BackfillExtraOwners(ctx stdContext.Context) (int64, error) {
+	var extras []struct {
+		Id     primitive.ObjectID `bson:"_id"`
+		CardId string             `bson:"cardId"`
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = database.FindMany(ctx, "card_extra", bson.M{"owner": bson.M{"$exists": false}}, &extras, "_id", "cardId")
+	if err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	for _, extra := range extras {
+		owners, err := database.Distinct(ctx, "card", "mtgjsonApiMeta.owner", cardQuery(extra.CardId, ""))
+		if err != nil {
+			return updated, err
+		}
+
+		owner, ok := "", len(owners) == 1
+		if ok {
+			owner, ok = owners[0].(string)
+		}
+
+		if !ok {
+			slog.Warn("Unable to attribute card extras to a single owner", "uuid", extra.CardId, "owners", owners)
+			continue
+		}
+
+		_, err = database.SetField(ctx, "card_extra", bson.M{"_id": extra.Id}, bson.M{"owner": owner})
+		if err != nil {
+			return updated, err
+		}
+
+		updated++
+	}
+
+	return updated, nil
+}
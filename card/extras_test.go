@@ -0,0 +1,89 @@
+package card
+
+import (
+	stdContext "context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+ownedCard Returns a card with the uuid and owner passed, whose large fields are stored in card_extra
+*/
+func ownedCard(uuid string, owner string) *card.CardSet {
+	return &card.CardSet{
+		Name:           "Lightning Bolt",
+		Identifiers:    &meta.CardIdentifiers{MtgjsonV4Id: uuid},
+		MtgjsonApiMeta: &meta.MTGJSONAPIMeta{Owner: owner},
+	}
+}
+
+func TestExtrasPerOwner(t *testing.T) {
+	const uuid = "5f8287b1-5bb6-5f4c-ad17-316a40d5bb0c"
+
+	viper.Set("card.split_large_fields", true)
+	t.Cleanup(func() { viper.Set("card.split_large_fields", false) })
+
+	ctx := context.WithDatabase(stdContext.Background(), server.NewMemoryDatabase())
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+
+	for _, owner := range []string{"system", "player@example.com"} {
+		_, err = database.Insert(ctx, "card", ownedCard(uuid, owner))
+		if err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+
+		_, err = database.Insert(ctx, "card_extra", &CardExtras{CardId: uuid, Owner: owner, Rulings: []*meta.CardRulings{{Text: owner}}})
+		if err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	cards := []*card.CardSet{ownedCard(uuid, "player@example.com"), ownedCard(uuid, "system")}
+
+	err = LoadExtras(ctx, cards)
+	if err != nil {
+		t.Fatalf("LoadExtras() error = %v", err)
+	}
+
+	for _, value := range cards {
+		if len(value.Rulings) != 1 || value.Rulings[0].Text != value.MtgjsonApiMeta.Owner {
+			t.Errorf("rulings of the card owned by %s = %v, want its own rulings", value.MtgjsonApiMeta.Owner, value.Rulings)
+		}
+	}
+
+	err = DeleteCard(ctx, uuid, "player@example.com")
+	if err != nil {
+		t.Fatalf("DeleteCard() error = %v", err)
+	}
+
+	count, err := database.Count(ctx, "card_extra", bson.M{"cardId": uuid, "owner": "system"})
+	if err != nil || count != 1 {
+		t.Errorf("Count() of the extras of the system card = %d, %v, want 1", count, err)
+	}
+
+	// extras stored before they were kept per owner are attributed to the only owner of the card
+	_, err = database.Insert(ctx, "card_extra", bson.M{"cardId": uuid})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	updated, err := BackfillExtraOwners(ctx)
+	if err != nil || updated != 1 {
+		t.Fatalf("BackfillExtraOwners() = %d, %v, want 1", updated, err)
+	}
+
+	count, err = database.Count(ctx, "card_extra", bson.M{"cardId": uuid, "owner": "system"})
+	if err != nil || count != 2 {
+		t.Errorf("Count() of the extras of the system card = %d, %v, want 2", count, err)
+	}
+}
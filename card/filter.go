@@ -0,0 +1,174 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/query"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+)
+
+/*
+Colors The color codes used by MTGJSON, in WUBRG order
+*/
+var Colors = []string{"W", "U", "B", "R", "G"}
+
+var ErrInvalidColor = errors.New("card: Operation failed. Colors must be one of W, U, B, R or G")
+var ErrInvalidManaValueRange = errors.New("card: Operation failed. The minimum mana value must not be greater than the maximum")
+
+/*
+CardFilter Describes the cards returned by FilterCards. Empty fields are ignored, and the remaining fields
+are combined with AND.
+
+Colors, Types, Subtypes and Keywords match cards that have every value passed. ColorIdentity matches cards
+whose color identity is within the colors passed, as required when building a Commander deck, so colorless
+cards always match. Rarities and SetCodes match cards with any of the values passed. MinManaValue and
+MaxManaValue bound the mana value of the card, inclusively. Power and Toughness are stored as strings, as
+they may hold values such as "*" or "1+*", so they must match exactly
+*/
+type CardFilter struct {
+	Colors        []string
+	ColorIdentity []string
+	Types         []string
+	Subtypes      []string
+	Keywords      []string
+	Rarities      []string
+	SetCodes      []string
+	MinManaValue  *int64
+	MaxManaValue  *int64
+	Power         string
+	Toughness     string
+}
+
+/*
+toValues Convert a slice of strings into the variadic values accepted by the query builder
+*/
+func toValues(values []string) []interface{} {
+	ret := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		ret = append(ret, value)
+	}
+
+	return ret
+}
+
+/*
+validateColors Returns ErrInvalidColor if any of the colors passed is not a valid color code
+*/
+func validateColors(colors []string) error {
+	for _, color := range colors {
+		if !slices.Contains(Colors, color) {
+			return ErrInvalidColor
+		}
+	}
+
+	return nil
+}
+
+/*
+Query Compile the filter into a query that can be passed to SearchCards, or extended with further
+conditions. Returns ErrInvalidColor if a color is not a valid color code, or ErrInvalidManaValueRange if
+the mana value range is empty
+*/
+func (f *CardFilter) Query() (*query.Query, error) {
+	ret := query.New()
+	if f == nil {
+		return ret, nil
+	}
+
+	err := validateColors(f.Colors)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateColors(f.ColorIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.MinManaValue != nil && f.MaxManaValue != nil && *f.MinManaValue > *f.MaxManaValue {
+		return nil, ErrInvalidManaValueRange
+	}
+
+	for field, values := range map[string][]string{"colors": f.Colors, "types": f.Types, "subtypes": f.Subtypes, "keywords": f.Keywords} {
+		if len(values) != 0 {
+			ret.All(field, toValues(values)...)
+		}
+	}
+
+	if len(f.ColorIdentity) != 0 {
+		excluded := []string{}
+		for _, color := range Colors {
+			if !slices.Contains(f.ColorIdentity, color) {
+				excluded = append(excluded, color)
+			}
+		}
+
+		if len(excluded) != 0 {
+			ret.Nin("colorIdentity", toValues(excluded)...)
+		}
+	}
+
+	if len(f.Rarities) != 0 {
+		ret.In("rarity", toValues(f.Rarities)...)
+	}
+
+	if len(f.SetCodes) != 0 {
+		ret.In("setCode", toValues(f.SetCodes)...)
+	}
+
+	if f.MinManaValue != nil {
+		ret.Gte("manaValue", *f.MinManaValue)
+	}
+
+	if f.MaxManaValue != nil {
+		ret.Lte("manaValue", *f.MaxManaValue)
+	}
+
+	if f.Power != "" {
+		ret.Eq("power", f.Power)
+	}
+
+	if f.Toughness != "" {
+		ret.Eq("toughness", f.Toughness)
+	}
+
+	return ret, nil
+}
+
+/*
+FilterCards Returns a single page of the cards matching the filter passed in the parameter, see CardFilter.
+Pass the NextCursor of the returned page in the options to fetch the next page. A nil filter matches every
+card
+*/
+func FilterCards(ctx stdContext.Context, filter *CardFilter, opts *server.PageOptions) ([]*card.CardSet, *server.Page, error) {
+	built, err := filter.Query()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compiled, err := built.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ret []*card.CardSet
+
+	page, err := database.Paginate(ctx, "card", compiled, opts, &ret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, page, nil
+}
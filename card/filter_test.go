@@ -0,0 +1,100 @@
+package card
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCardFilterQuery(t *testing.T) {
+	one, three := int64(1), int64(3)
+
+	tests := []struct {
+		name   string
+		filter *CardFilter
+		want   bson.M
+	}{
+		{
+			name:   "nil filter",
+			filter: nil,
+			want:   bson.M{},
+		},
+		{
+			name:   "every value is required",
+			filter: &CardFilter{Colors: []string{"W", "U"}, Types: []string{"Creature"}},
+			want: bson.M{
+				"colors": bson.M{"$all": bson.A{"W", "U"}},
+				"types":  bson.M{"$all": bson.A{"Creature"}},
+			},
+		},
+		{
+			name:   "color identity excludes the other colors",
+			filter: &CardFilter{ColorIdentity: []string{"W", "U", "B"}},
+			want:   bson.M{"colorIdentity": bson.M{"$nin": bson.A{"R", "G"}}},
+		},
+		{
+			name:   "color identity of every color",
+			filter: &CardFilter{ColorIdentity: []string{"W", "U", "B", "R", "G"}},
+			want:   bson.M{},
+		},
+		{
+			name:   "any value matches",
+			filter: &CardFilter{Rarities: []string{"rare", "mythic"}, SetCodes: []string{"M10"}},
+			want: bson.M{
+				"rarity":  bson.M{"$in": bson.A{"rare", "mythic"}},
+				"setCode": bson.M{"$in": bson.A{"M10"}},
+			},
+		},
+		{
+			name:   "mana value range and stats",
+			filter: &CardFilter{MinManaValue: &one, MaxManaValue: &three, Power: "*"},
+			want: bson.M{
+				"manaValue": bson.M{"$gte": one, "$lte": three},
+				"power":     bson.M{"$eq": "*"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			built, err := test.filter.Query()
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+
+			got, err := built.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Query() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCardFilterQueryInvalid(t *testing.T) {
+	one, three := int64(1), int64(3)
+
+	tests := []struct {
+		name   string
+		filter *CardFilter
+		want   error
+	}{
+		{"invalid color", &CardFilter{Colors: []string{"P"}}, ErrInvalidColor},
+		{"invalid color identity", &CardFilter{ColorIdentity: []string{"w"}}, ErrInvalidColor},
+		{"empty mana value range", &CardFilter{MinManaValue: &three, MaxManaValue: &one}, ErrInvalidManaValueRange},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.filter.Query()
+			if !errors.Is(err, test.want) {
+				t.Errorf("Query() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+)
+
+var ErrInvalidLanguage = errors.New("card: Operation failed. The foreignData entry has an unsupported language")
+var ErrDuplicateLanguage = errors.New("card: Operation failed. The card has more than one foreignData entry for the same language")
+var ErrForeignDataMissingName = errors.New("card: Operation failed. A foreignData entry is missing a name")
+
+/*
+Languages The languages supported in foreignData, keyed by their language code. The values match the
+language names used by MTGJSON, which is what is stored on the card
+*/
+var Languages = map[string]string{
+	"en":  "English",
+	"de":  "German",
+	"es":  "Spanish",
+	"fr":  "French",
+	"it":  "Italian",
+	"ja":  "Japanese",
+	"ko":  "Korean",
+	"pt":  "Portuguese (Brazil)",
+	"ru":  "Russian",
+	"zhs": "Chinese Simplified",
+	"zht": "Chinese Traditional",
+	"he":  "Hebrew",
+	"la":  "Latin",
+	"grc": "Ancient Greek",
+	"ar":  "Arabic",
+	"sa":  "Sanskrit",
+	"ph":  "Phyrexian",
+}
+
+/*
+NormalizeLanguage Convert a language code or MTGJSON language name into the MTGJSON language name.
+Matching is case-insensitive. Returns ErrInvalidLanguage if the language is not supported
+*/
+func NormalizeLanguage(language string) (string, error) {
+	language = strings.TrimSpace(language)
+
+	name, ok := Languages[strings.ToLower(language)]
+	if ok {
+		return name, nil
+	}
+
+	for _, name := range Languages {
+		if strings.EqualFold(name, language) {
+			return name, nil
+		}
+	}
+
+	return "", ErrInvalidLanguage
+}
+
+/*
+ValidateForeignData Validate the foreignData entries supplied for a custom card. Each entry must have a
+name and a supported language, and a language may only appear once. Language codes are normalized to
+their MTGJSON language name in place
+*/
+func ValidateForeignData(foreignData []*meta.ForeignData) error {
+	seen := map[string]bool{}
+
+	for _, entry := range foreignData {
+		if entry.Name == "" {
+			return ErrForeignDataMissingName
+		}
+
+		language, err := NormalizeLanguage(entry.Language)
+		if err != nil {
+			return err
+		}
+
+		if seen[language] {
+			return ErrDuplicateLanguage
+		}
+
+		seen[language] = true
+		entry.Language = language
+	}
+
+	return nil
+}
+
+/*
+findByForeignData Return up to limit cards (0 for no limit) with a foreignData entry matching the $elemMatch
+condition passed. When large fields are split, the card_extra collection is searched instead, and the extras
+found are merged onto the cards, so the card_extra collection is only queried once. Fields are used as a
+projection of the cards, see extraFields
+*/
+func findByForeignData(ctx stdContext.Context, database server.DatabaseInterface, match bson.M, limit int64, fields ...string) ([]*card.CardSet, error) {
+	var ret []*card.CardSet
+
+	query := bson.M{"foreignData": bson.M{"$elemMatch": match}}
+
+	if !SplitLargeFields() {
+		err := database.FindManyLimited(ctx, "card", query, []string{"name"}, limit, &ret, fields...)
+		if err != nil {
+			return nil, err
+		}
+
+		return ret, nil
+	}
+
+	var extras []*CardExtras
+
+	err := database.FindManyLimited(ctx, "card_extra", query, []string{"cardId"}, limit, &extras, extraFields(fields)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(extras) == 0 {
+		return ret, nil
+	}
+
+	uuids := make([]string, 0, len(extras))
+	matched := map[extrasKey]bool{}
+	for _, extra := range extras {
+		uuids = append(uuids, extra.CardId)
+		matched[extrasKey{cardId: extra.CardId, owner: extra.Owner}] = true
+	}
+
+	var cards []*card.CardSet
+	err = database.FindMultiple(ctx, "card", "identifiers.mtgjsonV4Id", uuids, &cards, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	// copies of the cards owned by other users share the MTGJSONv4 ID, but not the extras that matched
+	for _, value := range cards {
+		if key, ok := cardExtrasKey(value); ok && matched[key] {
+			ret = append(ret, value)
+		}
+	}
+
+	mergeExtras(ret, extras)
+
+	return ret, nil
+}
+
+/*
+FindCardsByForeignName Return the cards with a foreignData entry whose name matches the name passed,
+ignoring case. If a language is passed, only entries in that language are matched. When large fields
+are split, the card_extra collection is searched instead and the extras are loaded onto the results
+*/
+func FindCardsByForeignName(ctx stdContext.Context, name string, language string) ([]*card.CardSet, error) {
+	pattern, err := namePattern(name, NameMatchExact, false)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.M{"name": pattern}
+
+	if language != "" {
+		normalized, err := NormalizeLanguage(language)
+		if err != nil {
+			return nil, err
+		}
+
+		match["language"] = normalized
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := findByForeignData(ctx, database, match, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ret) == 0 {
+		return nil, sdkErrors.ErrNoCards
+	}
+
+	return ret, nil
+}
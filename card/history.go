@@ -0,0 +1,322 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	REVISION_COLLECTION         = "card_revision"
+	REVISION_COUNTER_COLLECTION = "card_revision_counter"
+
+	RevisionReplace  = "replace"
+	RevisionUpsert   = "upsert"
+	RevisionUpdate   = "update"
+	RevisionRollback = "rollback"
+)
+
+var ErrNoCardRevision = errors.New("card: Failed to find the revision of the card with the specified UUID")
+var ErrRevisionFailed = errors.New("card: Operation failed. Failed to record the revision of the card")
+
+/*
+CardRevision A previous version of a card, recorded when the card was replaced with ReplaceCard or UpsertCard,
+patched with UpdateCard, or had its rulings changed. Revisions of a card are numbered from 1 in the order they
+were recorded, separately for each owner of the card, and the number is unique per card and owner. Numbers are
+taken from a counter (see REVISION_COUNTER_COLLECTION), so an edit that fails without a transaction can leave a
+gap in the numbering. Reason describes the
+edit that replaced this version (see RevisionReplace, RevisionUpdate and RevisionRollback), and Changes lists
+the top level fields that it changed. The large fields of the card are included in the version
+*/
+type CardRevision struct {
+	CardId      string        `bson:"cardId" json:"cardId"`
+	Owner       string        `bson:"owner" json:"owner"`
+	Revision    int64         `bson:"revision" json:"revision"`
+	Reason      string        `bson:"reason" json:"reason"`
+	Changes     []string      `bson:"changes" json:"changes"`
+	RevisedDate string        `bson:"revisedDate" json:"revisedDate"`
+	Card        *card.CardSet `bson:"card" json:"card"`
+}
+
+/*
+revisionRepository Returns a Repository for the card_revision collection, using the database of the
+context passed
+*/
+func revisionRepository(ctx stdContext.Context) (*server.Repository[CardRevision], error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.NewRepository[CardRevision](database, REVISION_COLLECTION, ErrNoCardRevision), nil
+}
+
+/*
+snapshotCard Return the stored version of a card, including its large fields, so that it can be recorded
+as a revision before it is edited
+*/
+func snapshotCard(ctx stdContext.Context, uuid string, owner string) (*card.CardSet, error) {
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := repo.FindOne(ctx, cardQuery(uuid, owner))
+	if err != nil {
+		return nil, err
+	}
+
+	if SplitLargeFields() {
+		err = LoadExtras(ctx, []*card.CardSet{ret})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+changedFields Returns the top level fields that differ between the two versions of a card passed, ignoring
+its API metadata
+*/
+func changedFields(before *card.CardSet, after *card.CardSet) []string {
+	decode := func(model *card.CardSet) bson.M {
+		ret := bson.M{}
+
+		document, err := bson.Marshal(model)
+		if err == nil {
+			bson.Unmarshal(document, &ret)
+		}
+
+		delete(ret, "_id")
+		delete(ret, "mtgjsonApiMeta")
+
+		return ret
+	}
+
+	beforeFields, afterFields := decode(before), decode(after)
+
+	ret := []string{}
+	for field, value := range afterFields {
+		if !reflect.DeepEqual(beforeFields[field], value) {
+			ret = append(ret, field)
+		}
+	}
+
+	for field := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			ret = append(ret, field)
+		}
+	}
+
+	slices.Sort(ret)
+
+	return ret
+}
+
+/*
+recordRevision Store the version of a card passed as its next revision. The revision number is taken from the
+counter of the card and its owner with server.DatabaseInterface.NextSequence, which increments it atomically, so
+concurrent edits never receive the same number and a transaction is never aborted by a duplicate key. Returns
+ErrRevisionFailed if the revision could not be stored
+*/
+func recordRevision(ctx stdContext.Context, previous *card.CardSet, reason string, changes []string) error {
+	if previous == nil || previous.Identifiers == nil || previous.MtgjsonApiMeta == nil {
+		return nil
+	}
+
+	repo, err := revisionRepository(ctx)
+	if err != nil {
+		return err
+	}
+
+	cardId, owner := previous.Identifiers.MtgjsonV4Id, previous.MtgjsonApiMeta.Owner
+
+	number, err := repo.Database.NextSequence(ctx, REVISION_COUNTER_COLLECTION, bson.M{"cardId": cardId, "owner": owner}, "revision")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRevisionFailed, err)
+	}
+
+	revision := &CardRevision{
+		CardId:      cardId,
+		Owner:       owner,
+		Revision:    number,
+		Reason:      reason,
+		Changes:     changes,
+		RevisedDate: util.CreateTimestampStr(),
+		Card:        previous,
+	}
+
+	err = repo.Insert(ctx, revision)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRevisionFailed, err)
+	}
+
+	return nil
+}
+
+/*
+BackfillRevisionCounters Set the revision counter of every card and owner that has recorded revisions to its
+highest revision number, so that revisions recorded before the counters were introduced are not numbered again.
+Counters are only ever raised, so this is safe to re-run. Returns the number of counters that were set
+*/
+func BackfillRevisionCounters(ctx stdContext.Context) (int64, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	type counterKey struct {
+		CardId string `bson:"cardId"`
+		Owner  string `bson:"owner"`
+	}
+
+	highest := map[counterKey]int64{}
+	for raw, err := range database.FindStream(ctx, REVISION_COLLECTION, bson.M{}, "cardId", "owner", "revision") {
+		if err != nil {
+			return 0, err
+		}
+
+		var revision CardRevision
+		err = bson.Unmarshal(raw, &revision)
+		if err != nil {
+			return 0, err
+		}
+
+		key := counterKey{CardId: revision.CardId, Owner: revision.Owner}
+		highest[key] = max(highest[key], revision.Revision)
+	}
+
+	var updated int64
+	for key, revision := range highest {
+		query := bson.M{"cardId": key.CardId, "owner": key.Owner, "revision": bson.M{"$gte": revision}}
+
+		count, err := database.Count(ctx, REVISION_COUNTER_COLLECTION, query)
+		if err != nil {
+			return updated, err
+		}
+
+		if count != 0 {
+			continue
+		}
+
+		_, err = database.Upsert(ctx, REVISION_COUNTER_COLLECTION, bson.M{"cardId": key.CardId, "owner": key.Owner}, bson.M{"cardId": key.CardId, "owner": key.Owner, "revision": revision})
+		if err != nil {
+			return updated, err
+		}
+
+		updated++
+	}
+
+	return updated, nil
+}
+
+/*
+withRevision Run the edit passed inside a transaction along with the recording of the version of the card that
+it replaces, so that an edit is never stored without its revision. The changes of the revision are found by
+comparing the card before and after the edit. Nothing is recorded if the edit fails, or if the card did not
+exist before it
+*/
+func withRevision(ctx stdContext.Context, uuid string, owner string, reason string, edit func(ctx stdContext.Context) error) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	return database.WithTransaction(ctx, func(ctx stdContext.Context) error {
+		previous, err := snapshotCard(ctx, uuid, owner)
+		if err != nil && !errors.Is(err, sdkErrors.ErrNoCard) {
+			return err
+		}
+
+		err = edit(ctx)
+		if err != nil || previous == nil {
+			return err
+		}
+
+		current, err := snapshotCard(ctx, uuid, owner)
+		if err != nil {
+			return err
+		}
+
+		return recordRevision(ctx, previous, reason, changedFields(previous, current))
+	})
+}
+
+/*
+GetHistory Returns every recorded revision of the card with the UUID passed that is owned by the owner passed,
+oldest first. An empty slice is returned if the card has never been edited. Returns ErrInvalidUUID if the UUID
+is not valid
+*/
+func GetHistory(ctx stdContext.Context, uuid string, owner string) ([]*CardRevision, error) {
+	if !ValidateUUID(uuid) {
+		return nil, sdkErrors.ErrInvalidUUID
+	}
+
+	repo, err := revisionRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := repo.FindManySorted(ctx, bson.M{"cardId": uuid, "owner": owner}, []string{"revision"})
+	if err != nil {
+		return nil, err
+	}
+
+	if ret == nil {
+		return []*CardRevision{}, nil
+	}
+
+	return ret, nil
+}
+
+/*
+Rollback Restore the card with the UUID and owner passed to the version recorded in the revision passed, for
+fixing bad edits. The rollback replaces the card with ReplaceCard, so the version it replaces is recorded as a new revision
+and can itself be restored. Returns ErrNoCardRevision if the card has no such revision, or ErrNoCard if the card
+no longer exists
+*/
+func Rollback(ctx stdContext.Context, uuid string, owner string, revision int64) error {
+	if !ValidateUUID(uuid) {
+		return sdkErrors.ErrInvalidUUID
+	}
+
+	repo, err := revisionRepository(ctx)
+	if err != nil {
+		return err
+	}
+
+	found, err := repo.FindOne(ctx, bson.M{"cardId": uuid, "owner": owner, "revision": revision})
+	if err != nil {
+		return err
+	}
+
+	if found.Card == nil || found.Card.MtgjsonApiMeta == nil {
+		return ErrNoCardRevision
+	}
+
+	existing, err := GetCard(ctx, uuid, owner)
+	if err != nil {
+		return err
+	}
+
+	if existing.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
+	}
+
+	// the restored version must carry the current modified date to pass the version check of the replace
+	restored := found.Card
+	restored.MtgjsonApiMeta.ModifiedDate = existing.MtgjsonApiMeta.ModifiedDate
+
+	return replaceCard(ctx, restored, RevisionRollback)
+}
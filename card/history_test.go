@@ -0,0 +1,126 @@
+package card
+
+import (
+	stdContext "context"
+	"reflect"
+	"testing"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestChangedFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		before *card.CardSet
+		after  *card.CardSet
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			before: &card.CardSet{Name: "Lightning Bolt", Colors: []string{"R"}},
+			after:  &card.CardSet{Name: "Lightning Bolt", Colors: []string{"R"}},
+			want:   []string{},
+		},
+		{
+			name:   "changed fields are sorted",
+			before: &card.CardSet{Name: "Shock", Text: "Deal 2 damage", ManaCost: "{R}"},
+			after:  &card.CardSet{Name: "Lightning Bolt", Text: "Deal 3 damage", ManaCost: "{R}"},
+			want:   []string{"name", "text"},
+		},
+		{
+			name:   "nested changes are reported by their top level field",
+			before: &card.CardSet{Legalities: &meta.CardLegalities{Modern: "Legal"}},
+			after:  &card.CardSet{Legalities: &meta.CardLegalities{Modern: "Banned"}},
+			want:   []string{"legalities"},
+		},
+		{
+			name:   "API metadata is ignored",
+			before: &card.CardSet{Name: "Shock", MtgjsonApiMeta: &meta.MTGJSONAPIMeta{ModifiedDate: "2024-01-01"}},
+			after:  &card.CardSet{Name: "Shock", MtgjsonApiMeta: &meta.MTGJSONAPIMeta{ModifiedDate: "2024-06-01"}},
+			want:   []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := changedFields(test.before, test.after); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("changedFields() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecordRevision(t *testing.T) {
+	const uuid = "5f8287b1-5bb6-5f4c-ad17-316a40d5bb0c"
+
+	ctx := context.WithDatabase(stdContext.Background(), server.NewMemoryDatabase())
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+
+	// a revision recorded before the counters were introduced
+	_, err = database.Insert(ctx, REVISION_COLLECTION, &CardRevision{CardId: uuid, Owner: "system", Revision: 1})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	updated, err := BackfillRevisionCounters(ctx)
+	if err != nil || updated != 1 {
+		t.Fatalf("BackfillRevisionCounters() = %d, %v, want 1", updated, err)
+	}
+
+	for _, owner := range []string{"system", "player@example.com", "system"} {
+		previous := &card.CardSet{
+			Identifiers:    &meta.CardIdentifiers{MtgjsonV4Id: uuid},
+			MtgjsonApiMeta: &meta.MTGJSONAPIMeta{Owner: owner},
+		}
+
+		err = recordRevision(ctx, previous, RevisionUpdate, []string{"name"})
+		if err != nil {
+			t.Fatalf("recordRevision() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		owner string
+		want  []int64
+	}{
+		{"system", []int64{1, 2, 3}},
+		{"player@example.com", []int64{1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.owner, func(t *testing.T) {
+			history, err := GetHistory(ctx, uuid, test.owner)
+			if err != nil {
+				t.Fatalf("GetHistory() error = %v", err)
+			}
+
+			var got []int64
+			for _, revision := range history {
+				got = append(got, revision.Revision)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("revisions = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// counters are only raised, so backfilling again leaves them untouched
+	updated, err = BackfillRevisionCounters(ctx)
+	if err != nil || updated != 0 {
+		t.Errorf("BackfillRevisionCounters() = %d, %v, want 0", updated, err)
+	}
+
+	count, err := database.Count(ctx, REVISION_COUNTER_COLLECTION, bson.M{"cardId": uuid, "owner": "system", "revision": 3})
+	if err != nil || count != 1 {
+		t.Errorf("Count() = %d, %v, want 1", count, err)
+	}
+}
@@ -0,0 +1,99 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	IdentifierScryfall         = "scryfallId"
+	IdentifierScryfallOracle   = "scryfallOracleId"
+	IdentifierMultiverse       = "multiverseId"
+	IdentifierMtgo             = "mtgoId"
+	IdentifierMtgArena         = "mtgArenaId"
+	IdentifierTcgplayerProduct = "tcgplayerProductId"
+)
+
+/*
+Identifiers The alternate identifiers that cards can be looked up by with GetCardByIdentifier, named after
+their field in the identifiers of a card
+*/
+var Identifiers = []string{
+	IdentifierScryfall,
+	IdentifierScryfallOracle,
+	IdentifierMultiverse,
+	IdentifierMtgo,
+	IdentifierMtgArena,
+	IdentifierTcgplayerProduct,
+}
+
+var ErrInvalidIdentifier = errors.New("card: Operation failed. The identifier must be one of scryfallId, scryfallOracleId, multiverseId, mtgoId, mtgArenaId or tcgplayerProductId")
+
+/*
+identifierQuery Build the query matching cards whose identifier of the kind passed equals the value passed
+*/
+func identifierQuery(kind string, value string) (bson.M, error) {
+	if !slices.Contains(Identifiers, kind) {
+		return nil, ErrInvalidIdentifier
+	}
+
+	return bson.M{"identifiers." + kind: value}, nil
+}
+
+/*
+GetCardsByIdentifier Returns every card whose identifier of the kind passed (see Identifiers) equals the value
+passed, for integrations that do not have MTGJSONv4 IDs. A Scryfall Oracle ID is shared by every printing of a
+card, and the faces of a multi faced card share most identifiers, so several cards may be returned. Returns
+ErrInvalidIdentifier if the kind is not supported
+*/
+func GetCardsByIdentifier(ctx stdContext.Context, kind string, value string, fields ...string) ([]*card.CardSet, error) {
+	query, err := identifierQuery(kind, value)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := repo.FindMany(ctx, query, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, nil
+}
+
+/*
+GetCardByIdentifier Returns the first card whose identifier of the kind passed (see Identifiers) equals the
+value passed. Use GetCardsByIdentifier to return every printing of a Scryfall Oracle ID. Returns
+ErrInvalidIdentifier if the kind is not supported, or ErrNoCard if no card matches
+*/
+func GetCardByIdentifier(ctx stdContext.Context, kind string, value string) (*card.CardSet, error) {
+	query, err := identifierQuery(kind, value)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := repo.FindOne(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(1)
+
+	return ret, nil
+}
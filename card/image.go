@@ -0,0 +1,83 @@
+package card
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+)
+
+const (
+	SCRYFALL_IMAGE_URL = "https://cards.scryfall.io"
+
+	ImageSmall      = "small"
+	ImageNormal     = "normal"
+	ImageLarge      = "large"
+	ImagePng        = "png"
+	ImageArtCrop    = "art_crop"
+	ImageBorderCrop = "border_crop"
+)
+
+/*
+ImageSizes The image sizes served by Scryfall
+*/
+var ImageSizes = []string{ImageSmall, ImageNormal, ImageLarge, ImagePng, ImageArtCrop, ImageBorderCrop}
+
+/*
+doubleFacedLayouts The layouts of cards that have a separate image for their back face. The faces of other
+multi faced cards (e.g. split, flip and adventure cards) are printed on the front of the card
+*/
+var doubleFacedLayouts = []string{"transform", "modal_dfc", "double_faced_token", "reversible_card"}
+
+var ErrInvalidImageSize = errors.New("card: Operation failed. The image size must be one of small, normal, large, png, art_crop or border_crop")
+var ErrNoScryfallId = errors.New("card: Operation failed. The card has no Scryfall ID to derive image URLs from")
+
+/*
+ImageURLs The Scryfall image URLs of a card. Back is only set for double faced cards, and both faces of a
+double faced card share the same URLs, with Front holding the image of its a side
+*/
+type ImageURLs struct {
+	Front string `json:"front"`
+	Back  string `json:"back,omitempty"`
+}
+
+/*
+imageURL Build the URL of a face of the Scryfall image with the ID and size passed. Scryfall shards its images
+by the first two characters of the ID
+*/
+func imageURL(scryfallId string, size string, face string) string {
+	extension := ".jpg"
+	if size == ImagePng {
+		extension = ".png"
+	}
+
+	return SCRYFALL_IMAGE_URL + "/" + size + "/" + face + "/" + scryfallId[0:1] + "/" + scryfallId[1:2] + "/" + scryfallId + extension
+}
+
+/*
+GetImageURLs Returns the Scryfall image URLs of the card passed in the size passed (see ImageSizes), derived
+from its Scryfall ID so that no request to Scryfall is needed. If size is an empty string, ImageNormal is used.
+Returns ErrInvalidImageSize if the size is not supported, or ErrNoScryfallId if the card has no Scryfall ID
+*/
+func GetImageURLs(card *card.CardSet, size string) (*ImageURLs, error) {
+	if size == "" {
+		size = ImageNormal
+	}
+
+	if !slices.Contains(ImageSizes, size) {
+		return nil, ErrInvalidImageSize
+	}
+
+	if card.Identifiers == nil || len(card.Identifiers.ScryfallId) < 2 {
+		return nil, ErrNoScryfallId
+	}
+
+	scryfallId := card.Identifiers.ScryfallId
+
+	ret := &ImageURLs{Front: imageURL(scryfallId, size, "front")}
+	if slices.Contains(doubleFacedLayouts, card.Layout) {
+		ret.Back = imageURL(scryfallId, size, "back")
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,116 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	LegalityLegal      = "Legal"
+	LegalityRestricted = "Restricted"
+	LegalityBanned     = "Banned"
+)
+
+/*
+Formats The formats that cards have a legality for, named after their field in the legalities of a card
+*/
+var Formats = []string{
+	"alchemy", "brawl", "commander", "duel", "explorer", "future", "gladiator", "historic", "historicBrawl",
+	"legacy", "modern", "oathbreaker", "oldschool", "pauper", "paupercommander", "penny", "pioneer", "predh",
+	"premodern", "standard", "standardbrawl", "timeless", "vintage",
+}
+
+/*
+Legalities The legality statuses that a card may have in a format. A card without a status for a format is
+not legal in it
+*/
+var Legalities = []string{LegalityLegal, LegalityRestricted, LegalityBanned}
+
+var ErrInvalidFormat = errors.New("card: Operation failed. The format is not supported")
+var ErrInvalidLegality = errors.New("card: Operation failed. The legality must be either Legal, Restricted or Banned")
+
+/*
+normalizeLegality Return the legality passed in the casing stored by MTGJSON (e.g. legal becomes Legal).
+Returns ErrInvalidLegality if it is not one of the Legalities
+*/
+func normalizeLegality(status string) (string, error) {
+	for _, value := range Legalities {
+		if strings.EqualFold(value, status) {
+			return value, nil
+		}
+	}
+
+	return "", ErrInvalidLegality
+}
+
+/*
+Legality Returns the legality status of the card passed in the format passed (e.g. "Legal"), or an empty
+string if the card is not legal in the format or the format is not supported
+*/
+func Legality(card *card.CardSet, format string) string {
+	if card.Legalities == nil || !slices.Contains(Formats, format) {
+		return ""
+	}
+
+	document, err := bson.Marshal(card.Legalities)
+	if err != nil {
+		return ""
+	}
+
+	status, _ := bson.Raw(document).Lookup(format).StringValueOK()
+
+	return status
+}
+
+/*
+IsLegalIn Returns true if the card passed may be played in the format passed. Restricted cards are legal,
+but may only be included once in a deck
+*/
+func IsLegalIn(card *card.CardSet, format string) bool {
+	status := Legality(card, format)
+
+	return status == LegalityLegal || status == LegalityRestricted
+}
+
+/*
+GetCardsByLegality Returns a single page of the cards with the legality status passed (see Legalities) in the
+format passed (see Formats), such as every card banned in modern. The status is matched ignoring case. Pass
+the NextCursor of the returned page in the options to fetch the next page. If database is nil, the database
+of the context passed is used. Returns ErrInvalidFormat or ErrInvalidLegality if either is not supported
+*/
+func GetCardsByLegality(ctx stdContext.Context, database server.DatabaseInterface, format string, status string, opts *server.PageOptions) ([]*card.CardSet, *server.Page, error) {
+	if !slices.Contains(Formats, format) {
+		return nil, nil, ErrInvalidFormat
+	}
+
+	status, err := normalizeLegality(status)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if database == nil {
+		database, err = context.GetDatabase(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var ret []*card.CardSet
+
+	page, err := database.Paginate(ctx, "card", bson.M{"legalities." + format: status}, opts, &ret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, page, nil
+}
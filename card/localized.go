@@ -0,0 +1,151 @@
+package card
+
+import (
+	"cmp"
+	stdContext "context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+ForeignMatch A card found by its localized name, along with the foreignData entry whose name matched
+*/
+type ForeignMatch struct {
+	Card *card.CardSet     `json:"card"`
+	Face *meta.ForeignData `json:"face"`
+}
+
+/*
+matchedFace Return the foreignData entry of the card passed whose name matches the pattern, preferring an
+entry whose name is an exact match. Only entries in the language passed are considered, unless it is an
+empty string. Returns nil if no entry matches
+*/
+func matchedFace(foreignData []*meta.ForeignData, pattern *regexp.Regexp, name string, language string) *meta.ForeignData {
+	var ret *meta.ForeignData
+
+	for _, entry := range foreignData {
+		if language != "" && entry.Language != language {
+			continue
+		}
+
+		if strings.EqualFold(entry.Name, name) {
+			return entry
+		}
+
+		if ret == nil && pattern.MatchString(entry.Name) {
+			ret = entry
+		}
+	}
+
+	return ret
+}
+
+/*
+SearchForeign Returns the cards with a localized name (see ForeignData) containing the name passed, ignoring
+case, along with the localized face that matched, so that non-English users can find cards in their own
+language. If a language is passed, as either a language code or MTGJSON language name, only names in that
+language are matched. Cards whose localized name is an exact match are returned first, followed by the rest
+in order of their localized name, up to DEFAULT_SEARCH_LIMIT cards. Exact matches and partial matches are each
+fetched with a limit of DEFAULT_SEARCH_LIMIT, so a broad search never loads every matching card, and the rulings
+and purchase URLs of the cards are not fetched. If database is nil, the database of the context passed is used.
+Returns ErrInvalidLanguage if the language is not supported
+*/
+func SearchForeign(ctx stdContext.Context, database server.DatabaseInterface, name string, language string) ([]*ForeignMatch, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return []*ForeignMatch{}, nil
+	}
+
+	var err error
+	if language != "" {
+		language, err = NormalizeLanguage(language)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if database == nil {
+		database, err = context.GetDatabase(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var pattern primitive.Regex
+	var cards []*card.CardSet
+
+	// exact matches are fetched separately, so that they are never crowded out by the limit of partial matches
+	for _, nameMatch := range []string{NameMatchExact, NameMatchContains} {
+		pattern, err = namePattern(name, nameMatch, false)
+		if err != nil {
+			return nil, err
+		}
+
+		match := bson.M{"name": pattern}
+		if language != "" {
+			match["language"] = language
+		}
+
+		found, err := findByForeignData(ctx, database, match, DEFAULT_SEARCH_LIMIT, "-rulings", "-purchaseUrls")
+		if err != nil {
+			return nil, err
+		}
+
+		cards = append(cards, found...)
+	}
+
+	compiled := regexp.MustCompile("(?i)" + pattern.Pattern)
+
+	type cardKey struct {
+		uuid  string
+		owner string
+	}
+
+	seen := map[cardKey]bool{}
+	ret := []*ForeignMatch{}
+	for _, value := range cards {
+		if value.Identifiers != nil && value.MtgjsonApiMeta != nil {
+			key := cardKey{uuid: value.Identifiers.MtgjsonV4Id, owner: value.MtgjsonApiMeta.Owner}
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+		}
+
+		face := matchedFace(value.ForeignData, compiled, name, language)
+		if face != nil {
+			ret = append(ret, &ForeignMatch{Card: value, Face: face})
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a *ForeignMatch, b *ForeignMatch) int {
+		aExact, bExact := strings.EqualFold(a.Face.Name, name), strings.EqualFold(b.Face.Name, name)
+		if aExact != bExact {
+			if aExact {
+				return -1
+			}
+
+			return 1
+		}
+
+		return cmp.Compare(a.Face.Name, b.Face.Name)
+	})
+
+	if len(ret) > DEFAULT_SEARCH_LIMIT {
+		ret = ret[:DEFAULT_SEARCH_LIMIT]
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, nil
+}
@@ -0,0 +1,94 @@
+package card
+
+import (
+	stdContext "context"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+/*
+foreignCards Returns the cards that the searches of TestSearchForeign are run against
+*/
+func foreignCards() []*card.CardSet {
+	return []*card.CardSet{
+		{
+			Name:           "Lightning Bolt",
+			Identifiers:    &meta.CardIdentifiers{MtgjsonV4Id: "5f8287b1-5bb6-5f4c-ad17-316a40d5bb0c"},
+			MtgjsonApiMeta: &meta.MTGJSONAPIMeta{Owner: "system"},
+			ForeignData:    []*meta.ForeignData{{Language: "German", Name: "Blitzschlag"}, {Language: "French", Name: "Foudre"}},
+		},
+		{
+			Name:           "Lightning Strike",
+			Identifiers:    &meta.CardIdentifiers{MtgjsonV4Id: "b0e2b3c5-4a0b-5a3d-9c8e-1f2a3b4c5d6e"},
+			MtgjsonApiMeta: &meta.MTGJSONAPIMeta{Owner: "system"},
+			ForeignData:    []*meta.ForeignData{{Language: "German", Name: "Blitzschlag des Zorns"}, {Language: "French", Name: "Coup de foudre"}},
+		},
+		{
+			Name:           "Shock",
+			Identifiers:    &meta.CardIdentifiers{MtgjsonV4Id: "c1d2e3f4-a5b6-5c7d-8e9f-0a1b2c3d4e5f"},
+			MtgjsonApiMeta: &meta.MTGJSONAPIMeta{Owner: "system"},
+			ForeignData:    []*meta.ForeignData{{Language: "German", Name: "Schock"}},
+		},
+	}
+}
+
+func TestSearchForeign(t *testing.T) {
+	tests := []struct {
+		name     string
+		search   string
+		language string
+		want     []string
+	}{
+		{"exact match first", "blitzschlag", "", []string{"Blitzschlag", "Blitzschlag des Zorns"}},
+		{"language", "foudre", "fr", []string{"Foudre", "Coup de foudre"}},
+		{"other language", "foudre", "de", []string{}},
+		{"no match", "Counterspell", "", []string{}},
+	}
+
+	for _, split := range []bool{false, true} {
+		viper.Set("card.split_large_fields", split)
+		t.Cleanup(func() { viper.Set("card.split_large_fields", false) })
+
+		ctx := stdContext.Background()
+		database := server.NewMemoryDatabase()
+
+		for _, value := range foreignCards() {
+			if split {
+				_, err := database.Insert(ctx, "card_extra", &CardExtras{CardId: value.Identifiers.MtgjsonV4Id, Owner: value.MtgjsonApiMeta.Owner, ForeignData: value.ForeignData})
+				if err != nil {
+					t.Fatalf("Insert() error = %v", err)
+				}
+
+				value.ForeignData = nil
+			}
+
+			_, err := database.Insert(ctx, "card", value)
+			if err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				matches, err := SearchForeign(ctx, database, test.search, test.language)
+				if err != nil {
+					t.Fatalf("SearchForeign() error = %v", err)
+				}
+
+				got := []string{}
+				for _, match := range matches {
+					got = append(got, match.Face.Name)
+				}
+
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("SearchForeign() with split fields %v = %v, want %v", split, got, test.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package card
+
+import (
+	"context"
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+)
+
+/*
+OwnedCard A card model annotated with the number of copies that the requesting user owns
+*/
+type OwnedCard struct {
+	Card        *card.CardSet `json:"card"`
+	OwnedCopies int64         `json:"ownedCopies"`
+}
+
+/*
+AnnotateOwnership Annotate each card passed with the number of copies the user passed in the email
+parameter owns. The users collection is fetched once, regardless of the number of cards
+*/
+func AnnotateOwnership(ctx context.Context, cards []*card.CardSet, email string) ([]*OwnedCard, error) {
+	var ret []*OwnedCard
+
+	owner, err := user.GetUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{}
+	for _, uuid := range owner.OwnedCards {
+		counts[uuid]++
+	}
+
+	for _, value := range cards {
+		owned := &OwnedCard{Card: value}
+		if value.Identifiers != nil {
+			owned.OwnedCopies = counts[value.Identifiers.MtgjsonV4Id]
+		}
+
+		ret = append(ret, owned)
+	}
+
+	return ret, nil
+}
+
+/*
+GetCardForUser Fetch a card using GetCard and annotate it with the number of copies the user passed
+in the email parameter owns
+*/
+func GetCardForUser(ctx context.Context, uuid string, owner string, email string) (*OwnedCard, error) {
+	result, err := GetCard(ctx, uuid, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	annotated, err := AnnotateOwnership(ctx, []*card.CardSet{result}, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return annotated[0], nil
+}
+
+/*
+GetCardsForUser Fetch a list of cards using GetCards and annotate each of them with the number of
+copies the user passed in the email parameter owns. Consumes two database calls in total
+*/
+func GetCardsForUser(ctx context.Context, uuids []string, email string) ([]*OwnedCard, error) {
+	results, err := GetCards(ctx, uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	return AnnotateOwnership(ctx, results, email)
+}
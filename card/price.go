@@ -0,0 +1,110 @@
+package card
+
+import (
+	stdContext "context"
+	"slices"
+	"time"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/price"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+GetPrices Returns the most recent paper and MTGO prices of the card with the UUID passed, as imported from
+MTGJSON with price.Import. Prices are narrowed to a single provider (e.g. tcgplayer or cardmarket) and finish
+(see price.Finishes) if either is not an empty string. Returns ErrInvalidUUID if the UUID is not valid, or
+price.ErrInvalidFinish if the finish is not supported. An empty slice is returned if the card has no prices
+*/
+func GetPrices(ctx stdContext.Context, uuid string, provider string, finish string) ([]*price.Price, error) {
+	if !ValidateUUID(uuid) {
+		return nil, sdkErrors.ErrInvalidUUID
+	}
+
+	if finish != "" && !slices.Contains(price.Finishes, finish) {
+		return nil, price.ErrInvalidFinish
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"cardId": uuid}
+	if provider != "" {
+		query["provider"] = provider
+	}
+
+	if finish != "" {
+		query["finish"] = finish
+	}
+
+	repo := server.NewRepository[price.Price](database, price.PRICE_COLLECTION, nil)
+
+	ret, err := repo.FindManySorted(ctx, query, []string{"market", "provider", "listType", "finish"})
+	if err != nil {
+		return nil, err
+	}
+
+	if ret == nil {
+		return []*price.Price{}, nil
+	}
+
+	return ret, nil
+}
+
+/*
+GetPriceHistory Returns the daily prices of the card with the UUID passed between the dates passed (inclusive),
+oldest first, for price charts and tracking the value of a collection over time. Only the date of from and to
+is used, and a zero time leaves that end of the range open. History is narrowed to a single provider if it is
+not an empty string. Each day holds a point for every market, list type and finish the provider prices the card
+in. Returns ErrInvalidUUID if the UUID is not valid, or price.ErrInvalidDateRange if to is before from. An empty
+slice is returned if the card has no history in the range
+*/
+func GetPriceHistory(ctx stdContext.Context, uuid string, provider string, from time.Time, to time.Time) ([]*price.Price, error) {
+	if !ValidateUUID(uuid) {
+		return nil, sdkErrors.ErrInvalidUUID
+	}
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return nil, price.ErrInvalidDateRange
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"cardId": uuid}
+	if provider != "" {
+		query["provider"] = provider
+	}
+
+	dates := bson.M{}
+	if !from.IsZero() {
+		dates["$gte"] = from.Format(price.PRICE_DATE_LAYOUT)
+	}
+
+	if !to.IsZero() {
+		dates["$lte"] = to.Format(price.PRICE_DATE_LAYOUT)
+	}
+
+	if len(dates) != 0 {
+		query["date"] = dates
+	}
+
+	repo := server.NewRepository[price.Price](database, price.PRICE_HISTORY_COLLECTION, nil)
+
+	ret, err := repo.FindManySorted(ctx, query, []string{"date", "market", "provider", "listType", "finish"})
+	if err != nil {
+		return nil, err
+	}
+
+	if ret == nil {
+		return []*price.Price{}, nil
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,112 @@
+package card
+
+import (
+	"cmp"
+	stdContext "context"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+setRelease The release date of a set, as read from the set collection
+*/
+type setRelease struct {
+	Code        string `bson:"code"`
+	ReleaseDate string `bson:"releaseDate"`
+}
+
+/*
+releaseDates Return the release date of each of the sets passed, keyed by set code. Sets that do not exist
+in the set collection are left out
+*/
+func releaseDates(ctx stdContext.Context, database server.DatabaseInterface, setCodes []string) (map[string]string, error) {
+	var sets []*setRelease
+
+	err := database.FindMultiple(ctx, "set", "code", setCodes, &sets, "code", "releaseDate")
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]string{}
+	for _, value := range sets {
+		ret[value.Code] = value.ReleaseDate
+	}
+
+	return ret, nil
+}
+
+/*
+GetPrintings Returns every printing of the card with the name passed in the parameter across all sets, oldest
+first, for use in printing pickers. Printings are ordered by the release date of their set, unless the card
+has its own original release date (as promos often do), and then by set code and collector number. Printings
+whose set does not exist in the database are returned last. If database is nil, the database of the server
+context is used. An empty slice is returned if no card has the name
+*/
+func GetPrintings(ctx stdContext.Context, database server.DatabaseInterface, name string) ([]*card.CardSet, error) {
+	if database == nil {
+		var err error
+
+		database, err = context.GetDatabase(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	repo := server.NewRepository[card.CardSet](database, "card", nil)
+
+	ret, err := repo.FindMany(ctx, bson.M{"name": name})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ret) == 0 {
+		return []*card.CardSet{}, nil
+	}
+
+	setCodes := []string{}
+	for _, printing := range ret {
+		if !slices.Contains(setCodes, printing.SetCode) {
+			setCodes = append(setCodes, printing.SetCode)
+		}
+	}
+
+	dates, err := releaseDates(ctx, database, setCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	releaseDate := func(printing *card.CardSet) string {
+		if printing.OriginalReleaseDate != "" {
+			return printing.OriginalReleaseDate
+		}
+
+		return dates[printing.SetCode]
+	}
+
+	slices.SortStableFunc(ret, func(a *card.CardSet, b *card.CardSet) int {
+		aDate, bDate := releaseDate(a), releaseDate(b)
+		if (aDate == "") != (bDate == "") {
+			if aDate == "" {
+				return 1
+			}
+
+			return -1
+		}
+
+		return cmp.Or(
+			cmp.Compare(aDate, bDate),
+			cmp.Compare(a.SetCode, b.SetCode),
+			cmp.Compare(a.Number, b.Number),
+			cmp.Compare(a.Side, b.Side),
+		)
+	})
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, nil
+}
@@ -0,0 +1,63 @@
+package card
+
+import (
+	stdContext "context"
+	"github.com/stevezaluk/mtgjson-models/card"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+)
+
+/*
+RandomCards Returns up to 'count' distinct cards chosen at random from those matching the filter passed in the
+parameter (see CardFilter), for features such as booster simulation. The cards are sampled by the database, so
+the collection is never downloaded. Fewer cards are returned if fewer match. A nil filter samples every card.
+If database is nil, the database of the context passed is used
+*/
+func RandomCards(ctx stdContext.Context, database server.DatabaseInterface, filter *CardFilter, count int64) ([]*card.CardSet, error) {
+	built, err := filter.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := built.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if database == nil {
+		database, err = context.GetDatabase(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ret []*card.CardSet
+
+	err = database.Sample(ctx, "card", compiled, count, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, nil
+}
+
+/*
+RandomCard Returns a single card chosen at random from those matching the filter passed in the parameter,
+see RandomCards. Returns ErrNoCards if no card matches
+*/
+func RandomCard(ctx stdContext.Context, database server.DatabaseInterface, filter *CardFilter) (*card.CardSet, error) {
+	ret, err := RandomCards(ctx, database, filter, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ret) == 0 {
+		return nil, sdkErrors.ErrNoCards
+	}
+
+	return ret[0], nil
+}
@@ -0,0 +1,172 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"time"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	RULING_DATE_LAYOUT = "2006-01-02"
+)
+
+var ErrNoRuling = errors.New("card: failed to find the ruling on the specified card")
+var ErrInvalidRuling = errors.New("card: Operation failed. Rulings require text and a date in YYYY-MM-DD form")
+
+/*
+ValidateRuling Returns ErrInvalidRuling if the ruling passed has no text, or its date is not a valid date in
+the YYYY-MM-DD form used by MTGJSON
+*/
+func ValidateRuling(ruling *meta.CardRulings) error {
+	if ruling == nil || ruling.Text == "" {
+		return ErrInvalidRuling
+	}
+
+	_, err := time.Parse(RULING_DATE_LAYOUT, ruling.Date)
+	if err != nil {
+		return ErrInvalidRuling
+	}
+
+	return nil
+}
+
+/*
+cardQuery Build the query locating a card by its UUID, and its owner if the owner is not an empty string
+*/
+func cardQuery(uuid string, owner string) bson.M {
+	ret := bson.M{"identifiers.mtgjsonV4Id": uuid}
+	if owner != "" {
+		ret["mtgjsonApiMeta.owner"] = owner
+	}
+
+	return ret
+}
+
+/*
+updateRulings Apply the rulings update passed to the card, and update the modified date of its API metadata.
+When large fields are split the rulings are stored in the card_extra collection, so the rulings and the
+modified date are updated separately. The rulings update is only applied if the card also matches the query
+passed in 'match', and a failed match is reported as ErrNoRuling if the card exists, or ErrNoCard otherwise. The
+version of the card before the update is recorded in its history, see GetHistory
+*/
+func updateRulings(ctx stdContext.Context, uuid string, owner string, rulings bson.M, match bson.M) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = withRevision(ctx, uuid, owner, RevisionUpdate, func(ctx stdContext.Context) error {
+		modified := bson.M{"$set": bson.M{"mtgjsonApiMeta.modifiedDate": util.CreateTimestampStr()}}
+		query := cardQuery(uuid, owner)
+
+		if !SplitLargeFields() {
+			update := bson.M{"$set": modified["$set"]}
+			for operator, fields := range rulings {
+				update[operator] = fields
+			}
+
+			for key, value := range match {
+				query[key] = value
+			}
+
+			result, err := database.Update(ctx, "card", query, update)
+			if err != nil {
+				return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+			}
+
+			if result.MatchedCount != 0 {
+				return nil
+			}
+
+			count, err := database.Count(ctx, "card", cardQuery(uuid, owner))
+			if err != nil {
+				return err
+			}
+
+			if count == 0 {
+				return sdkErrors.ErrNoCard
+			}
+
+			return ErrNoRuling
+		}
+
+		result, err := database.Update(ctx, "card", query, modified)
+		if err != nil {
+			return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+		}
+
+		if result.MatchedCount == 0 {
+			return sdkErrors.ErrNoCard
+		}
+
+		extraQuery := extrasQuery(uuid, owner)
+		for key, value := range match {
+			extraQuery[key] = value
+		}
+
+		result, err = database.Update(ctx, "card_extra", extraQuery, rulings)
+		if err != nil {
+			return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+		}
+
+		if result.MatchedCount == 0 {
+			return ErrNoRuling
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindCard, uuid)
+
+	return nil
+}
+
+/*
+AddRuling Append the ruling passed in the parameter to the rulings of a card, without replacing the rest of the
+card, and update the modified date of its API metadata. The ruling is validated with ValidateRuling, and is not
+added again if the card already has an identical ruling. If owner is an empty string, the card is located by
+its UUID alone. Returns ErrNoCard if the card does not exist
+*/
+func AddRuling(ctx stdContext.Context, uuid string, owner string, ruling *meta.CardRulings) error {
+	err := ValidateRuling(ruling)
+	if err != nil {
+		return err
+	}
+
+	entry := bson.M{"date": ruling.Date, "text": ruling.Text}
+	duplicate := bson.M{"rulings": bson.M{"$not": bson.M{"$elemMatch": entry}}}
+
+	// a failed match means the card already has the ruling
+	err = updateRulings(ctx, uuid, owner, bson.M{"$push": bson.M{"rulings": ruling}}, duplicate)
+	if errors.Is(err, ErrNoRuling) {
+		return nil
+	}
+
+	return err
+}
+
+/*
+RemoveRuling Remove the ruling with the date and text passed in the parameter from the rulings of a card, and
+update the modified date of its API metadata. If owner is an empty string, the card is located by its UUID
+alone. Returns ErrNoCard if the card does not exist, or ErrNoRuling if the card does not have the ruling
+*/
+func RemoveRuling(ctx stdContext.Context, uuid string, owner string, ruling *meta.CardRulings) error {
+	if ruling == nil {
+		return ErrInvalidRuling
+	}
+
+	entry := bson.M{"date": ruling.Date, "text": ruling.Text}
+
+	return updateRulings(ctx, uuid, owner, bson.M{"$pull": bson.M{"rulings": entry}}, bson.M{"rulings": bson.M{"$elemMatch": entry}})
+}
@@ -0,0 +1,287 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/stevezaluk/mtgjson-models/card"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/query"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	DEFAULT_SEARCH_LIMIT = 25
+	SEARCH_MAX_EDITS     = 1
+
+	NameMatchExact    = "exact"
+	NameMatchPrefix   = "prefix"
+	NameMatchContains = "contains"
+)
+
+/*
+indexNotFoundCode The error code returned by MongoDB when a $text query is run against a collection without
+a text index
+*/
+const indexNotFoundCode = 27
+
+var ErrInvalidNameMatch = errors.New("card: Operation failed. The name match must be either exact, prefix or contains")
+
+/*
+SearchIndex Returns the name of the Atlas Search index on the card collection, set with 'card.search.index'.
+The index must map name as an autocomplete field, and text and type as string fields. An empty string means
+no index is configured and Search falls back to regex queries
+*/
+func SearchIndex() string {
+	return viper.GetString("card.search.index")
+}
+
+/*
+searchPipeline Build the aggregation pipeline used to search the card collection with Atlas Search. Names are
+matched as the user types, and each of the name, text and type fields tolerate a single typo. Cards whose name
+matches are ranked above cards that only match on their rules text or type line
+*/
+func searchPipeline(index string, text string, limit int64, softDelete bool) mongo.Pipeline {
+	fuzzy := bson.M{"maxEdits": SEARCH_MAX_EDITS}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$search", Value: bson.M{
+			"index": index,
+			"compound": bson.M{
+				"should": bson.A{
+					bson.M{"autocomplete": bson.M{"query": text, "path": "name", "fuzzy": fuzzy, "score": bson.M{"boost": bson.M{"value": 3}}}},
+					bson.M{"text": bson.M{"query": text, "path": "text", "fuzzy": fuzzy}},
+					bson.M{"text": bson.M{"query": text, "path": "type", "fuzzy": fuzzy}},
+				},
+				"minimumShouldMatch": 1,
+			},
+		}}},
+	}
+
+	if softDelete {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{server.SoftDeleteField: bson.M{"$exists": false}}}})
+	}
+
+	return append(pipeline,
+		bson.D{{Key: "$limit", Value: limit}},
+		bson.D{{Key: "$project", Value: server.Projection(ListingFields...)}},
+	)
+}
+
+/*
+regexSearch Search the name, text and type of every card for the text passed using case-insensitive regex
+queries. This cannot tolerate typos or rank the results, so the cards are returned ordered by name
+*/
+func regexSearch(ctx stdContext.Context, text string, limit int64) ([]*card.CardSet, error) {
+	filter := query.New().Or(
+		query.New().Contains("name", text),
+		query.New().Contains("text", text),
+		query.New().Contains("type", text),
+	)
+
+	compiled, err := filter.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := repo.FindManyLimited(ctx, compiled, []string{"name"}, limit, ListingFields...)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(cards))
+
+	return cards, nil
+}
+
+/*
+Search Returns up to 'limit' cards whose name, rules text or type line match the text passed in the parameter,
+without their large fields (see ListingFields). A partial name (e.g. "lightning bo") is enough to match. When
+an Atlas Search index is configured (see SearchIndex) the search tolerates typos and the best matches are
+returned first, otherwise it falls back to regex queries. If limit is 0, DEFAULT_SEARCH_LIMIT is used
+*/
+func Search(ctx stdContext.Context, text string, limit int64) ([]*card.CardSet, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return []*card.CardSet{}, nil
+	}
+
+	if limit <= 0 {
+		limit = DEFAULT_SEARCH_LIMIT
+	}
+
+	index := SearchIndex()
+	if index == "" {
+		return regexSearch(ctx, text, limit)
+	}
+
+	database, err := context.GetMongoDatabase(ctx)
+	if err != nil {
+		return regexSearch(ctx, text, limit)
+	}
+
+	var ret []*card.CardSet
+
+	err = database.Aggregate(ctx, "card", searchPipeline(index, text, limit, database.SoftDelete), &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, nil
+}
+
+/*
+NameSearchOptions Controls how SearchByName matches card names. Match is one of NameMatchExact,
+NameMatchPrefix or NameMatchContains, and defaults to NameMatchContains. Names are matched ignoring case
+unless CaseSensitive is set. If Limit is 0, DEFAULT_SEARCH_LIMIT is used. If fields are passed, they are
+used as a projection
+*/
+type NameSearchOptions struct {
+	Match         string
+	CaseSensitive bool
+	Limit         int64
+	Fields        []string
+}
+
+/*
+namePattern Build the regular expression matching card names against the name passed, as described by
+the match
+*/
+func namePattern(name string, match string, caseSensitive bool) (primitive.Regex, error) {
+	pattern := regexp.QuoteMeta(name)
+
+	switch match {
+	case NameMatchExact:
+		pattern = "^" + pattern + "$"
+	case NameMatchPrefix:
+		pattern = "^" + pattern
+	case NameMatchContains:
+	default:
+		return primitive.Regex{}, ErrInvalidNameMatch
+	}
+
+	options := "i"
+	if caseSensitive {
+		options = ""
+	}
+
+	return primitive.Regex{Pattern: pattern, Options: options}, nil
+}
+
+/*
+namePhrase Return the words of the name passed that must appear in full in any name that it matches, joined
+back into a phrase for a $text query. The last word of a prefix may be partial, as may the first and last
+words of a substring, so they are left out. An empty string is returned if no word is known to be whole
+*/
+func namePhrase(name string, match string) string {
+	words := strings.Fields(name)
+
+	switch match {
+	case NameMatchPrefix:
+		words = words[:len(words)-1]
+	case NameMatchContains:
+		if len(words) < 3 {
+			return ""
+		}
+
+		words = words[1 : len(words)-1]
+	}
+
+	phrase := strings.Join(words, " ")
+	if strings.Contains(phrase, `"`) {
+		return ""
+	}
+
+	return phrase
+}
+
+/*
+SearchByName Returns up to 'limit' cards, ordered by name, whose name matches the name passed in the
+parameter exactly, by prefix or as a substring, see NameSearchOptions. This allows a card to be found without
+knowing its MTGJSONv4 ID. Case-sensitive exact and prefix matches are served by the index on name, while
+case-insensitive matches are narrowed with the text index on the card collection (see server.SearchIndexes)
+before the regex is applied. If the text index is missing, or the phrase only contains words it does not
+index, the regex is applied to the whole collection instead. The limit is applied by the database to both
+queries, so the fallback stops reading cards once enough names have matched. If database is nil, the database
+of the context passed is used
+*/
+func SearchByName(ctx stdContext.Context, database server.DatabaseInterface, name string, opts *NameSearchOptions) ([]*card.CardSet, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return []*card.CardSet{}, nil
+	}
+
+	normalized := NameSearchOptions{Match: NameMatchContains}
+	if opts != nil {
+		normalized = *opts
+	}
+
+	if normalized.Match == "" {
+		normalized.Match = NameMatchContains
+	}
+
+	if normalized.Limit <= 0 {
+		normalized.Limit = DEFAULT_SEARCH_LIMIT
+	}
+
+	pattern, err := namePattern(name, normalized.Match, normalized.CaseSensitive)
+	if err != nil {
+		return nil, err
+	}
+
+	if database == nil {
+		database, err = context.GetDatabase(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	repo := server.NewRepository[card.CardSet](database, "card", sdkErrors.ErrNoCard)
+
+	var ret []*card.CardSet
+	filter := bson.M{"name": pattern}
+
+	if normalized.CaseSensitive && normalized.Match == NameMatchExact {
+		filter = bson.M{"name": name}
+	}
+
+	// case-sensitive exact and prefix matches can already use the index on name
+	indexed := normalized.CaseSensitive && normalized.Match != NameMatchContains
+
+	phrase := namePhrase(name, normalized.Match)
+	if _, ok := database.(*server.Database); ok && phrase != "" && !indexed {
+		textFilter := bson.M{"$text": bson.M{"$search": `"` + phrase + `"`}, "name": pattern}
+
+		ret, err = repo.FindManyLimited(ctx, textFilter, []string{"name"}, normalized.Limit, normalized.Fields...)
+		var serverErr mongo.ServerError
+		if err != nil && !(errors.As(err, &serverErr) && serverErr.HasErrorCode(indexNotFoundCode)) {
+			return nil, err
+		}
+	}
+
+	if len(ret) == 0 {
+		ret, err = repo.FindManyLimited(ctx, filter, []string{"name"}, normalized.Limit, normalized.Fields...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, nil
+}
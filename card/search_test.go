@@ -0,0 +1,66 @@
+package card
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNamePattern(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		match         string
+		caseSensitive bool
+		want          primitive.Regex
+	}{
+		{"exact", "Lightning Bolt", NameMatchExact, false, primitive.Regex{Pattern: "^Lightning Bolt$", Options: "i"}},
+		{"prefix", "Lightning", NameMatchPrefix, false, primitive.Regex{Pattern: "^Lightning", Options: "i"}},
+		{"contains", "Bolt", NameMatchContains, false, primitive.Regex{Pattern: "Bolt", Options: "i"}},
+		{"case sensitive", "Bolt", NameMatchContains, true, primitive.Regex{Pattern: "Bolt"}},
+		{"escaped", "Mr. Orfeo (Big)", NameMatchExact, false, primitive.Regex{Pattern: `^Mr\. Orfeo \(Big\)$`, Options: "i"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := namePattern(test.input, test.match, test.caseSensitive)
+			if err != nil {
+				t.Fatalf("namePattern() error = %v", err)
+			}
+
+			if got != test.want {
+				t.Errorf("namePattern() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	_, err := namePattern("Bolt", "fuzzy", false)
+	if !errors.Is(err, ErrInvalidNameMatch) {
+		t.Errorf("namePattern() error = %v, want %v", err, ErrInvalidNameMatch)
+	}
+}
+
+func TestNamePhrase(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		match string
+		want  string
+	}{
+		{"exact", "Lightning Bolt", NameMatchExact, "Lightning Bolt"},
+		{"prefix drops the last word", "Lightning Bo", NameMatchPrefix, "Lightning"},
+		{"prefix of a single word", "Light", NameMatchPrefix, ""},
+		{"contains drops the first and last words", "ning of the Wi", NameMatchContains, "of the"},
+		{"contains of two words", "ning Bo", NameMatchContains, ""},
+		{"quotes are not searched", `Kongming, "Sleeping Dragon"`, NameMatchExact, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := namePhrase(test.input, test.match); got != test.want {
+				t.Errorf("namePhrase() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"time"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrCardDeleted = errors.New("card: Operation failed. A deleted card exists under the same ID, restore it with RestoreCard or purge it first")
+
+/*
+deletedCardExists Returns true if a card matching the uuid and owner passed was soft deleted. Deleted cards are
+excluded from every other lookup, see server.SoftDeleteField
+*/
+func deletedCardExists(ctx stdContext.Context, uuid string, owner string) (bool, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := cardQuery(uuid, owner)
+	query[server.SoftDeleteField] = bson.M{"$exists": true}
+
+	count, err := database.Count(ctx, "card", query)
+	if err != nil {
+		return false, err
+	}
+
+	return count != 0, nil
+}
+
+/*
+RestoreCard Restore a card that was soft deleted with DeleteCard, along with its extras. Returns ErrNoCard
+if no deleted card matches the uuid and owner passed
+*/
+func RestoreCard(ctx stdContext.Context, uuid string, owner string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the owner is resolved so that only the extras of the restored card are restored with it
+	if owner == "" {
+		query := cardQuery(uuid, "")
+		query[server.SoftDeleteField] = bson.M{"$exists": true}
+
+		owner, err = cardOwner(ctx, database, query)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = database.Restore(ctx, "card", cardQuery(uuid, owner))
+	if errors.Is(err, server.ErrNotFound) {
+		return sdkErrors.ErrNoCard
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+	}
+
+	_, err = database.Restore(ctx, "card_extra", extrasQuery(uuid, owner))
+	if err != nil && !errors.Is(err, server.ErrNotFound) {
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindCard, uuid)
+
+	return nil
+}
+
+/*
+PurgeDeletedCards Permanently remove every card, and its extras, that was soft deleted before the time passed
+in the parameter. Returns the number of cards removed
+*/
+func PurgeDeletedCards(ctx stdContext.Context, before time.Time) (int64, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := database.PurgeDeleted(ctx, "card", before)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = database.PurgeDeleted(ctx, "card_extra", before)
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
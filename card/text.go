@@ -0,0 +1,168 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MAX_TEXT_PATTERN_LENGTH = 256
+
+	TextMatchKeyword = "keyword"
+	TextMatchRegex   = "regex"
+)
+
+/*
+wordCharRegex Matches a single word character, used to decide whether a keyword phrase starts and ends on a
+word boundary
+*/
+var wordCharRegex = regexp.MustCompile(`^\w$`)
+
+var ErrInvalidTextMatch = errors.New("card: Operation failed. The text match must be either keyword or regex")
+var ErrInvalidTextPattern = errors.New("card: Operation failed. The text pattern is empty or is not a valid regular expression")
+var ErrUnsafeTextPattern = errors.New("card: Operation failed. The text pattern is too long or nests repetition, and may take too long to evaluate")
+
+/*
+TextSearchOptions Controls how SearchText matches the rules text of cards. Match is either TextMatchKeyword
+(the default), which matches the words passed as a phrase, or TextMatchRegex, which matches a regular
+expression. Matches ignore case unless CaseSensitive is set. Page selects the page of results returned
+*/
+type TextSearchOptions struct {
+	Match         string
+	CaseSensitive bool
+	Page          *server.PageOptions
+}
+
+/*
+unsafeRepetition Returns true if the regular expression passed repeats, without an upper bound, an expression
+that itself repeats or alternates, such as (a+)+ or (a|aa)*. These are the patterns that cause catastrophic
+backtracking in the PCRE engine used by MongoDB
+*/
+func unsafeRepetition(expr *syntax.Regexp, repeated bool) bool {
+	unbounded := expr.Op == syntax.OpStar || expr.Op == syntax.OpPlus || (expr.Op == syntax.OpRepeat && expr.Max == -1)
+
+	switch expr.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat, syntax.OpAlternate:
+		if repeated {
+			return true
+		}
+	}
+
+	for _, sub := range expr.Sub {
+		if unsafeRepetition(sub, repeated || unbounded) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+textPattern Build the regular expression matching rules text against the pattern passed, as described by the
+match. Keywords are matched as a phrase, with any whitespace between words, and on word boundaries. Regular
+expressions must also be valid RE2 syntax, which excludes the backreferences and lookarounds that PCRE would
+otherwise allow, and are rejected with ErrUnsafeTextPattern if they are too long or nest repetition
+*/
+func textPattern(pattern string, match string, caseSensitive bool) (primitive.Regex, error) {
+	options := "i"
+	if caseSensitive {
+		options = ""
+	}
+
+	switch match {
+	case TextMatchKeyword:
+		words := strings.Fields(pattern)
+		if len(words) == 0 {
+			return primitive.Regex{}, ErrInvalidTextPattern
+		}
+
+		first, last := words[0], words[len(words)-1]
+
+		for index, word := range words {
+			words[index] = regexp.QuoteMeta(word)
+		}
+
+		phrase := strings.Join(words, `\s+`)
+
+		if wordCharRegex.MatchString(first[:1]) {
+			phrase = `\b` + phrase
+		}
+
+		if wordCharRegex.MatchString(last[len(last)-1:]) {
+			phrase += `\b`
+		}
+
+		return primitive.Regex{Pattern: phrase, Options: options}, nil
+	case TextMatchRegex:
+		if strings.TrimSpace(pattern) == "" {
+			return primitive.Regex{}, ErrInvalidTextPattern
+		}
+
+		if len(pattern) > MAX_TEXT_PATTERN_LENGTH {
+			return primitive.Regex{}, ErrUnsafeTextPattern
+		}
+
+		parsed, err := syntax.Parse(pattern, syntax.Perl)
+		if err != nil {
+			return primitive.Regex{}, ErrInvalidTextPattern
+		}
+
+		if unsafeRepetition(parsed, false) {
+			return primitive.Regex{}, ErrUnsafeTextPattern
+		}
+
+		return primitive.Regex{Pattern: pattern, Options: options}, nil
+	}
+
+	return primitive.Regex{}, ErrInvalidTextMatch
+}
+
+/*
+SearchText Returns a single page of the cards whose rules text matches the pattern passed, such as every card
+containing "create a Treasure token". See TextSearchOptions for how the pattern is matched, and pass the
+NextCursor of the returned page in its Page options to fetch the next page. If database is nil, the database
+of the context passed is used. Returns ErrInvalidTextPattern or ErrUnsafeTextPattern if the pattern is rejected
+*/
+func SearchText(ctx stdContext.Context, database server.DatabaseInterface, pattern string, opts *TextSearchOptions) ([]*card.CardSet, *server.Page, error) {
+	normalized := TextSearchOptions{}
+	if opts != nil {
+		normalized = *opts
+	}
+
+	if normalized.Match == "" {
+		normalized.Match = TextMatchKeyword
+	}
+
+	regex, err := textPattern(pattern, normalized.Match, normalized.CaseSensitive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if database == nil {
+		database, err = context.GetDatabase(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var ret []*card.CardSet
+
+	page, err := database.Paginate(ctx, "card", bson.M{"text": regex}, normalized.Page, &ret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serverMetrics.AddCardsServed(len(ret))
+
+	return ret, page, nil
+}
@@ -0,0 +1,273 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	TOKEN_COLLECTION = "card_token"
+)
+
+var ErrNoToken = errors.New("card: failed to find token with specified uuid")
+var ErrTokenMissingId = errors.New("card: Token is missing a name and/or a mtgjsonV4Id")
+var ErrTokenAlreadyExists = errors.New("card: Operation failed. Token already exists")
+var ErrTokenDeleteFailed = errors.New("card: Operation failed. Failed to remove token")
+
+/*
+CardToken A token card, mirroring the tokens of MTGJSON's set files. Tokens are stored in the card_token
+collection keyed by their MTGJSONv4 ID. The reverseRelated field of RelatedCards holds the names of the
+cards that create the token, which is used to link tokens and cards, see GetCardTokens and GetTokenCreators
+*/
+type CardToken struct {
+	Name           string                `bson:"name" json:"name"`
+	AsciiName      string                `bson:"asciiName,omitempty" json:"asciiName,omitempty"`
+	FaceName       string                `bson:"faceName,omitempty" json:"faceName,omitempty"`
+	Side           string                `bson:"side,omitempty" json:"side,omitempty"`
+	Layout         string                `bson:"layout" json:"layout"`
+	Type           string                `bson:"type" json:"type"`
+	Supertypes     []string              `bson:"supertypes" json:"supertypes"`
+	Types          []string              `bson:"types" json:"types"`
+	Subtypes       []string              `bson:"subtypes" json:"subtypes"`
+	Text           string                `bson:"text" json:"text"`
+	Power          string                `bson:"power,omitempty" json:"power,omitempty"`
+	Toughness      string                `bson:"toughness,omitempty" json:"toughness,omitempty"`
+	Loyalty        string                `bson:"loyalty,omitempty" json:"loyalty,omitempty"`
+	Colors         []string              `bson:"colors" json:"colors"`
+	ColorIdentity  []string              `bson:"colorIdentity" json:"colorIdentity"`
+	ColorIndicator []string              `bson:"colorIndicator,omitempty" json:"colorIndicator,omitempty"`
+	Keywords       []string              `bson:"keywords" json:"keywords"`
+	Artist         string                `bson:"artist,omitempty" json:"artist,omitempty"`
+	Number         string                `bson:"number" json:"number"`
+	SetCode        string                `bson:"setCode" json:"setCode"`
+	Finishes       []string              `bson:"finishes" json:"finishes"`
+	Identifiers    *meta.CardIdentifiers `bson:"identifiers" json:"identifiers"`
+	RelatedCards   *meta.RelatedCards    `bson:"relatedCards" json:"relatedCards"`
+	MtgjsonApiMeta *meta.MTGJSONAPIMeta  `bson:"mtgjsonApiMeta" json:"mtgjsonApiMeta"`
+}
+
+/*
+tokenRepository Returns a typed repository for the card_token collection. Missing tokens are reported as
+ErrNoToken
+*/
+func tokenRepository(ctx stdContext.Context) (*server.Repository[CardToken], error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.NewRepository[CardToken](database, TOKEN_COLLECTION, ErrNoToken), nil
+}
+
+/*
+GetToken Returns the token with the MTGJSONv4 ID passed in the parameter. If owner is an empty string, the
+token is returned regardless of its owner. Returns ErrNoToken if the token does not exist
+*/
+func GetToken(ctx stdContext.Context, uuid string, owner string) (*CardToken, error) {
+	if !ValidateUUID(uuid) {
+		return nil, sdkErrors.ErrInvalidUUID
+	}
+
+	repo, err := tokenRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"identifiers.mtgjsonV4Id": uuid}
+	if owner != "" {
+		query["mtgjsonApiMeta.owner"] = owner
+	}
+
+	return repo.FindOne(ctx, query)
+}
+
+/*
+GetTokens Returns every token whose MTGJSONv4 ID is one of the UUIDs passed in the parameter, such as the
+tokens listed by a set. UUIDs that do not exist are skipped
+*/
+func GetTokens(ctx stdContext.Context, uuids []string) ([]*CardToken, error) {
+	repo, err := tokenRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.FindMany(ctx, bson.M{"identifiers.mtgjsonV4Id": bson.M{"$in": uuids}})
+}
+
+/*
+NewToken Insert a new token into the database under the owner passed in the parameter, or the system user
+if it is an empty string. The token must have a name and an MTGJSONv4 ID, and cannot already exist under
+the same ID. Returns ErrTokenAlreadyExists if it does
+*/
+func NewToken(ctx stdContext.Context, token *CardToken, owner string) error {
+	if token.Identifiers == nil || token.Name == "" || token.Identifiers.MtgjsonV4Id == "" {
+		return ErrTokenMissingId
+	}
+
+	if owner == "" {
+		owner = user.SystemUser
+	}
+
+	if owner != user.SystemUser {
+		_, err := user.GetUser(ctx, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := GetToken(ctx, token.Identifiers.MtgjsonV4Id, owner)
+	if err == nil {
+		return ErrTokenAlreadyExists
+	}
+
+	if !errors.Is(err, ErrNoToken) {
+		return err
+	}
+
+	if token.RelatedCards == nil {
+		token.RelatedCards = &meta.RelatedCards{ReverseRelated: []string{}, Spellbook: []string{}}
+	}
+
+	currentDate := util.CreateTimestampStr()
+	token.MtgjsonApiMeta = &meta.MTGJSONAPIMeta{
+		Owner:        owner,
+		Type:         "Card",
+		Subtype:      "Token",
+		CreationDate: currentDate,
+		ModifiedDate: currentDate,
+	}
+
+	repo, err := tokenRepository(ctx)
+	if err != nil {
+		return err
+	}
+
+	return repo.Insert(ctx, token)
+}
+
+/*
+ReplaceToken Replace an existing token in the database with the token passed in the parameter. The token
+is located using its MTGJSONv4 ID and the owner stored in its API metadata, and is only replaced if it has
+not been modified since it was read, see ReplaceCard. Returns ErrCardUpdateFailed if the token cannot be
+located, wrapping server.ErrConflict if another writer modified the token first
+*/
+func ReplaceToken(ctx stdContext.Context, token *CardToken) error {
+	if token.Identifiers == nil || token.Identifiers.MtgjsonV4Id == "" {
+		return ErrTokenMissingId
+	}
+
+	if token.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
+	}
+
+	repo, err := tokenRepository(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"identifiers.mtgjsonV4Id": token.Identifiers.MtgjsonV4Id, "mtgjsonApiMeta.owner": token.MtgjsonApiMeta.Owner}
+
+	expected := token.MtgjsonApiMeta.ModifiedDate
+	token.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr()
+
+	err = repo.ReplaceVersion(ctx, query, server.VersionField, expected, token)
+	if err != nil {
+		token.MtgjsonApiMeta.ModifiedDate = expected
+		return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+	}
+
+	return nil
+}
+
+/*
+DeleteToken Remove a token from the database. If owner is an empty string, the token is removed regardless
+of its owner. Returns ErrNoToken if the token does not exist
+*/
+func DeleteToken(ctx stdContext.Context, uuid string, owner string) error {
+	repo, err := tokenRepository(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"identifiers.mtgjsonV4Id": uuid}
+	if owner != "" {
+		query["mtgjsonApiMeta.owner"] = owner
+	}
+
+	err = repo.Delete(ctx, query)
+	if err != nil && !errors.Is(err, ErrNoToken) {
+		return fmt.Errorf("%w: %w", ErrTokenDeleteFailed, err)
+	}
+
+	return err
+}
+
+/*
+IndexTokens Returns all tokens in the database. The limit parameter will be passed directly to the database
+query to limit the number of models returned. Sort fields can be passed to order the tokens before the limit
+is applied, see server.SortBy
+*/
+func IndexTokens(ctx stdContext.Context, limit int64, sort ...string) ([]*CardToken, error) {
+	var result []*CardToken
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Index(ctx, TOKEN_COLLECTION, limit, &result, sort...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+GetCardTokens Returns every token created by the card passed in the parameter, as listed in the reverseRelated
+field of the token. Tokens created by a single face of a multi faced card are matched on its face name as well
+*/
+func GetCardTokens(ctx stdContext.Context, card *card.CardSet) ([]*CardToken, error) {
+	names := []string{card.Name}
+	if card.FaceName != "" && card.FaceName != card.Name {
+		names = append(names, card.FaceName)
+	}
+
+	repo, err := tokenRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.FindMany(ctx, bson.M{"relatedCards.reverseRelated": bson.M{"$in": names}})
+}
+
+/*
+GetTokenCreators Returns the cards that create the token passed in the parameter, as listed in the
+reverseRelated field of its RelatedCards. Every printing of each card is returned, without their large
+fields (see ListingFields)
+*/
+func GetTokenCreators(ctx stdContext.Context, token *CardToken) ([]*card.CardSet, error) {
+	if token.RelatedCards == nil || len(token.RelatedCards.ReverseRelated) == 0 {
+		return []*card.CardSet{}, nil
+	}
+
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := token.RelatedCards.ReverseRelated
+	query := bson.M{"$or": bson.A{bson.M{"name": bson.M{"$in": names}}, bson.M{"faceName": bson.M{"$in": names}}}}
+
+	return repo.FindMany(ctx, query, ListingFields...)
+}
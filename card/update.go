@@ -0,0 +1,216 @@
+package card
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/query"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+ImmutableFields The fields of a card that cannot be changed with UpdateCard. The identifiers of a card are
+referenced by decks, sets and collections, and its API metadata is maintained by the SDK
+*/
+var ImmutableFields = []string{"_id", "uuid", "identifiers", "mtgjsonApiMeta"}
+
+/*
+largeFields The fields stored in the card_extra collection when large fields are split, see SplitLargeFields
+*/
+var largeFields = []string{"foreignData", "rulings", "purchaseUrls"}
+
+/*
+validatedFields The fields of a card that are checked by ValidateCard. Updates to them are validated against the
+rest of the card, as the type line is checked against the supertypes, types and subtypes
+*/
+var validatedFields = []string{
+	"manaCost", "colors", "colorIdentity", "colorIndicator", "rarity", "layout", "type", "supertypes", "types", "subtypes",
+}
+
+var ErrImmutableField = errors.New("card: Operation failed. The update modifies a field that cannot be changed")
+var ErrPartialUpdate = errors.New("card: Operation failed. Fields that are validated must be updated in full rather than by a dotted path")
+
+/*
+rootField Returns the top level field of the dotted path passed (e.g. identifiers for identifiers.scryfallId)
+*/
+func rootField(path string) string {
+	root, _, _ := strings.Cut(path, ".")
+	return root
+}
+
+/*
+decodeForeignData Decode the value of a foreignData update into its model, so that it can be validated
+*/
+func decodeForeignData(value interface{}) ([]*meta.ForeignData, error) {
+	var ret struct {
+		ForeignData []*meta.ForeignData `bson:"foreignData"`
+	}
+
+	document, err := bson.Marshal(bson.M{"foreignData": value})
+	if err != nil {
+		return nil, err
+	}
+
+	err = bson.Unmarshal(document, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret.ForeignData, nil
+}
+
+/*
+validateUpdate Apply the updates to validated fields passed to the stored version of the card, and check the
+result with ValidateCard. Returns ErrNoCard if the card does not exist
+*/
+func validateUpdate(ctx stdContext.Context, uuid string, owner string, fields bson.M) error {
+	repo, err := repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := repo.FindOne(ctx, cardQuery(uuid, owner), validatedFields...)
+	if err != nil {
+		return err
+	}
+
+	document, err := bson.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	merged := bson.M{}
+	err = bson.Unmarshal(document, &merged)
+	if err != nil {
+		return err
+	}
+
+	for field, value := range fields {
+		merged[field] = value
+	}
+
+	document, err = bson.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+	}
+
+	var updated card.CardSet
+	err = bson.Unmarshal(document, &updated)
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+	}
+
+	return ValidateCard(&updated)
+}
+
+/*
+UpdateCard Set the fields passed in the parameter on an existing card, without replacing the rest of the card.
+Fields are keyed by their bson name and may be dotted paths (e.g. legalities.commander). The modified date of
+the API metadata of the card is updated along with them. If owner is an empty string, the card is located by
+its UUID alone. Returns ErrImmutableField if any field is one of the ImmutableFields, query.ErrInvalidField
+if a field name is unsafe, and ErrNoCard if the card does not exist. Unless the card is owned by the system
+user, a foreignData field is validated with ValidateForeignData, and the result of updating the fields checked by
+ValidateCard is validated against the rest of the card. These fields must be passed in full, and ErrPartialUpdate
+is returned for a dotted path below them (e.g. foreignData.0.language). When large fields are split,
+updates to them are written to the card_extra collection. The version of the card before the update is recorded
+in its history, see GetHistory
+*/
+func UpdateCard(ctx stdContext.Context, uuid string, owner string, fields bson.M) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	validate := owner != user.SystemUser
+
+	cardFields, extraFields, validated := bson.M{}, bson.M{}, bson.M{}
+	for field, value := range fields {
+		if field == "" || strings.HasPrefix(field, "$") || strings.ContainsRune(field, 0) {
+			return query.ErrInvalidField
+		}
+
+		root := rootField(field)
+		if slices.Contains(ImmutableFields, root) {
+			return ErrImmutableField
+		}
+
+		if validate && field != root && (root == "foreignData" || slices.Contains(validatedFields, root)) {
+			return ErrPartialUpdate
+		}
+
+		if validate && slices.Contains(validatedFields, field) {
+			validated[field] = value
+		}
+
+		if validate && field == "foreignData" {
+			foreignData, err := decodeForeignData(value)
+			if err != nil {
+				return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+			}
+
+			err = ValidateForeignData(foreignData)
+			if err != nil {
+				return err
+			}
+
+			value = foreignData
+		}
+
+		if SplitLargeFields() && slices.Contains(largeFields, root) {
+			extraFields[field] = value
+			continue
+		}
+
+		cardFields[field] = value
+	}
+
+	cardFields["mtgjsonApiMeta.modifiedDate"] = util.CreateTimestampStr()
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = withRevision(ctx, uuid, owner, RevisionUpdate, func(ctx stdContext.Context) error {
+		if len(validated) != 0 {
+			err := validateUpdate(ctx, uuid, owner, validated)
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err := database.SetField(ctx, "card", cardQuery(uuid, owner), cardFields)
+		if err != nil {
+			return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+		}
+
+		if result.MatchedCount == 0 {
+			return sdkErrors.ErrNoCard
+		}
+
+		if len(extraFields) != 0 {
+			_, err = database.SetField(ctx, "card_extra", extrasQuery(uuid, owner), extraFields)
+			if err != nil {
+				return fmt.Errorf("%w: %w", sdkErrors.ErrCardUpdateFailed, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindCard, uuid)
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package card
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+)
+
+/*
+Rarities The rarities that MTGJSON assigns to cards
+*/
+var Rarities = []string{"common", "uncommon", "rare", "mythic", "special", "bonus"}
+
+/*
+Layouts The layouts that MTGJSON assigns to cards
+*/
+var Layouts = []string{
+	"adventure", "aftermath", "art_series", "augment", "battle", "case", "class", "double_faced_token", "emblem",
+	"flip", "host", "leveler", "meld", "modal_dfc", "mutate", "normal", "planar", "prototype", "reversible_card",
+	"saga", "scheme", "split", "token", "transform", "vanguard",
+}
+
+/*
+CardTypes The card types that may appear on the type line of a card
+*/
+var CardTypes = []string{
+	"Artifact", "Battle", "Conspiracy", "Creature", "Dungeon", "Enchantment", "Instant", "Kindred", "Land",
+	"Phenomenon", "Plane", "Planeswalker", "Scheme", "Sorcery", "Tribal", "Vanguard",
+}
+
+/*
+Supertypes The supertypes that may appear on the type line of a card
+*/
+var Supertypes = []string{"Basic", "Elite", "Host", "Legendary", "Ongoing", "Snow", "World"}
+
+/*
+manaCostRegex Matches a mana cost made up of any number of mana symbols, such as {2}{W}{U/P}
+*/
+var manaCostRegex = regexp.MustCompile(`^(\{[^{}]+\})*$`)
+
+/*
+manaSymbolRegex Matches the contents of a single mana symbol: generic and variable costs, colored and colorless
+mana, snow, half mana, and the hybrid and Phyrexian symbols
+*/
+var manaSymbolRegex = regexp.MustCompile(`^([0-9]+|[WUBRGCXYZSP]|½|∞|H[WUBRG]|[WUBRG]/[WUBRG](/P)?|[2C]/[WUBRG]|[WUBRGC]/P)$`)
+
+var ErrInvalidCard = errors.New("card: Operation failed. The card does not match the MTGJSON schema")
+
+/*
+Violation A single field of a card that does not match the MTGJSON schema
+*/
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+/*
+ValidationError Returned when a card fails ValidateCard, listing every violation found. It matches
+ErrInvalidCard with errors.Is
+*/
+type ValidationError struct {
+	Violations []*Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Field+": "+violation.Message)
+	}
+
+	return ErrInvalidCard.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidCard
+}
+
+/*
+colorViolations Return a violation for the field passed if any of its colors are not one of Colors, or a
+color appears more than once
+*/
+func colorViolations(field string, colors []string) []*Violation {
+	var ret []*Violation
+
+	seen := map[string]bool{}
+	for _, color := range colors {
+		if !slices.Contains(Colors, color) {
+			ret = append(ret, &Violation{Field: field, Message: "'" + color + "' is not one of W, U, B, R or G"})
+			continue
+		}
+
+		if seen[color] {
+			ret = append(ret, &Violation{Field: field, Message: "'" + color + "' appears more than once"})
+		}
+
+		seen[color] = true
+	}
+
+	return ret
+}
+
+/*
+manaCostViolations Return a violation if the mana cost passed is not made up of valid mana symbols
+*/
+func manaCostViolations(manaCost string) []*Violation {
+	if manaCost == "" {
+		return nil
+	}
+
+	if !manaCostRegex.MatchString(manaCost) {
+		return []*Violation{{Field: "manaCost", Message: "'" + manaCost + "' is not a sequence of mana symbols such as {2}{W}"}}
+	}
+
+	var ret []*Violation
+	for _, symbol := range strings.Split(strings.Trim(manaCost, "{}"), "}{") {
+		if !manaSymbolRegex.MatchString(symbol) {
+			ret = append(ret, &Violation{Field: "manaCost", Message: "'{" + symbol + "}' is not a valid mana symbol"})
+		}
+	}
+
+	return ret
+}
+
+/*
+typeLineViolations Return violations for any supertypes or types that are not known, and for a type line that
+is not made up of the supertypes, types and subtypes of the card (e.g. "Legendary Creature — Elf Druid")
+*/
+func typeLineViolations(card *card.CardSet) []*Violation {
+	var ret []*Violation
+
+	for _, value := range card.Supertypes {
+		if !slices.Contains(Supertypes, value) {
+			ret = append(ret, &Violation{Field: "supertypes", Message: "'" + value + "' is not a known supertype"})
+		}
+	}
+
+	for _, value := range card.Types {
+		if !slices.Contains(CardTypes, value) {
+			ret = append(ret, &Violation{Field: "types", Message: "'" + value + "' is not a known card type"})
+		}
+	}
+
+	if card.Type == "" && len(card.Types) == 0 {
+		return ret
+	}
+
+	expected := strings.Join(append(slices.Clone(card.Supertypes), card.Types...), " ")
+	if len(card.Subtypes) != 0 {
+		expected += " — " + strings.Join(card.Subtypes, " ")
+	}
+
+	if card.Type != expected {
+		ret = append(ret, &Violation{Field: "type", Message: "'" + card.Type + "' does not match its supertypes, types and subtypes, expected '" + expected + "'"})
+	}
+
+	return ret
+}
+
+/*
+ValidateCard Check the card passed against the values used by MTGJSON: the syntax of its mana cost, its colors,
+color identity and color indicator, its rarity and layout, and the consistency of its type line with its
+supertypes, types and subtypes. Empty fields are not checked. Returns a ValidationError listing every violation
+found, or nil if the card is valid
+*/
+func ValidateCard(card *card.CardSet) error {
+	var violations []*Violation
+
+	violations = append(violations, manaCostViolations(card.ManaCost)...)
+	violations = append(violations, colorViolations("colors", card.Colors)...)
+	violations = append(violations, colorViolations("colorIdentity", card.ColorIdentity)...)
+	violations = append(violations, colorViolations("colorIndicator", card.ColorIndicator)...)
+
+	if card.Rarity != "" && !slices.Contains(Rarities, card.Rarity) {
+		violations = append(violations, &Violation{Field: "rarity", Message: "'" + card.Rarity + "' is not one of " + strings.Join(Rarities, ", ")})
+	}
+
+	if card.Layout != "" && !slices.Contains(Layouts, card.Layout) {
+		violations = append(violations, &Violation{Field: "layout", Message: "'" + card.Layout + "' is not a known layout"})
+	}
+
+	violations = append(violations, typeLineViolations(card)...)
+
+	if len(violations) != 0 {
+		return &ValidationError{Violations: violations}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package card
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stevezaluk/mtgjson-models/card"
+)
+
+func TestValidateCard(t *testing.T) {
+	tests := []struct {
+		name string
+		card *card.CardSet
+		want []string
+	}{
+		{
+			name: "empty card",
+			card: &card.CardSet{},
+		},
+		{
+			name: "valid card",
+			card: &card.CardSet{
+				ManaCost:      "{1}{G}{G}",
+				Colors:        []string{"G"},
+				ColorIdentity: []string{"G"},
+				Rarity:        "rare",
+				Layout:        "normal",
+				Supertypes:    []string{"Legendary"},
+				Types:         []string{"Creature"},
+				Subtypes:      []string{"Elf", "Druid"},
+				Type:          "Legendary Creature — Elf Druid",
+			},
+		},
+		{
+			name: "hybrid, Phyrexian and variable mana symbols",
+			card: &card.CardSet{ManaCost: "{X}{W/U}{B/P}{2/R}{C}{S}"},
+		},
+		{
+			name: "mana cost that is not made of symbols",
+			card: &card.CardSet{ManaCost: "1G"},
+			want: []string{"manaCost"},
+		},
+		{
+			name: "unknown mana symbol",
+			card: &card.CardSet{ManaCost: "{1}{Q}"},
+			want: []string{"manaCost"},
+		},
+		{
+			name: "unknown and repeated colors",
+			card: &card.CardSet{Colors: []string{"R", "R"}, ColorIdentity: []string{"P"}},
+			want: []string{"colors", "colorIdentity"},
+		},
+		{
+			name: "unknown rarity and layout",
+			card: &card.CardSet{Rarity: "legendary", Layout: "sideways"},
+			want: []string{"rarity", "layout"},
+		},
+		{
+			name: "unknown supertype and type",
+			card: &card.CardSet{Supertypes: []string{"Epic"}, Types: []string{"Hero"}, Type: "Epic Hero"},
+			want: []string{"supertypes", "types"},
+		},
+		{
+			name: "type line that does not match its types",
+			card: &card.CardSet{Types: []string{"Instant"}, Type: "Sorcery"},
+			want: []string{"type"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateCard(test.card)
+			if len(test.want) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateCard() error = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidCard) {
+				t.Fatalf("ValidateCard() error = %v, want %v", err, ErrInvalidCard)
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("ValidateCard() error = %T, want *ValidationError", err)
+			}
+
+			var fields []string
+			for _, violation := range validationErr.Violations {
+				fields = append(fields, violation.Field)
+			}
+
+			if !reflect.DeepEqual(fields, test.want) {
+				t.Errorf("ValidateCard() violations = %v, want %v", fields, test.want)
+			}
+		})
+	}
+}
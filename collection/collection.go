@@ -0,0 +1,170 @@
+package collection
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"slices"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrCardNotOwned = errors.New("collection: Operation failed. User does not own the requested card")
+var ErrNoLocation = errors.New("collection: Failed to find a storage location for the requested card")
+var ErrLocationEmpty = errors.New("collection: Operation failed. Storage location must have a box or binder")
+var ErrLocationUpdateFailed = errors.New("collection: Operation failed. Failed to update storage location")
+
+/*
+Location Represents where a single owned card physically lives. A location must specify either
+a box or a binder, page and slot are optional and are only meaningful when a binder is used. The storage
+fields are removed by visibility.Shape for anyone other than the owner
+*/
+type Location struct {
+	Owner  string `bson:"owner" json:"owner" visibility:"ownerKey"`
+	CardId string `bson:"cardId" json:"cardId"`
+	Box    string `bson:"box" json:"box,omitempty" visibility:"owner"`
+	Binder string `bson:"binder" json:"binder,omitempty" visibility:"owner"`
+	Page   int64  `bson:"page" json:"page,omitempty" visibility:"owner"`
+	Slot   int64  `bson:"slot" json:"slot,omitempty" visibility:"owner"`
+}
+
+/*
+GetCardLocation Fetch the storage location of a card owned by the user passed in the email parameter.
+Returns ErrNoLocation if no location has been assigned to the card
+*/
+func GetCardLocation(ctx stdContext.Context, email string, uuid string) (*Location, error) {
+	var result *Location
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "collection_location", bson.M{"owner": email, "cardId": uuid}, &result)
+	if errors.Is(err, server.ErrNotFound) {
+		return nil, ErrNoLocation
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+SetCardLocation Assign a storage location to a card owned by the user passed in the email parameter. If the
+card already has a location assigned, it will be overwritten. Returns ErrCardNotOwned if the user does not
+own at least one copy of the card
+*/
+func SetCardLocation(ctx stdContext.Context, email string, uuid string, location *Location) error {
+	if location.Box == "" && location.Binder == "" {
+		return ErrLocationEmpty
+	}
+
+	owner, err := user.GetUser(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(owner.OwnedCards, uuid) {
+		return ErrCardNotOwned
+	}
+
+	location.Owner = email
+	location.CardId = uuid
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = GetCardLocation(ctx, email, uuid)
+	if errors.Is(err, ErrNoLocation) {
+		_, err = database.Insert(ctx, "collection_location", location)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrLocationUpdateFailed, err)
+		}
+
+		return nil
+	}
+
+	_, err = database.Replace(ctx, "collection_location", bson.M{"owner": email, "cardId": uuid}, location)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLocationUpdateFailed, err)
+	}
+
+	return nil
+}
+
+/*
+RemoveCardLocation Remove the storage location assigned to a card. Returns ErrNoLocation if the card
+does not have a location assigned
+*/
+func RemoveCardLocation(ctx stdContext.Context, email string, uuid string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Delete(ctx, "collection_location", bson.M{"owner": email, "cardId": uuid})
+	if errors.Is(err, server.ErrNotFound) {
+		return ErrNoLocation
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+/*
+FindByLocation Return all cards owned by the user that are stored in the location passed. Only the
+non-empty fields of the location are used for matching, so passing only a binder will return every
+card in that binder regardless of page or slot
+*/
+func FindByLocation(ctx stdContext.Context, email string, location *Location) ([]*Location, error) {
+	var result []*Location
+
+	if email == "" {
+		return nil, sdkErrors.ErrUserMissingId
+	}
+
+	query := bson.M{"owner": email}
+	if location.Box != "" {
+		query["box"] = location.Box
+	}
+
+	if location.Binder != "" {
+		query["binder"] = location.Binder
+	}
+
+	if location.Page != 0 {
+		query["page"] = location.Page
+	}
+
+	if location.Slot != 0 {
+		query["slot"] = location.Slot
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.FindMany(ctx, "collection_location", query, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoLocation
+	}
+
+	return result, nil
+}
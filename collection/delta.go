@@ -0,0 +1,199 @@
+package collection
+
+import (
+	stdContext "context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/card"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+MAX_OWNED_COPIES The most copies of a single card that a user may own. Copies are stored as repeated UUID's,
+so this bounds the growth of the ownedCards field of a user
+*/
+const MAX_OWNED_COPIES = 1000
+
+var ErrInvalidDeltaFile = errors.New("collection: Operation failed. Delta file is not a valid CSV of uuid,delta rows")
+var ErrCollectionModified = errors.New("collection: Operation failed. Collection was modified while deltas were being applied")
+
+/*
+Delta A single signed quantity change for a card in a users collection. A positive quantity
+adds copies of the card, a negative quantity removes them
+*/
+type Delta struct {
+	Line     int    `json:"line"`
+	CardId   string `json:"cardId"`
+	Quantity int64  `json:"quantity"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+/*
+DeltaReport A summary of an ApplyDeltas operation. Rejected rows are not applied to the collection
+and carry the reason they were rejected
+*/
+type DeltaReport struct {
+	Applied  []*Delta `json:"applied"`
+	Rejected []*Delta `json:"rejected"`
+	Added    int64    `json:"added"`
+	Removed  int64    `json:"removed"`
+}
+
+/*
+CountOwned Return the number of copies of a card in the slice of owned cards passed. Copies are
+represented as repeated MTGJSONv4 UUID's within the users ownedCards field
+*/
+func CountOwned(ownedCards []string, uuid string) int64 {
+	var ret int64
+
+	for _, value := range ownedCards {
+		if value == uuid {
+			ret++
+		}
+	}
+
+	return ret
+}
+
+/*
+parseDeltas Read a CSV of uuid,delta rows from the reader passed. A header row is skipped if present
+*/
+func parseDeltas(reader io.Reader) ([]*Delta, error) {
+	var ret []*Delta
+
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = 2
+	csvReader.TrimLeadingSpace = true
+
+	line := 0
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, ErrInvalidDeltaFile
+		}
+
+		line++
+		if line == 1 && strings.EqualFold(record[0], "uuid") {
+			continue
+		}
+
+		delta := &Delta{Line: line, CardId: strings.TrimSpace(record[0])}
+
+		quantity, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+		if err != nil {
+			delta.Reason = "quantity is not a valid integer"
+		}
+
+		delta.Quantity = quantity
+		ret = append(ret, delta)
+	}
+
+	return ret, nil
+}
+
+/*
+ApplyDeltas Apply signed quantity changes from a CSV of uuid,delta rows to the collection of the user passed
+in the email parameter. Rows are applied in order, and any row that would take the quantity of a card below
+zero or above MAX_OWNED_COPIES, references an invalid UUID, or adds a card that does not exist is rejected. All accepted rows are written
+in a single update, and ErrCollectionModified is returned if the collection changed while the deltas were
+being computed
+*/
+func ApplyDeltas(ctx stdContext.Context, email string, reader io.Reader) (*DeltaReport, error) {
+	owner, err := user.GetUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas, err := parseDeltas(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for _, delta := range deltas {
+		if delta.Reason == "" && delta.Quantity > 0 && !slices.Contains(added, delta.CardId) {
+			added = append(added, delta.CardId)
+		}
+	}
+
+	var noExistCards []string
+	if len(added) != 0 {
+		err, _, noExistCards = card.ValidateCards(ctx, added)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &DeltaReport{Applied: []*Delta{}, Rejected: []*Delta{}}
+	ownedCards := slices.Clone(owner.OwnedCards)
+
+	for _, delta := range deltas {
+		if delta.Reason == "" && !card.ValidateUUID(delta.CardId) {
+			delta.Reason = "uuid is not a valid MTGJSONv4 id"
+		} else if delta.Reason == "" && delta.Quantity > 0 && slices.Contains(noExistCards, delta.CardId) {
+			delta.Reason = "card does not exist"
+		} else if delta.Reason == "" && CountOwned(ownedCards, delta.CardId)+delta.Quantity < 0 {
+			delta.Reason = "quantity would become negative"
+		} else if delta.Reason == "" && CountOwned(ownedCards, delta.CardId)+delta.Quantity > MAX_OWNED_COPIES {
+			delta.Reason = "quantity would exceed " + strconv.Itoa(MAX_OWNED_COPIES) + " copies"
+		}
+
+		if delta.Reason != "" {
+			report.Rejected = append(report.Rejected, delta)
+			continue
+		}
+
+		if delta.Quantity > 0 {
+			for i := int64(0); i < delta.Quantity; i++ {
+				ownedCards = append(ownedCards, delta.CardId)
+			}
+
+			report.Added += delta.Quantity
+		}
+
+		remaining := -delta.Quantity
+		for index := len(ownedCards) - 1; index >= 0 && remaining > 0; index-- {
+			if ownedCards[index] == delta.CardId {
+				ownedCards = slices.Delete(ownedCards, index, index+1)
+				remaining--
+				report.Removed++
+			}
+		}
+
+		report.Applied = append(report.Applied, delta)
+	}
+
+	if len(report.Applied) == 0 {
+		return report, nil
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"email": email, "ownedCards": owner.OwnedCards}
+	result, err := database.SetField(ctx, "user", query, bson.M{"ownedCards": ownedCards})
+	if err != nil {
+		return report, fmt.Errorf("%w: %w", sdkErrors.ErrUserUpdateFailed, err)
+	}
+
+	if result.MatchedCount != 1 {
+		return report, ErrCollectionModified
+	}
+
+	return report, nil
+}
@@ -0,0 +1,327 @@
+package collection
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"time"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/deck"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrNoLoan = errors.New("collection: Failed to find a loan with the specified id")
+var ErrLoanEmpty = errors.New("collection: Operation failed. A loan must contain a deck or at least one card")
+var ErrLoanSelf = errors.New("collection: Operation failed. Cards cannot be loaned to their owner")
+var ErrLoanDueDate = errors.New("collection: Operation failed. Loan due date must be in the future")
+var ErrCardUnavailable = errors.New("collection: Operation failed. Not enough available copies of the card to loan")
+var ErrDeckOnLoan = errors.New("collection: Operation failed. The deck is already on loan")
+var ErrLoanUpdateFailed = errors.New("collection: Operation failed. Failed to update loan")
+
+/*
+Loan Represents a deck or a list of owned cards that has been lent to another user. A loan is considered
+active until it is returned, and overdue once the due date has passed
+*/
+type Loan struct {
+	LoanId     string    `bson:"loanId" json:"loanId"`
+	Owner      string    `bson:"owner" json:"owner"`
+	Borrower   string    `bson:"borrower" json:"borrower"`
+	DeckCode   string    `bson:"deckCode" json:"deckCode,omitempty"`
+	CardIds    []string  `bson:"cardIds" json:"cardIds"`
+	DueDate    time.Time `bson:"dueDate" json:"dueDate"`
+	LoanedDate string    `bson:"loanedDate" json:"loanedDate"`
+	Returned   bool      `bson:"returned" json:"returned"`
+}
+
+/*
+IsOverdue Returns true if the loan has not been returned and its due date has passed
+*/
+func (l *Loan) IsOverdue() bool {
+	return !l.Returned && time.Now().After(l.DueDate)
+}
+
+/*
+GetLoan Fetch a single loan using the id passed in the parameter. Returns ErrNoLoan if the loan
+cannot be located
+*/
+func GetLoan(ctx stdContext.Context, loanId string) (*Loan, error) {
+	var result *Loan
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "collection_loan", bson.M{"loanId": loanId}, &result)
+	if errors.Is(err, server.ErrNotFound) {
+		return nil, ErrNoLoan
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+GetActiveLoans Return all loans that have not been returned yet where the user passed in the email
+parameter is the owner. Returns ErrNoLoan if the user has no active loans
+*/
+func GetActiveLoans(ctx stdContext.Context, email string) ([]*Loan, error) {
+	var result []*Loan
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.FindMany(ctx, "collection_loan", bson.M{"owner": email, "returned": false}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoLoan
+	}
+
+	return result, nil
+}
+
+/*
+GetBorrowedLoans Return all loans that have not been returned yet where the user passed in the email
+parameter is the borrower. Returns ErrNoLoan if the user is not borrowing anything
+*/
+func GetBorrowedLoans(ctx stdContext.Context, email string) ([]*Loan, error) {
+	var result []*Loan
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.FindMany(ctx, "collection_loan", bson.M{"borrower": email, "returned": false}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoLoan
+	}
+
+	return result, nil
+}
+
+/*
+GetDueLoans Return all active loans across every user that are due before the time passed in the
+parameter. This is intended to be polled by a reminder job so both the owner and borrower can be
+notified before (or after) a loan is due
+*/
+func GetDueLoans(ctx stdContext.Context, before time.Time) ([]*Loan, error) {
+	var result []*Loan
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"returned": false, "dueDate": bson.M{"$lte": before}}
+	err = database.FindMany(ctx, "collection_loan", query, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoLoan
+	}
+
+	return result, nil
+}
+
+/*
+GetAvailableCopies Return the number of copies of a card that the user owns and that are not currently
+on loan to another user
+*/
+func GetAvailableCopies(ctx stdContext.Context, email string, uuid string) (int64, error) {
+	owner, err := user.GetUser(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+
+	available := CountOwned(owner.OwnedCards, uuid)
+
+	loans, err := GetActiveLoans(ctx, email)
+	if errors.Is(err, ErrNoLoan) {
+		return available, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, loan := range loans {
+		available -= CountOwned(loan.CardIds, uuid)
+	}
+
+	return max(available, 0), nil
+}
+
+/*
+newLoan Validate the loan passed and insert it into the database. When loaning individual cards, every card
+must have enough available copies in the owners collection to cover it. Deck loans skip this check as a deck
+is not required to be built from the owners collection
+*/
+func newLoan(ctx stdContext.Context, loan *Loan) error {
+	if loan.Owner == loan.Borrower {
+		return ErrLoanSelf
+	}
+
+	if !loan.DueDate.After(time.Now()) {
+		return ErrLoanDueDate
+	}
+
+	_, err := user.GetUser(ctx, loan.Borrower)
+	if err != nil {
+		return err
+	}
+
+	checked := map[string]bool{}
+	for _, uuid := range loan.CardIds {
+		if loan.DeckCode != "" || checked[uuid] {
+			continue
+		}
+
+		available, err := GetAvailableCopies(ctx, loan.Owner, uuid)
+		if err != nil {
+			return err
+		}
+
+		if available < CountOwned(loan.CardIds, uuid) {
+			return ErrCardUnavailable
+		}
+
+		checked[uuid] = true
+	}
+
+	loan.LoanId = primitive.NewObjectID().Hex()
+	loan.LoanedDate = util.CreateTimestampStr()
+	loan.Returned = false
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Insert(ctx, "collection_loan", loan)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLoanUpdateFailed, err)
+	}
+
+	return nil
+}
+
+/*
+LoanCards Mark a list of owned cards as on loan to the borrower until the due date passed. Returns
+ErrCardUnavailable if the owner does not have enough copies of a card that are not already on loan
+*/
+func LoanCards(ctx stdContext.Context, owner string, borrower string, cards []string, dueDate time.Time) (*Loan, error) {
+	if len(cards) == 0 {
+		return nil, ErrLoanEmpty
+	}
+
+	loan := &Loan{
+		Owner:    owner,
+		Borrower: borrower,
+		CardIds:  cards,
+		DueDate:  dueDate,
+	}
+
+	err := newLoan(ctx, loan)
+	if err != nil {
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+/*
+LoanDeck Mark a deck owned by the user as on loan to the borrower until the due date passed. Every card
+in the deck is treated as loaned, and any copies the owner has in their collection are subtracted from
+their available copies until the loan is returned. Returns ErrDeckOnLoan if the deck is already on an active loan
+*/
+func LoanDeck(ctx stdContext.Context, owner string, borrower string, code string, dueDate time.Time) (*Loan, error) {
+	loanedDeck, err := deck.GetDeck(ctx, code, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := deck.AllCardIds(loanedDeck.ContentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cards) == 0 {
+		return nil, ErrLoanEmpty
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := database.Count(ctx, "collection_loan", bson.M{"owner": owner, "deckCode": code, "returned": false})
+	if err != nil {
+		return nil, err
+	}
+
+	if count != 0 {
+		return nil, ErrDeckOnLoan
+	}
+
+	loan := &Loan{
+		Owner:    owner,
+		Borrower: borrower,
+		DeckCode: code,
+		CardIds:  cards,
+		DueDate:  dueDate,
+	}
+
+	err = newLoan(ctx, loan)
+	if err != nil {
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+/*
+ReturnLoan Mark a loan as returned, making the loaned cards available in the owners collection again.
+Only the owner of the loan can mark it as returned
+*/
+func ReturnLoan(ctx stdContext.Context, owner string, loanId string) error {
+	if owner == "" {
+		return sdkErrors.ErrUserMissingId
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := database.SetField(ctx, "collection_loan", bson.M{"loanId": loanId, "owner": owner}, bson.M{"returned": true})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLoanUpdateFailed, err)
+	}
+
+	if result.MatchedCount != 1 {
+		return ErrNoLoan
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package collection
+
+import (
+	stdContext "context"
+	"errors"
+	"testing"
+	"time"
+
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	userModel "github.com/stevezaluk/mtgjson-models/user"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+const (
+	testOwner    = "owner@example.com"
+	testBorrower = "borrower@example.com"
+	testCard     = "5f8287b1-5bb6-5f4c-ad17-316a40d5bb0c"
+)
+
+/*
+loanContext Returns a context holding a memory database with an owner holding two copies of testCard, a
+borrower, and a deck of the owner built from a single copy of testCard
+*/
+func loanContext(t *testing.T) stdContext.Context {
+	ctx := context.WithDatabase(stdContext.Background(), server.NewMemoryDatabase())
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+
+	documents := map[string][]interface{}{
+		"user": {
+			&userModel.User{Email: testOwner, OwnedCards: []string{testCard, testCard}},
+			&userModel.User{Email: testBorrower},
+		},
+		"deck": {
+			&deckModel.Deck{
+				Code:           "ESP",
+				Name:           "Esper Control",
+				ContentIds:     &deckModel.DeckContentIds{MainBoard: []string{testCard}},
+				MtgjsonApiMeta: &meta.MTGJSONAPIMeta{Owner: testOwner},
+			},
+		},
+	}
+
+	for collection, models := range documents {
+		_, err = database.InsertMany(ctx, collection, models)
+		if err != nil {
+			t.Fatalf("InsertMany() error = %v", err)
+		}
+	}
+
+	return ctx
+}
+
+/*
+assertAvailable Fail the test if the owner does not have the number of available copies of testCard passed
+*/
+func assertAvailable(t *testing.T, ctx stdContext.Context, want int64) {
+	t.Helper()
+
+	available, err := GetAvailableCopies(ctx, testOwner, testCard)
+	if err != nil {
+		t.Fatalf("GetAvailableCopies() error = %v", err)
+	}
+
+	if available != want {
+		t.Errorf("GetAvailableCopies() = %d, want %d", available, want)
+	}
+}
+
+func TestLoanCards(t *testing.T) {
+	ctx := loanContext(t)
+	dueDate := time.Now().Add(24 * time.Hour)
+
+	loan, err := LoanCards(ctx, testOwner, testBorrower, []string{testCard, testCard}, dueDate)
+	if err != nil {
+		t.Fatalf("LoanCards() error = %v", err)
+	}
+
+	assertAvailable(t, ctx, 0)
+
+	_, err = LoanCards(ctx, testOwner, testBorrower, []string{testCard}, dueDate)
+	if !errors.Is(err, ErrCardUnavailable) {
+		t.Errorf("LoanCards() error = %v, want %v", err, ErrCardUnavailable)
+	}
+
+	err = ReturnLoan(ctx, testOwner, loan.LoanId)
+	if err != nil {
+		t.Fatalf("ReturnLoan() error = %v", err)
+	}
+
+	assertAvailable(t, ctx, 2)
+
+	tests := []struct {
+		name     string
+		borrower string
+		cards    []string
+		dueDate  time.Time
+		want     error
+	}{
+		{"empty", testBorrower, nil, dueDate, ErrLoanEmpty},
+		{"self", testOwner, []string{testCard}, dueDate, ErrLoanSelf},
+		{"due date in the past", testBorrower, []string{testCard}, time.Now().Add(-time.Hour), ErrLoanDueDate},
+		{"more copies than owned", testBorrower, []string{testCard, testCard, testCard}, dueDate, ErrCardUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoanCards(ctx, testOwner, test.borrower, test.cards, test.dueDate)
+			if !errors.Is(err, test.want) {
+				t.Errorf("LoanCards() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoanDeck(t *testing.T) {
+	ctx := loanContext(t)
+	dueDate := time.Now().Add(24 * time.Hour)
+
+	loan, err := LoanDeck(ctx, testOwner, testBorrower, "ESP", dueDate)
+	if err != nil {
+		t.Fatalf("LoanDeck() error = %v", err)
+	}
+
+	assertAvailable(t, ctx, 1)
+
+	_, err = LoanDeck(ctx, testOwner, testBorrower, "ESP", dueDate)
+	if !errors.Is(err, ErrDeckOnLoan) {
+		t.Errorf("LoanDeck() error = %v, want %v", err, ErrDeckOnLoan)
+	}
+
+	err = ReturnLoan(ctx, testOwner, loan.LoanId)
+	if err != nil {
+		t.Fatalf("ReturnLoan() error = %v", err)
+	}
+
+	_, err = LoanDeck(ctx, testOwner, testBorrower, "ESP", dueDate)
+	if err != nil {
+		t.Errorf("LoanDeck() error = %v", err)
+	}
+}
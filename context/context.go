@@ -24,7 +24,8 @@ var ServerContext = context.Background()
 /*
 Initialize viper to parse our config file or use environmental varibales to provide
 the values we need. Additionally, a config path can be passed to the function to override
-the default value
+the default value. If a config profile is selected, its values are merged over the top level
+values of the config file
 */
 func InitConfig(configPath string) {
 	if configPath != "" {
@@ -45,6 +46,10 @@ func InitConfig(configPath string) {
 	if err := viper.ReadInConfig(); err != nil {
 		panic(err)
 	}
+
+	if err := applyProfile(); err != nil {
+		panic(err)
+	}
 }
 
 /*
@@ -72,51 +77,231 @@ func InitLog() {
 
 	ctx := context.WithValue(ServerContext, "logger", logger)
 	ServerContext = ctx
+
+	setComponentState(ComponentLogger, StateConnected)
 }
 
 /*
-Fetch the Logger object that is stored in the ServerContext
+Fetch the Logger object that is stored in the ServerContext. If the logger has not been
+initialized then the default slog logger is returned
 */
 func GetLogger() *slog.Logger {
-	logger := ServerContext.Value("logger")
+	logger, ok := ServerContext.Value("logger").(*slog.Logger)
+	if !ok {
+		return slog.Default()
+	}
 
-	return logger.(*slog.Logger)
+	return logger
 }
 
 /*
 Initialize our MongoDB instance using values stored within viper, and store
-it within the ServerContext
+it within the ServerContext. If 'mongo.uri' is set it is used as is, otherwise
+the URI is built from 'mongo.hosts' and 'mongo.replica_set', falling back to
+'mongo.ip' and 'mongo.port'
 */
 func InitDatabase() {
-	database := &server.Database{}
-
-	viper.Set("mongo.uri", server.BuildDatabaseURI(
-		viper.GetString("mongo.ip"),
-		viper.GetInt("mongo.port"),
-		viper.GetString("mongo.user"),
-		viper.GetString("mongo.pass")))
+	switch {
+	case viper.GetString("mongo.uri") != "":
+		// a full connection string takes precedence, allowing mongodb+srv:// URIs and any driver option
+	case len(viper.GetStringSlice("mongo.hosts")) != 0:
+		viper.Set("mongo.uri", server.BuildReplicaSetURI(
+			viper.GetStringSlice("mongo.hosts"),
+			viper.GetString("mongo.replica_set"),
+			viper.GetString("mongo.user"),
+			viper.GetString("mongo.pass")))
+	default:
+		viper.Set("mongo.uri", server.BuildDatabaseURI(
+			viper.GetString("mongo.ip"),
+			viper.GetInt("mongo.port"),
+			viper.GetString("mongo.user"),
+			viper.GetString("mongo.pass")))
+	}
 
-	database.Connect(viper.GetString("mongo.uri")) // externalize errors to here and check
+	database := newDatabase(viper.GetString("mongo.uri"), viper.GetString("mongo.collection_prefix"))
 
 	ctx := context.WithValue(ServerContext, "database", database)
 	ServerContext = ctx
+
+	if viper.GetBool("mongo.ensure_indexes") {
+		built, err := database.EnsureIndexes(ServerContext)
+		if err != nil {
+			GetLogger().Error("Failed to ensure required indexes", "err", err)
+		} else if len(built) != 0 {
+			GetLogger().Info("Built missing indexes", "indexes", built)
+		}
+	}
+
+	CheckDatabase()
+}
+
+/*
+newDatabase Create a Database connected to the URI passed, using the TLS, pool, write concern, explain and
+retry options stored within viper. These options are shared by the default database and every tenant database
+*/
+func newDatabase(uri string, collectionPrefix string) *server.Database {
+	database := &server.Database{}
+
+	database.TLSOptions = &server.TLSOptions{
+		Enabled:            viper.GetBool("mongo.tls.enabled"),
+		CAFile:             viper.GetString("mongo.tls.ca_file"),
+		CertFile:           viper.GetString("mongo.tls.cert_file"),
+		KeyFile:            viper.GetString("mongo.tls.key_file"),
+		InsecureSkipVerify: viper.GetBool("mongo.tls.insecure_skip_verify"),
+	}
+
+	database.CollectionPrefix = collectionPrefix
+	database.SlowQueryThreshold = viper.GetDuration("mongo.slow_query_threshold")
+
+	database.PoolOptions = server.PoolOptions{
+		MaxPoolSize:     viper.GetUint64("mongo.pool.max_size"),
+		MinPoolSize:     viper.GetUint64("mongo.pool.min_size"),
+		MaxConnIdleTime: viper.GetDuration("mongo.pool.max_idle_time"),
+	}
+
+	database.Connect(uri) // externalize errors to here and check
+
+	for _, class := range []string{server.OperationClassCatalog, server.OperationClassUser} {
+		key := "mongo.write_concern." + class
+		if !viper.IsSet(key) {
+			continue
+		}
+
+		database.SetWriteConcern(class, viper.GetString(key+".w"), viper.GetBool(key+".journal"))
+	}
+
+	database.ExplainOptions = server.ExplainOptions{
+		Enabled:    viper.GetBool("mongo.explain.enabled"),
+		Threshold:  viper.GetDuration("mongo.explain.threshold"),
+		SampleRate: viper.GetFloat64("mongo.explain.sample_rate"),
+	}
+
+	database.Transactions = viper.GetBool("mongo.transactions")
+	database.SoftDelete = viper.GetBool("mongo.soft_delete")
+
+	if viper.GetBool("mongo.audit.enabled") {
+		database.EnableAudit(viper.GetDuration("mongo.audit.retention"))
+	}
+
+	database.RetryPolicy = server.DefaultRetryPolicy
+	if viper.IsSet("mongo.retry.max_attempts") {
+		database.RetryPolicy.MaxAttempts = viper.GetInt("mongo.retry.max_attempts")
+	}
+
+	if viper.IsSet("mongo.retry.initial_backoff") {
+		database.RetryPolicy.InitialBackoff = viper.GetDuration("mongo.retry.initial_backoff")
+	}
+
+	if viper.IsSet("mongo.retry.max_backoff") {
+		database.RetryPolicy.MaxBackoff = viper.GetDuration("mongo.retry.max_backoff")
+	}
+
+	if viper.IsSet("mongo.retry.jitter") {
+		database.RetryPolicy.Jitter = viper.GetFloat64("mongo.retry.jitter")
+	}
+
+	return database
 }
 
 /*
-Fetch the Database object that is stored in the ServerContext
+databaseKey The key a request scoped database is stored under in a context
 */
-func GetDatabase() *server.Database {
-	database := ServerContext.Value("database")
+type databaseKey struct{}
 
-	return database.(*server.Database)
+/*
+WithDatabase Return a copy of the context passed that routes every entity operation it is passed to to the
+database passed, in place of the database stored in the ServerContext. This is intended for the per request
+copy returned by Database.WithRequest, so that the request comment is attached to every query the request makes
+*/
+func WithDatabase(ctx context.Context, database server.DatabaseInterface) context.Context {
+	return context.WithValue(ctx, databaseKey{}, database)
 }
 
 /*
-Disconnect the database object that is stored in the ServerContext
+Fetch the database that entity operations made with the context passed should use. This is the database
+attached to the context with WithDatabase if there is one, otherwise the MongoDB Database created by
+InitDatabase, or the database passed to SetDatabase. If the context selects a tenant with WithTenant, the
+database of that tenant is returned instead. Returns ErrDatabaseNotInitialized if neither has been called,
+or ErrUnknownTenant if the selected tenant has no database
+*/
+func GetDatabase(ctx context.Context) (server.DatabaseInterface, error) {
+	if database, ok := ctx.Value(databaseKey{}).(server.DatabaseInterface); ok {
+		return database, nil
+	}
+
+	if tenant := GetTenant(ctx); tenant != "" {
+		return GetTenantDatabase(tenant)
+	}
+
+	database, ok := ServerContext.Value("database").(server.DatabaseInterface)
+	if !ok {
+		return nil, ErrDatabaseNotInitialized
+	}
+
+	return database, nil
+}
+
+/*
+GetMongoDatabase Fetch the MongoDB Database that entity operations made with the context passed should use,
+for operations that are only available against a real deployment. Like GetDatabase, the database attached to
+the context with WithDatabase is preferred, followed by the database of the tenant selected in the context. Returns
+ErrDatabaseNotInitialized if InitDatabase has not been called, including when a MemoryDatabase has been set
+with SetDatabase
+*/
+func GetMongoDatabase(ctx context.Context) (*server.Database, error) {
+	if database, ok := ctx.Value(databaseKey{}).(server.DatabaseInterface); ok {
+		mongoDatabase, ok := database.(*server.Database)
+		if !ok {
+			return nil, ErrDatabaseNotInitialized
+		}
+
+		return mongoDatabase, nil
+	}
+
+	if tenant := GetTenant(ctx); tenant != "" {
+		database, err := GetTenantDatabase(tenant)
+		if err != nil {
+			return nil, err
+		}
+
+		mongoDatabase, ok := database.(*server.Database)
+		if !ok {
+			return nil, ErrDatabaseNotInitialized
+		}
+
+		return mongoDatabase, nil
+	}
+
+	database, ok := ServerContext.Value("database").(*server.Database)
+	if !ok {
+		return nil, ErrDatabaseNotInitialized
+	}
+
+	return database, nil
+}
+
+/*
+SetDatabase Store the database passed in the ServerContext in place of the one created by InitDatabase.
+This allows unit tests to run the SDK against a server.MemoryDatabase
+*/
+func SetDatabase(database server.DatabaseInterface) {
+	ServerContext = context.WithValue(ServerContext, "database", database)
+	setComponentState(ComponentDatabase, StateConnected)
+}
+
+/*
+Disconnect the database object that is stored in the ServerContext, along with the database of every tenant
 */
 func DestroyDatabase() {
-	var database = GetDatabase()
+	destroyTenantDatabases()
+
+	database, ok := ServerContext.Value("database").(*server.Database)
+	if !ok {
+		return
+	}
+
 	database.Disconnect()
+	setComponentState(ComponentDatabase, StateNotInitialized)
 }
 
 /*
@@ -141,6 +326,8 @@ func InitAuthAPI() {
 
 	ctx := context.WithValue(ServerContext, "auth", authAPI)
 	ServerContext = ctx
+
+	setComponentState(ComponentAuth, StateConnected)
 }
 
 /*
@@ -163,22 +350,32 @@ func InitAuthManagementAPI() {
 
 	ctx := context.WithValue(ServerContext, "management", managementAPI)
 	ServerContext = ctx
+
+	setComponentState(ComponentManagement, StateConnected)
 }
 
 /*
-Fetch the Authentication management client object that is stored in the ServerContext
+Fetch the Authentication management client object that is stored in the ServerContext. Returns
+ErrManagementNotInitialized if InitAuthManagementAPI has not been called
 */
-func GetAuthManagementAPI() *management.Management {
-	managementAPI := ServerContext.Value("management")
+func GetAuthManagementAPI() (*management.Management, error) {
+	managementAPI, ok := ServerContext.Value("management").(*management.Management)
+	if !ok {
+		return nil, ErrManagementNotInitialized
+	}
 
-	return managementAPI.(*management.Management)
+	return managementAPI, nil
 }
 
 /*
-Fetch the Authentication client object that is stored in the ServerContext
+Fetch the Authentication client object that is stored in the ServerContext. Returns
+ErrAuthNotInitialized if InitAuthAPI has not been called
 */
-func GetAuthAPI() *authentication.Authentication {
-	authAPI := ServerContext.Value("auth")
+func GetAuthAPI() (*authentication.Authentication, error) {
+	authAPI, ok := ServerContext.Value("auth").(*authentication.Authentication)
+	if !ok {
+		return nil, ErrAuthNotInitialized
+	}
 
-	return authAPI.(*authentication.Authentication)
+	return authAPI, nil
 }
@@ -0,0 +1,178 @@
+package context
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	DEFAULT_GROWTH_WARN_RATIO = 0.8
+
+	GrowthLevelWarning  = "warning"
+	GrowthLevelExceeded = "exceeded"
+)
+
+/*
+GrowthAlert Raised when a collection approaches or exceeds one of its configured limits. Metric is
+either "documents" or "size", and Ratio is the current value divided by the limit
+*/
+type GrowthAlert struct {
+	Collection string  `json:"collection"`
+	Metric     string  `json:"metric"`
+	Level      string  `json:"level"`
+	Current    int64   `json:"current"`
+	Limit      int64   `json:"limit"`
+	Ratio      float64 `json:"ratio"`
+}
+
+/*
+GrowthAlertHandler A function that is called for every alert raised by CheckCollectionGrowth
+*/
+type GrowthAlertHandler func(alert *GrowthAlert)
+
+var (
+	growthHandlers []GrowthAlertHandler
+	growthLock     sync.RWMutex
+)
+
+/*
+RegisterGrowthAlertHandler Register a function to be notified of collection growth alerts, for
+example to forward them to a notification channel. Every alert is also logged
+*/
+func RegisterGrowthAlertHandler(handler GrowthAlertHandler) {
+	growthLock.Lock()
+	defer growthLock.Unlock()
+
+	growthHandlers = append(growthHandlers, handler)
+}
+
+/*
+raiseGrowthAlert Log the alert and pass it to every registered handler
+*/
+func raiseGrowthAlert(alert *GrowthAlert) {
+	GetLogger().Warn("Collection is approaching its configured limit",
+		"collection", alert.Collection,
+		"metric", alert.Metric,
+		"level", alert.Level,
+		"current", alert.Current,
+		"limit", alert.Limit,
+		"ratio", alert.Ratio)
+
+	growthLock.RLock()
+	defer growthLock.RUnlock()
+
+	for _, handler := range growthHandlers {
+		handler(alert)
+	}
+}
+
+/*
+checkLimit Compare a value against its limit and return an alert if the value is above the warning
+ratio. A limit of 0 disables the check
+*/
+func checkLimit(collection string, metric string, current int64, limit int64, warnRatio float64) *GrowthAlert {
+	if limit <= 0 {
+		return nil
+	}
+
+	ratio := float64(current) / float64(limit)
+	if ratio < warnRatio {
+		return nil
+	}
+
+	level := GrowthLevelWarning
+	if ratio >= 1 {
+		level = GrowthLevelExceeded
+	}
+
+	return &GrowthAlert{
+		Collection: collection,
+		Metric:     metric,
+		Level:      level,
+		Current:    current,
+		Limit:      limit,
+		Ratio:      ratio,
+	}
+}
+
+/*
+CheckCollectionGrowth Compare the document count and storage size of each collection with limits
+configured under 'mongo.limits', keyed by collection name:
+
+	{
+	  "mongo": {
+	    "limits": {
+	      "warn_ratio": 0.8,
+	      "deck": {"max_documents": 500000, "max_size": 1073741824}
+	    }
+	  }
+	}
+
+An alert is raised for every limit that the collection has reached the warning ratio of, and the raised
+alerts are returned. Sizes are in bytes
+*/
+func CheckCollectionGrowth() ([]*GrowthAlert, error) {
+	ret := []*GrowthAlert{}
+
+	database, err := GetMongoDatabase(ServerContext)
+	if err != nil {
+		return nil, err
+	}
+
+	warnRatio := DEFAULT_GROWTH_WARN_RATIO
+	if viper.IsSet("mongo.limits.warn_ratio") {
+		warnRatio = viper.GetFloat64("mongo.limits.warn_ratio")
+	}
+
+	for collection := range viper.GetStringMap("mongo.limits") {
+		if collection == "warn_ratio" {
+			continue
+		}
+
+		key := "mongo.limits." + collection
+		stats, err := database.Stats(ServerContext, collection)
+		if err != nil {
+			continue
+		}
+
+		alerts := []*GrowthAlert{
+			checkLimit(collection, "documents", stats.Count, viper.GetInt64(key+".max_documents"), warnRatio),
+			checkLimit(collection, "size", stats.StorageSize, viper.GetInt64(key+".max_size"), warnRatio),
+		}
+
+		for _, alert := range alerts {
+			if alert == nil {
+				continue
+			}
+
+			raiseGrowthAlert(alert)
+			ret = append(ret, alert)
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+WatchCollectionGrowth Call CheckCollectionGrowth on the interval passed until the context is cancelled.
+This blocks, and should be started in its own goroutine
+*/
+func WatchCollectionGrowth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := CheckCollectionGrowth()
+			if err != nil {
+				GetLogger().Error("Failed to check collection growth", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package context
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	PROFILE_ENV_VAR = "MTGJSON_PROFILE"
+
+	ProfileDev     = "dev"
+	ProfileStaging = "staging"
+	ProfileProd    = "prod"
+)
+
+var ErrUnknownProfile = errors.New("context: Config profile does not exist in the config file")
+var ErrDestructiveOpsDisabled = errors.New("context: Operation failed. Destructive operations are disabled outside of the dev profile")
+
+/*
+applyProfile Merge the values of the selected profile over the top level values of the config file. The
+profile is selected with the MTGJSON_PROFILE environment variable, falling back to the 'profile' key
+of the config file. Profiles are stored under the 'profiles' key, keyed by their name:
+
+	{
+	  "profile": "dev",
+	  "mongo": {"ip": "127.0.0.1", "port": 27017},
+	  "profiles": {
+	    "prod": {"mongo": {"ip": "10.0.0.5"}}
+	  }
+	}
+
+If no profile is selected then the config file is used as is
+*/
+func applyProfile() error {
+	profile := os.Getenv(PROFILE_ENV_VAR)
+	if profile == "" {
+		profile = viper.GetString("profile")
+	}
+
+	if profile == "" {
+		return nil
+	}
+
+	key := "profiles." + profile
+	if !viper.IsSet(key) {
+		return ErrUnknownProfile
+	}
+
+	err := viper.MergeConfigMap(viper.GetStringMap(key))
+	if err != nil {
+		return err
+	}
+
+	viper.Set("profile", profile)
+	slog.Info("Applied config profile", "profile", profile)
+
+	return nil
+}
+
+/*
+GetProfile Return the name of the active config profile. Returns an empty string if no profile was
+selected
+*/
+func GetProfile() string {
+	return viper.GetString("profile")
+}
+
+/*
+RequireDestructiveOps Safety interlock for destructive bulk operations (dropping or replacing collections,
+purging documents). Returns ErrDestructiveOpsDisabled unless the active profile is dev. This check can be
+overridden for a profile by setting 'safety.allow_destructive' to true within it
+*/
+func RequireDestructiveOps() error {
+	if GetProfile() == ProfileDev || viper.GetBool("safety.allow_destructive") {
+		return nil
+	}
+
+	return ErrDestructiveOpsDisabled
+}
@@ -0,0 +1,158 @@
+package context
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/auth0/go-auth0/authentication/oauth"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	SelfTestDatabaseRead  = "database.read"
+	SelfTestDatabaseWrite = "database.write"
+	SelfTestAuthToken     = "auth0.token"
+	SelfTestLogWrite      = "log.write"
+	SelfTestIndexes       = "database.indexes"
+)
+
+/*
+SelfTestCheck The result of a single self-test check. Message holds the reason the check failed
+and is empty when the check passes
+*/
+type SelfTestCheck struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Message  string        `json:"message,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+/*
+SelfTestReport A structured pass/fail summary of every self-test check. Passed is only true if every
+check passed
+*/
+type SelfTestReport struct {
+	Passed bool             `json:"passed"`
+	Checks []*SelfTestCheck `json:"checks"`
+}
+
+/*
+add Run a single check and record its result in the report
+*/
+func (r *SelfTestReport) add(name string, check func() string) {
+	start := time.Now()
+	message := check()
+
+	result := &SelfTestCheck{
+		Name:     name,
+		Passed:   message == "",
+		Message:  message,
+		Duration: time.Since(start),
+	}
+
+	if !result.Passed {
+		r.Passed = false
+		GetLogger().Error("Self-test check failed", "check", name, "message", message)
+	}
+
+	r.Checks = append(r.Checks, result)
+}
+
+/*
+SelfTest Exercise each initialized server component and return a structured summary of the results. A
+document is written to and read back from a scratch collection, a token is requested from Auth0 using
+client credentials, a file is written to the log directory, and the required database indexes are
+verified. This is intended to be run by deploy pipelines before a new instance receives traffic
+*/
+func SelfTest(ctx context.Context) *SelfTestReport {
+	report := &SelfTestReport{Passed: true}
+	marker := bson.M{"selfTest": time.Now().UnixNano()}
+
+	report.add(SelfTestDatabaseWrite, func() string {
+		database, err := GetDatabase(ctx)
+		if err != nil {
+			return err.Error()
+		}
+
+		_, err = database.Insert(ctx, "selftest", marker)
+		if err != nil {
+			return "failed to insert document into scratch collection: " + err.Error()
+		}
+
+		return ""
+	})
+
+	report.add(SelfTestDatabaseRead, func() string {
+		var result bson.M
+
+		database, err := GetDatabase(ctx)
+		if err != nil {
+			return err.Error()
+		}
+
+		err = database.Find(ctx, "selftest", marker, &result)
+		if err != nil {
+			return "failed to read document back from scratch collection: " + err.Error()
+		}
+
+		database.Delete(ctx, "selftest", marker)
+
+		return ""
+	})
+
+	report.add(SelfTestIndexes, func() string {
+		database, err := GetMongoDatabase(ctx)
+		if err != nil {
+			return err.Error()
+		}
+
+		missing, err := database.MissingIndexes(ctx)
+		if err != nil {
+			return err.Error()
+		}
+
+		if len(missing) != 0 {
+			message := "missing required indexes:"
+			for collection, keys := range missing {
+				for _, key := range keys {
+					message += " " + collection + "." + key
+				}
+			}
+
+			return message
+		}
+
+		return ""
+	})
+
+	report.add(SelfTestAuthToken, func() string {
+		authAPI, err := GetAuthAPI()
+		if err != nil {
+			return err.Error()
+		}
+
+		request := oauth.LoginWithClientCredentialsRequest{Audience: viper.GetString("auth0.audience")}
+		_, err = authAPI.OAuth.LoginWithClientCredentials(ctx, request, oauth.IDTokenValidationOptions{})
+		if err != nil {
+			return err.Error()
+		}
+
+		return ""
+	})
+
+	report.add(SelfTestLogWrite, func() string {
+		file, err := os.CreateTemp(viper.GetString("log.path"), "selftest-*")
+		if err != nil {
+			return err.Error()
+		}
+
+		file.Close()
+		os.Remove(file.Name())
+
+		return ""
+	})
+
+	return report
+}
@@ -0,0 +1,94 @@
+package context
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+type ComponentState string
+
+const (
+	StateNotInitialized ComponentState = "NotInitialized"
+	StateConnected      ComponentState = "Connected"
+	StateDegraded       ComponentState = "Degraded"
+)
+
+const (
+	ComponentLogger     = "logger"
+	ComponentDatabase   = "database"
+	ComponentAuth       = "auth"
+	ComponentManagement = "management"
+)
+
+var ErrDatabaseNotInitialized = errors.New("context: Operation failed. The database has not been initialized")
+var ErrAuthNotInitialized = errors.New("context: Operation failed. The Auth0 authentication client has not been initialized")
+var ErrManagementNotInitialized = errors.New("context: Operation failed. The Auth0 management client has not been initialized")
+
+var (
+	componentStates = map[string]ComponentState{}
+	stateLock       sync.RWMutex
+)
+
+/*
+setComponentState Record the state of a server component. Transitions are logged so that a component
+becoming degraded is visible to operators
+*/
+func setComponentState(component string, state ComponentState) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	if componentStates[component] != state {
+		slog.Info("Component state changed", "component", component, "state", state)
+	}
+
+	componentStates[component] = state
+}
+
+/*
+GetComponentState Return the current state of the requested component. Components that have not been
+initialized with their Init function will return StateNotInitialized
+*/
+func GetComponentState(component string) ComponentState {
+	stateLock.RLock()
+	defer stateLock.RUnlock()
+
+	state, ok := componentStates[component]
+	if !ok {
+		return StateNotInitialized
+	}
+
+	return state
+}
+
+/*
+GetComponentStates Return a copy of the state of every server component, keyed by component name
+*/
+func GetComponentStates() map[string]ComponentState {
+	ret := map[string]ComponentState{}
+
+	for _, component := range []string{ComponentLogger, ComponentDatabase, ComponentAuth, ComponentManagement} {
+		ret[component] = GetComponentState(component)
+	}
+
+	return ret
+}
+
+/*
+CheckDatabase Ping the database stored in the ServerContext and update its component state. The database
+is marked as Degraded if the ping fails, and is marked as Connected again once a ping succeeds
+*/
+func CheckDatabase() ComponentState {
+	database, err := GetDatabase(ServerContext)
+	if err != nil {
+		return StateNotInitialized
+	}
+
+	if database.Ping(ServerContext) != nil {
+		setComponentState(ComponentDatabase, StateDegraded)
+		return StateDegraded
+	}
+
+	setComponentState(ComponentDatabase, StateConnected)
+	return StateConnected
+}
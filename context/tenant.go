@@ -0,0 +1,120 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+var ErrUnknownTenant = errors.New("context: Operation failed. No database has been registered for the tenant")
+
+var (
+	tenantDatabases = map[string]server.DatabaseInterface{}
+	tenantLock      sync.RWMutex
+)
+
+/*
+InitTenantDatabases Connect a Database for every tenant configured under 'mongo.tenants' and register it with
+SetTenantDatabase. Each tenant is keyed by its name (e.g. an organization or environment), and sets its own
+'uri' and optionally 'collection_prefix'. Every other option, such as TLS, pooling and retries, is shared with
+the default database configured under 'mongo'
+*/
+func InitTenantDatabases() {
+	for tenant := range viper.GetStringMap("mongo.tenants") {
+		key := "mongo.tenants." + tenant
+
+		database := newDatabase(viper.GetString(key+".uri"), viper.GetString(key+".collection_prefix"))
+		SetTenantDatabase(tenant, database)
+
+		GetLogger().Info("Connected tenant database", "tenant", tenant)
+	}
+}
+
+/*
+SetTenantDatabase Register the database passed as the database of the tenant. Entity operations are routed to
+it for contexts that select the tenant with WithTenant. Registering a database for an existing tenant replaces it
+*/
+func SetTenantDatabase(tenant string, database server.DatabaseInterface) {
+	tenantLock.Lock()
+	defer tenantLock.Unlock()
+
+	tenantDatabases[tenant] = database
+}
+
+/*
+GetTenantDatabase Fetch the database registered for the tenant passed in the parameter. Returns
+ErrUnknownTenant if no database has been registered for it
+*/
+func GetTenantDatabase(tenant string) (server.DatabaseInterface, error) {
+	tenantLock.RLock()
+	defer tenantLock.RUnlock()
+
+	database, ok := tenantDatabases[tenant]
+	if !ok {
+		return nil, ErrUnknownTenant
+	}
+
+	return database, nil
+}
+
+/*
+GetTenants Return the names of every tenant that has a registered database, in sorted order
+*/
+func GetTenants() []string {
+	tenantLock.RLock()
+	defer tenantLock.RUnlock()
+
+	ret := make([]string, 0, len(tenantDatabases))
+	for tenant := range tenantDatabases {
+		ret = append(ret, tenant)
+	}
+
+	slices.Sort(ret)
+
+	return ret
+}
+
+/*
+tenantKey The key the selected tenant is stored under in a context
+*/
+type tenantKey struct{}
+
+/*
+WithTenant Return a copy of the context passed that routes every entity operation it is passed to to the
+database of the tenant. The tenant is carried by the context of a single request, so concurrent requests for
+different tenants never see each others selection. Pass an empty string to route operations to the default
+database
+*/
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+/*
+GetTenant Return the key of the tenant selected in the context passed with WithTenant, or an empty string if
+operations are routed to the default database
+*/
+func GetTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+
+	return tenant
+}
+
+/*
+destroyTenantDatabases Disconnect every MongoDB tenant database and remove them from the registry
+*/
+func destroyTenantDatabases() {
+	tenantLock.Lock()
+	defer tenantLock.Unlock()
+
+	for tenant, database := range tenantDatabases {
+		if mongoDatabase, ok := database.(*server.Database); ok {
+			mongoDatabase.Disconnect()
+		}
+
+		delete(tenantDatabases, tenant)
+	}
+}
@@ -1,19 +1,29 @@
 package deck
 
 import (
+	stdContext "context"
 	"errors"
 	cardModel "github.com/stevezaluk/mtgjson-models/card"
 	"github.com/stevezaluk/mtgjson-models/meta"
 	"github.com/stevezaluk/mtgjson-sdk/card"
 	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/metrics"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	serverMetrics "github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"github.com/stevezaluk/mtgjson-sdk/server/tracing"
+	"github.com/stevezaluk/mtgjson-sdk/slug"
 	"github.com/stevezaluk/mtgjson-sdk/user"
 	"github.com/stevezaluk/mtgjson-sdk/util"
 
+	"fmt"
 	"slices"
+	"time"
 
 	deckModel "github.com/stevezaluk/mtgjson-models/deck"
 	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -22,42 +32,142 @@ const (
 	BoardCommander = "commander"
 )
 
+var ErrInvalidCommander = errors.New("deck: Operation failed. The cards in the commander board cannot be commanders of the same deck")
+
+/*
+validateCommanders Returns ErrInvalidCommander if the cards in the commander board of the deck passed cannot
+together be its commanders, see card.CanBeCommanders. Unknown and repeated UUID's are rejected, as a commander
+must be a single copy of a card that exists. A deck without a commander board is always valid
+*/
+func validateCommanders(ctx stdContext.Context, deck *deckModel.Deck) error {
+	if deck.ContentIds == nil || len(deck.ContentIds.Commander) == 0 {
+		return nil
+	}
+
+	uuids := deck.ContentIds.Commander
+
+	cards, err := card.GetCards(ctx, uuids, card.ListingFields...)
+	if err != nil {
+		return err
+	}
+
+	if len(cards) != len(uuids) || !card.CanBeCommanders(cards) {
+		return ErrInvalidCommander
+	}
+
+	return nil
+}
+
+/*
+repository Returns a typed repository for the deck collection. Missing decks are reported as ErrNoDeck
+*/
+func repository(ctx stdContext.Context) (*server.Repository[deckModel.Deck], error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.NewRepository[deckModel.Deck](database, "deck", sdkErrors.ErrNoDeck), nil
+}
+
 /*
 ReplaceDeck Replace the entire deck in the database with the deck model
-passed in the parameter. Returns ErrDeckUpdateFailed if the deck
-cannot be located
+passed in the parameter. Deck codes are only unique per owner, so the deck
+is located using both its code and owner. The deck is only replaced if it has
+not been modified since the model was read, which is checked using the modified
+date of its API metadata, and the modified date is updated on success. The summary
+fields of the deck, including its share id and slug, are stored in the same replace.
+Returns ErrDeckUpdateFailed if the deck cannot be located, wrapping server.ErrConflict
+if another writer modified the deck first, or ErrInvalidCommander if the cards in its commander board
+cannot lead the deck
 */
-func ReplaceDeck(deck *deckModel.Deck) error {
-	var database = context.GetDatabase()
+func ReplaceDeck(ctx stdContext.Context, deck *deckModel.Deck) error {
+	if deck.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
+	}
 
-	_, err := database.Replace("deck", bson.M{"code": deck.Code}, &deck)
-	if !err {
-		return sdkErrors.ErrDeckUpdateFailed
+	err := validateCommanders(ctx, deck)
+	if err != nil {
+		return err
 	}
 
+	repo, err := repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	expected := deck.MtgjsonApiMeta.ModifiedDate
+	deck.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr()
+
+	fields, err := summaryFields(ctx, deck)
+	if err != nil {
+		deck.MtgjsonApiMeta.ModifiedDate = expected
+		return err
+	}
+
+	document, err := deckDocument(deck, fields)
+	if err != nil {
+		deck.MtgjsonApiMeta.ModifiedDate = expected
+		return err
+	}
+
+	query := bson.M{"code": deck.Code, "mtgjsonApiMeta.owner": deck.MtgjsonApiMeta.Owner}
+	err = repo.ReplaceVersionWith(ctx, query, server.VersionField, expected, document)
+	if err != nil {
+		deck.MtgjsonApiMeta.ModifiedDate = expected
+		return fmt.Errorf("%w: %w", sdkErrors.ErrDeckUpdateFailed, err)
+	}
+
+	invalidation.Publish(ctx, invalidation.KindDeck, ShareId(deck))
+
 	return nil
 }
 
 /*
 DeleteDeck Remove a deck from the MongoDB database using the code passed in the
-parameter. Returns ErrNoDeck if the deck does not exist. Returns
-ErrDeckDeleteFailed if the deleted count does not equal 1
+parameter, and remove it from the ownedDecks field of its owner. Returns ErrNoDeck if
+the deck does not exist. Returns ErrDeckDeleteFailed if the deleted count does not equal 1
 */
-func DeleteDeck(code string, owner string) error {
-	var database = context.GetDatabase()
+func DeleteDeck(ctx stdContext.Context, code string, owner string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	deck, err := GetDeck(ctx, code, owner)
+	if err != nil {
+		return err
+	}
 
 	query := bson.M{"code": code}
 	if owner != "" {
 		query = bson.M{"code": code, "mtgjsonApiMeta.owner": owner}
 	}
 
-	result, err := database.Delete("deck", query)
-	if !err {
-		return sdkErrors.ErrNoDeck
+	err = database.WithTransaction(ctx, func(ctx stdContext.Context) error {
+		_, err := database.Delete(ctx, "deck", query)
+		if errors.Is(err, server.ErrNotFound) {
+			return sdkErrors.ErrNoDeck
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %w", sdkErrors.ErrDeckDeleteFailed, err)
+		}
+
+		if deck.MtgjsonApiMeta == nil {
+			return nil
+		}
+
+		return user.RemoveOwnedDeck(ctx, deck.MtgjsonApiMeta.Owner, code)
+	})
+	if err != nil {
+		return err
 	}
 
-	if result.DeletedCount != 1 {
-		return sdkErrors.ErrDeckDeleteFailed
+	invalidation.Publish(ctx, invalidation.KindDeck, ShareId(deck))
+
+	if deck.MtgjsonApiMeta != nil {
+		slug.Remove(ctx, slug.KindDeck, code, deck.MtgjsonApiMeta.Owner)
 	}
 
 	return nil
@@ -68,48 +178,163 @@ GetDeck Fetch a deck from the MongoDB database using the code passed in the para
 is the email address of the user that you want to assign to the deck. If the string is empty
 then it does not filter by user. Returns ErrNoDeck if the deck does not exist or cannot be located
 */
-func GetDeck(code string, owner string) (*deckModel.Deck, error) {
-	var result *deckModel.Deck
-
-	var database = context.GetDatabase()
+func GetDeck(ctx stdContext.Context, code string, owner string) (*deckModel.Deck, error) {
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	query := bson.M{"code": code}
 	if owner != "" {
 		query = bson.M{"code": code, "mtgjsonApiMeta.owner": owner}
 	}
 
-	err := database.Find("deck", query, &result)
-	if !err {
-		return result, sdkErrors.ErrNoDeck
+	return repo.FindOne(ctx, query)
+}
+
+/*
+IndexDecks Returns all decks in the database unmarshalled as deck models. The limit parameter
+will be passed directly to the database query to limit the number of models returned. Sort fields
+can be passed to order the decks before the limit is applied (e.g. "-mtgjsonApiMeta.modifiedDate"
+for the most recently modified decks first), see server.SortBy
+*/
+func IndexDecks(ctx stdContext.Context, limit int64, sort ...string) ([]*deckModel.Deck, error) {
+	var result []*deckModel.Deck
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Index(ctx, "deck", limit, &result, sort...)
+	if err != nil {
+		return result, err
 	}
 
 	return result, nil
 }
 
 /*
-IndexDecks Returns all decks in the database unmarshalled as deck models. The limit parameter
-will be passed directly to the database query to limit the number of models returned
+PageDecks Returns a single page of decks from the database. Pass the NextCursor of the returned page in
+the options to fetch the next page. Large collections should be paged through this way rather than
+with IndexDecks, which is limited to a single capped query
 */
-func IndexDecks(limit int64) ([]*deckModel.Deck, error) {
+func PageDecks(ctx stdContext.Context, opts *server.PageOptions) ([]*deckModel.Deck, *server.Page, error) {
 	var result []*deckModel.Deck
 
-	var database = context.GetDatabase()
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, err := database.Paginate(ctx, "deck", bson.M{}, opts, &result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, page, nil
+}
 
-	err := database.Index("deck", limit, &result)
-	if !err {
-		return result, sdkErrors.ErrNoDecks
+/*
+prepareDeck Fill the empty fields of a deck model with their default values and assign its API metadata
+*/
+func prepareDeck(deck *deckModel.Deck, owner string) {
+	if deck.ContentIds == nil {
+		deck.ContentIds = &deckModel.DeckContentIds{
+			MainBoard: []string{},
+			SideBoard: []string{},
+			Commander: []string{},
+		}
 	}
 
-	return result, nil
+	currentDate := util.CreateTimestampStr()
+	if deck.ReleaseDate == "" {
+		deck.ReleaseDate = currentDate
+	}
+
+	deck.MtgjsonApiMeta = &meta.MTGJSONAPIMeta{
+		Owner:        owner,
+		Type:         "Deck",
+		CreationDate: currentDate,
+		ModifiedDate: currentDate,
+	}
+}
+
+/*
+UpsertDeck Create the deck passed in the parameter, or replace it if a deck with the same code already
+exists for the owner. This does not require the existence check performed by NewDeck, so sync jobs can
+call it repeatedly with the same deck. The deck is only added to the ownedDecks of the owner when it is
+created. The API metadata of a replaced deck is regenerated. Returns ErrInvalidCommander if the cards in
+its commander board cannot lead the deck, or ErrDeckUpdateFailed wrapping ErrDeckDeleted if the deck was soft
+deleted
+*/
+func UpsertDeck(ctx stdContext.Context, deck *deckModel.Deck, owner string) error {
+	if deck.Name == "" || deck.Code == "" {
+		return sdkErrors.ErrDeckMissingId
+	}
+
+	if owner == "" {
+		owner = user.SystemUser
+	}
+
+	if owner != user.SystemUser {
+		_, err := user.GetUser(ctx, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := validateCommanders(ctx, deck)
+	if err != nil {
+		return err
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	prepareDeck(deck, owner)
+
+	fields, err := summaryFields(ctx, deck)
+	if err != nil {
+		return err
+	}
+
+	document, err := deckDocument(deck, fields)
+	if err != nil {
+		return err
+	}
+
+	result, err := database.Upsert(ctx, "deck", bson.M{"code": deck.Code, "mtgjsonApiMeta.owner": owner}, document)
+	if errors.Is(err, server.ErrDeleted) {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrDeckUpdateFailed, ErrDeckDeleted)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if result.UpsertedCount == 1 {
+		err = user.AddOwnedDeck(ctx, owner, deck.Code)
+		if err != nil {
+			return err
+		}
+	}
+
+	invalidation.Publish(ctx, invalidation.KindDeck, ShareId(deck))
+
+	return nil
 }
 
 /*
 NewDeck Insert a new deck in the form of a model into the MongoDB database. The deck model must have a
 valid name and deck code, additionally the deck cannot already exist under the same deck code. Owner is
 the email address of the owner you want to assign the deck to. If the string is empty, it will be assigned
-to the system user
+to the system user. A soft deleted deck still holds its code, in which case ErrDeckAlreadyExists is returned
+wrapping ErrDeckDeleted. Returns ErrInvalidCommander if the cards in its commander board cannot lead the deck
 */
-func NewDeck(deck *deckModel.Deck, owner string) error {
+func NewDeck(ctx stdContext.Context, deck *deckModel.Deck, owner string) error {
 	if deck.Name == "" || deck.Code == "" {
 		return sdkErrors.ErrDeckMissingId
 	}
@@ -119,40 +344,65 @@ func NewDeck(deck *deckModel.Deck, owner string) error {
 	}
 
 	if owner != user.SystemUser {
-		_, err := user.GetUser(owner)
+		_, err := user.GetUser(ctx, owner)
 		if err != nil {
 			return err
 		}
 	}
 
-	var database = context.GetDatabase()
+	err := validateCommanders(ctx, deck)
+	if err != nil {
+		return err
+	}
 
-	_, err := GetDeck(deck.Code, owner)
-	if !errors.Is(err, sdkErrors.ErrNoDeck) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = GetDeck(ctx, deck.Code, owner)
+	if err == nil {
 		return sdkErrors.ErrDeckAlreadyExists
 	}
 
-	if deck.ContentIds == nil {
-		deck.ContentIds = &deckModel.DeckContentIds{
-			MainBoard: []string{},
-			SideBoard: []string{},
-			Commander: []string{},
-		}
+	if !errors.Is(err, sdkErrors.ErrNoDeck) {
+		return err
 	}
 
-	currentDate := util.CreateTimestampStr()
-	if deck.ReleaseDate == "" {
-		deck.ReleaseDate = currentDate
+	deleted, err := deletedDeckExists(ctx, deck.Code, owner)
+	if err != nil {
+		return err
 	}
 
-	deck.MtgjsonApiMeta = &meta.MTGJSONAPIMeta{
-		Owner:        owner,
-		Type:         "Deck",
-		CreationDate: currentDate,
-		ModifiedDate: currentDate,
+	if deleted {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrDeckAlreadyExists, ErrDeckDeleted)
 	}
 
-	database.Insert("deck", &deck)
+	prepareDeck(deck, owner)
+
+	fields, err := summaryFields(ctx, deck)
+	if err != nil {
+		return err
+	}
+
+	document, err := deckDocument(deck, fields)
+	if err != nil {
+		return err
+	}
+
+	err = database.WithTransaction(ctx, func(ctx stdContext.Context) error {
+		_, err := database.Insert(ctx, "deck", document)
+		if err != nil {
+			return err
+		}
+
+		return user.AddOwnedDeck(ctx, owner, deck.Code)
+	})
+	if err != nil {
+		return err
+	}
+
+	serverMetrics.IncDecksCreated()
 
 	return nil
 }
@@ -161,7 +411,7 @@ func NewDeck(deck *deckModel.Deck, owner string) error {
 GetBoardContents Return a slice of CardSet pointers representing a deck boards content. If the requested board
 does not exist, it will return ErrBoardNotExist
 */
-func GetBoardContents(contentIds *deckModel.DeckContentIds, board string) ([]*cardModel.CardSet, error) {
+func GetBoardContents(ctx stdContext.Context, contentIds *deckModel.DeckContentIds, board string) ([]*cardModel.CardSet, error) {
 	var boardIds []string
 
 	if board == BoardMainboard {
@@ -174,21 +424,26 @@ func GetBoardContents(contentIds *deckModel.DeckContentIds, board string) ([]*ca
 		return nil, sdkErrors.ErrBoardNotExist
 	}
 
-	return card.GetCards(boardIds)
+	return card.GetCards(ctx, boardIds, card.ListingFields...)
 }
 
 /*
 GetDeckContents Update the 'contents' field of the deck passed in the parameter. This accepts a
 pointer and updates this in place to avoid having to copy large amounts of data
 */
-func GetDeckContents(deck *deckModel.Deck) error {
+func GetDeckContents(ctx stdContext.Context, deck *deckModel.Deck) error {
+	defer metrics.Since(metrics.DeckGetContents, time.Now())
+
+	ctx, span := tracing.Start(ctx, "deck.GetDeckContents", attribute.String("deck.code", deck.Code))
+
 	if deck.ContentIds == nil {
+		tracing.End(span, sdkErrors.ErrDeckMissingId)
 		return sdkErrors.ErrDeckMissingId
 	}
 
-	mainBoardContents, _ := GetBoardContents(deck.ContentIds, BoardMainboard)
-	sideBoardContents, _ := GetBoardContents(deck.ContentIds, BoardSideboard)
-	commanderContents, _ := GetBoardContents(deck.ContentIds, BoardCommander)
+	mainBoardContents, _ := GetBoardContents(ctx, deck.ContentIds, BoardMainboard)
+	sideBoardContents, _ := GetBoardContents(ctx, deck.ContentIds, BoardSideboard)
+	commanderContents, _ := GetBoardContents(ctx, deck.ContentIds, BoardCommander)
 
 	contents := &deckModel.DeckContents{
 		MainBoard: mainBoardContents,
@@ -197,6 +452,10 @@ func GetDeckContents(deck *deckModel.Deck) error {
 	}
 
 	deck.Contents = contents
+	metrics.Add(metrics.DeckCardsResolved, int64(len(mainBoardContents)+len(sideBoardContents)+len(commanderContents)))
+
+	span.SetAttributes(attribute.Int("cards", len(mainBoardContents)+len(sideBoardContents)+len(commanderContents)))
+	tracing.End(span, nil)
 
 	return nil
 }
@@ -219,28 +478,92 @@ func AllCardIds(contents *deckModel.DeckContentIds) ([]string, error) {
 }
 
 /*
-AddCards Update the content ids in the deck model passed with new cards. This should
-probably validate cards in the future
+boardFields The field of the deck document that stores each board
+*/
+var boardFields = map[string]string{
+	BoardMainboard: "contentIds.mainBoard",
+	BoardSideboard: "contentIds.sideBoard",
+	BoardCommander: "contentIds.commander",
+}
+
+/*
+boardChanges Build the value of an update operator for every board of the content ids passed that has cards,
+keyed by the field of the board. The cards of each board are passed to the value function
+*/
+func boardChanges(contents *deckModel.DeckContentIds, value func(cards bson.A) interface{}) bson.M {
+	ret := bson.M{}
+
+	boards := map[string][]string{
+		BoardMainboard: contents.MainBoard,
+		BoardSideboard: contents.SideBoard,
+		BoardCommander: contents.Commander,
+	}
+
+	for board, cards := range boards {
+		if len(cards) == 0 {
+			continue
+		}
+
+		values := bson.A{}
+		for _, uuid := range cards {
+			values = append(values, uuid)
+		}
+
+		ret[boardFields[board]] = value(values)
+	}
+
+	return ret
+}
+
+/*
+cloneContents Return a copy of the content ids passed that shares none of its boards
 */
-func AddCards(deck *deckModel.Deck, newCards *deckModel.DeckContentIds) error {
+func cloneContents(contents *deckModel.DeckContentIds) *deckModel.DeckContentIds {
+	return &deckModel.DeckContentIds{
+		MainBoard: slices.Clone(contents.MainBoard),
+		SideBoard: slices.Clone(contents.SideBoard),
+		Commander: slices.Clone(contents.Commander),
+	}
+}
+
+/*
+AddCards Append new cards to the boards of the deck model passed, and to the stored deck with a single update
+that also increments its counters (see updateContents). Cards added to the commander board are validated
+alongside the existing commanders of the deck, returning ErrInvalidCommander if they cannot lead it. The
+content ids of the model are left unchanged on failure
+*/
+func AddCards(ctx stdContext.Context, deck *deckModel.Deck, newCards *deckModel.DeckContentIds) error {
 	if deck.ContentIds == nil {
 		return sdkErrors.ErrDeckMissingId
 	}
 
-	deck.ContentIds.MainBoard = append(deck.ContentIds.MainBoard, newCards.MainBoard...)
-	deck.ContentIds.SideBoard = append(deck.ContentIds.SideBoard, newCards.SideBoard...)
-	deck.ContentIds.Commander = append(deck.ContentIds.Commander, newCards.Commander...)
+	previous := cloneContents(deck.ContentIds)
+
+	deck.ContentIds.MainBoard = append(slices.Clone(deck.ContentIds.MainBoard), newCards.MainBoard...)
+	deck.ContentIds.SideBoard = append(slices.Clone(deck.ContentIds.SideBoard), newCards.SideBoard...)
+	deck.ContentIds.Commander = append(slices.Clone(deck.ContentIds.Commander), newCards.Commander...)
 
-	deck.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr() // need better error checking here
+	err := validateCommanders(ctx, deck)
+	if err == nil {
+		pushed := boardChanges(newCards, func(cards bson.A) interface{} {
+			return bson.M{"$each": cards}
+		})
+
+		err = updateContents(ctx, deck, previous, bson.M{"$push": pushed})
+	}
 
-	err := ReplaceDeck(deck)
 	if err != nil {
+		deck.ContentIds = previous
 		return err
 	}
 
 	return nil
 }
 
+/*
+RemoveCardsFromBoard Remove every copy of the cards passed from a single board of the deck model passed.
+Returns ErrBoardNotExist if the board does not exist
+*/
 func RemoveCardsFromBoard(deck *deckModel.Deck, cards []string, board string) error {
 	if deck.ContentIds == nil {
 		return sdkErrors.ErrDeckMissingId
@@ -257,44 +580,64 @@ func RemoveCardsFromBoard(deck *deckModel.Deck, cards []string, board string) er
 		return sdkErrors.ErrBoardNotExist
 	}
 
-	for _, uuid := range cards {
-		for index, value := range *sourceBoard {
-			if value == uuid {
-				*sourceBoard = slices.Delete(*sourceBoard, index, index+1)
-			}
-		}
-	}
+	*sourceBoard = slices.DeleteFunc(*sourceBoard, func(value string) bool {
+		return slices.Contains(cards, value)
+	})
 
 	return nil
 }
 
 /*
-RemoveCards Remove cards from the content ids in the deck model passed.
+RemoveCards Remove every copy of the cards passed from the boards of the deck model passed, and from the stored
+deck with a single update that also decrements its counters (see updateContents). The content ids of the model
+are left unchanged on failure
 */
-func RemoveCards(deck *deckModel.Deck, removeCards *deckModel.DeckContentIds) error {
+func RemoveCards(ctx stdContext.Context, deck *deckModel.Deck, removeCards *deckModel.DeckContentIds) error {
 	if deck.ContentIds == nil {
 		return sdkErrors.ErrDeckMissingId
 	}
 
+	previous := cloneContents(deck.ContentIds)
+
 	err := RemoveCardsFromBoard(deck, removeCards.MainBoard, BoardMainboard)
-	if err != nil {
-		return err
+	if err == nil {
+		err = RemoveCardsFromBoard(deck, removeCards.SideBoard, BoardSideboard)
 	}
 
-	err = RemoveCardsFromBoard(deck, removeCards.SideBoard, BoardSideboard)
-	if err != nil {
-		return err
+	if err == nil {
+		err = RemoveCardsFromBoard(deck, removeCards.Commander, BoardCommander)
 	}
 
-	err = RemoveCardsFromBoard(deck, removeCards.Commander, BoardCommander)
-	if err != nil {
-		return err
+	if err == nil {
+		pulled := boardChanges(removeCards, func(cards bson.A) interface{} {
+			return bson.M{"$in": cards}
+		})
+
+		err = updateContents(ctx, deck, previous, bson.M{"$pull": pulled})
 	}
 
-	err = ReplaceDeck(deck)
 	if err != nil {
+		deck.ContentIds = previous
 		return err
 	}
 
 	return nil
 }
+
+/*
+CountDecks Returns the number of decks in the database owned by the user passed in the parameter, for use in
+pagination metadata. If owner is an empty string, the estimated size of the whole collection is returned
+instead, which does not require a collection scan
+*/
+func CountDecks(ctx stdContext.Context, owner string) (int64, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if owner == "" {
+		return database.EstimatedCount(ctx, "deck")
+	}
+
+	return database.Count(ctx, "deck", bson.M{"mtgjsonApiMeta.owner": owner})
+}
@@ -0,0 +1,139 @@
+package deck
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+
+	cardModel "github.com/stevezaluk/mtgjson-models/card"
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+)
+
+/*
+htmlTemplate The template used to render a deck as a self-contained HTML snippet. Styles are inlined so
+the snippet can be pasted into a blog or forum post without any external stylesheets
+*/
+var htmlTemplate = template.Must(template.New("deck").Funcs(template.FuncMap{"cardLink": CardLink}).Parse(`<div class="mtgjson-deck" style="font-family: sans-serif;">
+<h2 style="margin-bottom: 0.25em;">{{ .Name }}</h2>
+{{- range .Boards }}
+<h3 style="margin-bottom: 0.25em;">{{ .Name }}</h3>
+{{- range .Groups }}
+<h4 style="margin: 0.5em 0 0.25em 0;">{{ .Name }} ({{ .Count }})</h4>
+<ul style="list-style: none; padding-left: 0; margin: 0;">
+{{- range .Entries }}
+<li>{{ .Quantity }} <a href="{{ cardLink .Card }}">{{ .Card.Name }}</a></li>
+{{- end }}
+</ul>
+{{- end }}
+{{- end }}
+</div>
+`))
+
+/*
+formattedBoard A named board of a deck and its grouped contents, used when rendering a deck
+*/
+type formattedBoard struct {
+	Name   string
+	Groups []*CardGroup
+}
+
+/*
+formattedBoards Return the non-empty boards of a deck in display order
+*/
+func formattedBoards(contents *GroupedDeckContents) []*formattedBoard {
+	var ret []*formattedBoard
+
+	boards := []*formattedBoard{
+		{Name: "Commander", Groups: contents.Commander},
+		{Name: "Mainboard", Groups: contents.MainBoard},
+		{Name: "Sideboard", Groups: contents.SideBoard},
+	}
+
+	for _, board := range boards {
+		if len(board.Groups) != 0 {
+			ret = append(ret, board)
+		}
+	}
+
+	return ret
+}
+
+/*
+CardLink Return a link to the Scryfall page of a card. If the card has no set code or collector number,
+a Scryfall search for its exact name is returned instead
+*/
+func CardLink(card *cardModel.CardSet) string {
+	if card.SetCode != "" && card.Number != "" {
+		return "https://scryfall.com/card/" + strings.ToLower(card.SetCode) + "/" + url.PathEscape(card.Number)
+	}
+
+	return "https://scryfall.com/search?q=" + url.QueryEscape("!\""+card.Name+"\"")
+}
+
+/*
+escapeMarkdown Escape characters that would break a markdown table cell
+*/
+func escapeMarkdown(value string) string {
+	replacer := strings.NewReplacer("|", "\\|", "[", "\\[", "]", "\\]", "\n", " ")
+	return replacer.Replace(value)
+}
+
+/*
+FormatMarkdown Render a deck as markdown tables suitable for Reddit or other forums. Each board is rendered as
+a table with one row per card, grouped by type, with each card linking to its Scryfall page
+*/
+func FormatMarkdown(ctx context.Context, deck *deckModel.Deck) (string, error) {
+	contents, err := GetGroupedDeckContents(ctx, deck, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString("# " + escapeMarkdown(deck.Name) + "\n")
+
+	for _, board := range formattedBoards(contents) {
+		builder.WriteString("\n## " + board.Name + "\n\n")
+		builder.WriteString("| Qty | Card | Type | Mana Cost |\n")
+		builder.WriteString("|----:|------|------|-----------|\n")
+
+		for _, group := range board.Groups {
+			builder.WriteString("| | **" + escapeMarkdown(group.Name) + " (" + strconv.FormatInt(group.Count, 10) + ")** | | |\n")
+
+			for _, entry := range group.Entries {
+				builder.WriteString("| " + strconv.FormatInt(entry.Quantity, 10) +
+					" | [" + escapeMarkdown(entry.Card.Name) + "](" + CardLink(entry.Card) + ")" +
+					" | " + escapeMarkdown(entry.Card.Type) +
+					" | " + escapeMarkdown(entry.Card.ManaCost) + " |\n")
+			}
+		}
+	}
+
+	return builder.String(), nil
+}
+
+/*
+FormatHTML Render a deck as a self-contained HTML snippet suitable for embedding in a blog post. Cards are grouped
+by type within each board, and each card links to its Scryfall page. All values are HTML escaped
+*/
+func FormatHTML(ctx context.Context, deck *deckModel.Deck) (string, error) {
+	contents, err := GetGroupedDeckContents(ctx, deck, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+
+	err = htmlTemplate.Execute(&buffer, struct {
+		Name   string
+		Boards []*formattedBoard
+	}{Name: deck.Name, Boards: formattedBoards(contents)})
+	if err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
@@ -0,0 +1,185 @@
+package deck
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+
+	cardModel "github.com/stevezaluk/mtgjson-models/card"
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+)
+
+/*
+TypeOrder The order that card type groups are displayed in a standard decklist layout. Cards are grouped
+under the first type in this list that they have
+*/
+var TypeOrder = []string{"Creature", "Planeswalker", "Battle", "Instant", "Sorcery", "Artifact", "Enchantment", "Land"}
+
+/*
+GroupFunc Returns the name of the group a card belongs to when laying out a decklist
+*/
+type GroupFunc func(card *cardModel.CardSet) string
+
+/*
+DeckEntry A single card in a decklist along with the number of copies of it in the board
+*/
+type DeckEntry struct {
+	Card     *cardModel.CardSet `json:"card"`
+	Quantity int64              `json:"quantity"`
+}
+
+/*
+CardGroup A named group of cards in a decklist, such as all creatures in the mainboard
+*/
+type CardGroup struct {
+	Name    string       `json:"name"`
+	Count   int64        `json:"count"`
+	Entries []*DeckEntry `json:"entries"`
+}
+
+/*
+GroupedDeckContents The contents of each board of a deck, grouped and sorted for display
+*/
+type GroupedDeckContents struct {
+	MainBoard []*CardGroup `json:"mainBoard"`
+	SideBoard []*CardGroup `json:"sideBoard"`
+	Commander []*CardGroup `json:"commander"`
+}
+
+/*
+GroupByType Group cards by their primary card type, using the order defined in TypeOrder. Cards with
+none of these types are grouped under "Other"
+*/
+func GroupByType(card *cardModel.CardSet) string {
+	for _, cardType := range TypeOrder {
+		if slices.Contains(card.Types, cardType) {
+			return cardType
+		}
+	}
+
+	return "Other"
+}
+
+/*
+GroupByManaValue Group cards by their mana value
+*/
+func GroupByManaValue(card *cardModel.CardSet) string {
+	return strconv.FormatInt(card.ManaValue, 10)
+}
+
+/*
+GroupByColor Group cards by their colors in WUBRG order. Colorless cards are grouped under "C", and
+multicolored cards are grouped under their combined colors (e.g. "WU")
+*/
+func GroupByColor(card *cardModel.CardSet) string {
+	if len(card.Colors) == 0 {
+		return "C"
+	}
+
+	colors := slices.Clone(card.Colors)
+	slices.SortFunc(colors, func(a string, b string) int {
+		return slices.Index(ColorOrder, a) - slices.Index(ColorOrder, b)
+	})
+
+	ret := ""
+	for _, color := range colors {
+		ret += color
+	}
+
+	return ret
+}
+
+/*
+compareEntries Sort decklist entries by mana value and then by name
+*/
+func compareEntries(a *DeckEntry, b *DeckEntry) int {
+	if a.Card.ManaValue != b.Card.ManaValue {
+		return cmp.Compare(a.Card.ManaValue, b.Card.ManaValue)
+	}
+
+	return cmp.Compare(a.Card.Name, b.Card.Name)
+}
+
+/*
+GroupCards Group the cards passed using the group function, counting the number of copies of each card
+using the board ids passed. Groups are ordered by TypeOrder when grouping by type, and alphabetically
+otherwise. Cards within a group are sorted by mana value and then by name
+*/
+func GroupCards(cards []*cardModel.CardSet, boardIds []string, groupBy GroupFunc) []*CardGroup {
+	quantities := map[string]int64{}
+	for _, uuid := range boardIds {
+		quantities[uuid]++
+	}
+
+	var groups []*CardGroup
+	byName := map[string]*CardGroup{}
+
+	for _, card := range cards {
+		name := groupBy(card)
+
+		group, ok := byName[name]
+		if !ok {
+			group = &CardGroup{Name: name}
+			byName[name] = group
+			groups = append(groups, group)
+		}
+
+		quantity := int64(1)
+		if card.Identifiers != nil && quantities[card.Identifiers.MtgjsonV4Id] > 0 {
+			quantity = quantities[card.Identifiers.MtgjsonV4Id]
+		}
+
+		group.Entries = append(group.Entries, &DeckEntry{Card: card, Quantity: quantity})
+		group.Count += quantity
+	}
+
+	for _, group := range groups {
+		slices.SortFunc(group.Entries, compareEntries)
+	}
+
+	slices.SortFunc(groups, func(a *CardGroup, b *CardGroup) int {
+		indexA, indexB := slices.Index(TypeOrder, a.Name), slices.Index(TypeOrder, b.Name)
+		if indexA != -1 || indexB != -1 {
+			if indexA == -1 {
+				return 1
+			}
+
+			if indexB == -1 {
+				return -1
+			}
+
+			return cmp.Compare(indexA, indexB)
+		}
+
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return groups
+}
+
+/*
+GetGroupedDeckContents Resolve the contents of each board of a deck and return them grouped and sorted for
+display. If groupBy is nil, cards are grouped by type to match the standard decklist layout
+*/
+func GetGroupedDeckContents(ctx context.Context, deck *deckModel.Deck, groupBy GroupFunc) (*GroupedDeckContents, error) {
+	if deck.ContentIds == nil {
+		return nil, sdkErrors.ErrDeckMissingContentIds
+	}
+
+	if groupBy == nil {
+		groupBy = GroupByType
+	}
+
+	err := GetDeckContents(ctx, deck)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupedDeckContents{
+		MainBoard: GroupCards(deck.Contents.MainBoard, deck.ContentIds.MainBoard, groupBy),
+		SideBoard: GroupCards(deck.Contents.SideBoard, deck.ContentIds.SideBoard, groupBy),
+		Commander: GroupCards(deck.Contents.Commander, deck.ContentIds.Commander, groupBy),
+	}, nil
+}
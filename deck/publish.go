@@ -0,0 +1,311 @@
+package deck
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrNoDraft = errors.New("deck: Failed to find a draft for the deck with the specified code")
+var ErrNoRevision = errors.New("deck: Failed to find a previous revision for the deck with the specified code")
+var ErrDeckPublishFailed = errors.New("deck: Operation failed. Failed to publish deck")
+
+/*
+draftBaseField The field of a draft document holding the version of the public deck that the draft was started
+from (see server.VersionField). Publish only replaces the public deck if it still holds this version
+*/
+const draftBaseField = "draftBase"
+
+/*
+findDraft Fetch the draft version of a deck along with the version of the public deck that it was started from.
+Returns server.ErrNotFound if the deck has no draft
+*/
+func findDraft(ctx stdContext.Context, database server.DatabaseInterface, query bson.M) (*deckModel.Deck, string, error) {
+	var stored bson.M
+
+	err := database.Find(ctx, "deck_draft", query, &stored)
+	if err != nil {
+		return nil, "", err
+	}
+
+	base, _ := stored[draftBaseField].(string)
+
+	document, err := bson.Marshal(stored)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var draft *deckModel.Deck
+	err = bson.Unmarshal(document, &draft)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return draft, base, nil
+}
+
+/*
+DeckRevision A previously published version of a deck. Only the revision directly before the current
+published deck is kept
+*/
+type DeckRevision struct {
+	Code          string          `bson:"code" json:"code"`
+	Owner         string          `bson:"owner" json:"owner"`
+	PublishedDate string          `bson:"publishedDate" json:"publishedDate"`
+	Deck          *deckModel.Deck `bson:"deck" json:"deck"`
+}
+
+/*
+GetDraft Fetch the draft version of a deck. If the deck has no draft, the currently published deck is
+returned so that editing can begin from it. Returns ErrNoDeck if neither a draft nor a published deck exist
+*/
+func GetDraft(ctx stdContext.Context, code string, owner string) (*deckModel.Deck, error) {
+	var result *deckModel.Deck
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "deck_draft", bson.M{"code": code, "mtgjsonApiMeta.owner": owner}, &result)
+	if errors.Is(err, server.ErrNotFound) {
+		return GetDeck(ctx, code, owner)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+SaveDraft Store the deck passed in the parameter as the draft version of the deck. Changes made to a draft are
+not visible to viewers of the deck until it is published with Publish. The first save of a draft records the
+modified date of the deck passed as the version of the public deck it was started from (see GetDraft), so that
+Publish can detect edits made to the public deck in the meantime
+*/
+func SaveDraft(ctx stdContext.Context, deck *deckModel.Deck) error {
+	if deck.Code == "" || deck.Name == "" {
+		return sdkErrors.ErrDeckMissingId
+	}
+
+	if deck.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"code": deck.Code, "mtgjsonApiMeta.owner": deck.MtgjsonApiMeta.Owner}
+
+	_, base, err := findDraft(ctx, database, query)
+	exists := err == nil
+	if errors.Is(err, server.ErrNotFound) {
+		base = deck.MtgjsonApiMeta.ModifiedDate
+	} else if err != nil {
+		return err
+	}
+
+	deck.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr()
+
+	document, err := deckDocument(deck, bson.M{draftBaseField: base})
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		_, err = database.Insert(ctx, "deck_draft", document)
+	} else {
+		_, err = database.Replace(ctx, "deck_draft", query, document)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrDeckUpdateFailed, err)
+	}
+
+	return nil
+}
+
+/*
+DiscardDraft Remove the draft version of a deck without publishing it. Returns ErrNoDraft if the deck
+does not have a draft
+*/
+func DiscardDraft(ctx stdContext.Context, code string, owner string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.Delete(ctx, "deck_draft", bson.M{"code": code, "mtgjsonApiMeta.owner": owner})
+	if errors.Is(err, server.ErrNotFound) {
+		return ErrNoDraft
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+/*
+Publish Promote the draft version of a deck to the public version. The currently published deck is kept as
+the previous revision, and the public deck is swapped with a single replace operation so viewers never see
+a partially edited deck. The replace only succeeds if the public deck still holds the version that the draft
+was started from (see SaveDraft), otherwise ErrDeckPublishFailed is returned wrapping server.ErrConflict, and the
+draft is kept so that it can be reconciled with the public deck.
+
+When transactions are enabled (see server.Database.Transactions) every write of the publish runs in a single
+transaction. Otherwise the writes are ordered so that a failure part way never leaves a half published deck:
+the previous revision is written first, then the public deck is swapped, the deck is added to the ownedDecks of
+its owner and the draft is discarded. A failure before the swap leaves the public deck and the draft untouched,
+although the previous revision may already hold the current public deck, and calling Publish again completes
+it. Returns ErrNoDraft if the deck does not have a draft, or ErrInvalidCommander if the cards in the commander
+board of the draft cannot lead the deck
+*/
+func Publish(ctx stdContext.Context, code string, owner string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	repo, err := repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"code": code, "mtgjsonApiMeta.owner": owner}
+	draft, base, err := findDraft(ctx, database, query)
+	if errors.Is(err, server.ErrNotFound) {
+		return ErrNoDraft
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if draft.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
+	}
+
+	err = validateCommanders(ctx, draft)
+	if err != nil {
+		return err
+	}
+
+	published, err := GetDeck(ctx, code, owner)
+	if err != nil && !errors.Is(err, sdkErrors.ErrNoDeck) {
+		return err
+	}
+
+	if published != nil {
+		if published.MtgjsonApiMeta == nil {
+			return sdkErrors.ErrMissingMetaApi
+		}
+
+		// drafts saved before their base was recorded are checked against the deck read here instead
+		if base == "" {
+			base = published.MtgjsonApiMeta.ModifiedDate
+		}
+
+		if published.MtgjsonApiMeta.ModifiedDate != base {
+			return fmt.Errorf("%w: %w", ErrDeckPublishFailed, server.ErrConflict)
+		}
+
+		draft.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr()
+	}
+
+	err = database.WithTransaction(ctx, func(ctx stdContext.Context) error {
+		fields, err := summaryFields(ctx, draft)
+		if err != nil {
+			return err
+		}
+
+		document, err := deckDocument(draft, fields)
+		if err != nil {
+			return err
+		}
+
+		if published == nil {
+			_, err = database.Insert(ctx, "deck", document)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrDeckPublishFailed, err)
+			}
+
+			err = user.AddOwnedDeck(ctx, owner, code)
+			if err != nil {
+				return err
+			}
+
+			return DiscardDraft(ctx, code, owner)
+		}
+
+		revision := &DeckRevision{
+			Code:          code,
+			Owner:         owner,
+			PublishedDate: util.CreateTimestampStr(),
+			Deck:          published,
+		}
+
+		_, err = database.Upsert(ctx, "deck_revision", bson.M{"code": code, "owner": owner}, revision)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDeckPublishFailed, err)
+		}
+
+		err = repo.ReplaceVersionWith(ctx, query, server.VersionField, base, document)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDeckPublishFailed, err)
+		}
+
+		// repairs the ownedDecks of the owner if an earlier publish of a new deck failed after its insert
+		err = user.AddOwnedDeck(ctx, owner, code)
+		if err != nil {
+			return err
+		}
+
+		return DiscardDraft(ctx, code, owner)
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindDeck, ShareId(draft))
+
+	return nil
+}
+
+/*
+GetPreviousRevision Fetch the revision of a deck that was published before the current public version.
+Returns ErrNoRevision if the deck has only been published once
+*/
+func GetPreviousRevision(ctx stdContext.Context, code string, owner string) (*DeckRevision, error) {
+	var result *DeckRevision
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "deck_revision", bson.M{"code": code, "owner": owner}, &result)
+	if errors.Is(err, server.ErrNotFound) {
+		return nil, ErrNoRevision
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
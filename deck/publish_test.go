@@ -0,0 +1,164 @@
+package deck
+
+import (
+	stdContext "context"
+	"errors"
+	"reflect"
+	"testing"
+
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	userModel "github.com/stevezaluk/mtgjson-models/user"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const testOwner = "player@example.com"
+
+/*
+publishContext Returns a context holding a memory database with a single user, and an empty draft deck
+saved for that user
+*/
+func publishContext(t *testing.T) stdContext.Context {
+	ctx := context.WithDatabase(stdContext.Background(), server.NewMemoryDatabase())
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+
+	_, err = database.Insert(ctx, "user", &userModel.User{Email: testOwner, OwnedDecks: []string{}})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	err = SaveDraft(ctx, &deckModel.Deck{
+		Code:           "ESP",
+		Name:           "Esper Control",
+		ContentIds:     &deckModel.DeckContentIds{},
+		MtgjsonApiMeta: &meta.MTGJSONAPIMeta{Owner: testOwner},
+	})
+	if err != nil {
+		t.Fatalf("SaveDraft() error = %v", err)
+	}
+
+	return ctx
+}
+
+/*
+editDraft Save a draft of the ESP deck with the name passed, starting from the current draft or public deck
+*/
+func editDraft(t *testing.T, ctx stdContext.Context, name string) {
+	draft, err := GetDraft(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("GetDraft() error = %v", err)
+	}
+
+	draft.Name = name
+	err = SaveDraft(ctx, draft)
+	if err != nil {
+		t.Fatalf("SaveDraft() error = %v", err)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	ctx := publishContext(t)
+
+	err := Publish(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	owner, err := user.GetUser(ctx, testOwner)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+
+	if want := []string{"ESP"}; !reflect.DeepEqual(owner.OwnedDecks, want) {
+		t.Errorf("ownedDecks = %v, want %v", owner.OwnedDecks, want)
+	}
+
+	_, err = GetPreviousRevision(ctx, "ESP", testOwner)
+	if !errors.Is(err, ErrNoRevision) {
+		t.Errorf("GetPreviousRevision() error = %v, want %v", err, ErrNoRevision)
+	}
+
+	// the base of a draft is kept across saves, so saving twice does not hide edits to the public deck
+	editDraft(t, ctx, "Esper Midrange")
+	editDraft(t, ctx, "Esper Tempo")
+
+	err = Publish(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	published, err := GetDeck(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("GetDeck() error = %v", err)
+	}
+
+	if published.Name != "Esper Tempo" {
+		t.Errorf("published name = %q, want %q", published.Name, "Esper Tempo")
+	}
+
+	revision, err := GetPreviousRevision(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("GetPreviousRevision() error = %v", err)
+	}
+
+	if revision.Deck.Name != "Esper Control" {
+		t.Errorf("revision name = %q, want %q", revision.Deck.Name, "Esper Control")
+	}
+
+	err = Publish(ctx, "ESP", testOwner)
+	if !errors.Is(err, ErrNoDraft) {
+		t.Errorf("Publish() error = %v, want %v", err, ErrNoDraft)
+	}
+}
+
+func TestPublishConflict(t *testing.T) {
+	ctx := publishContext(t)
+
+	err := Publish(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	editDraft(t, ctx, "Esper Midrange")
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+
+	// another writer edits the public deck after the draft was started
+	_, err = database.Update(ctx, "deck", bson.M{"code": "ESP"}, bson.M{"$set": bson.M{"name": "Esper Walkers", server.VersionField: "edited"}})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	err = Publish(ctx, "ESP", testOwner)
+	if !errors.Is(err, server.ErrConflict) || !errors.Is(err, ErrDeckPublishFailed) {
+		t.Fatalf("Publish() error = %v, want %v", err, server.ErrConflict)
+	}
+
+	published, err := GetDeck(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("GetDeck() error = %v", err)
+	}
+
+	if published.Name != "Esper Walkers" {
+		t.Errorf("published name = %q, want %q", published.Name, "Esper Walkers")
+	}
+
+	draft, err := GetDraft(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("GetDraft() error = %v", err)
+	}
+
+	if draft.Name != "Esper Midrange" {
+		t.Errorf("draft name = %q, want %q", draft.Name, "Esper Midrange")
+	}
+}
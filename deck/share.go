@@ -0,0 +1,80 @@
+package deck
+
+import (
+	stdContext "context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+ShareId Return the global share id of a deck. Deck codes are only unique per owner, so the share id
+is derived from both the owner and the code, allowing a deck to be referenced without knowing its
+owner. The id is deterministic, and is stored on the deck document as shareId so that GetDeckByShareId can
+find the deck with an index
+*/
+func ShareId(deck *deckModel.Deck) string {
+	owner := ""
+	if deck.MtgjsonApiMeta != nil {
+		owner = deck.MtgjsonApiMeta.Owner
+	}
+
+	sum := sha256.Sum256([]byte(owner + ":" + deck.Code))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+/*
+GetDeckByShareId Fetch a deck using its global share id. Returns ErrNoDeck if no deck exists with
+the share id passed
+*/
+func GetDeckByShareId(ctx stdContext.Context, shareId string) (*deckModel.Deck, error) {
+	var result *deckModel.Deck
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "deck", bson.M{"shareId": shareId}, &result)
+	if errors.Is(err, server.ErrNotFound) {
+		return nil, sdkErrors.ErrNoDeck
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+BackfillShareIds Store the share id and summary fields on every deck in the database, and return the number
+of decks that were updated. This is applied by the backfill-deck-share-ids migration to decks that were
+created before share ids were introduced
+*/
+func BackfillShareIds(ctx stdContext.Context) (int64, error) {
+	var ret int64
+
+	decks, err := IndexDecks(ctx, 0)
+	if err != nil {
+		return ret, err
+	}
+
+	for _, deck := range decks {
+		err = updateSummary(ctx, deck)
+		if err != nil {
+			return ret, err
+		}
+
+		ret++
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,27 @@
+package deck
+
+import (
+	"context"
+	"errors"
+
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/slug"
+)
+
+/*
+GetDeckBySlug Fetch a deck using its slug. Slugs are generated from the name of the deck whenever its
+summary is updated. Returns ErrNoDeck if no deck exists with the slug passed
+*/
+func GetDeckBySlug(ctx context.Context, deckSlug string) (*deckModel.Deck, error) {
+	entry, err := slug.Resolve(ctx, slug.KindDeck, deckSlug)
+	if errors.Is(err, slug.ErrNoSlug) {
+		return nil, sdkErrors.ErrNoDeck
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return GetDeck(ctx, entry.Code, entry.Owner)
+}
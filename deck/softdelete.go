@@ -0,0 +1,97 @@
+package deck
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"time"
+
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrDeckDeleted = errors.New("deck: Operation failed. A deleted deck exists under the same code, restore it with RestoreDeck or purge it first")
+
+/*
+deletedDeckExists Returns true if a deck matching the code and owner passed was soft deleted. Deleted decks still
+hold the unique index on their owner and code, see server.SoftDeleteField
+*/
+func deletedDeckExists(ctx stdContext.Context, code string, owner string) (bool, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := database.Count(ctx, "deck", bson.M{"code": code, "mtgjsonApiMeta.owner": owner, server.SoftDeleteField: bson.M{"$exists": true}})
+	if err != nil {
+		return false, err
+	}
+
+	return count != 0, nil
+}
+
+/*
+RestoreDeck Restore a deck that was soft deleted with DeleteDeck. The deck is added back to the owned decks
+of its owner and its summary and slug are regenerated. Returns ErrNoDeck if no deleted deck matches the code
+and owner passed
+*/
+func RestoreDeck(ctx stdContext.Context, code string, owner string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"code": code, server.SoftDeleteField: bson.M{"$exists": true}}
+	if owner != "" {
+		query["mtgjsonApiMeta.owner"] = owner
+	}
+
+	var deck *deckModel.Deck
+	err = database.Find(ctx, "deck", query, &deck)
+	if errors.Is(err, server.ErrNotFound) {
+		return sdkErrors.ErrNoDeck
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if deck.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
+	}
+
+	query = bson.M{"code": code, "mtgjsonApiMeta.owner": deck.MtgjsonApiMeta.Owner}
+	err = database.WithTransaction(ctx, func(ctx stdContext.Context) error {
+		_, err := database.Restore(ctx, "deck", query)
+		if err != nil {
+			return fmt.Errorf("%w: %w", sdkErrors.ErrDeckUpdateFailed, err)
+		}
+
+		return user.AddOwnedDeck(ctx, deck.MtgjsonApiMeta.Owner, code)
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindDeck, ShareId(deck))
+
+	return updateSummary(ctx, deck)
+}
+
+/*
+PurgeDeletedDecks Permanently remove every deck that was soft deleted before the time passed in the parameter.
+Returns the number of decks removed
+*/
+func PurgeDeletedDecks(ctx stdContext.Context, before time.Time) (int64, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return database.PurgeDeleted(ctx, "deck", before)
+}
@@ -0,0 +1,75 @@
+package deck
+
+import (
+	stdContext "context"
+	"errors"
+	"testing"
+	"time"
+
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	userModel "github.com/stevezaluk/mtgjson-models/user"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+func TestNewDeckDeleted(t *testing.T) {
+	database := server.NewMemoryDatabase()
+	database.SoftDelete = true
+
+	ctx := context.WithDatabase(stdContext.Background(), database)
+
+	_, err := database.Insert(ctx, "user", &userModel.User{Email: testOwner, OwnedDecks: []string{}})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	newDeck := func() *deckModel.Deck {
+		return &deckModel.Deck{Code: "ESP", Name: "Esper Control", ContentIds: &deckModel.DeckContentIds{}}
+	}
+
+	err = NewDeck(ctx, newDeck(), testOwner)
+	if err != nil {
+		t.Fatalf("NewDeck() error = %v", err)
+	}
+
+	err = DeleteDeck(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("DeleteDeck() error = %v", err)
+	}
+
+	err = NewDeck(ctx, newDeck(), testOwner)
+	if !errors.Is(err, sdkErrors.ErrDeckAlreadyExists) || !errors.Is(err, ErrDeckDeleted) {
+		t.Errorf("NewDeck() error = %v, want %v", err, ErrDeckDeleted)
+	}
+
+	err = UpsertDeck(ctx, newDeck(), testOwner)
+	if !errors.Is(err, sdkErrors.ErrDeckUpdateFailed) || !errors.Is(err, ErrDeckDeleted) {
+		t.Errorf("UpsertDeck() error = %v, want %v", err, ErrDeckDeleted)
+	}
+
+	err = RestoreDeck(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("RestoreDeck() error = %v", err)
+	}
+
+	err = UpsertDeck(ctx, newDeck(), testOwner)
+	if err != nil {
+		t.Errorf("UpsertDeck() error = %v", err)
+	}
+
+	err = DeleteDeck(ctx, "ESP", testOwner)
+	if err != nil {
+		t.Fatalf("DeleteDeck() error = %v", err)
+	}
+
+	_, err = PurgeDeletedDecks(ctx, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("PurgeDeletedDecks() error = %v", err)
+	}
+
+	err = NewDeck(ctx, newDeck(), testOwner)
+	if err != nil {
+		t.Errorf("NewDeck() error = %v", err)
+	}
+}
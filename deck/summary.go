@@ -0,0 +1,363 @@
+package deck
+
+import (
+	stdContext "context"
+	"fmt"
+	"slices"
+
+	cardModel "github.com/stevezaluk/mtgjson-models/card"
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-sdk/card"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/slug"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"github.com/stevezaluk/mtgjson-sdk/visibility"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+ColorOrder The canonical WUBRG ordering used when storing a decks color identity
+*/
+var ColorOrder = []string{"W", "U", "B", "R", "G"}
+
+/*
+DeckSummary A lightweight view of a deck containing its denormalized summary fields. These fields are
+maintained on the deck document every time its contents change, so deck lists can be rendered without
+resolving the contents of each deck
+*/
+type DeckSummary struct {
+	Name           string               `bson:"name" json:"name"`
+	Code           string               `bson:"code" json:"code"`
+	ShareId        string               `bson:"shareId" json:"shareId"`
+	Slug           string               `bson:"slug" json:"slug"`
+	Type           string               `bson:"type" json:"type"`
+	ColorIdentity  []string             `bson:"colorIdentity" json:"colorIdentity"`
+	ColorProfile   map[string]int64     `bson:"colorProfile" json:"colorProfile"`
+	MainBoardCount int64                `bson:"mainBoardCount" json:"mainBoardCount"`
+	SideBoardCount int64                `bson:"sideBoardCount" json:"sideBoardCount"`
+	CommanderCount int64                `bson:"commanderCount" json:"commanderCount"`
+	UniqueCards    int64                `bson:"uniqueCards" json:"uniqueCards"`
+	MtgjsonApiMeta *meta.MTGJSONAPIMeta `bson:"mtgjsonApiMeta" json:"mtgjsonApiMeta"`
+}
+
+/*
+CountCopies Return the number of copies of each card in the slice of content ids passed, keyed by MTGJSONv4 UUID.
+Copies are represented as repeated UUID's within the boards of a deck
+*/
+func CountCopies(cardIds []string) map[string]int64 {
+	ret := map[string]int64{}
+	for _, uuid := range cardIds {
+		ret[uuid]++
+	}
+
+	return ret
+}
+
+/*
+ComputeColorIdentity Return the combined color identity of the cards passed in WUBRG order, along with a
+color profile counting the number of cards of each color. The cards are distinct, so the number of copies of
+each card is read from the copies passed (see CountCopies), keyed by MTGJSONv4 UUID. Cards missing from it are
+counted once. Colorless cards are counted under "C"
+*/
+func ComputeColorIdentity(cards []*cardModel.CardSet, copies map[string]int64) ([]string, map[string]int64) {
+	identity := []string{}
+	profile := map[string]int64{}
+
+	for _, value := range cards {
+		count := int64(1)
+		if value.Identifiers != nil {
+			if n, ok := copies[value.Identifiers.MtgjsonV4Id]; ok {
+				count = n
+			}
+		}
+
+		if len(value.Colors) == 0 {
+			profile["C"] += count
+		}
+
+		for _, color := range value.Colors {
+			profile[color] += count
+		}
+
+		for _, color := range value.ColorIdentity {
+			if !slices.Contains(identity, color) {
+				identity = append(identity, color)
+			}
+		}
+	}
+
+	slices.SortFunc(identity, func(a string, b string) int {
+		return slices.Index(ColorOrder, a) - slices.Index(ColorOrder, b)
+	})
+
+	return identity, profile
+}
+
+/*
+CountUniqueCards Return the number of distinct cards across every board of a deck
+*/
+func CountUniqueCards(contents *deckModel.DeckContentIds) int64 {
+	cardIds, err := AllCardIds(contents)
+	if err != nil {
+		return 0
+	}
+
+	slices.Sort(cardIds)
+
+	return int64(len(slices.Compact(cardIds)))
+}
+
+/*
+summaryFields Compute the denormalized summary fields of a deck, including its share id and slug
+*/
+func summaryFields(ctx stdContext.Context, deck *deckModel.Deck) (bson.M, error) {
+	if deck.ContentIds == nil {
+		return nil, sdkErrors.ErrDeckMissingContentIds
+	}
+
+	cardIds, err := AllCardIds(deck.ContentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []*cardModel.CardSet
+	if len(cardIds) != 0 {
+		cards, err = card.GetCards(ctx, cardIds, "identifiers.mtgjsonV4Id", "colors", "colorIdentity")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	identity, profile := ComputeColorIdentity(cards, CountCopies(cardIds))
+
+	if deck.MtgjsonApiMeta == nil {
+		return nil, sdkErrors.ErrMissingMetaApi
+	}
+
+	deckSlug, err := slug.Assign(ctx, slug.KindDeck, deck.Name, deck.Code, deck.MtgjsonApiMeta.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{
+		"shareId":        ShareId(deck),
+		"slug":           deckSlug,
+		"colorIdentity":  identity,
+		"colorProfile":   profile,
+		"mainBoardCount": len(deck.ContentIds.MainBoard),
+		"sideBoardCount": len(deck.ContentIds.SideBoard),
+		"commanderCount": len(deck.ContentIds.Commander),
+		"uniqueCards":    CountUniqueCards(deck.ContentIds),
+	}, nil
+}
+
+/*
+deckDocument Returns the document stored for the deck passed: the deck model along with the summary fields
+passed. Replacing a deck with this document keeps its summary fields, which are not part of the deck model
+*/
+func deckDocument(deck *deckModel.Deck, fields bson.M) (bson.M, error) {
+	// boards are stored as empty arrays rather than null, so that cards can be pushed to them by updateContents
+	if deck.ContentIds != nil {
+		for _, board := range []*[]string{&deck.ContentIds.MainBoard, &deck.ContentIds.SideBoard, &deck.ContentIds.Commander} {
+			if *board == nil {
+				*board = []string{}
+			}
+		}
+	}
+
+	document, err := bson.Marshal(deck)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := bson.M{}
+	err = bson.Unmarshal(document, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range fields {
+		ret[key] = value
+	}
+
+	return ret, nil
+}
+
+/*
+updateSummary Recompute the denormalized summary fields of a deck, including its slug, and store them on the
+deck document
+*/
+func updateSummary(ctx stdContext.Context, deck *deckModel.Deck) error {
+	fields, err := summaryFields(ctx, deck)
+	if err != nil {
+		return err
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"code": deck.Code, "mtgjsonApiMeta.owner": deck.MtgjsonApiMeta.Owner}
+	_, err = database.SetField(ctx, "deck", query, fields)
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrDeckUpdateFailed, err)
+	}
+
+	return nil
+}
+
+/*
+updateContents Store a change to the content ids of a deck, passed as the update operators that apply it (e.g. a
+$push of new cards), along with its summary fields in the same update. The content ids of the deck model passed
+must already hold the change, and before must hold the content ids it replaced. The board counts and unique cards
+are incremented by the difference between the two with $inc rather than recomputed, while the color identity and
+profile are computed from the new contents. The update is only applied if the deck has not been modified since
+the model was read (see ReplaceDeck), and the modified date is updated on success. Returns ErrDeckUpdateFailed
+if the deck cannot be located, wrapping server.ErrConflict if another writer modified the deck first
+*/
+func updateContents(ctx stdContext.Context, deck *deckModel.Deck, before *deckModel.DeckContentIds, change bson.M) error {
+	if deck.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
+	}
+
+	cardIds, err := AllCardIds(deck.ContentIds)
+	if err != nil {
+		return err
+	}
+
+	var cards []*cardModel.CardSet
+	if len(cardIds) != 0 {
+		cards, err = card.GetCards(ctx, cardIds, "identifiers.mtgjsonV4Id", "colors", "colorIdentity")
+		if err != nil {
+			return err
+		}
+	}
+
+	identity, profile := ComputeColorIdentity(cards, CountCopies(cardIds))
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	modified := util.CreateTimestampStr()
+
+	update := bson.M{
+		"$inc": bson.M{
+			"mainBoardCount": len(deck.ContentIds.MainBoard) - len(before.MainBoard),
+			"sideBoardCount": len(deck.ContentIds.SideBoard) - len(before.SideBoard),
+			"commanderCount": len(deck.ContentIds.Commander) - len(before.Commander),
+			"uniqueCards":    CountUniqueCards(deck.ContentIds) - CountUniqueCards(before),
+		},
+		"$set": bson.M{
+			server.VersionField: modified,
+			"colorIdentity":     identity,
+			"colorProfile":      profile,
+		},
+	}
+
+	for operator, fields := range change {
+		update[operator] = fields
+	}
+
+	query := bson.M{"code": deck.Code, "mtgjsonApiMeta.owner": deck.MtgjsonApiMeta.Owner}
+	versioned := bson.M{server.VersionField: deck.MtgjsonApiMeta.ModifiedDate}
+	for key, value := range query {
+		versioned[key] = value
+	}
+
+	result, err := database.Update(ctx, "deck", versioned, update)
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrDeckUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := database.Count(ctx, "deck", query)
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return sdkErrors.ErrNoDeck
+		}
+
+		return fmt.Errorf("%w: %w", sdkErrors.ErrDeckUpdateFailed, server.ErrConflict)
+	}
+
+	deck.MtgjsonApiMeta.ModifiedDate = modified
+
+	invalidation.Publish(ctx, invalidation.KindDeck, ShareId(deck))
+
+	return nil
+}
+
+/*
+IndexDeckSummaries Returns the summary of every deck in the database without resolving their contents. The limit
+parameter will be passed directly to the database query to limit the number of models returned. Sort fields can
+be passed to order the decks before the limit is applied, see IndexDecks
+*/
+func IndexDeckSummaries(ctx stdContext.Context, limit int64, sort ...string) ([]*DeckSummary, error) {
+	var result []*DeckSummary
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Index(ctx, "deck", limit, &result, sort...)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+/*
+FindDecksByColorIdentity Returns the summary of every deck whose color identity matches the colors passed. If
+exact is true, the deck must have exactly these colors (e.g. W, U, B for Esper), otherwise any deck that contains
+all of these colors is returned. Passing no colors returns colorless decks. Only public decks, owned by the system
+user, and the decks of the viewer are searched, and the summaries are shaped for the viewer with visibility.Shape.
+The viewer may be empty for anonymous requests
+*/
+func FindDecksByColorIdentity(ctx stdContext.Context, colors []string, exact bool, viewer string) ([]*DeckSummary, error) {
+	var result []*DeckSummary
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"$all": colors}
+	if exact {
+		filter["$size"] = len(colors)
+	}
+
+	if len(colors) == 0 {
+		filter = bson.M{"$size": 0}
+	}
+
+	owners := []string{user.SystemUser}
+	if viewer != "" && viewer != user.SystemUser {
+		owners = append(owners, viewer)
+	}
+
+	query := bson.M{"colorIdentity": filter, "mtgjsonApiMeta.owner": bson.M{"$in": owners}}
+	err = database.FindMany(ctx, "deck", query, &result)
+	if err != nil {
+		return result, err
+	}
+
+	if len(result) == 0 {
+		return result, sdkErrors.ErrNoDecks
+	}
+
+	visibility.Shape(result, viewer)
+
+	return result, nil
+}
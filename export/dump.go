@@ -0,0 +1,289 @@
+package export
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/stevezaluk/mtgjson-sdk/storage"
+	"go.mongodb.org/mongo-driver/bson"
+
+	deckModel "github.com/stevezaluk/mtgjson-models/deck"
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+)
+
+const (
+	DUMP_PREFIX     = "dumps/"
+	DUMP_LATEST_KEY = DUMP_PREFIX + "latest.json"
+	DUMP_LOCK_NAME  = "public-dumps"
+
+	DumpPublicDecks = "public-decks.json"
+	DumpSummary     = "summary.json"
+	DumpCardUsage   = "card-usage.json"
+	DumpManifest    = "manifest.json"
+)
+
+var ErrDumpFailed = errors.New("export: Operation failed. Failed to gather data for the public dump")
+
+/*
+PublicDeck The public view of a deck included in the data dumps. The owner of the deck is not included,
+decks are identified by their share ID instead
+*/
+type PublicDeck struct {
+	ShareId       string                    `bson:"shareId" json:"shareId"`
+	Name          string                    `bson:"name" json:"name"`
+	Code          string                    `bson:"code" json:"code"`
+	Type          string                    `bson:"type" json:"type"`
+	ReleaseDate   string                    `bson:"releaseDate" json:"releaseDate"`
+	ColorIdentity []string                  `bson:"colorIdentity" json:"colorIdentity"`
+	ContentIds    *deckModel.DeckContentIds `bson:"contentIds" json:"contentIds"`
+}
+
+/*
+Summary Aggregate statistics across the database included in the data dumps
+*/
+type Summary struct {
+	Cards           int64            `json:"cards"`
+	Sets            int64            `json:"sets"`
+	Decks           int64            `json:"decks"`
+	Users           int64            `json:"users"`
+	DecksByType     map[string]int64 `json:"decksByType"`
+	DecksByIdentity map[string]int64 `json:"decksByColorIdentity"`
+}
+
+/*
+CardUsage The number of public decks a card appears in, and the total number of copies across them
+*/
+type CardUsage struct {
+	CardId string `json:"cardId"`
+	Decks  int64  `json:"decks"`
+	Copies int64  `json:"copies"`
+}
+
+/*
+ManifestFile A single file written as part of a data dump
+*/
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+/*
+Manifest Describes a single versioned data dump. The manifest of the most recent dump is also written
+to dumps/latest.json so consumers can discover it
+*/
+type Manifest struct {
+	Version     string          `json:"version"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Files       []*ManifestFile `json:"files"`
+}
+
+/*
+gatherSummary Count the documents in each collection and group the public decks by type and color identity
+*/
+func gatherSummary(ctx context.Context, decks []*PublicDeck) (*Summary, error) {
+	database, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Summary{
+		Decks:           int64(len(decks)),
+		DecksByType:     map[string]int64{},
+		DecksByIdentity: map[string]int64{},
+	}
+
+	for collection, field := range map[string]*int64{"card": &ret.Cards, "set": &ret.Sets, "user": &ret.Users} {
+		count, err := database.Count(ctx, collection, bson.M{})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDumpFailed, err)
+		}
+
+		*field = count
+	}
+
+	for _, value := range decks {
+		identity := "C"
+		if len(value.ColorIdentity) != 0 {
+			identity = ""
+			for _, color := range value.ColorIdentity {
+				identity += color
+			}
+		}
+
+		ret.DecksByType[value.Type]++
+		ret.DecksByIdentity[identity]++
+	}
+
+	return ret, nil
+}
+
+/*
+gatherCardUsage Count the number of public decks each card appears in across all of their boards, sorted
+by the number of decks
+*/
+func gatherCardUsage(decks []*PublicDeck) []*CardUsage {
+	ret := []*CardUsage{}
+	byId := map[string]*CardUsage{}
+
+	for _, value := range decks {
+		if value.ContentIds == nil {
+			continue
+		}
+
+		seen := map[string]bool{}
+		boards := [][]string{value.ContentIds.MainBoard, value.ContentIds.SideBoard, value.ContentIds.Commander}
+		for _, board := range boards {
+			for _, uuid := range board {
+				usage, ok := byId[uuid]
+				if !ok {
+					usage = &CardUsage{CardId: uuid}
+					byId[uuid] = usage
+					ret = append(ret, usage)
+				}
+
+				if !seen[uuid] {
+					seen[uuid] = true
+					usage.Decks++
+				}
+
+				usage.Copies++
+			}
+		}
+	}
+
+	slices.SortFunc(ret, func(a *CardUsage, b *CardUsage) int {
+		if a.Decks != b.Decks {
+			return cmp.Compare(b.Decks, a.Decks)
+		}
+
+		return cmp.Compare(a.CardId, b.CardId)
+	})
+
+	return ret
+}
+
+/*
+writeFile Marshal the value passed as JSON and write it to storage, returning its manifest entry
+*/
+func writeFile(ctx context.Context, store storage.Storage, key string, name string, value interface{}) (*ManifestFile, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.Put(ctx, key, bytes.NewReader(data), "application/json")
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+
+	return &ManifestFile{Name: name, Key: key, Size: int64(len(data)), SHA256: hex.EncodeToString(hash[:])}, nil
+}
+
+/*
+ExportPublicDumps Generate the public data dumps and write them to storage under a versioned prefix,
+named for the time the dump was generated (dumps/<version>/). Each dump contains the public decks, an
+analytics summary, card usage statistics and a manifest listing every file with its size and checksum.
+The manifest is written last, and is then copied to dumps/latest.json
+*/
+func ExportPublicDumps(ctx context.Context, store storage.Storage) (*Manifest, error) {
+	var decks []*PublicDeck
+
+	database, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.FindMany(ctx, "deck", bson.M{}, &decks)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDumpFailed, err)
+	}
+
+	summary, err := gatherSummary(ctx, decks)
+	if err != nil {
+		return nil, err
+	}
+
+	generatedAt := time.Now().UTC()
+	manifest := &Manifest{
+		Version:     generatedAt.Format("20060102T150405Z"),
+		GeneratedAt: generatedAt,
+		Files:       []*ManifestFile{},
+	}
+
+	prefix := DUMP_PREFIX + manifest.Version + "/"
+	files := map[string]interface{}{
+		DumpPublicDecks: decks,
+		DumpSummary:     summary,
+		DumpCardUsage:   gatherCardUsage(decks),
+	}
+
+	for _, name := range []string{DumpPublicDecks, DumpSummary, DumpCardUsage} {
+		file, err := writeFile(ctx, store, prefix+name, name, files[name])
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	_, err = writeFile(ctx, store, prefix+DumpManifest, DumpManifest, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = writeFile(ctx, store, DUMP_LATEST_KEY, DumpManifest, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	mtgContext.GetLogger().Info("Exported public data dump", "version", manifest.Version, "decks", len(decks))
+
+	return manifest, nil
+}
+
+/*
+SchedulePublicDumps Call ExportPublicDumps on the interval passed until the context is cancelled. This
+blocks, and should be started in its own goroutine. A lock is held for each interval, so only one API
+replica exports each dump
+*/
+func SchedulePublicDumps(ctx context.Context, store storage.Storage, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			database, err := mtgContext.GetMongoDatabase(ctx)
+			if err != nil {
+				mtgContext.GetLogger().Error("Failed to export public data dump", "err", err)
+				continue
+			}
+
+			// the lock is left to expire just before the next interval rather than released, so replicas
+			// that tick later in the same interval do not export the dump again
+			_, err = database.AcquireLock(ctx, DUMP_LOCK_NAME, interval*9/10)
+			if err != nil {
+				continue
+			}
+
+			_, err = ExportPublicDumps(ctx, store)
+			if err != nil {
+				mtgContext.GetLogger().Error("Failed to export public data dump", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+const (
+	KindCard = "card"
+	KindDeck = "deck"
+	KindSet  = "set"
+
+	RETRY_INTERVAL = 5 * time.Second
+)
+
+var ErrListenFailed = errors.New("invalidation: Operation failed. Failed to open the cache invalidation change stream")
+
+/*
+Event Signals that the cached copies of an entity are stale. Key identifies the entity within its kind,
+for example a card UUID or a deck code. Origin is the replica that published the event
+*/
+type Event struct {
+	Kind      string    `bson:"kind" json:"kind"`
+	Key       string    `bson:"key" json:"key"`
+	Origin    string    `bson:"origin" json:"origin"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+/*
+Handler A function called for each invalidation event, used to evict the entity from an in-memory cache
+*/
+type Handler func(event *Event)
+
+var (
+	handlers     []Handler
+	handlersLock sync.RWMutex
+
+	origin = newOrigin()
+)
+
+/*
+newOrigin Return an id that is unique to this replica
+*/
+func newOrigin() string {
+	hostname, _ := os.Hostname()
+
+	return fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
+
+/*
+Enabled Returns true if the invalidation bus is enabled with 'cache.invalidation.enabled'. When disabled,
+events are only delivered to the handlers of the local replica
+*/
+func Enabled() bool {
+	return viper.GetBool("cache.invalidation.enabled")
+}
+
+/*
+Subscribe Register a function to be called for every invalidation event, whether it was published by this
+replica or another one
+*/
+func Subscribe(handler Handler) {
+	handlersLock.Lock()
+	defer handlersLock.Unlock()
+
+	handlers = append(handlers, handler)
+}
+
+/*
+dispatch Pass the event to every registered handler
+*/
+func dispatch(event *Event) {
+	handlersLock.RLock()
+	defer handlersLock.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+/*
+Publish Invalidate the entity passed on every replica. The local handlers are called immediately, and the
+event is written to the cache_invalidation collection to be picked up by other replicas running Listen
+*/
+func Publish(ctx context.Context, kind string, key string) {
+	event := &Event{Kind: kind, Key: key, Origin: origin, CreatedAt: time.Now()}
+
+	dispatch(event)
+
+	if !Enabled() {
+		return
+	}
+
+	database, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return
+	}
+
+	_, err = database.Insert(ctx, "cache_invalidation", event)
+	if err != nil {
+		mtgContext.GetLogger().Error("Failed to publish cache invalidation event", "kind", kind, "key", key, "err", err)
+	}
+}
+
+/*
+Listen Watch the cache_invalidation collection with a change stream, passing events published by other
+replicas to the local handlers, until the context is cancelled. If the change stream fails it is reopened.
+This blocks, and should be started in its own goroutine. Change streams require a replica set
+*/
+func Listen(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := listen(ctx)
+		if err != nil && ctx.Err() == nil {
+			mtgContext.GetLogger().Error("Cache invalidation change stream failed, retrying", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(RETRY_INTERVAL):
+		}
+	}
+}
+
+/*
+listen Open a single change stream and dispatch its events until it fails or the context is cancelled
+*/
+func listen(ctx context.Context) error {
+	database, err := mtgContext.GetMongoDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	changes, err := database.Watch(ctx, "cache_invalidation", server.MatchOperations(server.ChangeInsert))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrListenFailed, err)
+	}
+
+	for change := range changes {
+		if change.Err != nil {
+			return change.Err
+		}
+
+		var event *Event
+		err = change.Decode(&event)
+		if err != nil || event == nil {
+			continue
+		}
+
+		if event.Origin == origin {
+			continue
+		}
+
+		dispatch(event)
+	}
+
+	return nil
+}
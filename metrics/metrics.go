@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+const (
+	DeckGetContents       = "deck.get_contents"
+	DeckCardsResolved     = "deck.cards_resolved"
+	CardValidated         = "card.validate"
+	CardValidationInvalid = "card.validate.invalid_uuid"
+	CardValidationMissing = "card.validate.missing"
+	CardCreateRejected    = "card.create.rejected"
+	ImportSet             = "import.set"
+	ImportCardsImported   = "import.cards.imported"
+	ImportCardsSkipped    = "import.cards.skipped"
+	ImportCardsFailed     = "import.cards.failed"
+)
+
+/*
+registry Every domain metric, published through expvar under the 'mtgjson' key. When the default HTTP mux
+is served, the metrics can be scraped from /debug/vars
+*/
+var registry = expvar.NewMap("mtgjson")
+
+/*
+Add Increment the named counter by the delta passed
+*/
+func Add(name string, delta int64) {
+	registry.Add(name, delta)
+}
+
+/*
+Observe Record a single timed operation under the name passed. This maintains a count of operations
+(name.count) and their total duration in microseconds (name.totalMicros), from which the average duration
+and throughput can be derived
+*/
+func Observe(name string, elapsed time.Duration) {
+	registry.Add(name+".count", 1)
+	registry.Add(name+".totalMicros", elapsed.Microseconds())
+}
+
+/*
+Since Record a timed operation that started at the time passed. Intended to be deferred at the start of
+the function being timed:
+
+	defer metrics.Since(metrics.DeckGetContents, time.Now())
+*/
+func Since(name string, start time.Time) {
+	Observe(name, time.Since(start))
+}
+
+/*
+Snapshot Return the current value of every metric, keyed by name
+*/
+func Snapshot() map[string]int64 {
+	ret := map[string]int64{}
+
+	registry.Do(func(kv expvar.KeyValue) {
+		value, err := strconv.ParseInt(kv.Value.String(), 10, 64)
+		if err == nil {
+			ret[kv.Key] = value
+		}
+	})
+
+	return ret
+}
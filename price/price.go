@@ -0,0 +1,328 @@
+package price
+
+import (
+	stdContext "context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/upstream"
+)
+
+const (
+	PRICE_COLLECTION         = "price"
+	PRICE_HISTORY_COLLECTION = "price_history"
+	PRICE_BATCH_SIZE         = 1000
+	PRICE_DATE_LAYOUT        = "2006-01-02"
+
+	ALL_PRICES_FILE       = "AllPrices.json"
+	ALL_PRICES_TODAY_FILE = "AllPricesToday.json"
+
+	MarketPaper = "paper"
+	MarketMtgo  = "mtgo"
+
+	ListRetail  = "retail"
+	ListBuylist = "buylist"
+
+	FinishNormal = "normal"
+	FinishFoil   = "foil"
+	FinishEtched = "etched"
+)
+
+/*
+Finishes The finishes that a card may be priced in
+*/
+var Finishes = []string{FinishNormal, FinishFoil, FinishEtched}
+
+var ErrInvalidPriceFile = errors.New("price: Operation failed. The file is not a valid MTGJSON AllPrices file")
+var ErrInvalidFinish = errors.New("price: Operation failed. The finish must be either normal, foil or etched")
+var ErrInvalidDateRange = errors.New("price: Operation failed. The end of the date range is before its start")
+
+/*
+Price The most recent price of a card from a single provider (e.g. tcgplayer or cardmarket), for a single
+market, list type and finish. Prices are keyed by a deterministic _id, so re-importing a prices file
+overwrites the prices it contains
+*/
+type Price struct {
+	Id       string  `json:"-" bson:"_id"`
+	CardId   string  `json:"uuid" bson:"cardId"`
+	Market   string  `json:"market" bson:"market"`
+	Provider string  `json:"provider" bson:"provider"`
+	ListType string  `json:"listType" bson:"listType"`
+	Finish   string  `json:"finish" bson:"finish"`
+	Currency string  `json:"currency" bson:"currency"`
+	Date     string  `json:"date" bson:"date"`
+	Price    float64 `json:"price" bson:"price"`
+}
+
+/*
+ImportReport A summary of a prices import. Prices is the number of most recent prices written to the price
+collection, and Points is the number of daily points written to the price history
+*/
+type ImportReport struct {
+	Prices int64 `json:"prices"`
+	Points int64 `json:"points"`
+}
+
+/*
+providerPrices The prices of a card from a single provider, as stored in AllPrices.json. Both lists are keyed
+by finish, and then by date
+*/
+type providerPrices struct {
+	Buylist  map[string]map[string]float64 `json:"buylist"`
+	Retail   map[string]map[string]float64 `json:"retail"`
+	Currency string                        `json:"currency"`
+}
+
+/*
+cardPrices The prices of a single card as stored in AllPrices.json, keyed by market and then by provider
+*/
+type cardPrices map[string]map[string]*providerPrices
+
+/*
+priceId Build the _id of the price with the fields passed
+*/
+func priceId(uuid string, market string, provider string, listType string, finish string) string {
+	return strings.Join([]string{uuid, market, provider, listType, finish}, ":")
+}
+
+/*
+latest Returns the most recent date in the prices passed along with its price. MTGJSON dates are in
+the YYYY-MM-DD form, so they can be compared as strings
+*/
+func latest(prices map[string]float64) (string, float64) {
+	var date string
+	for key := range prices {
+		if key > date {
+			date = key
+		}
+	}
+
+	return date, prices[date]
+}
+
+/*
+flatten Convert the prices of the card passed into a Price for each of its providers, markets, list types
+and finishes, using the most recent date of each. Every dated price is also returned as a point of the price
+history, keyed by its date so that a day is only ever stored once
+*/
+func flatten(uuid string, prices cardPrices) ([]interface{}, []interface{}) {
+	var ret []interface{}
+	var points []interface{}
+
+	for market, providers := range prices {
+		for provider, value := range providers {
+			if value == nil {
+				continue
+			}
+
+			lists := map[string]map[string]map[string]float64{ListRetail: value.Retail, ListBuylist: value.Buylist}
+			for listType, finishes := range lists {
+				for finish, dates := range finishes {
+					if len(dates) == 0 {
+						continue
+					}
+
+					id := priceId(uuid, market, provider, listType, finish)
+					date, amount := latest(dates)
+					ret = append(ret, &Price{
+						Id:       id,
+						CardId:   uuid,
+						Market:   market,
+						Provider: provider,
+						ListType: listType,
+						Finish:   finish,
+						Currency: value.Currency,
+						Date:     date,
+						Price:    amount,
+					})
+
+					for date, amount := range dates {
+						points = append(points, &Price{
+							Id:       id + ":" + date,
+							CardId:   uuid,
+							Market:   market,
+							Provider: provider,
+							ListType: listType,
+							Finish:   finish,
+							Currency: value.Currency,
+							Date:     date,
+							Price:    amount,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return ret, points
+}
+
+/*
+expectDelim Read the next token from the decoder and return ErrInvalidPriceFile if it is not the delimiter passed
+*/
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return ErrInvalidPriceFile
+	}
+
+	if value, ok := token.(json.Delim); !ok || value != delim {
+		return ErrInvalidPriceFile
+	}
+
+	return nil
+}
+
+/*
+Import Read an MTGJSON AllPrices file (or AllPricesToday file) from the reader passed and store the most recent
+price of each card in the price collection, along with every dated price in the price history collection. The
+file is decoded one card at a time, as AllPrices.json is far too large to be decoded in full, and documents are
+written in batches of PRICE_BATCH_SIZE. Importing AllPricesToday.json daily therefore extends the history of
+each card by a day. If database is nil, the database of the context passed is used
+*/
+func Import(ctx stdContext.Context, database server.DatabaseInterface, r io.Reader) (*ImportReport, error) {
+	if database == nil {
+		var err error
+
+		database, err = context.GetDatabase(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &ImportReport{}
+
+	var batch []interface{}
+	var points []interface{}
+
+	flush := func(collection string, documents *[]interface{}, written *int64) error {
+		if len(*documents) == 0 {
+			return nil
+		}
+
+		_, err := database.UpsertMany(ctx, collection, *documents)
+		if err != nil {
+			return err
+		}
+
+		*written += int64(len(*documents))
+		*documents = nil
+
+		return nil
+	}
+
+	decoder := json.NewDecoder(r)
+
+	err := expectDelim(decoder, '{')
+	if err != nil {
+		return nil, err
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return report, ErrInvalidPriceFile
+		}
+
+		if key != "data" {
+			var skip json.RawMessage
+
+			err = decoder.Decode(&skip)
+			if err != nil {
+				return report, ErrInvalidPriceFile
+			}
+
+			continue
+		}
+
+		err = expectDelim(decoder, '{')
+		if err != nil {
+			return report, err
+		}
+
+		for decoder.More() {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+
+			token, err := decoder.Token()
+			if err != nil {
+				return report, ErrInvalidPriceFile
+			}
+
+			uuid, ok := token.(string)
+			if !ok {
+				return report, ErrInvalidPriceFile
+			}
+
+			var prices cardPrices
+
+			err = decoder.Decode(&prices)
+			if err != nil {
+				return report, ErrInvalidPriceFile
+			}
+
+			latest, history := flatten(uuid, prices)
+			batch = append(batch, latest...)
+			points = append(points, history...)
+
+			if len(batch) >= PRICE_BATCH_SIZE {
+				err = flush(PRICE_COLLECTION, &batch, &report.Prices)
+				if err != nil {
+					return report, err
+				}
+			}
+
+			if len(points) >= PRICE_BATCH_SIZE {
+				err = flush(PRICE_HISTORY_COLLECTION, &points, &report.Points)
+				if err != nil {
+					return report, err
+				}
+			}
+		}
+
+		err = expectDelim(decoder, '}')
+		if err != nil {
+			return report, err
+		}
+	}
+
+	err = flush(PRICE_COLLECTION, &batch, &report.Prices)
+	if err != nil {
+		return report, err
+	}
+
+	err = flush(PRICE_HISTORY_COLLECTION, &points, &report.Points)
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+/*
+ImportUpstream Download the prices file passed (either ALL_PRICES_FILE or ALL_PRICES_TODAY_FILE) from the
+upstream MTGJSON source and import it with Import. The file is streamed, so it is never held in memory
+*/
+func ImportUpstream(ctx stdContext.Context, file string) (*ImportReport, error) {
+	body, err := upstream.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	report, err := Import(ctx, nil, body)
+	if err != nil {
+		slog.Error("Failed to import prices from upstream", "file", file, "err", err)
+		return report, err
+	}
+
+	slog.Info("Finished importing prices from upstream", "file", file, "prices", report.Prices, "points", report.Points)
+
+	return report, nil
+}
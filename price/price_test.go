@@ -0,0 +1,91 @@
+package price
+
+import (
+	stdContext "context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const testPrices = `{
+	"meta": {"date": "2024-01-02", "version": "5.2.2"},
+	"data": {
+		"5f8287b1-5bb6-5f4c-ad17-316a40d5bb0c": {
+			"paper": {
+				"tcgplayer": {
+					"currency": "USD",
+					"retail": {
+						"normal": {"2024-01-01": 1.5, "2024-01-02": 1.75},
+						"foil": {"2024-01-02": 4.0}
+					},
+					"buylist": {"normal": {"2024-01-02": 0.9}}
+				}
+			}
+		}
+	}
+}`
+
+func TestImport(t *testing.T) {
+	ctx := stdContext.Background()
+	database := server.NewMemoryDatabase()
+
+	want := ImportReport{Prices: 3, Points: 4}
+
+	// importing the same file twice overwrites the prices and points it contains
+	for range 2 {
+		report, err := Import(ctx, database, strings.NewReader(testPrices))
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+
+		if *report != want {
+			t.Errorf("Import() = %+v, want %+v", *report, want)
+		}
+	}
+
+	counts := map[string]int64{PRICE_COLLECTION: 3, PRICE_HISTORY_COLLECTION: 4}
+	for collection, count := range counts {
+		got, err := database.Count(ctx, collection, bson.M{})
+		if err != nil {
+			t.Fatalf("Count() error = %v", err)
+		}
+
+		if got != count {
+			t.Errorf("Count() of %s = %d, want %d", collection, got, count)
+		}
+	}
+
+	var price Price
+
+	err := database.Find(ctx, PRICE_COLLECTION, bson.M{"_id": priceId("5f8287b1-5bb6-5f4c-ad17-316a40d5bb0c", MarketPaper, "tcgplayer", ListRetail, FinishNormal)}, &price)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	if price.Date != "2024-01-02" || price.Price != 1.75 || price.Currency != "USD" {
+		t.Errorf("Find() = %+v, want the price of 2024-01-02", price)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"not an object", `[]`},
+		{"card is not an object", `{"data": {"5f8287b1-5bb6-5f4c-ad17-316a40d5bb0c": []}}`},
+		{"truncated", `{"data": {"5f8287b1-5bb6-5f4c-ad17-316a40d5bb0c": {}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Import(stdContext.Background(), server.NewMemoryDatabase(), strings.NewReader(test.file))
+			if !errors.Is(err, ErrInvalidPriceFile) {
+				t.Errorf("Import() error = %v, want %v", err, ErrInvalidPriceFile)
+			}
+		})
+	}
+}
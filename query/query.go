@@ -0,0 +1,238 @@
+package query
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidField = errors.New("query: Operation failed. Field names must not be empty, begin with '$' or contain a null byte")
+
+/*
+Query A fluent builder for MongoDB filters. Conditions are combined with AND, and several conditions on
+the same field are merged into a single operator document. Field names are validated so filters can be
+built from user supplied input without allowing operator injection. The first invalid field is recorded
+and returned by Build. Sort fields are kept separately from the filter and returned by SortFields
+*/
+type Query struct {
+	fields  bson.M
+	clauses bson.A
+	sort    []string
+	err     error
+}
+
+/*
+New Create an empty query. An empty query matches every document
+*/
+func New() *Query {
+	return &Query{fields: bson.M{}}
+}
+
+/*
+validField Returns true if the field name is safe to use as a key in a filter
+*/
+func validField(field string) bool {
+	return field != "" && !strings.HasPrefix(field, "$") && !strings.ContainsRune(field, 0)
+}
+
+/*
+where Add an operator condition on a field. If the operator is already set on the field, the condition
+is added as a separate clause so neither is lost
+*/
+func (q *Query) where(field string, operator string, value interface{}) *Query {
+	if !validField(field) {
+		if q.err == nil {
+			q.err = ErrInvalidField
+		}
+
+		return q
+	}
+
+	conditions, ok := q.fields[field].(bson.M)
+	if !ok {
+		conditions = bson.M{}
+		q.fields[field] = conditions
+	}
+
+	if _, exists := conditions[operator]; exists {
+		q.clauses = append(q.clauses, bson.M{field: bson.M{operator: value}})
+		return q
+	}
+
+	conditions[operator] = value
+
+	return q
+}
+
+/*
+Eq Match documents where the field is equal to the value. For array fields this matches if any element
+is equal to the value
+*/
+func (q *Query) Eq(field string, value interface{}) *Query {
+	return q.where(field, "$eq", value)
+}
+
+/*
+Ne Match documents where the field is not equal to the value
+*/
+func (q *Query) Ne(field string, value interface{}) *Query {
+	return q.where(field, "$ne", value)
+}
+
+/*
+Gt Match documents where the field is greater than the value
+*/
+func (q *Query) Gt(field string, value interface{}) *Query {
+	return q.where(field, "$gt", value)
+}
+
+/*
+Gte Match documents where the field is greater than or equal to the value
+*/
+func (q *Query) Gte(field string, value interface{}) *Query {
+	return q.where(field, "$gte", value)
+}
+
+/*
+Lt Match documents where the field is less than the value
+*/
+func (q *Query) Lt(field string, value interface{}) *Query {
+	return q.where(field, "$lt", value)
+}
+
+/*
+Lte Match documents where the field is less than or equal to the value
+*/
+func (q *Query) Lte(field string, value interface{}) *Query {
+	return q.where(field, "$lte", value)
+}
+
+/*
+In Match documents where the field is equal to any of the values
+*/
+func (q *Query) In(field string, values ...interface{}) *Query {
+	return q.where(field, "$in", bson.A(values))
+}
+
+/*
+Nin Match documents where the field is equal to none of the values
+*/
+func (q *Query) Nin(field string, values ...interface{}) *Query {
+	return q.where(field, "$nin", bson.A(values))
+}
+
+/*
+All Match documents where the array field contains every one of the values
+*/
+func (q *Query) All(field string, values ...interface{}) *Query {
+	return q.where(field, "$all", bson.A(values))
+}
+
+/*
+Exists Match documents based on whether the field is present
+*/
+func (q *Query) Exists(field string, exists bool) *Query {
+	return q.where(field, "$exists", exists)
+}
+
+/*
+Regex Match documents where the field matches the regular expression passed in the parameter. The match
+is case insensitive. User supplied text should be passed to Contains instead, which escapes it
+*/
+func (q *Query) Regex(field string, pattern string) *Query {
+	return q.where(field, "$regex", primitive.Regex{Pattern: pattern, Options: "i"})
+}
+
+/*
+Contains Match documents where the field contains the text passed in the parameter, ignoring case. Any
+regular expression characters in the text are escaped
+*/
+func (q *Query) Contains(field string, text string) *Query {
+	return q.Regex(field, regexp.QuoteMeta(text))
+}
+
+/*
+Or Match documents that match at least one of the queries passed in the parameter. The first error
+recorded by any of the queries is carried over to this one
+*/
+func (q *Query) Or(queries ...*Query) *Query {
+	var branches bson.A
+	for _, other := range queries {
+		filter, err := other.Build()
+		if err != nil {
+			if q.err == nil {
+				q.err = err
+			}
+
+			return q
+		}
+
+		branches = append(branches, filter)
+	}
+
+	if len(branches) != 0 {
+		q.clauses = append(q.clauses, bson.M{"$or": branches})
+	}
+
+	return q
+}
+
+/*
+Sort Order the results by the fields passed in the parameter. A field prefixed with '-' is sorted in
+descending order, see server.SortBy. Calling Sort again adds tie breakers after the existing fields
+*/
+func (q *Query) Sort(fields ...string) *Query {
+	for _, field := range fields {
+		if !validField(strings.TrimPrefix(field, "-")) {
+			if q.err == nil {
+				q.err = ErrInvalidField
+			}
+
+			return q
+		}
+
+		q.sort = append(q.sort, field)
+	}
+
+	return q
+}
+
+/*
+SortFields Return the sort fields added with Sort, in order
+*/
+func (q *Query) SortFields() []string {
+	return q.sort
+}
+
+/*
+Build Compile the query into a filter that can be passed to the server package. Returns ErrInvalidField
+if any condition was added with an unsafe field name
+*/
+func (q *Query) Build() (bson.M, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	filter := bson.M{}
+	for field, conditions := range q.fields {
+		filter[field] = conditions
+	}
+
+	if len(q.clauses) == 0 {
+		return filter, nil
+	}
+
+	if len(filter) == 0 && len(q.clauses) == 1 {
+		return q.clauses[0].(bson.M), nil
+	}
+
+	and := bson.A{}
+	if len(filter) != 0 {
+		and = append(and, filter)
+	}
+
+	return bson.M{"$and": append(and, q.clauses...)}, nil
+}
@@ -0,0 +1,119 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		want  bson.M
+	}{
+		{
+			name:  "empty",
+			query: New(),
+			want:  bson.M{},
+		},
+		{
+			name:  "single condition",
+			query: New().Eq("name", "Lightning Bolt"),
+			want:  bson.M{"name": bson.M{"$eq": "Lightning Bolt"}},
+		},
+		{
+			name:  "conditions on the same field are merged",
+			query: New().Gte("manaValue", 1).Lte("manaValue", 3),
+			want:  bson.M{"manaValue": bson.M{"$gte": 1, "$lte": 3}},
+		},
+		{
+			name:  "repeated operators are kept as separate clauses",
+			query: New().All("colors", "W").All("colors", "U"),
+			want: bson.M{"$and": bson.A{
+				bson.M{"colors": bson.M{"$all": bson.A{"W"}}},
+				bson.M{"colors": bson.M{"$all": bson.A{"U"}}},
+			}},
+		},
+		{
+			name:  "set operators",
+			query: New().In("setCode", "LEA", "M10").Nin("rarity", "special").Exists("text", true),
+			want: bson.M{
+				"setCode": bson.M{"$in": bson.A{"LEA", "M10"}},
+				"rarity":  bson.M{"$nin": bson.A{"special"}},
+				"text":    bson.M{"$exists": true},
+			},
+		},
+		{
+			name:  "contains escapes the text",
+			query: New().Contains("name", "Mr. Orfeo"),
+			want:  bson.M{"name": bson.M{"$regex": primitive.Regex{Pattern: `Mr\. Orfeo`, Options: "i"}}},
+		},
+		{
+			name:  "or alone",
+			query: New().Or(New().Eq("colors", "U"), New().Eq("types", "Instant")),
+			want: bson.M{"$or": bson.A{
+				bson.M{"colors": bson.M{"$eq": "U"}},
+				bson.M{"types": bson.M{"$eq": "Instant"}},
+			}},
+		},
+		{
+			name:  "or combined with fields",
+			query: New().Ne("layout", "token").Or(New().Eq("colors", "U"), New().Eq("types", "Instant")),
+			want: bson.M{"$and": bson.A{
+				bson.M{"layout": bson.M{"$ne": "token"}},
+				bson.M{"$or": bson.A{
+					bson.M{"colors": bson.M{"$eq": "U"}},
+					bson.M{"types": bson.M{"$eq": "Instant"}},
+				}},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.query.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Build() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBuildInvalidField(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+	}{
+		{"empty field", New().Eq("", "Lightning Bolt")},
+		{"operator field", New().Eq("$where", "this.name")},
+		{"null byte", New().Eq("name\x00", "Lightning Bolt")},
+		{"invalid field in or", New().Or(New().Eq("name", "Shock"), New().Gt("$expr", 1))},
+		{"invalid sort field", New().Eq("name", "Shock").Sort("-$natural")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.query.Build()
+			if !errors.Is(err, ErrInvalidField) {
+				t.Errorf("Build() error = %v, want %v", err, ErrInvalidField)
+			}
+		})
+	}
+}
+
+func TestSortFields(t *testing.T) {
+	got := New().Sort("name", "-manaValue").Sort("setCode").SortFields()
+
+	want := []string{"name", "-manaValue", "setCode"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortFields() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ArchiveFormatJSON = "json"
+	ArchiveFormatBSON = "bson"
+
+	ImportModeInsert  = "insert"
+	ImportModeUpsert  = "upsert"
+	ImportModeReplace = "replace"
+
+	ARCHIVE_BATCH_SIZE = 500
+	MAX_DOCUMENT_SIZE  = 16 * 1024 * 1024
+)
+
+var ErrInvalidArchiveFormat = errors.New("server: Operation failed. The archive format must be either json or bson")
+var ErrInvalidImportMode = errors.New("server: Operation failed. The import mode must be insert, upsert or replace")
+var ErrInvalidArchive = errors.New("server: Operation failed. The archive contains a malformed document")
+
+/*
+ImportResult The number of documents processed by Import. Read is the number of documents read from the
+archive, Inserted the number written as new documents, Replaced the number that overwrote an existing
+document in upsert mode, and Skipped the number not written in insert mode because a document with the
+same _id already exists
+*/
+type ImportResult struct {
+	Read     int64 `json:"read"`
+	Inserted int64 `json:"inserted"`
+	Replaced int64 `json:"replaced"`
+	Skipped  int64 `json:"skipped"`
+}
+
+/*
+ImportProgress A function called by Import after every batch of documents is written, with the totals so far
+*/
+type ImportProgress func(result ImportResult)
+
+/*
+Export Stream every document in the collection to the writer passed in the parameter, and return the number
+of documents written. The json format writes one document per line as canonical MongoDB Extended JSON, so
+types such as dates and ObjectIds survive a round trip. The bson format writes the raw documents back to back,
+which is the same layout as a .bson file written by mongodump. Soft deleted documents are included so that
+the archive is a complete backup of the collection
+*/
+func (d *Database) Export(ctx context.Context, collection string, w io.Writer, format string) (int64, error) {
+	if format != ArchiveFormatJSON && format != ArchiveFormatBSON {
+		return 0, ErrInvalidArchiveFormat
+	}
+
+	coll := d.collection(collection)
+
+	slog.Debug("Export Collection", "collection", collection, "format", format)
+	cur, err := coll.Find(ctx, bson.M{}, options.Find().SetBatchSize(ARCHIVE_BATCH_SIZE).SetComment(d.comment(ctx)))
+	if err != nil {
+		slog.Error("Error during Export", "collection", collection, "err", err)
+		return 0, wrapError("Export", collection, err)
+	}
+	defer cur.Close(ctx)
+
+	writer := bufio.NewWriter(w)
+
+	var count int64
+	for cur.Next(ctx) {
+		document := cur.Current
+		if format == ArchiveFormatJSON {
+			document, err = bson.MarshalExtJSON(cur.Current, true, false)
+			if err != nil {
+				return count, fmt.Errorf("server: Export on %s failed: %w", collection, err)
+			}
+
+			document = append(document, '\n')
+		}
+
+		_, err = writer.Write(document)
+		if err != nil {
+			return count, fmt.Errorf("server: Export on %s failed: %w", collection, err)
+		}
+
+		count++
+	}
+
+	if cur.Err() != nil {
+		slog.Error("Error during Export", "collection", collection, "exported", count, "err", cur.Err())
+		return count, wrapError("Export", collection, cur.Err())
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return count, fmt.Errorf("server: Export on %s failed: %w", collection, err)
+	}
+
+	slog.Info("Exported collection", "collection", collection, "format", format, "count", count)
+
+	return count, nil
+}
+
+/*
+archiveReader Return a function that reads the next document from an archive written by Export. The function
+returns io.EOF once the archive has been fully read
+*/
+func archiveReader(r io.Reader, format string) func() (bson.Raw, error) {
+	if format == ArchiveFormatJSON {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), MAX_DOCUMENT_SIZE)
+
+		return func() (bson.Raw, error) {
+			for scanner.Scan() {
+				line := bytes.TrimSpace(scanner.Bytes())
+				if len(line) == 0 {
+					continue
+				}
+
+				var document bson.Raw
+				err := bson.UnmarshalExtJSON(line, true, &document)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+				}
+
+				return document, nil
+			}
+
+			if scanner.Err() != nil {
+				return nil, scanner.Err()
+			}
+
+			return nil, io.EOF
+		}
+	}
+
+	reader := bufio.NewReader(r)
+
+	return func() (bson.Raw, error) {
+		header := make([]byte, 4)
+		_, err := io.ReadFull(reader, header)
+		if err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, ErrInvalidArchive
+			}
+
+			return nil, err
+		}
+
+		length := int(binary.LittleEndian.Uint32(header))
+		if length < 5 || length > MAX_DOCUMENT_SIZE {
+			return nil, ErrInvalidArchive
+		}
+
+		document := make([]byte, length)
+		copy(document, header)
+
+		_, err = io.ReadFull(reader, document[4:])
+		if err != nil {
+			return nil, ErrInvalidArchive
+		}
+
+		err = bson.Raw(document).Validate()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+
+		return document, nil
+	}
+}
+
+/*
+importBatch Write a single batch of documents to the collection using the import mode passed, and add the
+outcome to the result
+*/
+func (d *Database) importBatch(ctx context.Context, collection string, mode string, batch []bson.Raw, result *ImportResult) error {
+	coll := d.collection(collection)
+
+	if mode == ImportModeUpsert {
+		models := make([]mongo.WriteModel, 0, len(batch))
+		for _, document := range batch {
+			id, err := document.LookupErr("_id")
+			if err != nil {
+				models = append(models, mongo.NewInsertOneModel().SetDocument(document))
+				continue
+			}
+
+			models = append(models, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": id}).SetReplacement(document).SetUpsert(true))
+		}
+
+		written, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false).SetComment(d.comment(ctx)))
+		if err != nil {
+			return wrapError("Import", collection, err)
+		}
+
+		result.Inserted += written.InsertedCount + written.UpsertedCount
+		result.Replaced += written.MatchedCount
+
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(batch))
+	for _, document := range batch {
+		documents = append(documents, document)
+	}
+
+	_, err := coll.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false).SetComment(d.comment(ctx)))
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return wrapError("Import", collection, err)
+			}
+		}
+
+		result.Inserted += int64(len(batch) - len(bulkErr.WriteErrors))
+		result.Skipped += int64(len(bulkErr.WriteErrors))
+		return nil
+	}
+
+	if err != nil {
+		return wrapError("Import", collection, err)
+	}
+
+	result.Inserted += int64(len(batch))
+
+	return nil
+}
+
+/*
+Import Restore documents from an archive written by Export into the collection passed in the parameter. In
+insert mode documents are only written if no document with the same _id exists, in upsert mode existing
+documents with the same _id are overwritten, and in replace mode every document in the collection is removed
+before the archive is written, leaving the indexes of the collection in place. Documents are written in
+batches of ARCHIVE_BATCH_SIZE, and the progress function, which may be nil, is called after each batch
+*/
+func (d *Database) Import(ctx context.Context, collection string, r io.Reader, format string, mode string, progress ImportProgress) (*ImportResult, error) {
+	if format != ArchiveFormatJSON && format != ArchiveFormatBSON {
+		return nil, ErrInvalidArchiveFormat
+	}
+
+	if mode != ImportModeInsert && mode != ImportModeUpsert && mode != ImportModeReplace {
+		return nil, ErrInvalidImportMode
+	}
+
+	result := &ImportResult{}
+
+	if mode == ImportModeReplace {
+		slog.Warn("Removing every document before import", "collection", collection)
+		_, err := d.collection(collection).DeleteMany(ctx, bson.M{}, options.Delete().SetComment(d.comment(ctx)))
+		if err != nil {
+			return result, wrapError("Import", collection, err)
+		}
+	}
+
+	next := archiveReader(r, format)
+	batch := make([]bson.Raw, 0, ARCHIVE_BATCH_SIZE)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := d.importBatch(ctx, collection, mode, batch, result)
+		if err != nil {
+			return err
+		}
+
+		batch = batch[:0]
+		if progress != nil {
+			progress(*result)
+		}
+
+		return nil
+	}
+
+	for {
+		document, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			slog.Error("Error reading archive", "collection", collection, "read", result.Read, "err", err)
+			return result, err
+		}
+
+		result.Read++
+		batch = append(batch, document)
+
+		if len(batch) == ARCHIVE_BATCH_SIZE {
+			err = flush()
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	err := flush()
+	if err != nil {
+		return result, err
+	}
+
+	slog.Info("Imported collection", "collection", collection, "mode", mode, "read", result.Read, "inserted", result.Inserted, "replaced", result.Replaced, "skipped", result.Skipped)
+
+	return result, nil
+}
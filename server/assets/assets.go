@@ -0,0 +1,391 @@
+package assets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DEFAULT_BUCKET_NAME  = "asset"
+	DEFAULT_CONTENT_TYPE = "application/octet-stream"
+)
+
+var ErrAssetNotFound = errors.New("assets: Failed to find an asset with the specified key")
+var ErrInvalidKey = errors.New("assets: Operation failed. Asset key is invalid")
+var ErrChecksumMismatch = errors.New("assets: Operation failed. The asset does not match its stored checksum")
+
+/*
+Asset The metadata of a blob stored in GridFS. Checksum is the hex encoded SHA-256 of the contents, computed
+while the asset is uploaded, and can be used as an ETag when serving the asset over HTTP
+*/
+type Asset struct {
+	Key         string    `json:"key"`
+	ContentType string    `json:"contentType"`
+	Checksum    string    `json:"checksum"`
+	Size        int64     `json:"size"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+}
+
+/*
+assetMetadata The metadata document stored on each file in the files collection of the bucket
+*/
+type assetMetadata struct {
+	ContentType string `bson:"contentType"`
+	Checksum    string `bson:"checksum"`
+}
+
+/*
+fileDocument A document from the files collection of the bucket
+*/
+type fileDocument struct {
+	Id         primitive.ObjectID `bson:"_id"`
+	Name       string             `bson:"filename"`
+	Length     int64              `bson:"length"`
+	UploadDate time.Time          `bson:"uploadDate"`
+	Metadata   assetMetadata      `bson:"metadata"`
+}
+
+/*
+asset Convert the file document into an Asset
+*/
+func (f *fileDocument) asset() *Asset {
+	return &Asset{
+		Key:         f.Name,
+		ContentType: f.Metadata.ContentType,
+		Checksum:    f.Metadata.Checksum,
+		Size:        f.Length,
+		UploadedAt:  f.UploadDate,
+	}
+}
+
+/*
+Store Card images and other binary assets stored in a GridFS bucket of the Database, so that the API can serve
+them without an external CDN. Assets are addressed by a slash separated key (e.g. "cards/<uuid>/normal.jpg").
+Writing to a key that already exists replaces the asset, and readers continue to see the previous revision
+until the new one has been fully uploaded
+*/
+type Store struct {
+	Database *server.Database
+	Bucket   string
+}
+
+/*
+NewStore Create a Store for the GridFS bucket passed in the parameter. The CollectionPrefix of the Database is
+applied to the bucket name. If name is empty, DEFAULT_BUCKET_NAME is used
+*/
+func NewStore(database *server.Database, name string) *Store {
+	if name == "" {
+		name = DEFAULT_BUCKET_NAME
+	}
+
+	return &Store{Database: database, Bucket: name}
+}
+
+/*
+bucket Return a handle to the GridFS bucket. A new handle is created for each operation as deadlines are set
+on the bucket rather than passed with a context
+*/
+func (s *Store) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(s.Database.Database, options.GridFSBucket().SetName(s.Database.CollectionName(s.Bucket)))
+}
+
+/*
+CardImageKey Return the key that the image of a card is stored under. The variant names the size or style of
+the image (e.g. "normal", "art_crop"), mirroring the image variants published by Scryfall
+*/
+func CardImageKey(uuid string, variant string) string {
+	return "cards/" + uuid + "/" + variant
+}
+
+/*
+validKey Returns true if the key can be used to address an asset
+*/
+func validKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && !strings.ContainsRune(key, 0)
+}
+
+/*
+latest Return the most recently uploaded revision of the asset stored under the key. Returns ErrAssetNotFound
+if no asset is stored under it
+*/
+func (s *Store) latest(ctx context.Context, bucket *gridfs.Bucket, key string) (*fileDocument, error) {
+	var file fileDocument
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "uploadDate", Value: -1}, {Key: "_id", Value: -1}})
+	err := bucket.GetFilesCollection().FindOne(ctx, bson.M{"filename": key}, opts).Decode(&file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrAssetNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("assets: failed to find %s: %w", key, err)
+	}
+
+	return &file, nil
+}
+
+/*
+Put Upload the contents of the reader as the asset stored under the key, and return its metadata. The checksum
+is computed while the contents are uploaded. Older revisions of the asset are removed once the upload has
+completed. If contentType is empty, DEFAULT_CONTENT_TYPE is used
+*/
+func (s *Store) Put(ctx context.Context, key string, reader io.Reader, contentType string) (*Asset, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	if contentType == "" {
+		contentType = DEFAULT_CONTENT_TYPE
+	}
+
+	bucket, err := s.bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := bucket.OpenUploadStream(key, options.GridFSUpload().SetMetadata(bson.M{"contentType": contentType}))
+	if err != nil {
+		return nil, fmt.Errorf("assets: failed to upload %s: %w", key, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetWriteDeadline(deadline)
+	}
+
+	digest := sha256.New()
+	size, err := io.Copy(stream, io.TeeReader(reader, digest))
+	if err != nil {
+		stream.Abort()
+		return nil, fmt.Errorf("assets: failed to upload %s: %w", key, err)
+	}
+
+	err = stream.Close()
+	if err != nil {
+		return nil, fmt.Errorf("assets: failed to upload %s: %w", key, err)
+	}
+
+	checksum := hex.EncodeToString(digest.Sum(nil))
+	_, err = bucket.GetFilesCollection().UpdateOne(ctx, bson.M{"_id": stream.FileID}, bson.M{"$set": bson.M{"metadata.checksum": checksum}})
+	if err != nil {
+		return nil, fmt.Errorf("assets: failed to store the checksum of %s: %w", key, err)
+	}
+
+	s.prune(ctx, bucket, key, stream.FileID)
+
+	slog.Debug("Uploaded asset", "bucket", s.Bucket, "key", key, "contentType", contentType, "size", size)
+
+	return &Asset{Key: key, ContentType: contentType, Checksum: checksum, Size: size, UploadedAt: time.Now().UTC()}, nil
+}
+
+/*
+prune Remove every revision of the asset stored under the key except the one passed. A failure is logged
+rather than returned, as the new revision has already been written and is the one returned to readers
+*/
+func (s *Store) prune(ctx context.Context, bucket *gridfs.Bucket, key string, keep interface{}) {
+	cur, err := bucket.FindContext(ctx, bson.M{"filename": key, "_id": bson.M{"$ne": keep}})
+	if err != nil {
+		slog.Warn("Failed to find old revisions of asset", "bucket", s.Bucket, "key", key, "err", err)
+		return
+	}
+
+	var files []fileDocument
+	err = cur.All(ctx, &files)
+	if err != nil {
+		slog.Warn("Failed to find old revisions of asset", "bucket", s.Bucket, "key", key, "err", err)
+		return
+	}
+
+	for _, file := range files {
+		err = bucket.DeleteContext(ctx, file.Id)
+		if err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			slog.Warn("Failed to remove old revision of asset", "bucket", s.Bucket, "key", key, "id", file.Id, "err", err)
+		}
+	}
+}
+
+/*
+Stat Return the metadata of the asset stored under the key without reading its contents. Returns
+ErrAssetNotFound if no asset is stored under it
+*/
+func (s *Store) Stat(ctx context.Context, key string) (*Asset, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	bucket, err := s.bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.latest(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.asset(), nil
+}
+
+/*
+verifyingReader Wraps a download stream and compares the checksum of the contents read against the checksum
+stored with the asset. ErrChecksumMismatch is returned in place of io.EOF if they differ
+*/
+type verifyingReader struct {
+	stream   *gridfs.DownloadStream
+	digest   hash.Hash
+	checksum string
+}
+
+/*
+Read Read from the download stream, verifying the checksum once the end of the stream is reached
+*/
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.stream.Read(p)
+	r.digest.Write(p[:n])
+
+	if errors.Is(err, io.EOF) && r.checksum != "" && hex.EncodeToString(r.digest.Sum(nil)) != r.checksum {
+		return n, ErrChecksumMismatch
+	}
+
+	return n, err
+}
+
+/*
+Close Close the download stream
+*/
+func (r *verifyingReader) Close() error {
+	return r.stream.Close()
+}
+
+/*
+Get Open the asset stored under the key for reading, and return it with its metadata. The caller must close
+the reader. The contents are verified against the stored checksum as they are read, and ErrChecksumMismatch
+is returned by the final read if they do not match. Returns ErrAssetNotFound if no asset is stored under
+the key
+*/
+func (s *Store) Get(ctx context.Context, key string) (io.ReadCloser, *Asset, error) {
+	if !validKey(key) {
+		return nil, nil, ErrInvalidKey
+	}
+
+	bucket, err := s.bucket()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := s.latest(ctx, bucket, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stream, err := bucket.OpenDownloadStream(file.Id)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, nil, ErrAssetNotFound
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("assets: failed to download %s: %w", key, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetReadDeadline(deadline)
+	}
+
+	return &verifyingReader{stream: stream, digest: sha256.New(), checksum: file.Metadata.Checksum}, file.asset(), nil
+}
+
+/*
+Delete Remove every revision of the asset stored under the key. Returns ErrAssetNotFound if no asset is stored
+under it
+*/
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	bucket, err := s.bucket()
+	if err != nil {
+		return err
+	}
+
+	file, err := s.latest(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	s.prune(ctx, bucket, key, file.Id)
+
+	err = bucket.DeleteContext(ctx, file.Id)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return ErrAssetNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("assets: failed to delete %s: %w", key, err)
+	}
+
+	return nil
+}
+
+/*
+Exists Returns true if an asset is stored under the key
+*/
+func (s *Store) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.Stat(ctx, key)
+	if errors.Is(err, ErrAssetNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+/*
+List Return the metadata of every asset whose key starts with the prefix passed, ordered by key
+*/
+func (s *Store) List(ctx context.Context, prefix string) ([]*Asset, error) {
+	bucket, err := s.bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"filename": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix)}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "filename", Value: 1}, {Key: "uploadDate", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$filename", "file": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceWith", Value: "$file"}},
+		{{Key: "$sort", Value: bson.M{"filename": 1}}},
+	}
+
+	cur, err := bucket.GetFilesCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("assets: failed to list %s: %w", prefix, err)
+	}
+
+	var files []fileDocument
+	err = cur.All(ctx, &files)
+	if err != nil {
+		return nil, fmt.Errorf("assets: failed to list %s: %w", prefix, err)
+	}
+
+	ret := make([]*Asset, 0, len(files))
+	for _, file := range files {
+		ret = append(ret, file.asset())
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AUDIT_COLLECTION = "audit"
+	AUDIT_SYSTEM     = "system"
+)
+
+/*
+auditExcluded Collections whose writes are never audited. Writes to the audit collection itself would
+recurse, and locks are renewed too often to be worth recording
+*/
+var auditExcluded = []string{AUDIT_COLLECTION, "lock"}
+
+/*
+actorKey The key the actor of an operation is stored under in a context
+*/
+type actorKey struct{}
+
+/*
+WithActor Return a copy of the context passed that attributes every write it is passed to to the actor, such
+as the email address of the user that made the API request. Writes made without an actor are attributed to
+AUDIT_SYSTEM
+*/
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+/*
+actor Return the actor of a write made with the context passed
+*/
+func actor(ctx context.Context) string {
+	if value, ok := ctx.Value(actorKey{}).(string); ok && value != "" {
+		return value
+	}
+
+	return AUDIT_SYSTEM
+}
+
+/*
+AuditChange A single field that was changed by a write, in dotted form. Before is empty for fields that were
+added, and After is empty for fields that were removed
+*/
+type AuditChange struct {
+	Field  string      `bson:"field" json:"field"`
+	Before interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After  interface{} `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+/*
+AuditEntry A record of a single write to the database. DocumentId is the _id of the document that was written,
+and Changes holds every field that differs between the document before and after the write
+*/
+type AuditEntry struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Actor      string             `bson:"actor" json:"actor"`
+	Operation  string             `bson:"operation" json:"operation"`
+	Collection string             `bson:"collection" json:"collection"`
+	DocumentId interface{}        `bson:"documentId,omitempty" json:"documentId,omitempty"`
+	Changes    []AuditChange      `bson:"changes" json:"changes"`
+	Timestamp  time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+/*
+EnableAudit Record every insert, replace, update and delete made through the Database in the audit collection.
+If retention is greater than 0, the audit collection is registered as an ephemeral collection so that entries
+are removed once they are older than the retention, see EnsureTTLIndex
+*/
+func (d *Database) EnableAudit(retention time.Duration) {
+	d.Audit = true
+
+	if retention > 0 {
+		RegisterEphemeralCollection(AUDIT_COLLECTION, "timestamp", retention)
+	}
+}
+
+/*
+audits Returns true if writes to the collection should be recorded in the audit collection
+*/
+func (d *Database) audits(collection string) bool {
+	return d.Audit && !slices.Contains(auditExcluded, collection)
+}
+
+/*
+snapshot Return the first document matching the query as it is stored in the database, or nil if no document
+matches. Soft deleted documents are not excluded, so the query must already exclude them if required
+*/
+func (d *Database) snapshot(ctx context.Context, collection string, query bson.M) bson.M {
+	var ret bson.M
+
+	err := d.collection(collection).FindOne(ctx, query, options.FindOne().SetComment(d.comment(ctx))).Decode(&ret)
+	if err != nil {
+		return nil
+	}
+
+	return ret
+}
+
+/*
+flattenDocument Add every field of the document to the map passed, keyed by its dotted path. Arrays are
+treated as a single value
+*/
+func flattenDocument(prefix string, document bson.M, ret map[string]interface{}) {
+	for key, value := range document {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		if nested, ok := value.(bson.M); ok && len(nested) != 0 {
+			flattenDocument(path, nested, ret)
+			continue
+		}
+
+		ret[path] = value
+	}
+}
+
+/*
+diffDocuments Return every field that differs between the two documents, ordered by field. Either document
+may be nil
+*/
+func diffDocuments(before bson.M, after bson.M) []AuditChange {
+	old, updated := map[string]interface{}{}, map[string]interface{}{}
+	flattenDocument("", before, old)
+	flattenDocument("", after, updated)
+
+	ret := []AuditChange{}
+	for field, value := range old {
+		newValue, ok := updated[field]
+		if !ok {
+			ret = append(ret, AuditChange{Field: field, Before: value})
+			continue
+		}
+
+		if !reflect.DeepEqual(value, newValue) {
+			ret = append(ret, AuditChange{Field: field, Before: value, After: newValue})
+		}
+	}
+
+	for field, value := range updated {
+		if _, ok := old[field]; !ok {
+			ret = append(ret, AuditChange{Field: field, After: value})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Field < ret[j].Field })
+
+	return ret
+}
+
+/*
+auditEntry Build the audit entry for a write that changed the document from before to after
+*/
+func auditEntry(ctx context.Context, operation string, collection string, before bson.M, after bson.M) *AuditEntry {
+	var id interface{}
+	if after != nil {
+		id = after["_id"]
+	}
+
+	if id == nil && before != nil {
+		id = before["_id"]
+	}
+
+	return &AuditEntry{
+		Actor:      actor(ctx),
+		Operation:  operation,
+		Collection: collection,
+		DocumentId: id,
+		Changes:    diffDocuments(before, after),
+		Timestamp:  time.Now().UTC(),
+	}
+}
+
+/*
+recordAudit Write the audit entries passed to the audit collection. A failure is logged rather than returned,
+as the write being audited has already been made
+*/
+func (d *Database) recordAudit(ctx context.Context, entries ...*AuditEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	documents := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		documents = append(documents, entry)
+	}
+
+	_, err := d.collection(AUDIT_COLLECTION).InsertMany(ctx, documents, options.InsertMany().SetOrdered(false).SetComment(d.comment(ctx)))
+	if err != nil {
+		slog.Error("Failed to record audit entries", "collection", entries[0].Collection, "operation", entries[0].Operation, "count", len(entries), "err", err)
+	}
+}
+
+/*
+auditWrite Record a single write in the audit collection, if writes to the collection are audited
+*/
+func (d *Database) auditWrite(ctx context.Context, operation string, collection string, before bson.M, after bson.M) {
+	if !d.audits(collection) || (before == nil && after == nil) {
+		return
+	}
+
+	d.recordAudit(ctx, auditEntry(ctx, operation, collection, before, after))
+}
+
+/*
+auditInserted Record the insert of each model passed, along with the _id it was inserted with. Models that
+failed to insert, as reported by the error of the insert, are skipped
+*/
+func (d *Database) auditInserted(ctx context.Context, operation string, collection string, models []interface{}, ids []interface{}, err error) {
+	if !d.audits(collection) {
+		return
+	}
+
+	failed := map[int]bool{}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = true
+		}
+	}
+
+	entries := make([]*AuditEntry, 0, len(models))
+	for i, model := range models {
+		if failed[i] || i >= len(ids) {
+			continue
+		}
+
+		document, err := toDocument(model)
+		if err != nil {
+			slog.Error("Failed to audit inserted document", "collection", collection, "err", err)
+			continue
+		}
+
+		document["_id"] = ids[i]
+		entries = append(entries, auditEntry(ctx, operation, collection, nil, document))
+	}
+
+	d.recordAudit(ctx, entries...)
+}
+
+/*
+auditSnapshot Return the document matching the query before it is written, if writes to the collection are
+audited. Returns nil otherwise
+*/
+func (d *Database) auditSnapshot(ctx context.Context, collection string, query bson.M) bson.M {
+	if !d.audits(collection) {
+		return nil
+	}
+
+	return d.snapshot(ctx, collection, query)
+}
+
+/*
+auditChange Record a write that changed the document with the id passed, comparing the document as it is now
+stored against the snapshot taken before the write. If id is nil, the _id of the snapshot is used
+*/
+func (d *Database) auditChange(ctx context.Context, operation string, collection string, before bson.M, id interface{}) {
+	if !d.audits(collection) {
+		return
+	}
+
+	if id == nil && before != nil {
+		id = before["_id"]
+	}
+
+	var after bson.M
+	if id != nil {
+		after = d.snapshot(ctx, collection, bson.M{"_id": id})
+	}
+
+	d.auditWrite(ctx, operation, collection, before, after)
+}
+
+/*
+AuditFilter Narrows the entries returned by AuditLog. Empty fields are ignored
+*/
+type AuditFilter struct {
+	Actor      string
+	Operation  string
+	Collection string
+	DocumentId interface{}
+	Since      time.Time
+	Until      time.Time
+}
+
+/*
+query Build the query matching the entries described by the filter
+*/
+func (f *AuditFilter) query() bson.M {
+	ret := bson.M{}
+	if f == nil {
+		return ret
+	}
+
+	if f.Actor != "" {
+		ret["actor"] = f.Actor
+	}
+
+	if f.Operation != "" {
+		ret["operation"] = f.Operation
+	}
+
+	if f.Collection != "" {
+		ret["collection"] = f.Collection
+	}
+
+	if f.DocumentId != nil {
+		ret["documentId"] = f.DocumentId
+	}
+
+	timestamp := bson.M{}
+	if !f.Since.IsZero() {
+		timestamp["$gte"] = f.Since
+	}
+
+	if !f.Until.IsZero() {
+		timestamp["$lt"] = f.Until
+	}
+
+	if len(timestamp) != 0 {
+		ret["timestamp"] = timestamp
+	}
+
+	return ret
+}
+
+/*
+AuditLog Return a single page of the audit entries matching the filter, which may be nil. Unless a sort key is
+set in the options, the most recent entries are returned first
+*/
+func (d *Database) AuditLog(ctx context.Context, filter *AuditFilter, opts *PageOptions) ([]*AuditEntry, *Page, error) {
+	normalized := PageOptions{}
+	if opts != nil {
+		normalized = *opts
+	}
+
+	if normalized.SortKey == "" {
+		normalized.SortKey = "timestamp"
+		normalized.Descending = true
+	}
+
+	var ret []*AuditEntry
+
+	page, err := d.Paginate(ctx, AUDIT_COLLECTION, filter.query(), &normalized, &ret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ret, page, nil
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDiffDocuments(t *testing.T) {
+	tests := []struct {
+		name   string
+		before bson.M
+		after  bson.M
+		want   []AuditChange
+	}{
+		{
+			name:   "identical",
+			before: bson.M{"name": "Lightning Bolt", "legalities": bson.M{"modern": "Legal"}},
+			after:  bson.M{"name": "Lightning Bolt", "legalities": bson.M{"modern": "Legal"}},
+			want:   []AuditChange{},
+		},
+		{
+			name:   "insert",
+			before: nil,
+			after:  bson.M{"name": "Shock"},
+			want:   []AuditChange{{Field: "name", After: "Shock"}},
+		},
+		{
+			name:   "delete",
+			before: bson.M{"name": "Shock"},
+			after:  nil,
+			want:   []AuditChange{{Field: "name", Before: "Shock"}},
+		},
+		{
+			name:   "changed, added and removed fields are ordered by field",
+			before: bson.M{"name": "Shock", "text": "Deal 2 damage"},
+			after:  bson.M{"name": "Lightning Bolt", "manaCost": "{R}"},
+			want: []AuditChange{
+				{Field: "manaCost", After: "{R}"},
+				{Field: "name", Before: "Shock", After: "Lightning Bolt"},
+				{Field: "text", Before: "Deal 2 damage"},
+			},
+		},
+		{
+			name:   "nested documents are compared by dotted path",
+			before: bson.M{"legalities": bson.M{"modern": "Legal", "legacy": "Legal"}},
+			after:  bson.M{"legalities": bson.M{"modern": "Banned", "legacy": "Legal"}},
+			want:   []AuditChange{{Field: "legalities.modern", Before: "Legal", After: "Banned"}},
+		},
+		{
+			name:   "arrays are compared as a single value",
+			before: bson.M{"printings": bson.A{"LEA", "M10"}},
+			after:  bson.M{"printings": bson.A{"LEA", "M10", "2XM"}},
+			want:   []AuditChange{{Field: "printings", Before: bson.A{"LEA", "M10"}, After: bson.A{"LEA", "M10", "2XM"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := diffDocuments(test.before, test.after)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("diffDocuments() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
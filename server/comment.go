@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"strings"
+)
+
+/*
+commentKey The key the operation comment is stored under in a context
+*/
+type commentKey struct{}
+
+/*
+WithRequest Return a shallow copy of the Database that tags every operation it performs with a $comment
+identifying the API request and user that caused it. The underlying client and connection pool are
+shared with the original Database, so this is cheap enough to call once per request. Operations found
+in the MongoDB profiler or the slow query log can then be correlated back to the originating request. Attach
+the copy to the request context with context.WithDatabase so that the entity operations of the request use it
+*/
+func (d *Database) WithRequest(requestId string, user string) *Database {
+	ret := *d
+	ret.Comment = BuildComment(requestId, user)
+
+	return &ret
+}
+
+/*
+WithComment Return a copy of the context passed that tags every database operation it is passed to with
+a $comment identifying the API request and user. Entity operations pass the context they are called with to
+every database operation, so the comment reaches each of their queries. This takes precedence over the comment
+set with WithRequest
+*/
+func WithComment(ctx context.Context, requestId string, user string) context.Context {
+	return context.WithValue(ctx, commentKey{}, BuildComment(requestId, user))
+}
+
+/*
+comment Return the comment to attach to an operation run with the context passed
+*/
+func (d *Database) comment(ctx context.Context) string {
+	if value, ok := ctx.Value(commentKey{}).(string); ok {
+		return value
+	}
+
+	return d.Comment
+}
+
+/*
+BuildComment Build the comment string attached to Mongo operations. Empty values are omitted
+*/
+func BuildComment(requestId string, user string) string {
+	var parts []string
+
+	if requestId != "" {
+		parts = append(parts, "requestId="+requestId)
+	}
+
+	if user != "" {
+		parts = append(parts, "user="+user)
+	}
+
+	return strings.Join(parts, " ")
+}
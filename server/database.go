@@ -2,48 +2,188 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"strconv"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+const (
+	OperationClassCatalog = "catalog"
+	OperationClassUser    = "user"
+
+	DEFAULT_DATABASE_NAME = "mtgjson"
 )
 
+/*
+catalogCollections The collections that hold imported MTGJSON catalog data. Writes to these collections
+use the write concern configured for OperationClassCatalog, while every other collection uses the write
+concern configured for OperationClassUser
+*/
+var catalogCollections = []string{"card", "set"}
+
 /*
 Database An abstraction of an active mongodb database connection. The same connection is re-used across
-all SDK operations to ensure that we don't exceed the connection pool limit
+all SDK operations to ensure that we don't exceed the connection pool limit. Every operation takes a context
+as its first parameter, allowing callers to enforce per-request timeouts and cancellation. Any operation that
+takes longer than SlowQueryThreshold is logged as a warning with its values redacted. If CollectionPrefix
+is set (e.g. "staging_") it is prepended to every collection name, so that several environments can share a
+single MongoDB database. If Audit is enabled (see EnableAudit) every write is recorded in the audit collection
 */
 type Database struct {
-	Client   *mongo.Client
-	Database *mongo.Database
+	Client             *mongo.Client
+	Database           *mongo.Database
+	WriteConcerns      map[string]*writeconcern.WriteConcern
+	ExplainOptions     ExplainOptions
+	Comment            string
+	Transactions       bool
+	TLSOptions         *TLSOptions
+	RetryPolicy        RetryPolicy
+	PoolOptions        PoolOptions
+	CollectionPrefix   string
+	SoftDelete         bool
+	SlowQueryThreshold time.Duration
+	Audit              bool
+
+	pool *poolCounters
 }
 
 /*
-Connect to the MongoDB instance defined in the Database object
+SetWriteConcern Set the write concern used for all collections within an operation class. The 'w' parameter
+accepts either "majority" or the number of nodes that must acknowledge the write, and journal controls if
+the write must be committed to the on-disk journal before being acknowledged
 */
-func (d *Database) Connect(uri string) {
-	opts := options.Client()
+func (d *Database) SetWriteConcern(class string, w string, journal bool) {
+	if d.WriteConcerns == nil {
+		d.WriteConcerns = map[string]*writeconcern.WriteConcern{}
+	}
+
+	concern := &writeconcern.WriteConcern{Journal: &journal}
+	if w == "majority" {
+		concern.W = w
+	} else {
+		nodes, err := strconv.Atoi(w)
+		if err != nil || nodes < 0 {
+			slog.Error("Invalid write concern, ignoring", "class", class, "w", w)
+			return
+		}
+
+		concern.W = nodes
+	}
+
+	slog.Info("Setting write concern", "class", class, "w", concern.W, "journal", journal)
+	d.WriteConcerns[class] = concern
+}
 
-	opts.ApplyURI(uri)
+/*
+CollectionName Return the name of the collection in MongoDB, with the CollectionPrefix of the Database applied
+*/
+func (d *Database) CollectionName(name string) string {
+	return d.CollectionPrefix + name
+}
+
+/*
+collection Return a handle to the requested collection using the write concern configured for its
+operation class. If no write concern has been configured, the client default is used. The name is
+resolved through CollectionName, so callers always pass the unprefixed name
+*/
+func (d *Database) collection(name string) *mongo.Collection {
+	class := OperationClassUser
+	if slices.Contains(catalogCollections, name) {
+		class = OperationClassCatalog
+	}
+
+	concern, ok := d.WriteConcerns[class]
+	if !ok {
+		return d.Database.Collection(d.CollectionName(name))
+	}
+
+	return d.Database.Collection(d.CollectionName(name), options.Collection().SetWriteConcern(concern))
+}
+
+/*
+NewDatabaseFromURI Create a Database connected with the MongoDB connection string passed in the parameter. Any
+URI supported by the driver is accepted, including mongodb+srv:// URIs (e.g. MongoDB Atlas), multiple seed hosts
+and the replicaSet option. If the URI names a database it is used, otherwise the mtgjson database is used.
+The TLS options are applied on top of any TLS options in the URI, and may be nil. Returns ErrInvalidURI if
+the URI cannot be parsed
+*/
+func NewDatabaseFromURI(uri string, tlsOptions *TLSOptions) (*Database, error) {
+	database := &Database{TLSOptions: tlsOptions}
 
-	slog.Info("Connecting to mongoDB")
+	err := database.connect(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return database, nil
+}
+
+/*
+connect Parse the connection string passed and connect the MongoDB client, applying the TLSOptions of the
+Database if they are enabled
+*/
+func (d *Database) connect(uri string) error {
+	parsed, err := connstring.ParseAndValidate(uri)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidURI, err)
+	}
+
+	name := parsed.Database
+	if name == "" {
+		name = DEFAULT_DATABASE_NAME
+	}
+
+	d.pool = &poolCounters{}
+	opts := options.Client().ApplyURI(uri).SetPoolMonitor(d.pool.monitor())
+	d.PoolOptions.apply(opts)
+	opts.SetMonitor((&slowQueryMonitor{database: d}).monitor())
+
+	tlsConfig, err := d.TLSOptions.Config()
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	slog.Info("Connecting to mongoDB", "hosts", parsed.Hosts, "replicaSet", parsed.ReplicaSet, "database", name, "tls", tlsConfig != nil)
 	client, err := mongo.Connect(context.TODO(), opts)
 	if err != nil {
-		slog.Error("Failed to connect to MongoDB", "uri", uri)
-		panic(1) // panic here as this is a fatal error
+		return fmt.Errorf("server: failed to connect to MongoDB: %w", err)
 	}
 
-	d.Database = client.Database("mtgjson")
+	d.Database = client.Database(name)
 	d.Client = client
+
+	return nil
+}
+
+/*
+Connect to the MongoDB instance defined in the Database object
+*/
+func (d *Database) Connect(uri string) {
+	err := d.connect(uri)
+	if err != nil {
+		slog.Error("Failed to connect to MongoDB", "err", err)
+		panic(1) // panic here as this is a fatal error
+	}
 }
 
 /*
 Disconnect Gracefully disconnect from your active MongoDB connection
 */
 func (d *Database) Disconnect() {
-	d.Health() // this will throw a fatal error when
-
 	slog.Info("Disconnecting from MongoDB")
 	err := d.Client.Disconnect(context.Background())
 	if err != nil {
@@ -53,198 +193,655 @@ func (d *Database) Disconnect() {
 }
 
 /*
-Health Ping the MongoDB database and panic if we don't get a response
+Ping the MongoDB database and return an error if we don't get a response. Use Health for a
+structured description of the deployment
 */
-func (d *Database) Health() {
-	err := d.Client.Ping(context.TODO(), nil)
+func (d *Database) Ping(ctx context.Context) error {
+	err := d.Client.Ping(ctx, nil)
 	if err != nil {
-		slog.Error("Failed to ping MongoDB for health", "err", err.Error())
-		panic(1)
+		slog.Error("Failed to ping MongoDB", "err", err.Error())
+		return fmt.Errorf("server: ping failed: %w", err)
 	}
+
+	return nil
 }
 
 /*
 Find a single document from the MongoDB instance and unmarshal it into the interface
-passed in the 'model' parameter
+passed in the 'model' parameter. If projection fields are passed only those fields are
+returned, see Projection. Returns ErrNotFound if no document matches the query
 */
-func (d *Database) Find(collection string, query bson.M, model interface{}) bool {
-	coll := d.Database.Collection(collection)
+func (d *Database) Find(ctx context.Context, collection string, query bson.M, model interface{}, projection ...string) error {
+	coll := d.collection(collection)
+	query = d.excludeDeleted(collection, query)
 
 	slog.Debug("FindOne Query", "collection", collection, "query", query)
-	err := coll.FindOne(context.TODO(), query).Decode(model)
+	start := time.Now()
+	err := d.retry(ctx, "FindOne", collection, func() error {
+		opts := options.FindOne().SetComment(d.comment(ctx))
+		if len(projection) != 0 {
+			opts.SetProjection(Projection(projection...))
+		}
+
+		return coll.FindOne(ctx, query, opts).Decode(model)
+	})
+	d.explainIfSlow(ctx, collection, query, time.Since(start))
 	if err != nil {
 		slog.Error("Error during FineOne Query", "collection", collection, "query", query, "err", err)
-		return false
+		return wrapError("FindOne", collection, err)
 	}
 
-	return true
+	return nil
 }
 
-func (d *Database) FindMultiple(collection string, key string, value []string, model interface{}) bool {
-	coll := d.Database.Collection(collection)
+/*
+FindMultiple Find all documents where the field passed in the 'key' parameter matches any of the values
+passed, and unmarshal them into the interface passed in the 'model' parameter. If projection fields are
+passed only those fields are returned, see Projection
+*/
+func (d *Database) FindMultiple(ctx context.Context, collection string, key string, value []string, model interface{}, projection ...string) error {
+	coll := d.collection(collection)
 
 	slog.Debug("FindMultiple Query", "collection", collection, "key", key, "value", value)
 	query := bson.M{key: bson.M{"$in": value}}
-	cur, err := coll.Find(context.TODO(), query)
+	query = d.excludeDeleted(collection, query)
+	start := time.Now()
+	opts := options.Find().SetComment(d.comment(ctx))
+	if len(projection) != 0 {
+		opts.SetProjection(Projection(projection...))
+	}
+
+	err := d.retry(ctx, "FindMultiple", collection, func() error {
+		cur, err := coll.Find(ctx, query, opts)
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, model)
+	})
+	d.explainIfSlow(ctx, collection, query, time.Since(start))
 	if err != nil {
 		slog.Error("Error during FindMultiple Query", "collection", collection, "key", key, "value", value, "err", err)
-		return false
+		return wrapError("FindMultiple", collection, err)
+	}
+
+	return nil
+}
+
+/*
+MultipleKeysQuery Build a query that matches documents where any of the keys passed matches any of the values
+*/
+func MultipleKeysQuery(keys []string, values []string) bson.M {
+	if len(keys) == 1 {
+		return bson.M{keys[0]: bson.M{"$in": values}}
+	}
+
+	clauses := bson.A{}
+	for _, key := range keys {
+		clauses = append(clauses, bson.M{key: bson.M{"$in": values}})
+	}
+
+	return bson.M{"$or": clauses}
+}
+
+/*
+FindMultipleKeys Find all documents where any of the fields passed in the 'keys' parameter matches any of the
+values passed, and unmarshal them into the interface passed in the 'model' parameter. This resolves a list of
+mixed identifiers (e.g. a mix of UUID's and names) in a single query. Each key should be indexed, otherwise
+the $or requires a collection scan
+*/
+func (d *Database) FindMultipleKeys(ctx context.Context, collection string, keys []string, values []string, model interface{}, projection ...string) error {
+	return d.FindMany(ctx, collection, MultipleKeysQuery(keys, values), model, projection...)
+}
+
+/*
+FindMany Find all documents matching the query passed in the 'query' parameter and unmarshal them
+into the interface passed in the 'model' parameter. If projection fields are passed only those fields
+are returned, see Projection
+*/
+func (d *Database) FindMany(ctx context.Context, collection string, query bson.M, model interface{}, projection ...string) error {
+	return d.FindManySorted(ctx, collection, query, nil, model, projection...)
+}
+
+/*
+FindManySorted Find all documents matching the query passed in the 'query' parameter, ordered by the
+fields passed in the 'sort' parameter (see SortBy), and unmarshal them into the interface passed in the
+'model' parameter. If projection fields are passed only those fields are returned, see Projection
+*/
+func (d *Database) FindManySorted(ctx context.Context, collection string, query bson.M, sort []string, model interface{}, projection ...string) error {
+	return d.FindManyLimited(ctx, collection, query, sort, 0, model, projection...)
+}
+
+/*
+FindManyLimited Identical to FindManySorted, however at most 'limit' documents are returned. The limit is
+applied by MongoDB after sorting, so the remaining documents are never read. If limit is 0, every matching
+document is returned
+*/
+func (d *Database) FindManyLimited(ctx context.Context, collection string, query bson.M, sort []string, limit int64, model interface{}, projection ...string) error {
+	coll := d.collection(collection)
+	query = d.excludeDeleted(collection, query)
+
+	slog.Debug("FindMany Query", "collection", collection, "query", query, "sort", sort)
+	start := time.Now()
+	opts := options.Find().SetComment(d.comment(ctx))
+	if len(projection) != 0 {
+		opts.SetProjection(Projection(projection...))
+	}
+
+	if len(sort) != 0 {
+		opts.SetSort(SortBy(sort...))
+	}
+
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	err := d.retry(ctx, "FindMany", collection, func() error {
+		cur, err := coll.Find(ctx, query, opts)
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, model)
+	})
+	d.explainIfSlow(ctx, collection, query, time.Since(start))
+	if err != nil {
+		slog.Error("Error during FindMany Query", "collection", collection, "query", query, "err", err)
+		return wrapError("FindMany", collection, err)
+	}
+
+	return nil
+}
+
+/*
+Aggregate Run the aggregation pipeline passed in the 'pipeline' parameter against the collection and
+unmarshal the results into the interface passed in the 'model' parameter. This allows grouping, joins
+with $lookup and statistics to be computed by MongoDB rather than in memory
+*/
+func (d *Database) Aggregate(ctx context.Context, collection string, pipeline mongo.Pipeline, model interface{}) error {
+	coll := d.collection(collection)
+
+	slog.Debug("Aggregate Query", "collection", collection, "pipeline", pipeline)
+	start := time.Now()
+	err := d.retry(ctx, "Aggregate", collection, func() error {
+		cur, err := coll.Aggregate(ctx, pipeline, options.Aggregate().SetComment(d.comment(ctx)))
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, model)
+	})
+	if elapsed := time.Since(start); d.ExplainOptions.Enabled && elapsed >= d.ExplainOptions.Threshold {
+		slog.Warn("Slow aggregation detected", "collection", collection, "pipeline", pipeline, "elapsed", elapsed, "comment", d.comment(ctx))
 	}
 
-	err = cur.All(context.TODO(), model)
 	if err != nil {
-		slog.Error("Error decoding FindMultiple Query", "collection", collection, "key", key, "value", value, "err", err)
-		return false
+		slog.Error("Error during Aggregate Query", "collection", collection, "pipeline", pipeline, "err", err)
+		return wrapError("Aggregate", collection, err)
+	}
+
+	return nil
+}
+
+/*
+Sample Unmarshal up to 'size' documents chosen at random from those matching the query into the model, using
+the $sample stage. A document is never returned twice in the same sample. If projection fields are passed
+only those fields are returned, see Projection
+*/
+func (d *Database) Sample(ctx context.Context, collection string, query bson.M, size int64, model interface{}, projection ...string) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: d.excludeDeleted(collection, query)}},
+		{{Key: "$sample", Value: bson.M{"size": size}}},
+	}
+
+	if len(projection) != 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: Projection(projection...)}})
 	}
 
-	return true
+	return d.Aggregate(ctx, collection, pipeline, model)
 }
 
 /*
-Replace a single document from the MongoDB instance and unmarshal it into the interface
-passed in the 'model' parameter
+Distinct Return the unique values of the field passed in the parameter across the documents matching the
+query. Array fields are unwound, so each element is returned as a separate value
 */
-func (d *Database) Replace(collection string, query bson.M, model interface{}) (*mongo.UpdateResult, bool) {
-	coll := d.Database.Collection(collection)
+func (d *Database) Distinct(ctx context.Context, collection string, field string, query bson.M) ([]interface{}, error) {
+	coll := d.collection(collection)
+
+	if query == nil {
+		query = bson.M{}
+	}
+	query = d.excludeDeleted(collection, query)
+
+	slog.Debug("Distinct Query", "collection", collection, "field", field, "query", query)
+	var values []interface{}
+	err := d.retry(ctx, "Distinct", collection, func() (err error) {
+		values, err = coll.Distinct(ctx, field, query, options.Distinct().SetComment(d.comment(ctx)))
+		return err
+	})
+	if err != nil {
+		slog.Error("Error during Distinct Query", "collection", collection, "field", field, "query", query, "err", err)
+		return nil, wrapError("Distinct", collection, err)
+	}
+
+	return values, nil
+}
+
+/*
+existingValues Return the requested values that are present in the distinct values passed, in the order they
+were requested and without duplicates
+*/
+func existingValues(distinct []interface{}, values []string) []string {
+	found := map[string]bool{}
+	for _, value := range distinct {
+		if str, ok := value.(string); ok {
+			found[str] = true
+		}
+	}
+
+	ret := []string{}
+	for _, value := range values {
+		if found[value] {
+			ret = append(ret, value)
+			delete(found, value)
+		}
+	}
+
+	return ret
+}
+
+/*
+ExistsMany Return the subset of the values passed for which a document exists with the key set to that value.
+Only the distinct values of the key are returned by MongoDB, so this is far cheaper than fetching the matching
+documents when only their existence needs to be checked
+*/
+func (d *Database) ExistsMany(ctx context.Context, collection string, key string, values []string) ([]string, error) {
+	if len(values) == 0 {
+		return []string{}, nil
+	}
+
+	distinct, err := d.Distinct(ctx, collection, key, bson.M{key: bson.M{"$in": values}})
+	if err != nil {
+		return nil, err
+	}
+
+	return existingValues(distinct, values), nil
+}
+
+/*
+Replace a single document from the MongoDB instance with the interface passed in the 'model'
+parameter. Returns ErrNotFound if no document matches the query
+*/
+func (d *Database) Replace(ctx context.Context, collection string, query bson.M, model interface{}) (*mongo.UpdateResult, error) {
+	coll := d.collection(collection)
+	query = d.excludeDeleted(collection, query)
+	before := d.auditSnapshot(ctx, collection, query)
 
 	slog.Debug("ReplaceOne Query", "collection", collection, "query", query)
-	result, err := coll.ReplaceOne(context.TODO(), query, model)
+	var result *mongo.UpdateResult
+	err := d.retry(ctx, "ReplaceOne", collection, func() (err error) {
+		result, err = coll.ReplaceOne(ctx, query, model, options.Replace().SetComment(d.comment(ctx)))
+		return err
+	})
 	if err != nil {
-		return nil, false
+		slog.Error("Error during ReplaceOne Query", "collection", collection, "query", query, "err", err)
+		return nil, wrapError("ReplaceOne", collection, err)
 	}
 
-	return result, true
+	if result.MatchedCount < 1 {
+		return result, wrapError("ReplaceOne", collection, mongo.ErrNoDocuments)
+	}
+
+	d.auditChange(ctx, "ReplaceOne", collection, before, nil)
+
+	return result, nil
 }
 
 /*
-Delete a single document from the MongoDB instance
+Upsert Replace the document matching the query with the interface passed in the 'model' parameter, or
+insert it if no document matches. The UpsertedCount of the result is 1 if a new document was created. If
+SoftDelete is enabled, soft deleted documents are never replaced, and ErrDeleted is returned if the only
+documents matching the query were soft deleted
 */
-func (d *Database) Delete(collection string, query bson.M) (*mongo.DeleteResult, bool) {
-	coll := d.Database.Collection(collection)
+func (d *Database) Upsert(ctx context.Context, collection string, query bson.M, model interface{}) (*mongo.UpdateResult, error) {
+	err := d.rejectDeleted(ctx, "Upsert", collection, query)
+	if err != nil {
+		return nil, err
+	}
+
+	coll := d.collection(collection)
+	query = d.excludeDeleted(collection, query)
+	before := d.auditSnapshot(ctx, collection, query)
+
+	slog.Debug("Upsert Query", "collection", collection, "query", query)
+	var result *mongo.UpdateResult
+	err = d.retry(ctx, "Upsert", collection, func() (err error) {
+		result, err = coll.ReplaceOne(ctx, query, model, options.Replace().SetUpsert(true).SetComment(d.comment(ctx)))
+		return err
+	})
+	if err != nil {
+		slog.Error("Error during Upsert Query", "collection", collection, "query", query, "err", err)
+		return nil, wrapError("Upsert", collection, err)
+	}
+
+	d.auditChange(ctx, "Upsert", collection, before, result.UpsertedID)
+
+	return result, nil
+}
+
+/*
+Delete a single document from the MongoDB instance. Returns ErrNotFound if no document matches
+the query. If SoftDelete is enabled, documents in the card, deck and set collections are marked as
+deleted with SoftDeleteField rather than removed, and are excluded from every other query until they
+are restored or purged
+*/
+func (d *Database) Delete(ctx context.Context, collection string, query bson.M) (*mongo.DeleteResult, error) {
+	if d.softDeletes(collection) {
+		_, err := d.markDeleted(ctx, collection, query)
+		if err != nil {
+			return nil, err
+		}
+
+		return &mongo.DeleteResult{DeletedCount: 1}, nil
+	}
+
+	coll := d.collection(collection)
+	before := d.auditSnapshot(ctx, collection, query)
 
 	slog.Debug("DeleteOne Query", "collection", collection, "query", query)
-	result, err := coll.DeleteOne(context.TODO(), query)
-	if err != nil { // includes ErrNoDocuments
+	var result *mongo.DeleteResult
+	err := d.retry(ctx, "DeleteOne", collection, func() (err error) {
+		result, err = coll.DeleteOne(ctx, query, options.Delete().SetComment(d.comment(ctx)))
+		return err
+	})
+	if err != nil {
 		slog.Error("Error during DeleteOne query", "collection", collection, "query", query, "err", err)
-		return nil, false
+		return nil, wrapError("DeleteOne", collection, err)
 	}
 
 	if result.DeletedCount < 1 {
-		return result, false
+		return result, wrapError("DeleteOne", collection, mongo.ErrNoDocuments)
 	}
 
-	return result, true
+	d.auditWrite(ctx, "DeleteOne", collection, before, nil)
+
+	return result, nil
 }
 
 /*
 Insert the interface represented in the 'model' parameter into the MongoDB
-instance
+instance. Returns ErrDuplicateKey if the document violates a unique index
 */
-func (d *Database) Insert(collection string, model interface{}) (*mongo.InsertOneResult, bool) {
-	coll := d.Database.Collection(collection)
+func (d *Database) Insert(ctx context.Context, collection string, model interface{}) (*mongo.InsertOneResult, error) {
+	coll := d.collection(collection)
 
 	slog.Debug("InsertOne Query", "collection", collection)
-	result, err := coll.InsertOne(context.TODO(), model)
+	result, err := coll.InsertOne(ctx, model, options.InsertOne().SetComment(d.comment(ctx)))
 	if err != nil {
 		slog.Debug("Error during InsertOne Query", "collection", collection, "err", err)
-		return nil, false
+		return nil, wrapError("InsertOne", collection, err)
 	}
 
-	return result, true
+	d.auditInserted(ctx, "InsertOne", collection, []interface{}{model}, []interface{}{result.InsertedID}, nil)
+
+	return result, nil
 }
 
 /*
-Index Return all documents in a collection and unmarshal them into the interface passed
-in the 'model' parameter
+InsertMany Insert every model passed in the 'models' parameter into the MongoDB instance in as few round
+trips as possible. The insert is unordered, so a document that fails to insert does not prevent the rest
+from being written. Returns ErrDuplicateKey if any of the documents violate a unique index
 */
-func (d *Database) Index(collection string, limit int64, model interface{}) bool {
-	opts := options.Find().SetLimit(limit)
-	coll := d.Database.Collection(collection)
+func (d *Database) InsertMany(ctx context.Context, collection string, models []interface{}) (*mongo.InsertManyResult, error) {
+	coll := d.collection(collection)
+
+	slog.Debug("InsertMany Query", "collection", collection, "count", len(models))
+	result, err := coll.InsertMany(ctx, models, options.InsertMany().SetOrdered(false).SetComment(d.comment(ctx)))
+	if result != nil {
+		d.auditInserted(ctx, "InsertMany", collection, models, result.InsertedIDs, err)
+	}
 
-	slog.Debug("Index Collection Query", "collection", collection)
-	cur, err := coll.Find(context.TODO(), bson.M{}, opts)
 	if err != nil {
-		slog.Error("Error during Indexing Collection", "collection", collection, "limit", limit, "err", err)
-		return false
+		slog.Error("Error during InsertMany Query", "collection", collection, "count", len(models), "err", err)
+		return result, wrapError("InsertMany", collection, err)
 	}
 
-	err = cur.All(context.TODO(), model)
-	if err != nil { // includes ErrNoDocuments
-		slog.Error("Error during Marshaling index results", "collection", collection, "limit", limit, "err", err)
-		return false
+	return result, nil
+}
+
+/*
+UpsertMany Write many documents to a collection in a single bulk write, replacing any existing document with
+the same _id and inserting the rest. Each document passed must have an _id. Used for bulk ingestion where
+documents are keyed by a deterministic _id, so that re-running an ingestion overwrites rather than duplicates
+*/
+func (d *Database) UpsertMany(ctx context.Context, collection string, models []interface{}) (*mongo.BulkWriteResult, error) {
+	coll := d.collection(collection)
+
+	writes := make([]mongo.WriteModel, 0, len(models))
+	for _, model := range models {
+		document, err := bson.Marshal(model)
+		if err != nil {
+			return nil, wrapError("UpsertMany", collection, err)
+		}
+
+		id, err := bson.Raw(document).LookupErr("_id")
+		if err != nil {
+			return nil, wrapError("UpsertMany", collection, err)
+		}
+
+		writes = append(writes, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": id}).SetReplacement(bson.Raw(document)).SetUpsert(true))
 	}
 
-	return true
+	if len(writes) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+
+	slog.Debug("UpsertMany Query", "collection", collection, "count", len(models))
+	var result *mongo.BulkWriteResult
+	err := d.retry(ctx, "UpsertMany", collection, func() (err error) {
+		result, err = coll.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false).SetComment(d.comment(ctx)))
+		return err
+	})
+	if err != nil {
+		slog.Error("Error during UpsertMany Query", "collection", collection, "count", len(models), "err", err)
+		return result, wrapError("UpsertMany", collection, err)
+	}
+
+	return result, nil
 }
 
 /*
-SetField Update a single field in a requested document in the Mongo Database
+Index Return all documents in a collection and unmarshal them into the interface passed
+in the 'model' parameter. If sort fields are passed the documents are ordered by them before
+the limit is applied, see SortBy
 */
-func (d *Database) SetField(collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, bool) {
-	coll := d.Database.Collection(collection)
+func (d *Database) Index(ctx context.Context, collection string, limit int64, model interface{}, sort ...string) error {
+	opts := options.Find().SetLimit(limit).SetComment(d.comment(ctx))
+	if len(sort) != 0 {
+		opts.SetSort(SortBy(sort...))
+	}
+
+	coll := d.collection(collection)
+
+	slog.Debug("Index Collection Query", "collection", collection, "sort", sort)
+	err := d.retry(ctx, "Index", collection, func() error {
+		cur, err := coll.Find(ctx, d.excludeDeleted(collection, bson.M{}), opts)
+		if err != nil {
+			return err
+		}
 
-	slog.Debug("SetField Query", "collection", collection, "query", query, "fields", fields)
-	results, err := coll.UpdateOne(context.TODO(), query, bson.M{"$set": fields})
+		return cur.All(ctx, model)
+	})
 	if err != nil {
-		slog.Error("Error during SetField Operation", "collection", collection, "query", query, "fields", fields, "err", err)
-		return nil, false
+		slog.Error("Error during Indexing Collection", "collection", collection, "limit", limit, "err", err)
+		return wrapError("Index", collection, err)
 	}
 
-	return results, true
+	return nil
 }
 
 /*
-AppendField Append an item to a field in a single document in the Mongo Database
+UpdateMany Apply the update document passed in the 'update' parameter to every document matching the query.
+Unlike the other update operations the update may combine several operators, such as $set and $rename. This
+is intended for bulk maintenance like migrations, so soft deleted documents are updated as well
 */
-func (d *Database) AppendField(collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, bool) {
-	coll := d.Database.Collection(collection)
+func (d *Database) UpdateMany(ctx context.Context, collection string, query bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	coll := d.collection(collection)
 
-	slog.Debug("AppendField Query", "collection", collection, "query", query, "fields", fields)
-	results, err := coll.UpdateOne(context.TODO(), query, bson.M{"$push": fields})
+	slog.Debug("UpdateMany Query", "collection", collection, "query", query, "update", update)
+	result, err := coll.UpdateMany(ctx, query, update, options.Update().SetComment(d.comment(ctx)))
 	if err != nil {
-		slog.Error("Error during AppendField Operation", "collection", collection, "query", query, "fields", fields, "err", err)
-		return nil, false
+		slog.Error("Error during UpdateMany Query", "collection", collection, "query", query, "err", err)
+		return nil, wrapError("UpdateMany", collection, err)
 	}
 
-	return results, true
+	return result, nil
 }
 
 /*
-PullField Remove all instances of an object from an array in a single document
+update Apply an update operator to a single document in the Mongo Database
+*/
+func (d *Database) update(ctx context.Context, name string, operator string, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return d.updateOne(ctx, name, collection, query, bson.M{operator: fields})
+}
+
+/*
+updateOne Apply the update document passed to a single document in the Mongo Database. Updates made up of
+only the idempotent $set and $pull operators are retried on transient errors
 */
-func (d *Database) PullField(collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, bool) {
-	coll := d.Database.Collection(collection)
+func (d *Database) updateOne(ctx context.Context, name string, collection string, query bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	coll := d.collection(collection)
+	query = d.excludeDeleted(collection, query)
+	before := d.auditSnapshot(ctx, collection, query)
+
+	slog.Debug(name+" Query", "collection", collection, "query", query, "update", update)
+	var results *mongo.UpdateResult
+	apply := func() (err error) {
+		results, err = coll.UpdateOne(ctx, query, update, options.Update().SetComment(d.comment(ctx)))
+		return err
+	}
+
+	idempotent := true
+	for operator := range update {
+		if operator != "$set" && operator != "$pull" {
+			idempotent = false
+		}
+	}
+
+	var err error
+	if idempotent {
+		err = d.retry(ctx, name, collection, apply)
+	} else {
+		err = apply()
+	}
 
-	slog.Debug("PullField Query", "collection", collection, "query", query, "fields", fields)
-	results, err := coll.UpdateOne(context.TODO(), query, bson.M{"$pull": fields})
 	if err != nil {
-		slog.Error("Error during PullField Operation", "collection", collection, "query", query, "fields", fields, "err", err)
-		return nil, false
+		slog.Error("Error during "+name+" Operation", "collection", collection, "query", query, "update", update, "err", err)
+		return nil, wrapError(name, collection, err)
+	}
+
+	if results.ModifiedCount > 0 {
+		d.auditChange(ctx, name, collection, before, nil)
 	}
 
-	return results, true
+	return results, nil
+}
+
+/*
+Update Apply the update document passed in the 'update' parameter to the first document matching the query.
+Unlike SetField and the other single operator updates, the update may combine several operators (e.g. a $push
+along with a $set), which are applied atomically
+*/
+func (d *Database) Update(ctx context.Context, collection string, query bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	return d.updateOne(ctx, "Update", collection, query, update)
+}
+
+/*
+SetField Update a single field in a requested document in the Mongo Database
+*/
+func (d *Database) SetField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return d.update(ctx, "SetField", "$set", collection, query, fields)
+}
+
+/*
+AppendField Append an item to a field in a single document in the Mongo Database
+*/
+func (d *Database) AppendField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return d.update(ctx, "AppendField", "$push", collection, query, fields)
+}
+
+/*
+PullField Remove all instances of an object from an array in a single document
+*/
+func (d *Database) PullField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return d.update(ctx, "PullField", "$pull", collection, query, fields)
 }
 
 /*
 IncrementField Increment a single field in a document
 */
-func (d *Database) IncrementField(collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, bool) {
-	coll := d.Database.Collection(collection)
+func (d *Database) IncrementField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return d.update(ctx, "IncrementField", "$inc", collection, query, fields)
+}
 
-	slog.Debug("IncrementField Query", "collection", collection, "query", query, "fields", fields)
-	results, err := coll.UpdateOne(context.TODO(), query, bson.M{"$inc": fields})
-	if err != nil {
-		slog.Error("Error during IncrementField Operation", "collection", collection, "query", query, "fields", fields, "err", err)
-		return nil, false
+/*
+sequenceValue Returns the integer stored in the top level field of the document passed, as returned by NextSequence
+*/
+func sequenceValue(document bson.M, field string) (int64, error) {
+	switch value := document[field].(type) {
+	case int32:
+		return int64(value), nil
+	case int64:
+		return value, nil
+	case float64:
+		return int64(value), nil
 	}
 
-	return results, true
+	return 0, fmt.Errorf("server: the %s field of the sequence is not a number", field)
+}
+
+/*
+NextSequence Atomically increment the top level field passed in the first document matching the query, and return
+its new value. If no document matches, one is created from the equality fields of the query, so a sequence starts
+at 1. This is intended for counters that number documents, as two callers never receive the same value. The
+increment is not idempotent, so it is not retried on transient errors
+*/
+func (d *Database) NextSequence(ctx context.Context, collection string, query bson.M, field string) (int64, error) {
+	coll := d.collection(collection)
+
+	slog.Debug("NextSequence Query", "collection", collection, "query", query, "field", field)
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{field: 1}).
+		SetComment(d.comment(ctx))
+
+	var result bson.M
+	err := coll.FindOneAndUpdate(ctx, query, bson.M{"$inc": bson.M{field: int64(1)}}, opts).Decode(&result)
+	if err != nil {
+		slog.Error("Error during NextSequence Operation", "collection", collection, "query", query, "field", field, "err", err)
+		return 0, wrapError("NextSequence", collection, err)
+	}
 
+	return sequenceValue(result, field)
 }
 
 /*
 BuildDatabaseURI Build a MongoDB connection URI using the values that are stored within our database object
 */
 func BuildDatabaseURI(ipAddress string, port int, username string, password string) string {
-	return "mongodb://" + username + ":" + password + "@" + ipAddress + ":" + strconv.Itoa(port)
+	return "mongodb://" + url.UserPassword(username, password).String() + "@" + ipAddress + ":" + strconv.Itoa(port)
+}
+
+/*
+BuildReplicaSetURI Build a MongoDB connection URI for a replica set using a list of seed hosts in host:port
+form. The replicaSet option is only added if a replica set name is passed
+*/
+func BuildReplicaSetURI(hosts []string, replicaSet string, username string, password string) string {
+	uri := "mongodb://" + url.UserPassword(username, password).String() + "@" + strings.Join(hosts, ",") + "/"
+	if replicaSet != "" {
+		uri += "?replicaSet=" + url.QueryEscape(replicaSet)
+	}
+
+	return uri
 }
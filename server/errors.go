@@ -0,0 +1,30 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrNotFound = errors.New("server: Failed to find a document matching the query")
+var ErrInvalidURI = errors.New("server: Operation failed. The MongoDB connection URI is not valid")
+var ErrDuplicateKey = errors.New("server: Operation failed. A document with the same unique key already exists")
+
+/*
+wrapError Wrap an error returned by the MongoDB driver with the operation and collection it occurred
+in. Missing documents are reported as ErrNotFound and unique index violations as ErrDuplicateKey, so
+callers can distinguish them from connection failures using errors.Is. The driver error is always
+kept in the chain
+*/
+func wrapError(operation string, collection string, err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("server: %s on %s: %w", operation, collection, ErrNotFound)
+	}
+
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("server: %s on %s: %w: %w", operation, collection, ErrDuplicateKey, err)
+	}
+
+	return fmt.Errorf("server: %s on %s failed: %w", operation, collection, err)
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+ExplainOptions Controls the sampling of query plans for slow queries. When enabled, any find operation
+that takes longer than the threshold has a chance (determined by the sample rate) of being explained,
+with the resulting plan written to the logs
+*/
+type ExplainOptions struct {
+	Enabled    bool
+	Threshold  time.Duration
+	SampleRate float64
+}
+
+/*
+ExplainResult A summarized view of the output of the MongoDB explain command. IndexesUsed will be empty
+if the winning plan performed a collection scan
+*/
+type ExplainResult struct {
+	Collection        string   `json:"collection"`
+	WinningStage      string   `json:"winningStage"`
+	IndexesUsed       []string `json:"indexesUsed"`
+	CollectionScan    bool     `json:"collectionScan"`
+	DocumentsReturned int64    `json:"documentsReturned"`
+	DocumentsExamined int64    `json:"documentsExamined"`
+	KeysExamined      int64    `json:"keysExamined"`
+	ExecutionTimeMS   int64    `json:"executionTimeMillis"`
+	Raw               bson.M   `json:"-"`
+}
+
+/*
+walkPlan Recursively walk a query plan stage, collecting the names of any indexes used and noting
+if any stage performed a collection scan
+*/
+func walkPlan(stage bson.M, result *ExplainResult) {
+	if stage == nil {
+		return
+	}
+
+	if name, ok := stage["stage"].(string); ok && name == "COLLSCAN" {
+		result.CollectionScan = true
+	}
+
+	if index, ok := stage["indexName"].(string); ok {
+		result.IndexesUsed = append(result.IndexesUsed, index)
+	}
+
+	if child, ok := stage["inputStage"].(bson.M); ok {
+		walkPlan(child, result)
+	}
+
+	if children, ok := stage["inputStages"].(bson.A); ok {
+		for _, child := range children {
+			if childStage, ok := child.(bson.M); ok {
+				walkPlan(childStage, result)
+			}
+		}
+	}
+}
+
+/*
+toInt64 Convert a numeric value returned from the explain command into an int64. Mongo may return
+these values as either int32, int64 or double depending on the server version
+*/
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+
+	return 0
+}
+
+/*
+Explain Run the explain command for a find query against the requested collection and log the resulting
+plan. The returned result summarizes the winning plan, the indexes it used, and its execution statistics
+*/
+func (d *Database) Explain(ctx context.Context, collection string, query bson.M) (*ExplainResult, error) {
+	var raw bson.M
+
+	command := bson.D{
+		{Key: "explain", Value: bson.D{{Key: "find", Value: d.CollectionName(collection)}, {Key: "filter", Value: query}}},
+		{Key: "verbosity", Value: "executionStats"},
+	}
+
+	err := d.Database.RunCommand(ctx, command).Decode(&raw)
+	if err != nil {
+		slog.Error("Error during Explain command", "collection", collection, "query", query, "err", err)
+		return nil, wrapError("Explain", collection, err)
+	}
+
+	result := &ExplainResult{Collection: collection, IndexesUsed: []string{}, Raw: raw}
+
+	if planner, ok := raw["queryPlanner"].(bson.M); ok {
+		if winningPlan, ok := planner["winningPlan"].(bson.M); ok {
+			result.WinningStage, _ = winningPlan["stage"].(string)
+			walkPlan(winningPlan, result)
+		}
+	}
+
+	if stats, ok := raw["executionStats"].(bson.M); ok {
+		result.DocumentsReturned = toInt64(stats["nReturned"])
+		result.DocumentsExamined = toInt64(stats["totalDocsExamined"])
+		result.KeysExamined = toInt64(stats["totalKeysExamined"])
+		result.ExecutionTimeMS = toInt64(stats["executionTimeMillis"])
+	}
+
+	slog.Info("Explain Query",
+		"collection", collection,
+		"query", query,
+		"winningStage", result.WinningStage,
+		"indexesUsed", result.IndexesUsed,
+		"collectionScan", result.CollectionScan,
+		"nReturned", result.DocumentsReturned,
+		"docsExamined", result.DocumentsExamined,
+		"keysExamined", result.KeysExamined,
+		"executionTimeMillis", result.ExecutionTimeMS)
+
+	return result, nil
+}
+
+/*
+explainIfSlow Sample the query plan of a find operation if it took longer than the configured explain
+threshold. The explain command is run in the background so that it does not add latency to the
+original operation
+*/
+func (d *Database) explainIfSlow(ctx context.Context, collection string, query bson.M, elapsed time.Duration) {
+	if !d.ExplainOptions.Enabled || elapsed < d.ExplainOptions.Threshold {
+		return
+	}
+
+	if rand.Float64() >= d.ExplainOptions.SampleRate {
+		return
+	}
+
+	slog.Warn("Slow query detected, sampling query plan", "collection", collection, "query", RedactQuery(query), "elapsed", elapsed, "comment", d.comment(ctx))
+	go d.Explain(context.Background(), collection, query)
+}
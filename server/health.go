@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	TopologyStandalone = "standalone"
+	TopologyReplicaSet = "replicaSet"
+	TopologySharded    = "sharded"
+)
+
+/*
+TopologyInfo Describes the MongoDB deployment the client is connected to, as reported by the hello command
+*/
+type TopologyInfo struct {
+	Kind              string   `json:"kind"`
+	ReplicaSet        string   `json:"replicaSet,omitempty"`
+	Primary           string   `json:"primary,omitempty"`
+	Hosts             []string `json:"hosts,omitempty"`
+	IsWritablePrimary bool     `json:"isWritablePrimary"`
+}
+
+/*
+HealthStatus The structured result of Health. Healthy is false if the database could not be reached, in
+which case Error holds the reason and only Latency and Pool are populated
+*/
+type HealthStatus struct {
+	Healthy       bool          `json:"healthy"`
+	Latency       time.Duration `json:"latency"`
+	ServerVersion string        `json:"serverVersion,omitempty"`
+	Topology      *TopologyInfo `json:"topology,omitempty"`
+	Pool          PoolStats     `json:"pool"`
+	Error         string        `json:"error,omitempty"`
+	CheckedAt     time.Time     `json:"checkedAt"`
+}
+
+/*
+Health Ping the MongoDB database and gather its server version, topology and the connection pool statistics
+of the client. This is intended to back a /health endpoint, and unlike Ping it describes the deployment as
+well as whether it is reachable. The error is also returned if the database cannot be pinged
+*/
+func (d *Database) Health(ctx context.Context) (*HealthStatus, error) {
+	ret := &HealthStatus{CheckedAt: time.Now().UTC(), Pool: d.PoolStats()}
+
+	start := time.Now()
+	err := d.Client.Ping(ctx, nil)
+	ret.Latency = time.Since(start)
+	if err != nil {
+		slog.Error("Failed to ping MongoDB for health", "err", err)
+		ret.Error = err.Error()
+		return ret, fmt.Errorf("server: ping failed: %w", err)
+	}
+
+	ret.Healthy = true
+
+	var hello struct {
+		SetName           string   `bson:"setName"`
+		Primary           string   `bson:"primary"`
+		Hosts             []string `bson:"hosts"`
+		Msg               string   `bson:"msg"`
+		IsWritablePrimary bool     `bson:"isWritablePrimary"`
+	}
+
+	err = d.Database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err == nil {
+		topology := &TopologyInfo{
+			Kind:              TopologyStandalone,
+			ReplicaSet:        hello.SetName,
+			Primary:           hello.Primary,
+			Hosts:             hello.Hosts,
+			IsWritablePrimary: hello.IsWritablePrimary,
+		}
+
+		if hello.Msg == "isdbgrid" {
+			topology.Kind = TopologySharded
+		} else if hello.SetName != "" {
+			topology.Kind = TopologyReplicaSet
+		}
+
+		ret.Topology = topology
+	}
+
+	var buildInfo struct {
+		Version string `bson:"version"`
+	}
+
+	err = d.Database.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo)
+	if err == nil {
+		ret.ServerVersion = buildInfo.Version
+	}
+
+	return ret, nil
+}
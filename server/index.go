@@ -0,0 +1,315 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+namespaceNotFoundCode The error code returned by MongoDB when listing the indexes of a collection that
+does not exist
+*/
+const namespaceNotFoundCode = 26
+
+/*
+RequiredIndexes The indexes the SDK relies on for its lookups, keyed by collection name. Each entry is
+the leading key of an index that must exist on the collection
+*/
+var RequiredIndexes = map[string][]string{
+	"card": {
+		"identifiers.mtgjsonV4Id",
+		"identifiers.scryfallId",
+		"identifiers.scryfallOracleId",
+		"identifiers.multiverseId",
+		"identifiers.mtgoId",
+		"identifiers.mtgArenaId",
+		"identifiers.tcgplayerProductId",
+		"name",
+		"setCode",
+	},
+	"card_atomic":   {"name"},
+	"card_revision": {"cardId"},
+	"card_token":    {"identifiers.mtgjsonV4Id", "relatedCards.reverseRelated"},
+	"deck":          {"code", "mtgjsonApiMeta.owner", "shareId"},
+	"price":         {"cardId"},
+	"price_history": {"cardId"},
+	"set":           {"code"},
+	"slug":          {"slug"},
+	"user":          {"email"},
+}
+
+/*
+UniqueIndexes The compound indexes that reject duplicate documents, keyed by collection name. Each entry lists
+the keys of a single index in order, and a write that would store two documents with the same values for every
+key fails
+*/
+var UniqueIndexes = map[string][][]string{
+	"card_revision":         {{"cardId", "owner", "revision"}},
+	"card_revision_counter": {{"cardId", "owner"}},
+	"deck":                  {{"mtgjsonApiMeta.owner", "code"}},
+}
+
+/*
+UniqueIndexName Returns the name that the unique index with the keys passed is built under
+*/
+func UniqueIndexName(keys []string) string {
+	return strings.Join(keys, "_") + "_unique"
+}
+
+/*
+DropIndex Remove the index with the name passed from the collection. This is intended for migrations that replace
+an index with a different set of keys. Returns false if the collection has no index with that name
+*/
+func (d *Database) DropIndex(ctx context.Context, collection string, name string) (bool, error) {
+	exists, err := d.HasIndexNamed(ctx, collection, name)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	coll := d.collection(collection)
+
+	_, err = coll.Indexes().DropOne(ctx, name)
+	if err != nil {
+		slog.Error("Error dropping index", "collection", collection, "name", name, "err", err)
+		return false, wrapError("DropIndex", collection, err)
+	}
+
+	slog.Info("Dropped index", "collection", collection, "name", name)
+
+	return true, nil
+}
+
+/*
+listIndexes Returns the specification of every index on the collection passed. A collection that does not exist
+yet has no indexes
+*/
+func (d *Database) listIndexes(ctx context.Context, collection string) ([]*mongo.IndexSpecification, error) {
+	coll := d.collection(collection)
+
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == namespaceNotFoundCode {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.Error("Error listing indexes", "collection", collection, "err", err)
+		return nil, wrapError("ListIndexes", collection, err)
+	}
+
+	return specs, nil
+}
+
+/*
+HasIndexNamed Returns true if the collection has an index with the name passed in the parameter, false otherwise
+*/
+func (d *Database) HasIndexNamed(ctx context.Context, collection string, name string) (bool, error) {
+	specs, err := d.listIndexes(ctx, collection)
+	if err != nil {
+		return false, err
+	}
+
+	for _, spec := range specs {
+		if spec.Name == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+/*
+HasIndex Returns true if the collection has an index whose leading key matches the key passed in
+the parameter, false otherwise. A collection that does not exist yet has no indexes
+*/
+func (d *Database) HasIndex(ctx context.Context, collection string, key string) (bool, error) {
+	specs, err := d.listIndexes(ctx, collection)
+	if err != nil {
+		return false, err
+	}
+
+	for _, spec := range specs {
+		elements, err := spec.KeysDocument.Elements()
+		if err != nil || len(elements) == 0 {
+			continue
+		}
+
+		if elements[0].Key() == key {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+/*
+MissingIndexes Return the required indexes that do not exist in the database, keyed by collection
+name. Missing unique indexes are listed by their UniqueIndexName. An empty map is returned if every
+required index exists
+*/
+func (d *Database) MissingIndexes(ctx context.Context) (map[string][]string, error) {
+	ret := map[string][]string{}
+
+	for collection, keys := range RequiredIndexes {
+		for _, key := range keys {
+			exists, err := d.HasIndex(ctx, collection, key)
+			if err != nil {
+				return nil, err
+			}
+
+			if !exists {
+				ret[collection] = append(ret[collection], key)
+			}
+		}
+	}
+
+	for collection, indexes := range UniqueIndexes {
+		for _, keys := range indexes {
+			name := UniqueIndexName(keys)
+
+			exists, err := d.HasIndexNamed(ctx, collection, name)
+			if err != nil {
+				return nil, err
+			}
+
+			if !exists {
+				ret[collection] = append(ret[collection], name)
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+SearchIndexes The fields included in the text index of each collection, keyed by collection name. MongoDB
+only allows a single text index per collection, so all fields are combined into one index
+*/
+var SearchIndexes = map[string][]string{
+	"card": {"name"},
+	"deck": {"name"},
+	"set":  {"name"},
+}
+
+/*
+textIndexKey The leading key of every text index, as reported by listIndexes
+*/
+const textIndexKey = "_fts"
+
+/*
+EnsureIndexes Create every index in RequiredIndexes, UniqueIndexes and SearchIndexes, along with the TTL index
+of every collection in EphemeralCollections, that does not exist yet, and return the indexes that were built in
+collection.key form. Indexes that already exist are left untouched, so this is safe to call on every startup.
+Building a unique index fails if the collection already holds duplicate documents
+*/
+func (d *Database) EnsureIndexes(ctx context.Context) ([]string, error) {
+	ret := []string{}
+
+	for collection, keys := range RequiredIndexes {
+		for _, key := range keys {
+			exists, err := d.HasIndex(ctx, collection, key)
+			if err != nil {
+				return ret, err
+			}
+
+			if exists {
+				continue
+			}
+
+			model := mongo.IndexModel{Keys: bson.D{{Key: key, Value: 1}}}
+			err = d.createIndex(ctx, collection, model)
+			if err != nil {
+				return ret, err
+			}
+
+			ret = append(ret, collection+"."+key)
+		}
+	}
+
+	for collection, indexes := range UniqueIndexes {
+		for _, keys := range indexes {
+			name := UniqueIndexName(keys)
+
+			exists, err := d.HasIndexNamed(ctx, collection, name)
+			if err != nil {
+				return ret, err
+			}
+
+			if exists {
+				continue
+			}
+
+			document := bson.D{}
+			for _, key := range keys {
+				document = append(document, bson.E{Key: key, Value: 1})
+			}
+
+			model := mongo.IndexModel{Keys: document, Options: options.Index().SetName(name).SetUnique(true)}
+			err = d.createIndex(ctx, collection, model)
+			if err != nil {
+				return ret, err
+			}
+
+			ret = append(ret, collection+"."+name)
+		}
+	}
+
+	for collection, fields := range SearchIndexes {
+		exists, err := d.HasIndex(ctx, collection, textIndexKey)
+		if err != nil {
+			return ret, err
+		}
+
+		if exists {
+			continue
+		}
+
+		keys := bson.D{}
+		for _, field := range fields {
+			keys = append(keys, bson.E{Key: field, Value: "text"})
+		}
+
+		err = d.createIndex(ctx, collection, mongo.IndexModel{Keys: keys})
+		if err != nil {
+			return ret, err
+		}
+
+		ret = append(ret, collection+"."+textIndexKey)
+	}
+
+	for collection, ttl := range EphemeralCollections {
+		built, err := d.EnsureTTLIndex(ctx, collection)
+		if err != nil {
+			return ret, err
+		}
+
+		if built {
+			ret = append(ret, collection+"."+ttl.Field)
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+createIndex Build a single index on the collection passed
+*/
+func (d *Database) createIndex(ctx context.Context, collection string, model mongo.IndexModel) error {
+	coll := d.collection(collection)
+
+	name, err := coll.Indexes().CreateOne(ctx, model)
+	if err != nil {
+		slog.Error("Error creating index", "collection", collection, "keys", model.Keys, "err", err)
+		return wrapError("CreateIndex", collection, err)
+	}
+
+	slog.Info("Built index", "collection", collection, "name", name)
+
+	return nil
+}
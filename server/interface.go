@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+DatabaseInterface The document operations used by the entity packages (card, deck, set, user, etc). It is
+implemented by Database, which is backed by MongoDB, and by MemoryDatabase, which keeps every collection in
+memory so that consumers of the SDK can unit test their logic without a running MongoDB instance. Operations
+that only make sense against a real deployment, such as Watch, Health and AcquireLock, are only available on
+Database
+*/
+type DatabaseInterface interface {
+	Find(ctx context.Context, collection string, query bson.M, model interface{}, projection ...string) error
+	FindMultiple(ctx context.Context, collection string, key string, value []string, model interface{}, projection ...string) error
+	FindMultipleKeys(ctx context.Context, collection string, keys []string, values []string, model interface{}, projection ...string) error
+	FindMany(ctx context.Context, collection string, query bson.M, model interface{}, projection ...string) error
+	FindManySorted(ctx context.Context, collection string, query bson.M, sort []string, model interface{}, projection ...string) error
+	FindManyLimited(ctx context.Context, collection string, query bson.M, sort []string, limit int64, model interface{}, projection ...string) error
+	FindStream(ctx context.Context, collection string, query bson.M, projection ...string) iter.Seq2[bson.Raw, error]
+	Sample(ctx context.Context, collection string, query bson.M, size int64, model interface{}, projection ...string) error
+	Distinct(ctx context.Context, collection string, field string, query bson.M) ([]interface{}, error)
+	ExistsMany(ctx context.Context, collection string, key string, values []string) ([]string, error)
+	Index(ctx context.Context, collection string, limit int64, model interface{}, sort ...string) error
+	Paginate(ctx context.Context, collection string, query bson.M, opts *PageOptions, model interface{}) (*Page, error)
+	Count(ctx context.Context, collection string, query bson.M) (int64, error)
+	EstimatedCount(ctx context.Context, collection string) (int64, error)
+
+	Insert(ctx context.Context, collection string, model interface{}) (*mongo.InsertOneResult, error)
+	InsertMany(ctx context.Context, collection string, models []interface{}) (*mongo.InsertManyResult, error)
+	Replace(ctx context.Context, collection string, query bson.M, model interface{}) (*mongo.UpdateResult, error)
+	Upsert(ctx context.Context, collection string, query bson.M, model interface{}) (*mongo.UpdateResult, error)
+	UpsertMany(ctx context.Context, collection string, models []interface{}) (*mongo.BulkWriteResult, error)
+	Delete(ctx context.Context, collection string, query bson.M) (*mongo.DeleteResult, error)
+	Update(ctx context.Context, collection string, query bson.M, update bson.M) (*mongo.UpdateResult, error)
+	SetField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error)
+	AppendField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error)
+	PullField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error)
+	IncrementField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error)
+	NextSequence(ctx context.Context, collection string, query bson.M, field string) (int64, error)
+
+	Restore(ctx context.Context, collection string, query bson.M) (*mongo.UpdateResult, error)
+	PurgeDeleted(ctx context.Context, collection string, before time.Time) (int64, error)
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	Ping(ctx context.Context) error
+}
+
+var (
+	_ DatabaseInterface = (*Database)(nil)
+	_ DatabaseInterface = (*MemoryDatabase)(nil)
+)
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrLockHeld = errors.New("server: Operation failed. The lock is held by another process")
+var ErrLockLost = errors.New("server: Operation failed. The lock is no longer held by this process")
+
+/*
+Lock A lease on a named lock stored in the lock collection. Lock documents are unique by name, so only
+one process can hold a lock at a time. A lock that is not renewed before it expires can be taken over
+by another process, so a crashed holder never blocks background work permanently
+*/
+type Lock struct {
+	Name      string    `bson:"_id" json:"name"`
+	Holder    string    `bson:"holder" json:"holder"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+
+	database *Database
+}
+
+/*
+newHolderId Return an id that is unique to this process and acquisition, used to ensure that a process
+only renews or releases a lock that it holds
+*/
+func newHolderId() string {
+	hostname, _ := os.Hostname()
+
+	return fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
+
+/*
+AcquireLock Take the named lock for the duration passed in the ttl parameter. An expired lock is removed
+before the lock is taken. Returns ErrLockHeld if another process holds an unexpired lease on the lock
+*/
+func (d *Database) AcquireLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	d.Delete(ctx, "lock", bson.M{"_id": name, "expiresAt": bson.M{"$lt": time.Now()}})
+
+	lock := &Lock{Name: name, Holder: newHolderId(), ExpiresAt: time.Now().Add(ttl), database: d}
+
+	_, err := d.Insert(ctx, "lock", lock)
+	if errors.Is(err, ErrDuplicateKey) {
+		return nil, ErrLockHeld
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+/*
+Renew Extend the lease on the lock by the duration passed in the ttl parameter. Returns ErrLockLost if
+the lock expired and was taken by another process
+*/
+func (l *Lock) Renew(ctx context.Context, ttl time.Duration) error {
+	expiresAt := time.Now().Add(ttl)
+
+	result, err := l.database.SetField(ctx, "lock", bson.M{"_id": l.Name, "holder": l.Holder}, bson.M{"expiresAt": expiresAt})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount != 1 {
+		return ErrLockLost
+	}
+
+	l.ExpiresAt = expiresAt
+
+	return nil
+}
+
+/*
+Release Remove the lock so that it can be taken immediately by another process. Returns ErrLockLost if
+the lock is no longer held by this process
+*/
+func (l *Lock) Release(ctx context.Context) error {
+	_, err := l.database.Delete(ctx, "lock", bson.M{"_id": l.Name, "holder": l.Holder})
+	if errors.Is(err, ErrNotFound) {
+		return ErrLockLost
+	}
+
+	return err
+}
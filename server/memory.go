@@ -0,0 +1,1443 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrUnsupportedQuery = errors.New("server: Operation failed. The query or update uses an operator that is not supported by the in-memory database")
+
+/*
+MemoryDatabase An in-memory implementation of DatabaseInterface intended for unit tests. Documents are stored
+as BSON maps, so models round trip exactly as they would through MongoDB. Queries support equality on dotted
+keys (matching array elements), the comparison operators, $in, $nin, $exists, $regex, $all, $size,
+$elemMatch, $not, $and, $or and $nor. Updates support $set, $unset, $inc, $push, $addToSet and $pull. Only
+_id is treated as a unique key, and WithTransaction does not roll back changes if the function fails. SoftDelete
+behaves as it does on Database
+*/
+type MemoryDatabase struct {
+	SoftDelete bool
+
+	mu          sync.RWMutex
+	collections map[string][]bson.M
+}
+
+/*
+softDeletes Returns true if documents in the collection passed are soft deleted, see Database.softDeletes
+*/
+func (m *MemoryDatabase) softDeletes(collection string) bool {
+	return m.SoftDelete && slices.Contains(softDeleteCollections, collection)
+}
+
+/*
+NewMemoryDatabase Create an empty MemoryDatabase
+*/
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{collections: map[string][]bson.M{}}
+}
+
+/*
+toDocument Convert a model, or a query, into a BSON map using the same encoding as the MongoDB driver
+*/
+func toDocument(model interface{}) (bson.M, error) {
+	ret := bson.M{}
+	if model == nil {
+		return ret, nil
+	}
+
+	raw, err := bson.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+
+	err = bson.Unmarshal(raw, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+/*
+decodeDocument Unmarshal a stored document into the model passed
+*/
+func decodeDocument(document bson.M, model interface{}) error {
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(raw, model)
+}
+
+/*
+decodeDocuments Unmarshal a list of stored documents into the slice pointed to by the model passed
+*/
+func decodeDocuments(documents []bson.M, model interface{}) error {
+	target := reflect.ValueOf(model)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Slice {
+		return errors.New("server: model must be a pointer to a slice")
+	}
+
+	slice := reflect.MakeSlice(target.Elem().Type(), len(documents), len(documents))
+	for i, document := range documents {
+		err := decodeDocument(document, slice.Index(i).Addr().Interface())
+		if err != nil {
+			return err
+		}
+	}
+	target.Elem().Set(slice)
+
+	return nil
+}
+
+/*
+toFloat Convert a numeric BSON value to a float64
+*/
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+/*
+compareValues Order two BSON values of the same kind. Returns false if the values cannot be compared
+*/
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return cmp.Compare(x, y), ok
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case primitive.DateTime:
+		if y, ok := b.(primitive.DateTime); ok {
+			return cmp.Compare(x, y), true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(x[:], y[:]), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0, true
+			}
+
+			if !x {
+				return -1, true
+			}
+
+			return 1, true
+		}
+	}
+
+	return 0, false
+}
+
+/*
+equalValues Returns true if two BSON values are equal
+*/
+func equalValues(a interface{}, b interface{}) bool {
+	if result, ok := compareValues(a, b); ok {
+		return result == 0
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+/*
+lookupPath Return every value found at a dotted path in a document. Arrays along the path are traversed, so
+"foreignData.language" returns the language of every element of foreignData. The boolean is false if the
+path does not exist
+*/
+func lookupPath(value interface{}, path []string) ([]interface{}, bool) {
+	if len(path) == 0 {
+		return []interface{}{value}, true
+	}
+
+	switch v := value.(type) {
+	case bson.M:
+		next, ok := v[path[0]]
+		if !ok {
+			return nil, false
+		}
+
+		return lookupPath(next, path[1:])
+	case bson.A:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i < 0 || i >= len(v) {
+				return nil, false
+			}
+
+			return lookupPath(v[i], path[1:])
+		}
+
+		var ret []interface{}
+		found := false
+		for _, element := range v {
+			values, ok := lookupPath(element, path)
+			if ok {
+				found = true
+				ret = append(ret, values...)
+			}
+		}
+
+		return ret, found
+	}
+
+	return nil, false
+}
+
+/*
+flatten Return the values passed along with the elements of any arrays among them
+*/
+func flatten(values []interface{}) []interface{} {
+	var ret []interface{}
+	for _, value := range values {
+		if array, ok := value.(bson.A); ok {
+			ret = append(ret, array...)
+		}
+
+		ret = append(ret, value)
+	}
+
+	return ret
+}
+
+/*
+isOperatorDocument Returns true if the value is a document whose keys are all query operators
+*/
+func isOperatorDocument(value interface{}) (bson.M, bool) {
+	document, ok := value.(bson.M)
+	if !ok || len(document) == 0 {
+		return nil, false
+	}
+
+	for key := range document {
+		if !strings.HasPrefix(key, "$") {
+			return nil, false
+		}
+	}
+
+	return document, true
+}
+
+/*
+matchEquals Returns true if any of the values, or any element of an array among them, equals the expected value
+*/
+func matchEquals(values []interface{}, expected interface{}) bool {
+	if expected == nil && len(values) == 0 {
+		return true
+	}
+
+	for _, value := range flatten(values) {
+		if equalValues(value, expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+matchRegex Returns true if any of the string values match the regular expression passed
+*/
+func matchRegex(values []interface{}, pattern interface{}, options string) (bool, error) {
+	var expr string
+	switch v := pattern.(type) {
+	case string:
+		expr = v
+	case primitive.Regex:
+		expr = v.Pattern
+		options += v.Options
+	default:
+		return false, ErrUnsupportedQuery
+	}
+
+	if strings.Contains(options, "i") {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false, err
+	}
+
+	for _, value := range flatten(values) {
+		if str, ok := value.(string); ok && re.MatchString(str) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+/*
+matchOperator Evaluate a single query operator against the values found at a path
+*/
+func matchOperator(values []interface{}, exists bool, operator string, arg interface{}, operators bson.M) (bool, error) {
+	switch operator {
+	case "$eq":
+		return matchEquals(values, arg), nil
+	case "$ne":
+		return !matchEquals(values, arg), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		for _, value := range flatten(values) {
+			result, ok := compareValues(value, arg)
+			if !ok {
+				continue
+			}
+
+			if (operator == "$gt" && result > 0) || (operator == "$gte" && result >= 0) ||
+				(operator == "$lt" && result < 0) || (operator == "$lte" && result <= 0) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case "$in", "$nin", "$all":
+		expected, ok := arg.(bson.A)
+		if !ok {
+			return false, ErrUnsupportedQuery
+		}
+
+		if operator == "$all" {
+			for _, element := range expected {
+				if !matchEquals(values, element) {
+					return false, nil
+				}
+			}
+
+			return len(expected) != 0, nil
+		}
+
+		found := slices.ContainsFunc(expected, func(element interface{}) bool {
+			return matchEquals(values, element)
+		})
+
+		return found == (operator == "$in"), nil
+	case "$exists":
+		want, ok := arg.(bool)
+		if !ok {
+			return false, ErrUnsupportedQuery
+		}
+
+		return exists == want, nil
+	case "$regex":
+		options, _ := operators["$options"].(string)
+		return matchRegex(values, arg, options)
+	case "$options":
+		return true, nil
+	case "$size":
+		size, ok := toFloat(arg)
+		if !ok {
+			return false, ErrUnsupportedQuery
+		}
+
+		for _, value := range values {
+			if array, ok := value.(bson.A); ok && float64(len(array)) == size {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case "$elemMatch":
+		condition, ok := arg.(bson.M)
+		if !ok {
+			return false, ErrUnsupportedQuery
+		}
+
+		for _, value := range values {
+			array, ok := value.(bson.A)
+			if !ok {
+				continue
+			}
+
+			for _, element := range array {
+				matched, err := matchElement(element, condition)
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+		}
+
+		return false, nil
+	case "$not":
+		matched, err := matchField(values, exists, arg)
+		return !matched, err
+	}
+
+	return false, fmt.Errorf("%w: %s", ErrUnsupportedQuery, operator)
+}
+
+/*
+matchField Evaluate the condition for a single field of a query against the values found at its path
+*/
+func matchField(values []interface{}, exists bool, condition interface{}) (bool, error) {
+	operators, ok := isOperatorDocument(condition)
+	if !ok {
+		if regex, ok := condition.(primitive.Regex); ok {
+			return matchRegex(values, regex, "")
+		}
+
+		return matchEquals(values, condition), nil
+	}
+
+	for operator, arg := range operators {
+		matched, err := matchOperator(values, exists, operator, arg, operators)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+/*
+matchElement Match a single array element against a condition, which may be either a query on the fields of
+the element, or a set of operators applied to the element itself
+*/
+func matchElement(element interface{}, condition bson.M) (bool, error) {
+	if _, ok := isOperatorDocument(condition); ok {
+		return matchField([]interface{}{element}, true, condition)
+	}
+
+	document, ok := element.(bson.M)
+	if !ok {
+		return false, nil
+	}
+
+	return matchDocument(document, condition)
+}
+
+/*
+matchDocument Returns true if the document matches every condition in the query
+*/
+func matchDocument(document bson.M, query bson.M) (bool, error) {
+	for key, condition := range query {
+		switch key {
+		case "$and", "$or", "$nor":
+			clauses, ok := condition.(bson.A)
+			if !ok {
+				return false, ErrUnsupportedQuery
+			}
+
+			matches := 0
+			for _, clause := range clauses {
+				clauseQuery, ok := clause.(bson.M)
+				if !ok {
+					return false, ErrUnsupportedQuery
+				}
+
+				matched, err := matchDocument(document, clauseQuery)
+				if err != nil {
+					return false, err
+				}
+
+				if matched {
+					matches++
+				}
+			}
+
+			if (key == "$and" && matches != len(clauses)) || (key == "$or" && matches == 0) || (key == "$nor" && matches != 0) {
+				return false, nil
+			}
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("%w: %s", ErrUnsupportedQuery, key)
+			}
+
+			values, exists := lookupPath(document, strings.Split(key, "."))
+			matched, err := matchField(values, exists, condition)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+/*
+getPath Return the value at a dotted path of nested documents
+*/
+func getPath(document bson.M, path []string) (interface{}, bool) {
+	for i, key := range path {
+		value, ok := document[key]
+		if !ok {
+			return nil, false
+		}
+
+		if i == len(path)-1 {
+			return value, true
+		}
+
+		document, ok = value.(bson.M)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+/*
+setPath Set the value at a dotted path, creating any documents along the path that do not exist
+*/
+func setPath(document bson.M, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := document[key].(bson.M)
+		if !ok {
+			next = bson.M{}
+			document[key] = next
+		}
+
+		document = next
+	}
+
+	document[path[len(path)-1]] = value
+}
+
+/*
+deletePath Remove the value at a dotted path
+*/
+func deletePath(document bson.M, path []string) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := document[key].(bson.M)
+		if !ok {
+			return
+		}
+
+		document = next
+	}
+
+	delete(document, path[len(path)-1])
+}
+
+/*
+project Apply a projection built from the fields passed (see Projection) to a copy of the document
+*/
+func project(document bson.M, fields []string) (bson.M, error) {
+	projection := Projection(fields...)
+	if projection == nil {
+		return document, nil
+	}
+
+	if projection[0].Value == 0 {
+		ret, err := toDocument(document)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range projection {
+			deletePath(ret, strings.Split(field.Key, "."))
+		}
+
+		return ret, nil
+	}
+
+	ret := bson.M{"_id": document["_id"]}
+	for _, field := range projection {
+		path := strings.Split(field.Key, ".")
+		if value, ok := getPath(document, path); ok {
+			setPath(ret, path, value)
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+applyUpdate Apply a single update operator to a document
+*/
+func applyUpdate(document bson.M, operator string, fields bson.M) error {
+	for key, value := range fields {
+		path := strings.Split(key, ".")
+		current, exists := getPath(document, path)
+
+		switch operator {
+		case "$set":
+			setPath(document, path, value)
+		case "$unset":
+			deletePath(document, path)
+		case "$inc":
+			x, ok := toFloat(value)
+			if !ok {
+				return ErrUnsupportedQuery
+			}
+
+			if !exists {
+				setPath(document, path, value)
+				continue
+			}
+
+			y, ok := toFloat(current)
+			if !ok {
+				return ErrUnsupportedQuery
+			}
+
+			_, floatValue := value.(float64)
+			_, floatCurrent := current.(float64)
+			if floatValue || floatCurrent {
+				setPath(document, path, x+y)
+			} else {
+				setPath(document, path, int64(x+y))
+			}
+		case "$push", "$addToSet":
+			array, ok := current.(bson.A)
+			if exists && !ok {
+				return ErrUnsupportedQuery
+			}
+
+			items := bson.A{value}
+			if each, ok := value.(bson.M); ok {
+				if values, ok := each["$each"].(bson.A); ok {
+					items = values
+				}
+			}
+
+			for _, item := range items {
+				if operator == "$addToSet" && slices.ContainsFunc(array, func(element interface{}) bool { return equalValues(element, item) }) {
+					continue
+				}
+
+				array = append(array, item)
+			}
+
+			setPath(document, path, array)
+		case "$pull":
+			array, ok := current.(bson.A)
+			if !ok {
+				continue
+			}
+
+			kept := bson.A{}
+			for _, element := range array {
+				remove := false
+				if condition, ok := value.(bson.M); ok {
+					matched, err := matchElement(element, condition)
+					if err != nil {
+						return err
+					}
+
+					remove = matched
+				} else {
+					remove = equalValues(element, value)
+				}
+
+				if !remove {
+					kept = append(kept, element)
+				}
+			}
+
+			setPath(document, path, kept)
+		default:
+			return fmt.Errorf("%w: %s", ErrUnsupportedQuery, operator)
+		}
+	}
+
+	return nil
+}
+
+/*
+matching Return the indexes of the documents in the collection that match the query. The caller must hold
+the lock
+*/
+func (m *MemoryDatabase) matching(collection string, query bson.M, limit int) ([]int, error) {
+	if m.softDeletes(collection) {
+		query = withoutDeleted(query)
+	}
+
+	normalized, err := toDocument(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []int
+	for i, document := range m.collections[collection] {
+		matched, err := matchDocument(document, normalized)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			ret = append(ret, i)
+			if limit > 0 && len(ret) == limit {
+				break
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+sortDocuments Order the documents by the fields passed, using the same syntax as SortBy. Documents with equal
+values for every field keep their insertion order
+*/
+func sortDocuments(documents []bson.M, sort []string) {
+	if len(sort) == 0 {
+		return
+	}
+
+	slices.SortStableFunc(documents, func(a bson.M, b bson.M) int {
+		for _, element := range SortBy(sort...) {
+			path := strings.Split(element.Key, ".")
+			x, _ := getPath(a, path)
+			y, _ := getPath(b, path)
+
+			result, _ := compareValues(x, y)
+			if result != 0 {
+				return result * element.Value.(int)
+			}
+		}
+
+		return 0
+	})
+}
+
+/*
+findDocuments Return the documents in the collection that match the query, ordered by the sort fields and with
+the projection applied
+*/
+func (m *MemoryDatabase) findDocuments(collection string, query bson.M, limit int, fields []string, sort ...string) ([]bson.M, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matchLimit := limit
+	if len(sort) != 0 {
+		matchLimit = 0
+	}
+
+	indexes, err := m.matching(collection, query, matchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]bson.M, 0, len(indexes))
+	for _, i := range indexes {
+		matched = append(matched, m.collections[collection][i])
+	}
+
+	sortDocuments(matched, sort)
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	ret := make([]bson.M, 0, len(matched))
+	for _, match := range matched {
+		document, err := project(match, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, document)
+	}
+
+	return ret, nil
+}
+
+/*
+Find Unmarshal the first document matching the query into the model. Returns ErrNotFound if no document matches
+*/
+func (m *MemoryDatabase) Find(ctx context.Context, collection string, query bson.M, model interface{}, projection ...string) error {
+	documents, err := m.findDocuments(collection, query, 1, projection)
+	if err != nil {
+		return wrapError("FindOne", collection, err)
+	}
+
+	if len(documents) == 0 {
+		return wrapError("FindOne", collection, mongo.ErrNoDocuments)
+	}
+
+	return decodeDocument(documents[0], model)
+}
+
+/*
+FindMultiple Unmarshal every document where the key is one of the values passed into the model
+*/
+func (m *MemoryDatabase) FindMultiple(ctx context.Context, collection string, key string, value []string, model interface{}, projection ...string) error {
+	return m.FindMany(ctx, collection, bson.M{key: bson.M{"$in": value}}, model, projection...)
+}
+
+/*
+FindMultipleKeys Unmarshal every document where any of the keys is one of the values passed into the model
+*/
+func (m *MemoryDatabase) FindMultipleKeys(ctx context.Context, collection string, keys []string, values []string, model interface{}, projection ...string) error {
+	return m.FindMany(ctx, collection, MultipleKeysQuery(keys, values), model, projection...)
+}
+
+/*
+FindMany Unmarshal every document matching the query into the model
+*/
+func (m *MemoryDatabase) FindMany(ctx context.Context, collection string, query bson.M, model interface{}, projection ...string) error {
+	return m.FindManySorted(ctx, collection, query, nil, model, projection...)
+}
+
+/*
+FindManySorted Unmarshal every document matching the query into the model, ordered by the sort fields
+*/
+func (m *MemoryDatabase) FindManySorted(ctx context.Context, collection string, query bson.M, sort []string, model interface{}, projection ...string) error {
+	return m.FindManyLimited(ctx, collection, query, sort, 0, model, projection...)
+}
+
+/*
+FindManyLimited Unmarshal up to 'limit' documents matching the query into the model, ordered by the sort fields.
+If limit is 0, every matching document is returned
+*/
+func (m *MemoryDatabase) FindManyLimited(ctx context.Context, collection string, query bson.M, sort []string, limit int64, model interface{}, projection ...string) error {
+	documents, err := m.findDocuments(collection, query, int(limit), projection, sort...)
+	if err != nil {
+		return wrapError("FindMany", collection, err)
+	}
+
+	return decodeDocuments(documents, model)
+}
+
+/*
+FindStream Return an iterator over every document matching the query. The documents are matched up front, but
+are only marshalled as the iterator is consumed
+*/
+func (m *MemoryDatabase) FindStream(ctx context.Context, collection string, query bson.M, projection ...string) iter.Seq2[bson.Raw, error] {
+	return func(yield func(bson.Raw, error) bool) {
+		documents, err := m.findDocuments(collection, query, 0, projection)
+		if err != nil {
+			yield(nil, wrapError("FindStream", collection, err))
+			return
+		}
+
+		for _, document := range documents {
+			raw, err := bson.Marshal(document)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(raw, nil) {
+				return
+			}
+		}
+	}
+}
+
+/*
+Sample Unmarshal up to 'size' documents chosen at random from those matching the query into the model
+*/
+func (m *MemoryDatabase) Sample(ctx context.Context, collection string, query bson.M, size int64, model interface{}, projection ...string) error {
+	documents, err := m.findDocuments(collection, query, 0, projection)
+	if err != nil {
+		return wrapError("Aggregate", collection, err)
+	}
+
+	rand.Shuffle(len(documents), func(i int, j int) {
+		documents[i], documents[j] = documents[j], documents[i]
+	})
+
+	if int64(len(documents)) > size {
+		documents = documents[:size]
+	}
+
+	return decodeDocuments(documents, model)
+}
+
+/*
+Distinct Return the unique values of the field across the documents matching the query
+*/
+func (m *MemoryDatabase) Distinct(ctx context.Context, collection string, field string, query bson.M) ([]interface{}, error) {
+	documents, err := m.findDocuments(collection, query, 0, nil)
+	if err != nil {
+		return nil, wrapError("Distinct", collection, err)
+	}
+
+	ret := []interface{}{}
+	for _, document := range documents {
+		values, _ := lookupPath(document, strings.Split(field, "."))
+		for _, value := range values {
+			items := []interface{}{value}
+			if array, ok := value.(bson.A); ok {
+				items = array
+			}
+
+			for _, item := range items {
+				if !slices.ContainsFunc(ret, func(existing interface{}) bool { return equalValues(existing, item) }) {
+					ret = append(ret, item)
+				}
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+ExistsMany Return the subset of the values passed for which a document exists with the key set to that value
+*/
+func (m *MemoryDatabase) ExistsMany(ctx context.Context, collection string, key string, values []string) ([]string, error) {
+	if len(values) == 0 {
+		return []string{}, nil
+	}
+
+	distinct, err := m.Distinct(ctx, collection, key, bson.M{key: bson.M{"$in": values}})
+	if err != nil {
+		return nil, err
+	}
+
+	return existingValues(distinct, values), nil
+}
+
+/*
+Index Unmarshal up to 'limit' documents from the collection into the model, ordered by the sort fields
+*/
+func (m *MemoryDatabase) Index(ctx context.Context, collection string, limit int64, model interface{}, sort ...string) error {
+	documents, err := m.findDocuments(collection, bson.M{}, int(limit), nil, sort...)
+	if err != nil {
+		return wrapError("Index", collection, err)
+	}
+
+	return decodeDocuments(documents, model)
+}
+
+/*
+Paginate Return a single page of the documents matching the query, using the same options and cursors as
+Database.Paginate
+*/
+func (m *MemoryDatabase) Paginate(ctx context.Context, collection string, query bson.M, opts *PageOptions, model interface{}) (*Page, error) {
+	normalized := PageOptions{}
+	if opts != nil {
+		normalized = *opts
+	}
+
+	opts = &normalized
+	opts.normalize()
+
+	documents, err := m.findDocuments(collection, query, 0, nil)
+	if err != nil {
+		return nil, wrapError("Paginate", collection, err)
+	}
+
+	sortKey := strings.Split(opts.SortKey, ".")
+	compareDocuments := func(a bson.M, b bson.M) int {
+		x, _ := getPath(a, sortKey)
+		y, _ := getPath(b, sortKey)
+
+		result, _ := compareValues(x, y)
+		if result == 0 {
+			result, _ = compareValues(a["_id"], b["_id"])
+		}
+
+		if opts.Descending {
+			return -result
+		}
+
+		return result
+	}
+	slices.SortStableFunc(documents, compareDocuments)
+
+	page := &Page{Limit: opts.Limit}
+	if opts.Cursor != "" {
+		cursor, err := DecodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if cursor.SortKey != opts.SortKey {
+			return nil, ErrInvalidCursor
+		}
+
+		position := bson.M{"_id": cursor.Id}
+		setPath(position, sortKey, cursor.Value)
+
+		start := 0
+		for start < len(documents) && compareDocuments(documents[start], position) <= 0 {
+			start++
+		}
+
+		documents = documents[start:]
+	} else if opts.Skip > 0 {
+		documents = documents[min(int(opts.Skip), len(documents)):]
+		page.Skip = opts.Skip
+	}
+
+	if int64(len(documents)) > opts.Limit {
+		documents = documents[:opts.Limit]
+		page.HasMore = true
+	}
+
+	err = decodeDocuments(documents, model)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Count = int64(len(documents))
+
+	if page.HasMore {
+		last := documents[len(documents)-1]
+		value, _ := getPath(last, sortKey)
+		cursor := &Cursor{SortKey: opts.SortKey, Value: value, Id: last["_id"]}
+
+		page.NextCursor, err = cursor.Encode()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+/*
+Count Return the number of documents matching the query
+*/
+func (m *MemoryDatabase) Count(ctx context.Context, collection string, query bson.M) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	indexes, err := m.matching(collection, query, 0)
+	if err != nil {
+		return 0, wrapError("CountDocuments", collection, err)
+	}
+
+	return int64(len(indexes)), nil
+}
+
+/*
+EstimatedCount Return the number of documents in the collection
+*/
+func (m *MemoryDatabase) EstimatedCount(ctx context.Context, collection string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.collections[collection])), nil
+}
+
+/*
+insert Add a document to the collection, assigning an _id if it does not have one. The caller must hold the lock
+*/
+func (m *MemoryDatabase) insert(collection string, model interface{}) (interface{}, error) {
+	document, err := toDocument(model)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := document["_id"]; !ok {
+		document["_id"] = primitive.NewObjectID()
+	}
+
+	if m.collections == nil {
+		m.collections = map[string][]bson.M{}
+	}
+
+	for _, existing := range m.collections[collection] {
+		if equalValues(existing["_id"], document["_id"]) {
+			return nil, fmt.Errorf("server: InsertOne on %s: %w", collection, ErrDuplicateKey)
+		}
+	}
+
+	m.collections[collection] = append(m.collections[collection], document)
+
+	return document["_id"], nil
+}
+
+/*
+Insert Add the model to the collection as a new document. Returns ErrDuplicateKey if a document with the
+same _id already exists
+*/
+func (m *MemoryDatabase) Insert(ctx context.Context, collection string, model interface{}) (*mongo.InsertOneResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, err := m.insert(collection, model)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+/*
+InsertMany Add every model to the collection. Like Database.InsertMany the insert is unordered, so a duplicate
+does not prevent the remaining models from being inserted
+*/
+func (m *MemoryDatabase) InsertMany(ctx context.Context, collection string, models []interface{}) (*mongo.InsertManyResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &mongo.InsertManyResult{}
+
+	var failed error
+	for _, model := range models {
+		id, err := m.insert(collection, model)
+		if err != nil {
+			failed = err
+			continue
+		}
+
+		result.InsertedIDs = append(result.InsertedIDs, id)
+	}
+
+	return result, failed
+}
+
+/*
+UpsertMany Replace the document with the same _id as each of the documents passed, inserting those that do not
+exist yet. Each document passed must have an _id
+*/
+func (m *MemoryDatabase) UpsertMany(ctx context.Context, collection string, models []interface{}) (*mongo.BulkWriteResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &mongo.BulkWriteResult{UpsertedIDs: map[int64]interface{}{}}
+
+	for index, model := range models {
+		document, err := toDocument(model)
+		if err != nil {
+			return result, wrapError("UpsertMany", collection, err)
+		}
+
+		id, ok := document["_id"]
+		if !ok {
+			return result, wrapError("UpsertMany", collection, errors.New("document is missing an _id"))
+		}
+
+		_, err = m.replace(collection, bson.M{"_id": id}, model)
+		if err == nil {
+			result.MatchedCount++
+			result.ModifiedCount++
+			continue
+		}
+
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return result, wrapError("UpsertMany", collection, err)
+		}
+
+		_, err = m.insert(collection, model)
+		if err != nil {
+			return result, err
+		}
+
+		result.UpsertedCount++
+		result.UpsertedIDs[int64(index)] = id
+	}
+
+	return result, nil
+}
+
+/*
+replace Replace the first document matching the query, keeping its _id. Returns ErrNotFound if no document matches
+*/
+func (m *MemoryDatabase) replace(collection string, query bson.M, model interface{}) (*mongo.UpdateResult, error) {
+	indexes, err := m.matching(collection, query, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(indexes) == 0 {
+		return &mongo.UpdateResult{}, mongo.ErrNoDocuments
+	}
+
+	document, err := toDocument(model)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := m.collections[collection][indexes[0]]
+	document["_id"] = existing["_id"]
+	m.collections[collection][indexes[0]] = document
+
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
+/*
+Replace Replace the first document matching the query with the model. Returns ErrNotFound if no document matches
+*/
+func (m *MemoryDatabase) Replace(ctx context.Context, collection string, query bson.M, model interface{}) (*mongo.UpdateResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result, err := m.replace(collection, query, model)
+	if err != nil {
+		return result, wrapError("ReplaceOne", collection, err)
+	}
+
+	return result, nil
+}
+
+/*
+Upsert Replace the first document matching the query with the model, or insert it if no document matches
+*/
+func (m *MemoryDatabase) Upsert(ctx context.Context, collection string, query bson.M, model interface{}) (*mongo.UpdateResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.softDeletes(collection) {
+		live, err := m.matching(collection, query, 1)
+		if err != nil {
+			return nil, wrapError("Upsert", collection, err)
+		}
+
+		deleted, err := m.matching(collection, onlyDeleted(query), 1)
+		if err != nil {
+			return nil, wrapError("Upsert", collection, err)
+		}
+
+		if len(live) == 0 && len(deleted) != 0 {
+			return nil, fmt.Errorf("server: Upsert on %s: %w", collection, ErrDeleted)
+		}
+	}
+
+	result, err := m.replace(collection, query, model)
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		if err != nil {
+			return nil, wrapError("Upsert", collection, err)
+		}
+
+		return result, nil
+	}
+
+	id, err := m.insert(collection, model)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mongo.UpdateResult{UpsertedCount: 1, UpsertedID: id}, nil
+}
+
+/*
+Delete Remove the first document matching the query, or mark it as deleted with SoftDeleteField if SoftDelete
+is enabled for the collection. Returns ErrNotFound if no document matches
+*/
+func (m *MemoryDatabase) Delete(ctx context.Context, collection string, query bson.M) (*mongo.DeleteResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	indexes, err := m.matching(collection, query, 1)
+	if err != nil {
+		return nil, wrapError("DeleteOne", collection, err)
+	}
+
+	if len(indexes) == 0 {
+		return &mongo.DeleteResult{}, wrapError("DeleteOne", collection, mongo.ErrNoDocuments)
+	}
+
+	if m.softDeletes(collection) {
+		fields, err := toDocument(bson.M{SoftDeleteField: time.Now().UTC()})
+		if err != nil {
+			return nil, wrapError("SoftDelete", collection, err)
+		}
+
+		err = applyUpdate(m.collections[collection][indexes[0]], "$set", fields)
+		if err != nil {
+			return nil, wrapError("SoftDelete", collection, err)
+		}
+
+		return &mongo.DeleteResult{DeletedCount: 1}, nil
+	}
+
+	m.collections[collection] = slices.Delete(m.collections[collection], indexes[0], indexes[0]+1)
+
+	return &mongo.DeleteResult{DeletedCount: 1}, nil
+}
+
+/*
+update Apply an update operator to the first document matching the query
+*/
+func (m *MemoryDatabase) update(name string, operator string, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return m.updateOne(name, collection, query, bson.M{operator: fields})
+}
+
+/*
+updateOne Apply every operator of the update document to the first document matching the query
+*/
+func (m *MemoryDatabase) updateOne(name string, collection string, query bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	indexes, err := m.matching(collection, query, 1)
+	if err != nil {
+		return nil, wrapError(name, collection, err)
+	}
+
+	if len(indexes) == 0 {
+		return &mongo.UpdateResult{}, nil
+	}
+
+	normalized, err := toDocument(update)
+	if err != nil {
+		return nil, wrapError(name, collection, err)
+	}
+
+	document := m.collections[collection][indexes[0]]
+	before, _ := bson.Marshal(document)
+
+	for operator, fields := range normalized {
+		operatorFields, ok := fields.(bson.M)
+		if !ok {
+			return nil, wrapError(name, collection, ErrUnsupportedQuery)
+		}
+
+		err = applyUpdate(document, operator, operatorFields)
+		if err != nil {
+			return nil, wrapError(name, collection, err)
+		}
+	}
+
+	result := &mongo.UpdateResult{MatchedCount: 1}
+	if after, _ := bson.Marshal(document); !bytes.Equal(before, after) {
+		result.ModifiedCount = 1
+	}
+
+	return result, nil
+}
+
+/*
+Update Apply every operator of the update document to the first document matching the query
+*/
+func (m *MemoryDatabase) Update(ctx context.Context, collection string, query bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	return m.updateOne("Update", collection, query, update)
+}
+
+/*
+SetField Set fields in the first document matching the query
+*/
+func (m *MemoryDatabase) SetField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return m.update("SetField", "$set", collection, query, fields)
+}
+
+/*
+AppendField Append items to array fields in the first document matching the query
+*/
+func (m *MemoryDatabase) AppendField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return m.update("AppendField", "$push", collection, query, fields)
+}
+
+/*
+PullField Remove items from array fields in the first document matching the query
+*/
+func (m *MemoryDatabase) PullField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return m.update("PullField", "$pull", collection, query, fields)
+}
+
+/*
+IncrementField Increment fields in the first document matching the query
+*/
+func (m *MemoryDatabase) IncrementField(ctx context.Context, collection string, query bson.M, fields bson.M) (*mongo.UpdateResult, error) {
+	return m.update("IncrementField", "$inc", collection, query, fields)
+}
+
+/*
+NextSequence Increment the top level field passed in the first document matching the query and return its new
+value. If no document matches, one is created from the query with the field set to 1
+*/
+func (m *MemoryDatabase) NextSequence(ctx context.Context, collection string, query bson.M, field string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	indexes, err := m.matching(collection, query, 1)
+	if err != nil {
+		return 0, wrapError("NextSequence", collection, err)
+	}
+
+	if len(indexes) == 0 {
+		document, err := toDocument(query)
+		if err != nil {
+			return 0, wrapError("NextSequence", collection, err)
+		}
+
+		document[field] = int64(1)
+
+		_, err = m.insert(collection, document)
+		if err != nil {
+			return 0, err
+		}
+
+		return 1, nil
+	}
+
+	document := m.collections[collection][indexes[0]]
+
+	err = applyUpdate(document, "$inc", bson.M{field: int64(1)})
+	if err != nil {
+		return 0, wrapError("NextSequence", collection, err)
+	}
+
+	return sequenceValue(document, field)
+}
+
+/*
+Restore Remove the SoftDeleteField from the first soft deleted document matching the query. Returns ErrNotFound
+if no deleted document matches
+*/
+func (m *MemoryDatabase) Restore(ctx context.Context, collection string, query bson.M) (*mongo.UpdateResult, error) {
+	result, err := m.update("Restore", "$unset", collection, onlyDeleted(query), bson.M{SoftDeleteField: ""})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount < 1 {
+		return result, wrapError("Restore", collection, mongo.ErrNoDocuments)
+	}
+
+	return result, nil
+}
+
+/*
+PurgeDeleted Remove every document that was soft deleted before the time passed in the parameter
+*/
+func (m *MemoryDatabase) PurgeDeleted(ctx context.Context, collection string, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	indexes, err := m.matching(collection, bson.M{SoftDeleteField: bson.M{"$lte": before.UTC()}}, 0)
+	if err != nil {
+		return 0, wrapError("PurgeDeleted", collection, err)
+	}
+
+	for i := len(indexes) - 1; i >= 0; i-- {
+		m.collections[collection] = slices.Delete(m.collections[collection], indexes[i], indexes[i]+1)
+	}
+
+	return int64(len(indexes)), nil
+}
+
+/*
+Ping Always succeeds, as the in-memory database cannot be unreachable
+*/
+func (m *MemoryDatabase) Ping(ctx context.Context) error {
+	return nil
+}
+
+/*
+WithTransaction Call the function passed. Changes made before the function fails are not rolled back
+*/
+func (m *MemoryDatabase) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+testDocument The document that the queries of TestMatchDocument are matched against
+*/
+var testDocument = bson.M{
+	"name":      "Lightning Bolt",
+	"manaCost":  "{R}",
+	"manaValue": float64(1),
+	"colors":    bson.A{"R"},
+	"types":     bson.A{"Instant"},
+	"printings": bson.A{"LEA", "M10", "2XM"},
+	"legalities": bson.M{
+		"modern": "Legal",
+		"legacy": "Legal",
+	},
+	"foreignData": bson.A{
+		bson.M{"language": "German", "name": "Blitzschlag"},
+		bson.M{"language": "French", "name": "Foudre"},
+	},
+}
+
+func TestMatchDocument(t *testing.T) {
+	tests := []struct {
+		name  string
+		query bson.M
+		want  bool
+	}{
+		{"empty query", bson.M{}, true},
+		{"equal", bson.M{"name": "Lightning Bolt"}, true},
+		{"not equal", bson.M{"name": "Shock"}, false},
+		{"array element", bson.M{"printings": "M10"}, true},
+		{"nested field", bson.M{"legalities.modern": "Legal"}, true},
+		{"missing nested field", bson.M{"legalities.vintage": "Legal"}, false},
+		{"field of array elements", bson.M{"foreignData.language": "French"}, true},
+		{"array index", bson.M{"foreignData.0.language": "French"}, false},
+		{"$eq", bson.M{"manaCost": bson.M{"$eq": "{R}"}}, true},
+		{"$ne", bson.M{"manaCost": bson.M{"$ne": "{R}"}}, false},
+		{"$ne missing field", bson.M{"text": bson.M{"$ne": "Deal 3 damage"}}, true},
+		{"$gt", bson.M{"manaValue": bson.M{"$gt": 0}}, true},
+		{"$gte", bson.M{"manaValue": bson.M{"$gte": int32(1)}}, true},
+		{"$lt", bson.M{"manaValue": bson.M{"$lt": int64(1)}}, false},
+		{"$lte range", bson.M{"manaValue": bson.M{"$gte": 1, "$lte": 2}}, true},
+		{"$gt string", bson.M{"name": bson.M{"$gt": "Island"}}, true},
+		{"$in", bson.M{"printings": bson.M{"$in": bson.A{"ZEN", "2XM"}}}, true},
+		{"$in none", bson.M{"printings": bson.M{"$in": bson.A{"ZEN"}}}, false},
+		{"$nin", bson.M{"colors": bson.M{"$nin": bson.A{"U", "B"}}}, true},
+		{"$all", bson.M{"printings": bson.M{"$all": bson.A{"LEA", "M10"}}}, true},
+		{"$all missing one", bson.M{"printings": bson.M{"$all": bson.A{"LEA", "ZEN"}}}, false},
+		{"$all empty", bson.M{"printings": bson.M{"$all": bson.A{}}}, false},
+		{"$exists", bson.M{"legalities": bson.M{"$exists": true}}, true},
+		{"$exists false", bson.M{"text": bson.M{"$exists": false}}, true},
+		{"$regex", bson.M{"name": bson.M{"$regex": "^light"}}, false},
+		{"$regex with $options", bson.M{"name": bson.M{"$regex": "^light", "$options": "i"}}, true},
+		{"primitive regex", bson.M{"name": primitive.Regex{Pattern: "bolt$", Options: "i"}}, true},
+		{"$size", bson.M{"printings": bson.M{"$size": 3}}, true},
+		{"$size mismatch", bson.M{"colors": bson.M{"$size": 0}}, false},
+		{"$elemMatch", bson.M{"foreignData": bson.M{"$elemMatch": bson.M{"language": "German", "name": "Blitzschlag"}}}, true},
+		{"$elemMatch across elements", bson.M{"foreignData": bson.M{"$elemMatch": bson.M{"language": "German", "name": "Foudre"}}}, false},
+		{"$elemMatch operators", bson.M{"printings": bson.M{"$elemMatch": bson.M{"$in": bson.A{"2XM"}}}}, true},
+		{"$not", bson.M{"name": bson.M{"$not": bson.M{"$regex": "Shock"}}}, true},
+		{"$and", bson.M{"$and": bson.A{bson.M{"colors": "R"}, bson.M{"types": "Instant"}}}, true},
+		{"$and one false", bson.M{"$and": bson.A{bson.M{"colors": "R"}, bson.M{"types": "Sorcery"}}}, false},
+		{"$or", bson.M{"$or": bson.A{bson.M{"colors": "U"}, bson.M{"types": "Instant"}}}, true},
+		{"$or none", bson.M{"$or": bson.A{bson.M{"colors": "U"}, bson.M{"types": "Sorcery"}}}, false},
+		{"$nor", bson.M{"$nor": bson.A{bson.M{"colors": "U"}, bson.M{"types": "Sorcery"}}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := toDocument(test.query)
+			if err != nil {
+				t.Fatalf("toDocument() error = %v", err)
+			}
+
+			got, err := matchDocument(testDocument, query)
+			if err != nil {
+				t.Fatalf("matchDocument() error = %v", err)
+			}
+
+			if got != test.want {
+				t.Errorf("matchDocument() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchDocumentUnsupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		query bson.M
+	}{
+		{"unknown operator", bson.M{"name": bson.M{"$near": "Lightning Bolt"}}},
+		{"unknown top level operator", bson.M{"$where": "this.name"}},
+		{"$in without an array", bson.M{"name": bson.M{"$in": "Lightning Bolt"}}},
+		{"$exists without a bool", bson.M{"name": bson.M{"$exists": 1}}},
+		{"$or without an array", bson.M{"$or": bson.M{"name": "Lightning Bolt"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := toDocument(test.query)
+			if err != nil {
+				t.Fatalf("toDocument() error = %v", err)
+			}
+
+			_, err = matchDocument(testDocument, query)
+			if !errors.Is(err, ErrUnsupportedQuery) {
+				t.Errorf("matchDocument() error = %v, want %v", err, ErrUnsupportedQuery)
+			}
+		})
+	}
+}
+
+func TestMemoryDatabaseUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		update bson.M
+		field  string
+		want   interface{}
+	}{
+		{"$set", bson.M{"$set": bson.M{"name": "Shock"}}, "name", "Shock"},
+		{"$set nested", bson.M{"$set": bson.M{"legalities.modern": "Banned"}}, "legalities", bson.M{"modern": "Banned"}},
+		{"$unset", bson.M{"$unset": bson.M{"name": ""}}, "name", nil},
+		{"$inc", bson.M{"$inc": bson.M{"count": 2}}, "count", int64(3)},
+		{"$inc missing field", bson.M{"$inc": bson.M{"total": 5}}, "total", int32(5)},
+		{"$inc float", bson.M{"$inc": bson.M{"count": 0.5}}, "count", 1.5},
+		{"$push", bson.M{"$push": bson.M{"cards": "b"}}, "cards", bson.A{"a", "a", "b"}},
+		{"$push $each", bson.M{"$push": bson.M{"cards": bson.M{"$each": bson.A{"b", "c"}}}}, "cards", bson.A{"a", "a", "b", "c"}},
+		{"$push missing field", bson.M{"$push": bson.M{"tags": "new"}}, "tags", bson.A{"new"}},
+		{"$addToSet", bson.M{"$addToSet": bson.M{"cards": bson.M{"$each": bson.A{"a", "b"}}}}, "cards", bson.A{"a", "a", "b"}},
+		{"$pull", bson.M{"$pull": bson.M{"cards": "a"}}, "cards", bson.A{}},
+		{"$pull condition", bson.M{"$pull": bson.M{"cards": bson.M{"$in": bson.A{"a", "z"}}}}, "cards", bson.A{}},
+		{"combined", bson.M{"$set": bson.M{"name": "Shock"}, "$inc": bson.M{"count": -1}}, "count", int64(0)},
+	}
+
+	ctx := context.Background()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			database := NewMemoryDatabase()
+
+			_, err := database.Insert(ctx, "test", bson.M{"name": "Lightning Bolt", "count": 1, "cards": bson.A{"a", "a"}, "legalities": bson.M{"modern": "Legal"}})
+			if err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+
+			result, err := database.Update(ctx, "test", bson.M{"name": "Lightning Bolt"}, test.update)
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			if result.MatchedCount != 1 {
+				t.Fatalf("Update() matched %d documents, want 1", result.MatchedCount)
+			}
+
+			var stored bson.M
+			err = database.Find(ctx, "test", bson.M{}, &stored)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+
+			if got := stored[test.field]; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s = %#v, want %#v", test.field, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMemoryDatabaseFindManyLimited(t *testing.T) {
+	ctx := context.Background()
+	database := NewMemoryDatabase()
+
+	for _, name := range []string{"Shock", "Lightning Bolt", "Counterspell", "Giant Growth"} {
+		_, err := database.Insert(ctx, "test", bson.M{"name": name})
+		if err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		sort  []string
+		limit int64
+		want  []string
+	}{
+		{"no limit", []string{"name"}, 0, []string{"Counterspell", "Giant Growth", "Lightning Bolt", "Shock"}},
+		{"limit after sort", []string{"name"}, 2, []string{"Counterspell", "Giant Growth"}},
+		{"descending", []string{"-name"}, 1, []string{"Shock"}},
+		{"limit above count", []string{"name"}, 10, []string{"Counterspell", "Giant Growth", "Lightning Bolt", "Shock"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result []struct {
+				Name string `bson:"name"`
+			}
+
+			err := database.FindManyLimited(ctx, "test", bson.M{}, test.sort, test.limit, &result)
+			if err != nil {
+				t.Fatalf("FindManyLimited() error = %v", err)
+			}
+
+			var got []string
+			for _, value := range result {
+				got = append(got, value.Name)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("FindManyLimited() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMemoryDatabaseSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	database := NewMemoryDatabase()
+	database.SoftDelete = true
+
+	query := bson.M{"code": "ESP", "mtgjsonApiMeta.owner": "player@example.com"}
+	deck := bson.M{"code": "ESP", "mtgjsonApiMeta": bson.M{"owner": "player@example.com"}}
+
+	_, err := database.Insert(ctx, "deck", deck)
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	_, err = database.Delete(ctx, "deck", query)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	var stored bson.M
+	err = database.Find(ctx, "deck", query, &stored)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find() error = %v, want %v", err, ErrNotFound)
+	}
+
+	_, err = database.Upsert(ctx, "deck", query, deck)
+	if !errors.Is(err, ErrDeleted) {
+		t.Errorf("Upsert() error = %v, want %v", err, ErrDeleted)
+	}
+
+	_, err = database.Restore(ctx, "deck", query)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	err = database.Find(ctx, "deck", query, &stored)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	_, err = database.Delete(ctx, "deck", query)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	purged, err := database.PurgeDeleted(ctx, "deck", time.Now().Add(time.Second))
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeleted() = %d, %v, want 1", purged, err)
+	}
+
+	result, err := database.Upsert(ctx, "deck", query, deck)
+	if err != nil || result.UpsertedCount != 1 {
+		t.Errorf("Upsert() = %+v, %v, want an upserted document", result, err)
+	}
+
+	// collections that are not soft deleted are always hard deleted
+	_, err = database.Insert(ctx, "user", bson.M{"email": "player@example.com"})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	_, err = database.Delete(ctx, "user", bson.M{"email": "player@example.com"})
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if count, _ := database.EstimatedCount(ctx, "user"); count != 0 {
+		t.Errorf("EstimatedCount() = %d, want 0", count)
+	}
+}
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	NAMESPACE = "mtgjson"
+)
+
+/*
+Collector The Prometheus metrics of the SDK. Database operations are counted and timed per collection and
+command, with failed operations counted separately so that an error rate can be derived, alongside domain
+counters such as the number of cards served. Collector implements prometheus.Collector, so hosting APIs can
+register it with their own registry, or serve it directly with Handler
+*/
+type Collector struct {
+	operations   *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	cardsServed  prometheus.Counter
+	decksCreated prometheus.Counter
+}
+
+/*
+NewCollector Create a Collector with every metric initialized to zero
+*/
+func NewCollector() *Collector {
+	labels := []string{"collection", "operation"}
+
+	return &Collector{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Subsystem: "database",
+			Name:      "operations_total",
+			Help:      "The number of operations sent to MongoDB.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Subsystem: "database",
+			Name:      "errors_total",
+			Help:      "The number of operations sent to MongoDB that failed.",
+		}, labels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Subsystem: "database",
+			Name:      "operation_duration_seconds",
+			Help:      "The time taken by MongoDB to complete an operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 16),
+		}, labels),
+		cardsServed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "cards_served_total",
+			Help:      "The number of cards returned by card lookups.",
+		}),
+		decksCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "decks_created_total",
+			Help:      "The number of decks created.",
+		}),
+	}
+}
+
+/*
+Describe Send the descriptors of every metric to the channel passed, implementing prometheus.Collector
+*/
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.operations.Describe(ch)
+	c.errors.Describe(ch)
+	c.latency.Describe(ch)
+	c.cardsServed.Describe(ch)
+	c.decksCreated.Describe(ch)
+}
+
+/*
+Collect Send the current value of every metric to the channel passed, implementing prometheus.Collector
+*/
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.operations.Collect(ch)
+	c.errors.Collect(ch)
+	c.latency.Collect(ch)
+	c.cardsServed.Collect(ch)
+	c.decksCreated.Collect(ch)
+}
+
+/*
+ObserveOperation Record a single database operation against the collection. If failed is true the operation
+is also counted as an error
+*/
+func (c *Collector) ObserveOperation(collection string, operation string, elapsed time.Duration, failed bool) {
+	c.operations.WithLabelValues(collection, operation).Inc()
+	c.latency.WithLabelValues(collection, operation).Observe(elapsed.Seconds())
+
+	if failed {
+		c.errors.WithLabelValues(collection, operation).Inc()
+	}
+}
+
+/*
+AddCardsServed Increment the number of cards served by the count passed
+*/
+func (c *Collector) AddCardsServed(count int) {
+	c.cardsServed.Add(float64(count))
+}
+
+/*
+IncDecksCreated Increment the number of decks created
+*/
+func (c *Collector) IncDecksCreated() {
+	c.decksCreated.Inc()
+}
+
+/*
+Default The Collector that the SDK records its metrics to
+*/
+var Default = NewCollector()
+
+/*
+ObserveOperation Record a single database operation on the Default collector
+*/
+func ObserveOperation(collection string, operation string, elapsed time.Duration, failed bool) {
+	Default.ObserveOperation(collection, operation, elapsed, failed)
+}
+
+/*
+AddCardsServed Increment the number of cards served on the Default collector
+*/
+func AddCardsServed(count int) {
+	Default.AddCardsServed(count)
+}
+
+/*
+IncDecksCreated Increment the number of decks created on the Default collector
+*/
+func IncDecksCreated() {
+	Default.IncDecksCreated()
+}
+
+/*
+Register Register the Default collector with the registerer passed, for APIs that already expose their own
+Prometheus metrics (e.g. prometheus.DefaultRegisterer)
+*/
+func Register(registerer prometheus.Registerer) error {
+	return registerer.Register(Default)
+}
+
+/*
+Handler Return an http.Handler that serves the Default collector in the Prometheus exposition format, for
+APIs that do not have a registry of their own
+*/
+func Handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(Default)
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/stevezaluk/mtgjson-sdk/card"
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/deck"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+metaTypes The value of MtgjsonApiMeta.Type for each collection that stores API metadata
+*/
+var metaTypes = map[string]string{
+	"card": "Card",
+	"deck": "Deck",
+	"set":  "Set",
+}
+
+/*
+RenameField Rename a field in every document of the collection that has it. This is a building block for
+migrations that change the name of a field in a model
+*/
+func RenameField(ctx context.Context, database *server.Database, collection string, from string, to string) error {
+	result, err := database.UpdateMany(ctx, collection, bson.M{from: bson.M{"$exists": true}}, bson.M{"$rename": bson.M{from: to}})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Renamed field", "collection", collection, "from", from, "to", to, "modified", result.ModifiedCount)
+
+	return nil
+}
+
+/*
+ensureIndexes Build every index the SDK relies on
+*/
+func ensureIndexes(ctx context.Context, database *server.Database) error {
+	_, err := database.EnsureIndexes(ctx)
+
+	return err
+}
+
+/*
+backfillApiMeta Assign API metadata owned by the system user to cards, decks and sets that were imported
+before MtgjsonApiMeta was introduced
+*/
+func backfillApiMeta(ctx context.Context, database *server.Database) error {
+	currentDate := util.CreateTimestampStr()
+
+	for collection, metaType := range metaTypes {
+		apiMeta := bson.M{
+			"owner":        user.SystemUser,
+			"type":         metaType,
+			"creationDate": currentDate,
+			"modifiedDate": currentDate,
+		}
+
+		result, err := database.UpdateMany(ctx, collection, bson.M{"mtgjsonApiMeta": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"mtgjsonApiMeta": apiMeta}})
+		if err != nil {
+			return err
+		}
+
+		slog.Info("Backfilled API metadata", "collection", collection, "modified", result.ModifiedCount)
+	}
+
+	return nil
+}
+
+/*
+backfillDeckShareIds Store the share id and summary fields on decks that were created before share ids were
+introduced, and build the unique index on the owner and code of each deck. The index can only be built once
+no owner holds two decks with the same code
+*/
+func backfillDeckShareIds(ctx context.Context, database *server.Database) error {
+	updated, err := deck.BackfillShareIds(mtgContext.WithDatabase(ctx, database))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Backfilled deck share ids", "modified", updated)
+
+	return ensureIndexes(ctx, database)
+}
+
+/*
+cardRevisionCounters Number card revisions per card and owner. The unique index on the cardId and revision of
+each revision is replaced with one that includes the owner, and the revision counter of every card and owner is
+set to its highest recorded revision so that new revisions continue from it
+*/
+func cardRevisionCounters(ctx context.Context, database *server.Database) error {
+	_, err := database.DropIndex(ctx, card.REVISION_COLLECTION, server.UniqueIndexName([]string{"cardId", "revision"}))
+	if err != nil {
+		return err
+	}
+
+	updated, err := card.BackfillRevisionCounters(mtgContext.WithDatabase(ctx, database))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Backfilled card revision counters", "modified", updated)
+
+	return ensureIndexes(ctx, database)
+}
+
+/*
+backfillCardExtraOwners Set the owner of the large fields of cards that were split before extras were kept per
+owner, see card.BackfillExtraOwners
+*/
+func backfillCardExtraOwners(ctx context.Context, database *server.Database) error {
+	updated, err := card.BackfillExtraOwners(mtgContext.WithDatabase(ctx, database))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Backfilled card extra owners", "modified", updated)
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	MIGRATION_COLLECTION = "migrations"
+	MIGRATION_LOCK       = "migrations"
+	MIGRATION_LOCK_TTL   = 30 * time.Minute
+)
+
+var ErrMigrationFailed = errors.New("migrations: Operation failed. A migration returned an error and was not recorded as applied")
+
+/*
+Migration A single ordered change to the database schema. Migrations are applied in order of their version,
+and each version is only ever applied once. Up must be safe to re-run if it fails part way through, as a
+failed migration is not recorded and will be retried on the next Run
+*/
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, database *server.Database) error
+}
+
+/*
+Record The document stored in the migrations collection once a migration has been applied
+*/
+type Record struct {
+	Version   int           `bson:"_id" json:"version"`
+	Name      string        `bson:"name" json:"name"`
+	AppliedAt time.Time     `bson:"appliedAt" json:"appliedAt"`
+	Duration  time.Duration `bson:"duration" json:"duration"`
+}
+
+/*
+registered Every migration known to the SDK, including those registered by consumers. The migrations that
+ship with the SDK are listed here
+*/
+var registered = []Migration{
+	{Version: 1, Name: "ensure-indexes", Up: ensureIndexes},
+	{Version: 2, Name: "backfill-api-meta", Up: backfillApiMeta},
+	{Version: 3, Name: "backfill-deck-share-ids", Up: backfillDeckShareIds},
+	{Version: 4, Name: "card-revision-counters", Up: cardRevisionCounters},
+	{Version: 5, Name: "backfill-card-extra-owners", Up: backfillCardExtraOwners},
+}
+
+/*
+Register Add a migration to the list of migrations applied by Run. This should be called from an init
+function. Panics if a migration with the same version has already been registered
+*/
+func Register(migration Migration) {
+	for _, existing := range registered {
+		if existing.Version == migration.Version {
+			panic(fmt.Sprintf("migrations: version %d is registered by both %q and %q", migration.Version, existing.Name, migration.Name))
+		}
+	}
+
+	registered = append(registered, migration)
+	slices.SortFunc(registered, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+}
+
+/*
+Registered Return every registered migration in the order they are applied
+*/
+func Registered() []Migration {
+	return slices.Clone(registered)
+}
+
+/*
+Applied Return the record of every migration that has been applied to the database, ordered by version
+*/
+func Applied(ctx context.Context, database *server.Database) ([]*Record, error) {
+	var ret []*Record
+
+	err := database.FindMany(ctx, MIGRATION_COLLECTION, bson.M{}, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(ret, func(a, b *Record) int {
+		return a.Version - b.Version
+	})
+
+	return ret, nil
+}
+
+/*
+Pending Return the registered migrations that have not been applied to the database yet
+*/
+func Pending(ctx context.Context, database *server.Database) ([]Migration, error) {
+	applied, err := Applied(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := map[int]bool{}
+	for _, record := range applied {
+		versions[record.Version] = true
+	}
+
+	var ret []Migration
+	for _, migration := range registered {
+		if !versions[migration.Version] {
+			ret = append(ret, migration)
+		}
+	}
+
+	return ret, nil
+}
+
+/*
+Run Apply every pending migration in order and return the records of the migrations that were applied. The
+migrations lock is held while running, so only one process migrates the database at a time, and
+server.ErrLockHeld is returned if another process is already migrating it. Run stops at the first migration
+that fails and returns ErrMigrationFailed along with the records of the migrations applied before it
+*/
+func Run(ctx context.Context, database *server.Database) ([]*Record, error) {
+	lock, err := database.AcquireLock(ctx, MIGRATION_LOCK, MIGRATION_LOCK_TTL)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release(ctx)
+
+	pending, err := Pending(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Record
+	for _, migration := range pending {
+		slog.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+
+		start := time.Now()
+		err = migration.Up(ctx, database)
+		if err != nil {
+			slog.Error("Migration failed", "version", migration.Version, "name", migration.Name, "err", err)
+			return ret, fmt.Errorf("%w: %d (%s): %w", ErrMigrationFailed, migration.Version, migration.Name, err)
+		}
+
+		record := &Record{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC(), Duration: time.Since(start)}
+		_, err = database.Insert(ctx, MIGRATION_COLLECTION, record)
+		if err != nil {
+			return ret, err
+		}
+
+		ret = append(ret, record)
+	}
+
+	return ret, nil
+}
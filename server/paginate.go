@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DEFAULT_PAGE_LIMIT = 100
+	MAX_PAGE_LIMIT     = 1000
+)
+
+var ErrInvalidCursor = errors.New("server: Operation failed. The pagination cursor is malformed or was issued for a different sort key")
+
+/*
+PageOptions Controls which page of a collection is returned by Paginate. If Cursor is set it takes
+precedence over Skip, and the page starts immediately after the document the cursor was issued for.
+SortKey defaults to _id. Documents with an equal sort key are ordered by _id so pages never overlap
+*/
+type PageOptions struct {
+	Limit      int64
+	Skip       int64
+	Cursor     string
+	SortKey    string
+	Descending bool
+}
+
+/*
+Cursor The position of the last document in a page. Cursors are passed to API consumers as an opaque
+string produced by Encode
+*/
+type Cursor struct {
+	SortKey string      `bson:"k"`
+	Value   interface{} `bson:"v"`
+	Id      interface{} `bson:"i"`
+}
+
+/*
+Page Describes the page returned by Paginate. NextCursor is empty when there are no more documents
+*/
+type Page struct {
+	Limit      int64  `json:"limit"`
+	Skip       int64  `json:"skip,omitempty"`
+	Count      int64  `json:"count"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+/*
+Encode Return the cursor as an opaque URL safe string
+*/
+func (c *Cursor) Encode() (string, error) {
+	raw, err := bson.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+/*
+DecodeCursor Parse a cursor previously returned in Page.NextCursor. Returns ErrInvalidCursor if the
+string cannot be decoded
+*/
+func DecodeCursor(value string) (*Cursor, error) {
+	var ret *Cursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	err = bson.Unmarshal(raw, &ret)
+	if err != nil || ret == nil || ret.Id == nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return ret, nil
+}
+
+/*
+normalize Apply the default sort key and limit
+*/
+func (o *PageOptions) normalize() {
+	if o.SortKey == "" {
+		o.SortKey = "_id"
+	}
+
+	if o.Limit <= 0 {
+		o.Limit = DEFAULT_PAGE_LIMIT
+	}
+
+	if o.Limit > MAX_PAGE_LIMIT {
+		o.Limit = MAX_PAGE_LIMIT
+	}
+}
+
+/*
+cursorQuery Extend the query passed with a filter that only matches documents after the cursor
+*/
+func (o *PageOptions) cursorQuery(query bson.M, cursor *Cursor) bson.M {
+	operator := "$gt"
+	if o.Descending {
+		operator = "$lt"
+	}
+
+	after := bson.M{"_id": bson.M{operator: cursor.Id}}
+	if o.SortKey != "_id" {
+		after = bson.M{"$or": bson.A{
+			bson.M{o.SortKey: bson.M{operator: cursor.Value}},
+			bson.M{o.SortKey: cursor.Value, "_id": bson.M{operator: cursor.Id}},
+		}}
+	}
+
+	if len(query) == 0 {
+		return after
+	}
+
+	return bson.M{"$and": bson.A{query, after}}
+}
+
+/*
+lookupKey Return the value of a dotted key in a raw document
+*/
+func lookupKey(document bson.Raw, key string) interface{} {
+	var ret interface{}
+
+	value, err := document.LookupErr(strings.Split(key, ".")...)
+	if err != nil {
+		return nil
+	}
+
+	value.Unmarshal(&ret)
+
+	return ret
+}
+
+/*
+Paginate Return a single page of the documents matching the query, and unmarshal them into the slice
+pointed to by the 'model' parameter. Pages can either be requested by skip/limit, or by passing the
+NextCursor of the previous page, which stays efficient on large collections as it does not need to scan
+the skipped documents. An index on the sort key should exist for cursor based pagination
+*/
+func (d *Database) Paginate(ctx context.Context, collection string, query bson.M, opts *PageOptions, model interface{}) (*Page, error) {
+	var raw []bson.Raw
+
+	normalized := PageOptions{}
+	if opts != nil {
+		normalized = *opts
+	}
+
+	opts = &normalized
+	opts.normalize()
+
+	target := reflect.ValueOf(model)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("server: Paginate on %s: model must be a pointer to a slice", collection)
+	}
+
+	if query == nil {
+		query = bson.M{}
+	}
+	query = d.excludeDeleted(collection, query)
+
+	direction := 1
+	if opts.Descending {
+		direction = -1
+	}
+
+	sort := bson.D{{Key: opts.SortKey, Value: direction}}
+	if opts.SortKey != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: direction})
+	}
+
+	findOpts := options.Find().SetSort(sort).SetLimit(opts.Limit + 1).SetComment(d.comment(ctx))
+
+	page := &Page{Limit: opts.Limit}
+	if opts.Cursor != "" {
+		cursor, err := DecodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if cursor.SortKey != opts.SortKey {
+			return nil, ErrInvalidCursor
+		}
+
+		query = opts.cursorQuery(query, cursor)
+	} else if opts.Skip > 0 {
+		findOpts.SetSkip(opts.Skip)
+		page.Skip = opts.Skip
+	}
+
+	coll := d.collection(collection)
+
+	slog.Debug("Paginate Query", "collection", collection, "query", query, "limit", opts.Limit, "sort", opts.SortKey)
+	start := time.Now()
+	err := d.retry(ctx, "Paginate", collection, func() error {
+		cur, err := coll.Find(ctx, query, findOpts)
+		if err != nil {
+			return err
+		}
+
+		return cur.All(ctx, &raw)
+	})
+	d.explainIfSlow(ctx, collection, query, time.Since(start))
+	if err != nil {
+		slog.Error("Error during Paginate Query", "collection", collection, "query", query, "err", err)
+		return nil, wrapError("Paginate", collection, err)
+	}
+
+	if int64(len(raw)) > opts.Limit {
+		raw = raw[:opts.Limit]
+		page.HasMore = true
+	}
+
+	slice := reflect.MakeSlice(target.Elem().Type(), len(raw), len(raw))
+	for i, document := range raw {
+		err = bson.Unmarshal(document, slice.Index(i).Addr().Interface())
+		if err != nil {
+			return nil, wrapError("Paginate", collection, err)
+		}
+	}
+	target.Elem().Set(slice)
+
+	page.Count = int64(len(raw))
+
+	if page.HasMore {
+		last := raw[len(raw)-1]
+		cursor := &Cursor{SortKey: opts.SortKey, Value: lookupKey(last, opts.SortKey), Id: lookupKey(last, "_id")}
+
+		page.NextCursor, err = cursor.Encode()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+PoolOptions Controls the size of the connection pool of the MongoDB client. A zero value leaves the driver
+default (or the value from the connection string) in place. MaxPoolSize limits the connections to each
+server, MinPoolSize is the number of idle connections kept open, and MaxConnIdleTime is how long an idle
+connection is kept before it is closed
+*/
+type PoolOptions struct {
+	MaxPoolSize     uint64
+	MinPoolSize     uint64
+	MaxConnIdleTime time.Duration
+}
+
+/*
+apply Set the non-zero pool options on the client options passed in the parameter
+*/
+func (o PoolOptions) apply(opts *options.ClientOptions) {
+	if o.MaxPoolSize != 0 {
+		opts.SetMaxPoolSize(o.MaxPoolSize)
+	}
+
+	if o.MinPoolSize != 0 {
+		opts.SetMinPoolSize(o.MinPoolSize)
+	}
+
+	if o.MaxConnIdleTime != 0 {
+		opts.SetMaxConnIdleTime(o.MaxConnIdleTime)
+	}
+}
+
+/*
+PoolStats A snapshot of the connection pool of the MongoDB client, aggregated across every server in the
+deployment. Open is the number of connections that have been established and not yet closed, and InUse is
+the number of those that are currently checked out by an operation. CheckedOut and CheckOutFailed count
+every check out since the client connected, and TimedOut is the number of failures caused by waiting
+longer than the server selection or operation timeout for a free connection. Wait times cover successful
+check outs only
+*/
+type PoolStats struct {
+	Open            int64         `json:"open"`
+	InUse           int64         `json:"inUse"`
+	Closed          int64         `json:"closed"`
+	CheckedOut      int64         `json:"checkedOut"`
+	CheckOutFailed  int64         `json:"checkOutFailed"`
+	TimedOut        int64         `json:"timedOut"`
+	TotalWaitTime   time.Duration `json:"totalWaitTime"`
+	AverageWaitTime time.Duration `json:"averageWaitTime"`
+	MaxWaitTime     time.Duration `json:"maxWaitTime"`
+}
+
+/*
+poolCounters The counters updated by the pool monitor installed when the client connects
+*/
+type poolCounters struct {
+	open           atomic.Int64
+	inUse          atomic.Int64
+	closed         atomic.Int64
+	checkedOut     atomic.Int64
+	checkOutFailed atomic.Int64
+	timedOut       atomic.Int64
+	waitTime       atomic.Int64
+	maxWaitTime    atomic.Int64
+}
+
+/*
+recordWait Add the time spent waiting for a connection to the counters
+*/
+func (c *poolCounters) recordWait(wait time.Duration) {
+	c.waitTime.Add(int64(wait))
+
+	for {
+		current := c.maxWaitTime.Load()
+		if int64(wait) <= current || c.maxWaitTime.CompareAndSwap(current, int64(wait)) {
+			return
+		}
+	}
+}
+
+/*
+monitor Return an event.PoolMonitor that updates the counters
+*/
+func (c *poolCounters) monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				c.open.Add(1)
+			case event.ConnectionClosed:
+				c.open.Add(-1)
+				c.closed.Add(1)
+			case event.GetSucceeded:
+				c.inUse.Add(1)
+				c.checkedOut.Add(1)
+				c.recordWait(e.Duration)
+			case event.GetFailed:
+				c.checkOutFailed.Add(1)
+				if e.Reason == event.ReasonTimedOut {
+					c.timedOut.Add(1)
+				}
+			case event.ConnectionReturned:
+				c.inUse.Add(-1)
+			}
+		},
+	}
+}
+
+/*
+snapshot Return the current value of the counters
+*/
+func (c *poolCounters) snapshot() PoolStats {
+	if c == nil {
+		return PoolStats{}
+	}
+
+	ret := PoolStats{
+		Open:           c.open.Load(),
+		InUse:          c.inUse.Load(),
+		Closed:         c.closed.Load(),
+		CheckedOut:     c.checkedOut.Load(),
+		CheckOutFailed: c.checkOutFailed.Load(),
+		TimedOut:       c.timedOut.Load(),
+		TotalWaitTime:  time.Duration(c.waitTime.Load()),
+		MaxWaitTime:    time.Duration(c.maxWaitTime.Load()),
+	}
+
+	if ret.CheckedOut != 0 {
+		ret.AverageWaitTime = ret.TotalWaitTime / time.Duration(ret.CheckedOut)
+	}
+
+	return ret
+}
+
+/*
+PoolStats Return a snapshot of the connection pool statistics. All values are zero if the client has not
+connected
+*/
+func (d *Database) PoolStats() PoolStats {
+	return d.pool.snapshot()
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+Projection Build a projection document from a list of field names. Fields are included by default, and
+a field prefixed with '-' is excluded instead (e.g. "-rulings"). MongoDB does not allow inclusions and
+exclusions to be mixed in one projection, with the exception of _id. Returns nil if no fields are passed,
+which returns the full document
+*/
+func Projection(fields ...string) bson.D {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	ret := bson.D{}
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") {
+			ret = append(ret, bson.E{Key: strings.TrimPrefix(field, "-"), Value: 0})
+			continue
+		}
+
+		ret = append(ret, bson.E{Key: field, Value: 1})
+	}
+
+	return ret
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+Repository A typed wrapper around a single collection of the Database. Results are unmarshalled into T,
+so callers no longer need to declare a model to decode into, and a missing document is reported with
+the NotFound error of the repository rather than ErrNotFound
+*/
+type Repository[T any] struct {
+	Database   DatabaseInterface
+	Collection string
+	NotFound   error
+}
+
+/*
+NewRepository Create a Repository for the collection passed in the parameter. The notFound error is
+returned by FindOne, Replace and Delete when no document matches the query. If it is nil, ErrNotFound
+is returned instead
+*/
+func NewRepository[T any](database DatabaseInterface, collection string, notFound error) *Repository[T] {
+	if notFound == nil {
+		notFound = ErrNotFound
+	}
+
+	return &Repository[T]{Database: database, Collection: collection, NotFound: notFound}
+}
+
+/*
+notFound Replace ErrNotFound with the NotFound error of the repository
+*/
+func (r *Repository[T]) notFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return r.NotFound
+	}
+
+	return err
+}
+
+/*
+FindOne Return the first document matching the query. If fields are passed, they are used as a
+projection (see Projection)
+*/
+func (r *Repository[T]) FindOne(ctx context.Context, query bson.M, fields ...string) (*T, error) {
+	var result T
+
+	err := r.Database.Find(ctx, r.Collection, query, &result, fields...)
+	if err != nil {
+		return nil, r.notFound(err)
+	}
+
+	return &result, nil
+}
+
+/*
+FindMany Return every document matching the query. An empty slice is returned when nothing matches
+*/
+func (r *Repository[T]) FindMany(ctx context.Context, query bson.M, fields ...string) ([]*T, error) {
+	var result []*T
+
+	err := r.Database.FindMany(ctx, r.Collection, query, &result, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+FindManySorted Return every document matching the query, ordered by the sort fields (see SortBy). An empty
+slice is returned when nothing matches
+*/
+func (r *Repository[T]) FindManySorted(ctx context.Context, query bson.M, sort []string, fields ...string) ([]*T, error) {
+	var result []*T
+
+	err := r.Database.FindManySorted(ctx, r.Collection, query, sort, &result, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+FindManyLimited Return up to 'limit' documents matching the query, ordered by the sort fields (see SortBy). The
+limit is applied by the database. An empty slice is returned when nothing matches
+*/
+func (r *Repository[T]) FindManyLimited(ctx context.Context, query bson.M, sort []string, limit int64, fields ...string) ([]*T, error) {
+	var result []*T
+
+	err := r.Database.FindManyLimited(ctx, r.Collection, query, sort, limit, &result, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+FindStream Return an iterator over every document matching the query, decoded as they are read from the
+cursor, see Database.FindStream
+*/
+func (r *Repository[T]) FindStream(ctx context.Context, query bson.M, fields ...string) iter.Seq2[*T, error] {
+	return Decode[T](r.Database.FindStream(ctx, r.Collection, query, fields...))
+}
+
+/*
+Insert Insert the model passed in the parameter as a new document
+*/
+func (r *Repository[T]) Insert(ctx context.Context, model *T) error {
+	_, err := r.Database.Insert(ctx, r.Collection, model)
+
+	return err
+}
+
+/*
+Replace Replace the first document matching the query with the model passed in the parameter
+*/
+func (r *Repository[T]) Replace(ctx context.Context, query bson.M, model *T) error {
+	_, err := r.Database.Replace(ctx, r.Collection, query, model)
+
+	return r.notFound(err)
+}
+
+/*
+ReplaceVersion Replace the first document matching the query with the model passed, but only if the version
+field of the stored document still holds the expected value (see VersionField). Returns ErrConflict if the
+document exists but was modified by another writer, or the NotFound error of the repository if it does not exist
+*/
+func (r *Repository[T]) ReplaceVersion(ctx context.Context, query bson.M, field string, expected interface{}, model *T) error {
+	return r.ReplaceVersionWith(ctx, query, field, expected, model)
+}
+
+/*
+ReplaceVersionWith Identical to ReplaceVersion, however the replacement is the document passed rather than a model
+of the repository. This is intended for collections whose documents store fields that are not part of the model
+*/
+func (r *Repository[T]) ReplaceVersionWith(ctx context.Context, query bson.M, field string, expected interface{}, document interface{}) error {
+	versioned := bson.M{field: expected}
+	for key, value := range query {
+		versioned[key] = value
+	}
+
+	_, err := r.Database.Replace(ctx, r.Collection, versioned, document)
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	count, countErr := r.Database.Count(ctx, r.Collection, query)
+	if countErr != nil {
+		return countErr
+	}
+
+	if count != 0 {
+		return ErrConflict
+	}
+
+	return r.NotFound
+}
+
+/*
+Delete Remove the first document matching the query
+*/
+func (r *Repository[T]) Delete(ctx context.Context, query bson.M) error {
+	_, err := r.Database.Delete(ctx, r.Collection, query)
+
+	return r.notFound(err)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+transientErrorCodes Server error codes returned while a replica set is electing a new primary, or while a
+node is unreachable. Operations that fail with one of these can be retried once the election completes
+*/
+var transientErrorCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+/*
+RetryPolicy Controls how Database operations are retried when they fail with a transient error. The delay
+before each retry starts at InitialBackoff and doubles up to MaxBackoff, and Jitter is the fraction of the
+delay that is randomized (e.g. 0.2 for +/- 20%) so replicas don't retry in lockstep. A MaxAttempts of 1 or
+less disables retries
+*/
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Jitter         float64
+}
+
+/*
+DefaultRetryPolicy Rides out a typical replica set election, which completes in a few seconds
+*/
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Jitter:         0.2,
+}
+
+/*
+IsTransientError Returns true if the error passed is a network error, a timeout, or a "not primary" style
+server error that is expected to resolve itself after a failover
+*/
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+
+	var labeled mongo.LabeledError
+	if errors.As(err, &labeled) && (labeled.HasErrorLabel("RetryableWriteError") || labeled.HasErrorLabel("TransientTransactionError")) {
+		return true
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		for _, code := range transientErrorCodes {
+			if serverErr.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+backoff Return the delay before the retry following the attempt passed, starting at 1
+*/
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff << (attempt - 1)
+	if delay > p.MaxBackoff || delay <= 0 {
+		delay = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+
+	return delay
+}
+
+/*
+retry Call the function passed until it succeeds, returns an error that is not transient, or the attempts
+of the RetryPolicy are exhausted. Only idempotent operations should be retried, as a write that failed with
+a network error may still have been applied
+*/
+func (d *Database) retry(ctx context.Context, name string, collection string, fn func() error) error {
+	err := fn()
+
+	for attempt := 1; attempt < d.RetryPolicy.MaxAttempts && IsTransientError(err); attempt++ {
+		delay := d.RetryPolicy.backoff(attempt)
+		slog.Warn("Transient MongoDB error, retrying", "operation", name, "collection", collection, "attempt", attempt, "delay", delay, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		err = fn()
+	}
+
+	return err
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrInvalidFixture = errors.New("server: Operation failed. The fixture file is not a JSON array of documents, or a document is missing its seed key")
+
+/*
+SeedKeys The fields that uniquely identify a document in each collection that can be seeded. Seeding a
+document replaces any existing document with the same values for these fields, so re-running Seed with
+the same fixtures does not create duplicates
+*/
+var SeedKeys = map[string][]string{
+	"card": {"identifiers.mtgjsonV4Id"},
+	"set":  {"code", "mtgjsonApiMeta.owner"},
+	"deck": {"code", "mtgjsonApiMeta.owner"},
+	"user": {"email"},
+}
+
+/*
+fixtureFiles Return the fixture files found at the path passed in the parameter. If the path is a directory
+every .json file directly inside it is returned in name order
+*/
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(files)
+
+	return files, nil
+}
+
+/*
+seedFile Upsert every document in a single fixture file into the collection passed, and return the number
+of documents written
+*/
+func (d *Database) seedFile(ctx context.Context, collection string, file string) (int64, error) {
+	keys, ok := SeedKeys[collection]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s: no seed key is defined for the %s collection", ErrInvalidFixture, file, collection)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	var documents []json.RawMessage
+	err = json.Unmarshal(data, &documents)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", ErrInvalidFixture, file, err)
+	}
+
+	var count int64
+	for i, document := range documents {
+		var raw bson.Raw
+		err = bson.UnmarshalExtJSON(document, false, &raw)
+		if err != nil {
+			return count, fmt.Errorf("%w: %s: document %d: %w", ErrInvalidFixture, file, i, err)
+		}
+
+		query := bson.M{}
+		for _, key := range keys {
+			value := lookupKey(raw, key)
+			if value == nil {
+				return count, fmt.Errorf("%w: %s: document %d is missing %s", ErrInvalidFixture, file, i, key)
+			}
+
+			query[key] = value
+		}
+
+		_, err = d.Upsert(ctx, collection, query, raw)
+		if err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+/*
+Seed Load JSON fixture files into the database, for development environments and integration tests. The path
+may be a single file or a directory of files, and each file is loaded into the collection matching its name
+(e.g. card.json is loaded into the card collection). A file must contain a JSON array of documents, and
+MongoDB Extended JSON such as {"$date": ...} is accepted. Documents are upserted using SeedKeys, so Seed
+can safely be run again with the same fixtures. Returns the number of documents written to each collection
+*/
+func (d *Database) Seed(ctx context.Context, path string) (map[string]int64, error) {
+	files, err := fixtureFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]int64{}
+	for _, file := range files {
+		collection := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+
+		count, err := d.seedFile(ctx, collection, file)
+		ret[collection] += count
+		if err != nil {
+			slog.Error("Failed to seed collection", "collection", collection, "file", file, "err", err)
+			return ret, err
+		}
+
+		slog.Info("Seeded collection", "collection", collection, "file", file, "count", count)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stevezaluk/mtgjson-sdk/server/metrics"
+	"github.com/stevezaluk/mtgjson-sdk/server/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+REDACTED_VALUE Replaces every value in a query written to the slow query log
+*/
+const REDACTED_VALUE = "?"
+
+/*
+slowQueryCommands The commands that are timed by the slow query log and recorded in the database metrics.
+Handshakes, authentication and session management are not user operations, so they are ignored
+*/
+var slowQueryCommands = []string{"find", "getMore", "aggregate", "count", "distinct", "insert", "update", "delete", "findAndModify"}
+
+/*
+slowQueryFields The fields of a command that describe which documents it operates on
+*/
+var slowQueryFields = []string{"filter", "query", "pipeline", "updates", "deletes"}
+
+/*
+startedCommand A command that has been sent to MongoDB and has not completed yet
+*/
+type startedCommand struct {
+	name       string
+	collection string
+	query      bson.M
+	span       trace.Span
+}
+
+/*
+redact Replace every value in a query with REDACTED_VALUE, keeping field names and operators so that the shape
+of the query is still visible. This keeps user data such as emails out of the logs
+*/
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.M:
+		ret := bson.M{}
+		for key, element := range v {
+			ret[key] = redact(element)
+		}
+
+		return ret
+	case bson.D:
+		ret := bson.M{}
+		for _, element := range v {
+			ret[element.Key] = redact(element.Value)
+		}
+
+		return ret
+	case bson.A:
+		ret := bson.A{}
+		for _, element := range v {
+			ret = append(ret, redact(element))
+		}
+
+		return ret
+	}
+
+	return REDACTED_VALUE
+}
+
+/*
+RedactQuery Return a copy of the query with every value replaced, for writing to the logs
+*/
+func RedactQuery(query bson.M) bson.M {
+	return redact(query).(bson.M)
+}
+
+/*
+slowQueryMonitor Times every command sent by the client, records it in the database metrics (see the
+server/metrics package) and logs the ones that exceed the SlowQueryThreshold of the Database
+*/
+type slowQueryMonitor struct {
+	database *Database
+	started  sync.Map
+}
+
+/*
+start Record a command that has been sent, if it is one that is timed, and start a span for it as a child
+of any span in the context of the operation. The query of the command is only redacted and kept if the slow
+query log is enabled
+*/
+func (m *slowQueryMonitor) start(ctx context.Context, e *event.CommandStartedEvent) {
+	if !slices.Contains(slowQueryCommands, e.CommandName) {
+		return
+	}
+
+	command := &startedCommand{name: e.CommandName, query: bson.M{}}
+	if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+		command.collection = collection
+	} else if collection, ok := e.Command.Lookup("collection").StringValueOK(); ok {
+		command.collection = collection
+	}
+
+	command.collection = strings.TrimPrefix(command.collection, m.database.CollectionPrefix)
+
+	if m.database.SlowQueryThreshold > 0 {
+		for _, field := range slowQueryFields {
+			value, err := e.Command.LookupErr(field)
+			if err != nil {
+				continue
+			}
+
+			var decoded interface{}
+			if value.Unmarshal(&decoded) == nil {
+				command.query[field] = redact(decoded)
+			}
+		}
+	}
+
+	_, command.span = tracing.Start(ctx, "mongo."+command.name+" "+command.collection,
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.name", e.DatabaseName),
+		attribute.String("db.operation", command.name),
+		attribute.String("db.mongodb.collection", command.collection),
+	)
+
+	m.started.Store(e.RequestID, command)
+}
+
+/*
+finish End the span of the command, record it in the database metrics, and log it if it took longer than
+the threshold
+*/
+func (m *slowQueryMonitor) finish(requestId int64, elapsed time.Duration, failure string) {
+	value, ok := m.started.LoadAndDelete(requestId)
+	if !ok {
+		return
+	}
+
+	command := value.(*startedCommand)
+	metrics.ObserveOperation(command.collection, command.name, elapsed, failure != "")
+
+	var err error
+	if failure != "" {
+		err = errors.New(failure)
+	}
+
+	tracing.End(command.span, err)
+
+	if m.database.SlowQueryThreshold <= 0 || elapsed < m.database.SlowQueryThreshold {
+		return
+	}
+
+	attrs := []any{"command", command.name, "collection", command.collection, "query", command.query, "elapsed", elapsed, "threshold", m.database.SlowQueryThreshold}
+	if failure != "" {
+		attrs = append(attrs, "failure", failure)
+	}
+
+	slog.Warn("Slow MongoDB operation", attrs...)
+}
+
+/*
+monitor Return an event.CommandMonitor that feeds the slow query log, the database metrics and tracing
+*/
+func (m *slowQueryMonitor) monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: m.start,
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			m.finish(e.RequestID, e.Duration, "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			m.finish(e.RequestID, e.Duration, e.Failure)
+		},
+	}
+}
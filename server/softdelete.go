@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+SoftDeleteField The field set to the time a document was deleted when soft deletes are enabled. It is
+stored alongside the API metadata of the document, but is not part of the MtgjsonApiMeta model
+*/
+const SoftDeleteField = "mtgjsonApiMeta.deletedAt"
+
+var ErrDeleted = errors.New("server: Operation failed. The document matching the query was soft deleted, restore or purge it first")
+
+/*
+softDeleteCollections The collections that are soft deleted when Database.SoftDelete is enabled. Every other
+collection is always hard deleted
+*/
+var softDeleteCollections = []string{"card", "card_extra", "deck", "set"}
+
+/*
+softDeletes Returns true if documents in the collection passed are soft deleted
+*/
+func (d *Database) softDeletes(collection string) bool {
+	return d.SoftDelete && slices.Contains(softDeleteCollections, collection)
+}
+
+/*
+excludeDeleted Return a copy of the query that does not match soft deleted documents. The query is returned
+unmodified if the collection is not soft deleted, or if the query already filters on SoftDeleteField, which
+allows deleted documents to be looked up explicitly
+*/
+func (d *Database) excludeDeleted(collection string, query bson.M) bson.M {
+	if !d.softDeletes(collection) {
+		return query
+	}
+
+	return withoutDeleted(query)
+}
+
+/*
+withoutDeleted Return a copy of the query that does not match soft deleted documents, unless it already filters
+on SoftDeleteField. This is shared by both implementations of DatabaseInterface, see Database.excludeDeleted
+*/
+func withoutDeleted(query bson.M) bson.M {
+	if _, ok := query[SoftDeleteField]; ok {
+		return query
+	}
+
+	ret := bson.M{SoftDeleteField: bson.M{"$exists": false}}
+	for key, value := range query {
+		ret[key] = value
+	}
+
+	return ret
+}
+
+/*
+onlyDeleted Return a copy of the query that only matches soft deleted documents
+*/
+func onlyDeleted(query bson.M) bson.M {
+	ret := bson.M{SoftDeleteField: bson.M{"$exists": true}}
+	for key, value := range query {
+		ret[key] = value
+	}
+
+	return ret
+}
+
+/*
+rejectDeleted Returns ErrDeleted if a soft deleted document matches the query and no other document does. Upserts
+are guarded with this, as they would otherwise insert a second document that collides with the deleted one once
+it is restored
+*/
+func (d *Database) rejectDeleted(ctx context.Context, operation string, collection string, query bson.M) error {
+	if !d.softDeletes(collection) {
+		return nil
+	}
+
+	live, err := d.Count(ctx, collection, query)
+	if err != nil || live != 0 {
+		return err
+	}
+
+	deleted, err := d.Count(ctx, collection, onlyDeleted(query))
+	if err != nil {
+		return err
+	}
+
+	if deleted != 0 {
+		return fmt.Errorf("server: %s on %s: %w", operation, collection, ErrDeleted)
+	}
+
+	return nil
+}
+
+/*
+markDeleted Set the SoftDeleteField of the first document matching the query that has not already been
+deleted. Returns ErrNotFound if no document matches
+*/
+func (d *Database) markDeleted(ctx context.Context, collection string, query bson.M) (*mongo.UpdateResult, error) {
+	result, err := d.update(ctx, "SoftDelete", "$set", collection, query, bson.M{SoftDeleteField: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount < 1 {
+		return result, wrapError("SoftDelete", collection, mongo.ErrNoDocuments)
+	}
+
+	return result, nil
+}
+
+/*
+Restore Remove the SoftDeleteField from the first soft deleted document matching the query, making it visible
+to queries again. Returns ErrNotFound if no deleted document matches
+*/
+func (d *Database) Restore(ctx context.Context, collection string, query bson.M) (*mongo.UpdateResult, error) {
+	result, err := d.update(ctx, "Restore", "$unset", collection, onlyDeleted(query), bson.M{SoftDeleteField: ""})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount < 1 {
+		return result, wrapError("Restore", collection, mongo.ErrNoDocuments)
+	}
+
+	return result, nil
+}
+
+/*
+PurgeDeleted Permanently remove every document in the collection that was soft deleted before the time
+passed in the parameter. Returns the number of documents removed
+*/
+func (d *Database) PurgeDeleted(ctx context.Context, collection string, before time.Time) (int64, error) {
+	coll := d.collection(collection)
+	query := bson.M{SoftDeleteField: bson.M{"$lte": before.UTC()}}
+
+	slog.Debug("PurgeDeleted Query", "collection", collection, "before", before)
+	var result *mongo.DeleteResult
+	err := d.retry(ctx, "PurgeDeleted", collection, func() (err error) {
+		result, err = coll.DeleteMany(ctx, query, options.Delete().SetComment(d.comment(ctx)))
+		return err
+	})
+	if err != nil {
+		slog.Error("Error during PurgeDeleted Query", "collection", collection, "before", before, "err", err)
+		return 0, wrapError("PurgeDeleted", collection, err)
+	}
+
+	slog.Info("Purged soft deleted documents", "collection", collection, "count", result.DeletedCount)
+
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+SortBy Build a sort document from a list of field names. Fields are sorted in ascending order by default, and
+a field prefixed with '-' is sorted in descending order instead (e.g. "-mtgjsonApiMeta.modifiedDate"). Later
+fields break ties between documents with equal values for earlier ones. Returns nil if no fields are passed,
+which leaves the documents in their natural order
+*/
+func SortBy(fields ...string) bson.D {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	ret := bson.D{}
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") {
+			ret = append(ret, bson.E{Key: strings.TrimPrefix(field, "-"), Value: -1})
+			continue
+		}
+
+		ret = append(ret, bson.E{Key: field, Value: 1})
+	}
+
+	return ret
+}
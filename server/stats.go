@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+CollectionStats The storage statistics of a single collection, as reported by the collStats command.
+All sizes are in bytes
+*/
+type CollectionStats struct {
+	Collection     string `json:"collection"`
+	Count          int64  `json:"count"`
+	Size           int64  `json:"size"`
+	StorageSize    int64  `json:"storageSize"`
+	TotalIndexSize int64  `json:"totalIndexSize"`
+}
+
+/*
+Count Return the number of documents in the collection that match the query passed in the parameter
+*/
+func (d *Database) Count(ctx context.Context, collection string, query bson.M) (int64, error) {
+	coll := d.collection(collection)
+	query = d.excludeDeleted(collection, query)
+
+	slog.Debug("CountDocuments Query", "collection", collection, "query", query)
+	var count int64
+	err := d.retry(ctx, "CountDocuments", collection, func() (err error) {
+		count, err = coll.CountDocuments(ctx, query, options.Count().SetComment(d.comment(ctx)))
+		return err
+	})
+	if err != nil {
+		slog.Error("Error during CountDocuments Query", "collection", collection, "query", query, "err", err)
+		return 0, wrapError("CountDocuments", collection, err)
+	}
+
+	return count, nil
+}
+
+/*
+EstimatedCount Return the approximate number of documents in the collection using its metadata. This does
+not scan the collection and is much faster than Count on large collections, but it cannot be filtered and
+may be inaccurate after an unclean shutdown
+*/
+func (d *Database) EstimatedCount(ctx context.Context, collection string) (int64, error) {
+	coll := d.collection(collection)
+
+	slog.Debug("EstimatedDocumentCount Query", "collection", collection)
+	var count int64
+	err := d.retry(ctx, "EstimatedDocumentCount", collection, func() (err error) {
+		count, err = coll.EstimatedDocumentCount(ctx, options.EstimatedDocumentCount().SetComment(d.comment(ctx)))
+		return err
+	})
+	if err != nil {
+		slog.Error("Error during EstimatedDocumentCount Query", "collection", collection, "err", err)
+		return 0, wrapError("EstimatedDocumentCount", collection, err)
+	}
+
+	return count, nil
+}
+
+/*
+Stats Return the storage statistics of the collection passed in the parameter
+*/
+func (d *Database) Stats(ctx context.Context, collection string) (*CollectionStats, error) {
+	var raw bson.M
+
+	err := d.Database.RunCommand(ctx, bson.D{{Key: "collStats", Value: d.CollectionName(collection)}}).Decode(&raw)
+	if err != nil {
+		slog.Error("Error during collStats command", "collection", collection, "err", err)
+		return nil, wrapError("collStats", collection, err)
+	}
+
+	return &CollectionStats{
+		Collection:     collection,
+		Count:          toInt64(raw["count"]),
+		Size:           toInt64(raw["size"]),
+		StorageSize:    toInt64(raw["storageSize"]),
+		TotalIndexSize: toInt64(raw["totalIndexSize"]),
+	}, nil
+}
+
+/*
+ListCollections Return the names of every collection in the database. If a CollectionPrefix is set, only
+the collections with the prefix are returned, and the prefix is removed from their names
+*/
+func (d *Database) ListCollections(ctx context.Context) ([]string, error) {
+	filter := bson.M{}
+	if d.CollectionPrefix != "" {
+		filter = bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(d.CollectionPrefix)}}
+	}
+
+	names, err := d.Database.ListCollectionNames(ctx, filter)
+	if err != nil {
+		slog.Error("Error listing collections", "err", err)
+		return nil, fmt.Errorf("server: listing collections failed: %w", err)
+	}
+
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, d.CollectionPrefix)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"iter"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	STREAM_BATCH_SIZE = 500
+)
+
+/*
+FindStream Return an iterator over every document matching the query passed in the 'query' parameter. Unlike
+FindMany, documents are read from the cursor in batches of STREAM_BATCH_SIZE as the iterator is consumed rather
+than all being decoded up front, so collections of any size can be processed with constant memory. The raw
+document is only valid until the next iteration, so decode it (see Decode) or copy it before then. A failure
+is yielded as the final error of the iterator, and breaking out of the loop closes the cursor. If projection
+fields are passed only those fields are returned, see Projection
+*/
+func (d *Database) FindStream(ctx context.Context, collection string, query bson.M, projection ...string) iter.Seq2[bson.Raw, error] {
+	coll := d.collection(collection)
+	query = d.excludeDeleted(collection, query)
+
+	return func(yield func(bson.Raw, error) bool) {
+		slog.Debug("FindStream Query", "collection", collection, "query", query)
+		opts := options.Find().SetBatchSize(STREAM_BATCH_SIZE).SetComment(d.comment(ctx))
+		if len(projection) != 0 {
+			opts.SetProjection(Projection(projection...))
+		}
+
+		var cur *mongo.Cursor
+		err := d.retry(ctx, "FindStream", collection, func() error {
+			var err error
+			cur, err = coll.Find(ctx, query, opts)
+
+			return err
+		})
+		if err != nil {
+			slog.Error("Error during FindStream Query", "collection", collection, "query", query, "err", err)
+			yield(nil, wrapError("FindStream", collection, err))
+			return
+		}
+		defer cur.Close(ctx)
+
+		for cur.Next(ctx) {
+			if !yield(cur.Current, nil) {
+				return
+			}
+		}
+
+		if cur.Err() != nil {
+			slog.Error("Error during FindStream Query", "collection", collection, "query", query, "err", cur.Err())
+			yield(nil, wrapError("FindStream", collection, cur.Err()))
+		}
+	}
+}
+
+/*
+Decode Wrap an iterator returned by FindStream so that each document is unmarshalled into a new T. Iteration
+stops at the first document that cannot be decoded, yielding its error
+*/
+func Decode[T any](documents iter.Seq2[bson.Raw, error]) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		for document, err := range documents {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			var model T
+			err = bson.Unmarshal(document, &model)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(&model, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+var ErrInvalidCertificate = errors.New("server: Operation failed. The CA certificate file does not contain any valid PEM certificates")
+
+/*
+TLSOptions Controls the TLS configuration of the MongoDB connection. CAFile is a PEM file of certificate
+authorities used to verify the server, and CertFile/KeyFile are the client certificate used for x.509
+authentication. If KeyFile is empty, the key is read from CertFile, matching the combined PEM file used
+by mongosh. InsecureSkipVerify disables verification of the server certificate, and should only be used
+for testing
+*/
+type TLSOptions struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+/*
+Config Build a tls.Config from the options. Returns nil if TLS is not enabled
+*/
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	if o == nil || !o.Enabled {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.InsecureSkipVerify {
+		slog.Warn("MongoDB TLS certificate verification is disabled")
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("server: failed to read CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidCertificate
+		}
+
+		config.RootCAs = pool
+	}
+
+	if o.CertFile != "" {
+		keyFile := o.KeyFile
+		if keyFile == "" {
+			keyFile = o.CertFile
+		}
+
+		cert, err := tls.LoadX509KeyPair(o.CertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("server: failed to load client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
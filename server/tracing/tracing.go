@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TRACER_NAME = "github.com/stevezaluk/mtgjson-sdk"
+)
+
+/*
+Tracer Return the OpenTelemetry tracer used by the SDK. Spans are sent to the global tracer provider, so
+nothing is recorded until the hosting API registers one with otel.SetTracerProvider
+*/
+func Tracer() trace.Tracer {
+	return otel.Tracer(TRACER_NAME)
+}
+
+/*
+Start Start a span with the name passed as a child of any span in the context, and return a context holding
+the new span. Pass the returned context to the Database operations made within the span, so that their spans
+are recorded as its children. Intended to be followed by a deferred call to End
+*/
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+/*
+End Finish the span passed, recording the error on the span and marking it as failed if the error is
+not nil
+*/
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+/*
+WithTransaction Run the function passed in the 'fn' parameter inside a multi-document transaction. Every
+Database operation that is passed the context given to fn takes part in the transaction, which is committed
+if fn returns nil and aborted otherwise. Transient transaction errors are retried by the driver, so fn may be
+called more than once. Transactions require MongoDB to be running as a replica set, so when Transactions is
+false on the Database, fn is called directly without a session
+*/
+func (d *Database) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !d.Transactions {
+		return fn(ctx)
+	}
+
+	session, err := d.Client.StartSession()
+	if err != nil {
+		slog.Error("Failed to start MongoDB session", "err", err)
+		return fmt.Errorf("server: failed to start session: %w", err)
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	if err != nil {
+		slog.Error("Transaction aborted", "err", err)
+		return err
+	}
+
+	return nil
+}
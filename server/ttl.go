@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrNotEphemeral = errors.New("server: Operation failed. The collection has not been registered as an ephemeral collection")
+
+/*
+TTLIndex The date field of an ephemeral collection that MongoDB uses to expire its documents. A document is
+removed once ExpireAfter has passed since the time stored in Field. An ExpireAfter of 0 treats Field as the
+time the document expires at, which allows every document to have its own lifetime
+*/
+type TTLIndex struct {
+	Field       string
+	ExpireAfter time.Duration
+}
+
+/*
+EphemeralCollections Collections whose documents are removed automatically by a TTL index, keyed by collection
+name. Their TTL indexes are built by EnsureIndexes along with the RequiredIndexes. MongoDB removes expired
+documents in a background task that runs once a minute, so expired documents may still be returned for a short
+time after they expire, see Unexpired
+*/
+var EphemeralCollections = map[string]TTLIndex{
+	"lock": {Field: "expiresAt"},
+}
+
+/*
+RegisterEphemeralCollection Register a collection (e.g. password reset attempts, share links or rate limit
+buckets) whose documents should expire using the date field passed, see TTLIndex. Registering a collection
+that is already registered replaces its TTL index the next time EnsureIndexes or EnsureTTLIndex is called
+*/
+func RegisterEphemeralCollection(collection string, field string, expireAfter time.Duration) {
+	EphemeralCollections[collection] = TTLIndex{Field: field, ExpireAfter: expireAfter}
+}
+
+/*
+EnsureTTLIndex Create the TTL index of an ephemeral collection if it does not exist yet. If an index already
+exists on the field with a different expiry, it is updated in place with collMod rather than being rebuilt.
+Returns true if the index was created or updated, or ErrNotEphemeral if the collection is not registered
+*/
+func (d *Database) EnsureTTLIndex(ctx context.Context, collection string) (bool, error) {
+	ttl, ok := EphemeralCollections[collection]
+	if !ok {
+		return false, ErrNotEphemeral
+	}
+
+	coll := d.collection(collection)
+	seconds := int32(ttl.ExpireAfter.Seconds())
+
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	var commandErr mongo.CommandError
+	if err != nil && !(errors.As(err, &commandErr) && commandErr.Code == namespaceNotFoundCode) {
+		slog.Error("Error listing indexes", "collection", collection, "err", err)
+		return false, wrapError("ListIndexes", collection, err)
+	}
+
+	for _, spec := range specs {
+		elements, err := spec.KeysDocument.Elements()
+		if err != nil || len(elements) != 1 || elements[0].Key() != ttl.Field {
+			continue
+		}
+
+		if spec.ExpireAfterSeconds != nil && *spec.ExpireAfterSeconds == seconds {
+			return false, nil
+		}
+
+		command := bson.D{
+			{Key: "collMod", Value: d.CollectionName(collection)},
+			{Key: "index", Value: bson.D{{Key: "name", Value: spec.Name}, {Key: "expireAfterSeconds", Value: seconds}}},
+		}
+
+		err = d.Database.RunCommand(ctx, command).Err()
+		if err != nil {
+			slog.Error("Error updating TTL index", "collection", collection, "field", ttl.Field, "err", err)
+			return false, wrapError("CollMod", collection, err)
+		}
+
+		slog.Info("Updated TTL index", "collection", collection, "field", ttl.Field, "expireAfter", ttl.ExpireAfter)
+
+		return true, nil
+	}
+
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: ttl.Field, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(seconds),
+	}
+
+	err = d.createIndex(ctx, collection, model)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+/*
+InsertWithTTL Insert the model passed into an ephemeral collection, setting its TTL field so that it expires
+once the duration passed has elapsed. The collection must be registered with an ExpireAfter of 0, as the
+field holds the time the document expires at. Returns ErrNotEphemeral if the collection is not registered
+*/
+func (d *Database) InsertWithTTL(ctx context.Context, collection string, model interface{}, ttl time.Duration) (*mongo.InsertOneResult, error) {
+	index, ok := EphemeralCollections[collection]
+	if !ok {
+		return nil, ErrNotEphemeral
+	}
+
+	document, err := toDocument(model)
+	if err != nil {
+		return nil, err
+	}
+
+	setPath(document, strings.Split(index.Field, "."), time.Now().Add(ttl))
+
+	return d.Insert(ctx, collection, document)
+}
+
+/*
+Unexpired Return a copy of the query that only matches documents of an ephemeral collection that have not
+expired yet. The query is returned unchanged if the collection is not registered
+*/
+func Unexpired(collection string, query bson.M) bson.M {
+	index, ok := EphemeralCollections[collection]
+	if !ok {
+		return query
+	}
+
+	ret := bson.M{}
+	for key, value := range query {
+		ret[key] = value
+	}
+
+	ret[index.Field] = bson.M{"$gt": time.Now().Add(-index.ExpireAfter)}
+
+	return ret
+}
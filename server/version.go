@@ -0,0 +1,20 @@
+package server
+
+import (
+	"errors"
+)
+
+/*
+VersionField The field used as the revision of a document for optimistic concurrency control. The models of
+mtgjson-models have no revision field, so the modified date of the API metadata is used as the revision token
+instead: it is rewritten by every write made through the SDK, and it travels with the model that the caller read.
+
+The token is only ever compared for equality, never ordered, so clock skew between hosts cannot cause a missed
+conflict. Its limits are that the modified date comes from util.CreateTimestampStr, which includes the monotonic
+clock reading of the process that wrote it, so it is not a portable timestamp, and that two writes are only told
+apart while their timestamps differ, which clocks coarser than a nanosecond do not guarantee. Writes made directly
+to MongoDB that do not update the modified date are not detected
+*/
+const VersionField = "mtgjsonApiMeta.modifiedDate"
+
+var ErrConflict = errors.New("server: Operation failed. The document was modified by another writer after it was read")
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ChangeInsert  = "insert"
+	ChangeUpdate  = "update"
+	ChangeReplace = "replace"
+	ChangeDelete  = "delete"
+
+	DEFAULT_WATCH_BUFFER = 64
+)
+
+/*
+ChangeEvent A single change reported by a change stream. FullDocument holds the document after the change
+for inserts, updates and replaces, and is empty for deletes. If the change stream fails, a final event is
+sent with Err set before the channel is closed
+*/
+type ChangeEvent struct {
+	OperationType string              `bson:"operationType"`
+	Namespace     ChangeNamespace     `bson:"ns"`
+	DocumentKey   bson.M              `bson:"documentKey"`
+	FullDocument  bson.Raw            `bson:"fullDocument"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	Err           error               `bson:"-"`
+}
+
+/*
+ChangeNamespace The database and collection a change event occurred in
+*/
+type ChangeNamespace struct {
+	Database   string `bson:"db"`
+	Collection string `bson:"coll"`
+}
+
+/*
+Decode Unmarshal the full document of the change into the interface passed in the 'model' parameter
+*/
+func (e *ChangeEvent) Decode(model interface{}) error {
+	if len(e.FullDocument) == 0 {
+		return ErrNotFound
+	}
+
+	return bson.Unmarshal(e.FullDocument, model)
+}
+
+/*
+MatchOperations Return a change stream pipeline that only passes the operation types passed, for example
+ChangeInsert and ChangeDelete
+*/
+func MatchOperations(operations ...string) mongo.Pipeline {
+	return mongo.Pipeline{bson.D{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": operations}}}}}
+}
+
+/*
+Watch Open a change stream on the collection passed and return a channel of its change events. The pipeline
+filters or reshapes the events, and may be nil to receive every change. The full document is looked up for
+updates, so card, deck and set modifications can be acted on without another query. The channel is closed
+when the context is cancelled or the change stream fails. Change streams require MongoDB to be running as a
+replica set
+*/
+func (d *Database) Watch(ctx context.Context, collection string, pipeline mongo.Pipeline) (<-chan *ChangeEvent, error) {
+	coll := d.collection(collection)
+
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+
+	slog.Debug("Watch Collection", "collection", collection, "pipeline", pipeline)
+	stream, err := coll.Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup).SetComment(d.comment(ctx)))
+	if err != nil {
+		slog.Error("Error opening change stream", "collection", collection, "err", err)
+		return nil, wrapError("Watch", collection, err)
+	}
+
+	events := make(chan *ChangeEvent, DEFAULT_WATCH_BUFFER)
+
+	go func() {
+		defer close(events)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			event := &ChangeEvent{}
+			err := stream.Decode(event)
+			if err != nil {
+				slog.Error("Error decoding change event", "collection", collection, "err", err)
+				continue
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if stream.Err() != nil && ctx.Err() == nil {
+			slog.Error("Change stream failed", "collection", collection, "err", stream.Err())
+
+			select {
+			case events <- &ChangeEvent{Err: wrapError("Watch", collection, stream.Err())}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,82 @@
+package set
+
+import (
+	stdContext "context"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/set"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+NewSets Insert a batch of sets into the MongoDB database using a single InsertMany call. Every set is
+validated the same way as NewSet, and the batch is rejected if any set is missing its name or code, is
+duplicated within the batch, or already exists for the owner
+*/
+func NewSets(ctx stdContext.Context, sets []*set.Set, owner string) error {
+	if len(sets) == 0 {
+		return nil
+	}
+
+	if owner == "" {
+		owner = user.SystemUser
+	}
+
+	if owner != user.SystemUser {
+		_, err := user.GetUser(ctx, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	var codes []string
+	seen := map[string]bool{}
+	for _, value := range sets {
+		if value.Name == "" || value.Code == "" {
+			return sdkErrors.ErrSetMissingId
+		}
+
+		if seen[value.Code] {
+			return sdkErrors.ErrSetAlreadyExists
+		}
+
+		seen[value.Code] = true
+		codes = append(codes, value.Code)
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	var existing []*set.Set
+	err = database.FindMany(ctx, "set", bson.M{"code": bson.M{"$in": codes}, "mtgjsonApiMeta.owner": owner}, &existing)
+	if err != nil {
+		return err
+	}
+
+	if len(existing) != 0 {
+		return sdkErrors.ErrSetAlreadyExists
+	}
+
+	models := make([]interface{}, 0, len(sets))
+	for _, value := range sets {
+		prepareSet(value, owner)
+		models = append(models, value)
+	}
+
+	_, err = database.InsertMany(ctx, "set", models)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range sets {
+		err = assignSlug(ctx, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
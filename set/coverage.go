@@ -0,0 +1,105 @@
+package set
+
+import (
+	"context"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-sdk/upstream"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+)
+
+/*
+SetCoverage Compares the number of cards stored locally for a set against the total set size
+reported by the upstream MTGJSON source
+*/
+type SetCoverage struct {
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	LocalCards    int64  `json:"localCards"`
+	UpstreamCards int64  `json:"upstreamCards"`
+	Imported      bool   `json:"imported"`
+	Complete      bool   `json:"complete"`
+}
+
+/*
+CoverageReport A summary of how complete the local catalog is compared to the upstream MTGJSON
+source. MissingSets contains the codes of sets that have not been imported at all, while IncompleteSets
+contains sets that were imported but are missing cards
+*/
+type CoverageReport struct {
+	UpstreamVersion string         `json:"upstreamVersion"`
+	UpstreamDate    string         `json:"upstreamDate"`
+	LocalSets       int64          `json:"localSets"`
+	UpstreamSets    int64          `json:"upstreamSets"`
+	LocalCards      int64          `json:"localCards"`
+	UpstreamCards   int64          `json:"upstreamCards"`
+	MissingSets     []string       `json:"missingSets"`
+	IncompleteSets  []*SetCoverage `json:"incompleteSets"`
+	Sets            []*SetCoverage `json:"sets"`
+}
+
+/*
+GetCoverage Compare the local set catalog against SetList.json from the upstream MTGJSON source and
+return a coverage report. Only sets owned by the system user are considered, as user created sets
+do not exist upstream
+*/
+func GetCoverage(ctx context.Context) (*CoverageReport, error) {
+	setList, meta, err := upstream.FetchSetList()
+	if err != nil {
+		return nil, err
+	}
+
+	localSets, err := IndexSets(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	local := map[string]int64{}
+	for _, value := range localSets {
+		if value.MtgjsonApiMeta != nil && value.MtgjsonApiMeta.Owner != user.SystemUser {
+			continue
+		}
+
+		local[value.Code] = int64(len(value.ContentIds))
+	}
+
+	report := &CoverageReport{
+		LocalSets:      int64(len(local)),
+		UpstreamSets:   int64(len(setList)),
+		MissingSets:    []string{},
+		IncompleteSets: []*SetCoverage{},
+		Sets:           []*SetCoverage{},
+	}
+
+	if meta != nil {
+		report.UpstreamVersion = meta.Version
+		report.UpstreamDate = meta.Date
+	}
+
+	for _, entry := range setList {
+		localCards, imported := local[entry.Code]
+
+		coverage := &SetCoverage{
+			Code:          entry.Code,
+			Name:          entry.Name,
+			LocalCards:    localCards,
+			UpstreamCards: entry.TotalSetSize,
+			Imported:      imported,
+			Complete:      imported && localCards >= entry.TotalSetSize,
+		}
+
+		report.LocalCards += localCards
+		report.UpstreamCards += entry.TotalSetSize
+		report.Sets = append(report.Sets, coverage)
+
+		if !imported {
+			report.MissingSets = append(report.MissingSets, entry.Code)
+		} else if !coverage.Complete {
+			report.IncompleteSets = append(report.IncompleteSets, coverage)
+		}
+	}
+
+	slices.Sort(report.MissingSets)
+
+	return report, nil
+}
@@ -0,0 +1,264 @@
+package set
+
+import (
+	stdContext "context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/set"
+	"github.com/stevezaluk/mtgjson-sdk/card"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/metrics"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/upstream"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const importStatusId = "mtgjson"
+
+var ErrNoImportStatus = errors.New("set: Operation failed. No data has been imported from the upstream MTGJSON source")
+
+/*
+ImportReport A summary of an import from the upstream MTGJSON source. Failed contains the codes of
+any sets that could not be imported
+*/
+type ImportReport struct {
+	Version  string   `json:"version"`
+	Imported int64    `json:"imported"`
+	Skipped  int64    `json:"skipped"`
+	Failed   []string `json:"failed"`
+}
+
+/*
+ImportStatus The version of the upstream MTGJSON data that was most recently imported, and when
+it was imported
+*/
+type ImportStatus struct {
+	Id         string `json:"-" bson:"_id"`
+	Version    string `json:"version" bson:"version"`
+	ImportedAt string `json:"importedAt" bson:"importedAt"`
+}
+
+/*
+GetImportStatus Return the version of the most recently imported upstream data. Returns ErrNoImportStatus
+if nothing has been imported yet
+*/
+func GetImportStatus(ctx stdContext.Context) (*ImportStatus, error) {
+	var ret *ImportStatus
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "import_status", bson.M{"_id": importStatusId}, &ret)
+	if errors.Is(err, server.ErrNotFound) {
+		return nil, ErrNoImportStatus
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+/*
+recordImport Store the version of the upstream data that was just imported. Failures are logged but
+not returned, as they should not fail the import itself
+*/
+func recordImport(ctx stdContext.Context, version string) {
+	if version == "" {
+		return
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return
+	}
+
+	status := &ImportStatus{Id: importStatusId, Version: version, ImportedAt: util.CreateTimestampStr()}
+
+	_, err = database.Upsert(ctx, "import_status", bson.M{"_id": importStatusId}, status)
+	if err != nil {
+		slog.Error("Failed to record import status", "version", version, "err", err)
+	}
+}
+
+/*
+ImportSetList Import the metadata of every set in SetList.json from the upstream MTGJSON source, without
+importing any cards. This allows a new deployment to browse sets immediately, with the contents of each
+set imported separately. Sets that already exist are skipped
+*/
+func ImportSetList(ctx stdContext.Context) (*ImportReport, error) {
+	setList, meta, err := upstream.FetchSetList()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Failed: []string{}}
+	if meta != nil {
+		report.Version = meta.Version
+	}
+
+	for _, entry := range setList {
+		newSet := &set.Set{
+			Code:             entry.Code,
+			Name:             entry.Name,
+			Type:             entry.Type,
+			ReleaseDate:      entry.ReleaseDate,
+			Block:            entry.Block,
+			ParentCode:       entry.ParentCode,
+			KeyruneCode:      entry.KeyruneCode,
+			BaseSetSize:      entry.BaseSetSize,
+			IsOnlineOnly:     entry.IsOnlineOnly,
+			IsFoilOnly:       entry.IsFoilOnly,
+			IsPartialPreview: entry.IsPartialPreview,
+		}
+
+		err = NewSet(ctx, newSet, "")
+		if errors.Is(err, sdkErrors.ErrSetAlreadyExists) {
+			report.Skipped++
+			continue
+		}
+
+		if err != nil {
+			slog.Error("Failed to import set from SetList", "code", entry.Code, "err", err)
+			report.Failed = append(report.Failed, entry.Code)
+			continue
+		}
+
+		report.Imported++
+	}
+
+	recordImport(ctx, report.Version)
+	slog.Info("Finished importing SetList", "version", report.Version, "imported", report.Imported, "skipped", report.Skipped, "failed", len(report.Failed))
+
+	return report, nil
+}
+
+/*
+ImportSetCards Fetch the set file for the set code passed in the parameter from the upstream MTGJSON source
+and import each of its cards. If the set does not exist yet it will be created, otherwise any newly imported
+cards are appended to its contentIds. Cards that already exist are skipped, so this is safe to re-run when
+a set receives new cards. The Failed field of the report contains the UUID's of any cards that could not be
+imported
+*/
+func ImportSetCards(ctx stdContext.Context, code string) (*ImportReport, error) {
+	return importSetCards(ctx, code, 0, nil)
+}
+
+/*
+importSetCards Import the cards of a set starting from the card at the offset passed. Cards before the
+offset are assumed to have been imported by a previous run, and are only added to the contentIds of the
+set. The checkpoint function, if not nil, is called with the offset of the next card after every batch
+of cards is imported, and when the context is cancelled. The import is aborted if it returns an error
+*/
+func importSetCards(ctx stdContext.Context, code string, offset int, checkpoint func(offset int) error) (*ImportReport, error) {
+	setFile, meta, err := upstream.FetchSet(code)
+	if err != nil {
+		return nil, err
+	}
+
+	defer metrics.Since(metrics.ImportSet, time.Now())
+
+	report := &ImportReport{Failed: []string{}}
+	if meta != nil {
+		report.Version = meta.Version
+	}
+
+	batchSize := importBatchSize()
+
+	var cardIds []string
+	for index, value := range setFile.Cards {
+		if index < offset {
+			if value.Identifiers != nil {
+				cardIds = append(cardIds, value.Identifiers.MtgjsonV4Id)
+			}
+
+			continue
+		}
+
+		if ctx.Err() != nil {
+			if checkpoint != nil {
+				checkpoint(index)
+			}
+
+			return report, ctx.Err()
+		}
+
+		if checkpoint != nil && index != offset && index%batchSize == 0 {
+			err = checkpoint(index)
+			if err != nil {
+				return report, err
+			}
+		}
+
+		err = card.NewCard(ctx, value, "")
+		if err != nil && !errors.Is(err, sdkErrors.ErrCardAlreadyExist) {
+			slog.Error("Failed to import card", "set", setFile.Code, "name", value.Name, "err", err)
+			metrics.Add(metrics.ImportCardsFailed, 1)
+			if value.Identifiers != nil {
+				report.Failed = append(report.Failed, value.Identifiers.MtgjsonV4Id)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			report.Skipped++
+			metrics.Add(metrics.ImportCardsSkipped, 1)
+		} else {
+			report.Imported++
+			metrics.Add(metrics.ImportCardsImported, 1)
+		}
+
+		cardIds = append(cardIds, value.Identifiers.MtgjsonV4Id)
+	}
+
+	existing, err := GetSet(ctx, setFile.Code, user.SystemUser)
+	if errors.Is(err, sdkErrors.ErrNoSet) {
+		newSet := &set.Set{
+			Code:        setFile.Code,
+			Name:        setFile.Name,
+			Type:        setFile.Type,
+			ReleaseDate: setFile.ReleaseDate,
+			BaseSetSize: setFile.BaseSetSize,
+			ContentIds:  cardIds,
+		}
+
+		err = NewSet(ctx, newSet, "")
+		if err != nil {
+			return report, err
+		}
+
+		recordImport(ctx, report.Version)
+
+		return report, nil
+	}
+
+	if err != nil {
+		return report, err
+	}
+
+	var newCards []string
+	for _, uuid := range cardIds {
+		if !slices.Contains(existing.ContentIds, uuid) {
+			newCards = append(newCards, uuid)
+		}
+	}
+
+	err = AddCards(ctx, existing, newCards)
+	if err != nil {
+		return report, err
+	}
+
+	recordImport(ctx, report.Version)
+	slog.Info("Finished importing set cards", "set", setFile.Code, "imported", report.Imported, "skipped", report.Skipped, "failed", len(report.Failed))
+
+	return report, nil
+}
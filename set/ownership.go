@@ -0,0 +1,110 @@
+package set
+
+import (
+	"cmp"
+	stdContext "context"
+	"slices"
+
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+	"go.mongodb.org/mongo-driver/bson"
+
+	userModel "github.com/stevezaluk/mtgjson-models/user"
+)
+
+/*
+CardOwnership The number of users that own a single card, and the total number of copies they own
+*/
+type CardOwnership struct {
+	CardId string `json:"cardId"`
+	Owners int64  `json:"owners"`
+	Copies int64  `json:"copies"`
+}
+
+/*
+OwnershipBreakdown A summary of how the cards of a set are owned across every user. Cards is sorted
+by the number of copies owned, and cards that nobody owns are omitted
+*/
+type OwnershipBreakdown struct {
+	Code        string           `json:"code"`
+	Owners      int64            `json:"owners"`
+	TotalCopies int64            `json:"totalCopies"`
+	Cards       []*CardOwnership `json:"cards"`
+}
+
+/*
+GetOwnershipBreakdown Return how many users own cards from the system owned set passed in the code
+parameter, along with the number of owners and copies of each card. Returns ErrNoSet if the set
+does not exist
+*/
+func GetOwnershipBreakdown(ctx stdContext.Context, code string) (*OwnershipBreakdown, error) {
+	var users []*userModel.User
+
+	result, err := GetSet(ctx, code, user.SystemUser)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &OwnershipBreakdown{Code: result.Code, Cards: []*CardOwnership{}}
+	if len(result.ContentIds) == 0 {
+		return ret, nil
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.FindMany(ctx, "user", bson.M{"ownedCards": bson.M{"$in": result.ContentIds}}, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	inSet := map[string]bool{}
+	for _, uuid := range result.ContentIds {
+		inSet[uuid] = true
+	}
+
+	byId := map[string]*CardOwnership{}
+	for _, owner := range users {
+		ownsCard := map[string]bool{}
+
+		for _, uuid := range owner.OwnedCards {
+			if !inSet[uuid] {
+				continue
+			}
+
+			entry, ok := byId[uuid]
+			if !ok {
+				entry = &CardOwnership{CardId: uuid}
+				byId[uuid] = entry
+			}
+
+			if !ownsCard[uuid] {
+				ownsCard[uuid] = true
+				entry.Owners++
+			}
+
+			entry.Copies++
+			ret.TotalCopies++
+		}
+
+		if len(ownsCard) != 0 {
+			ret.Owners++
+		}
+	}
+
+	for _, entry := range byId {
+		ret.Cards = append(ret.Cards, entry)
+	}
+
+	slices.SortFunc(ret.Cards, func(a *CardOwnership, b *CardOwnership) int {
+		if a.Copies != b.Copies {
+			return cmp.Compare(b.Copies, a.Copies)
+		}
+
+		return cmp.Compare(a.CardId, b.CardId)
+	})
+
+	return ret, nil
+}
@@ -0,0 +1,208 @@
+package set
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stevezaluk/mtgjson-sdk/upstream"
+	"github.com/stevezaluk/mtgjson-sdk/util"
+	"go.mongodb.org/mongo-driver/bson"
+
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+const (
+	DEFAULT_IMPORT_BATCH_SIZE = 100
+	DEFAULT_IMPORT_LOCK_TTL   = 10 * time.Minute
+
+	importCheckpointId = "mtgjson"
+	importLockName     = "mtgjson-import"
+)
+
+var ErrImportInProgress = errors.New("set: Operation failed. Another import is already in progress")
+var ErrCheckpointUpdateFailed = errors.New("set: Operation failed. Failed to store import checkpoint")
+
+/*
+ImportCheckpoint The progress of a full import. CompletedSets contains the codes of every set that was
+fully imported, while CurrentSet and Offset record the set being imported and the index of the next card
+to import from it. A checkpoint is only resumed if it was created for the same upstream version
+*/
+type ImportCheckpoint struct {
+	Id            string   `bson:"_id" json:"-"`
+	Version       string   `bson:"version" json:"version"`
+	CompletedSets []string `bson:"completedSets" json:"completedSets"`
+	CurrentSet    string   `bson:"currentSet" json:"currentSet"`
+	Offset        int      `bson:"offset" json:"offset"`
+	UpdatedAt     string   `bson:"updatedAt" json:"updatedAt"`
+}
+
+/*
+importBatchSize Return the number of cards imported between checkpoints, set with 'mtgjson.import.batch_size'
+*/
+func importBatchSize() int {
+	if viper.IsSet("mtgjson.import.batch_size") {
+		return max(viper.GetInt("mtgjson.import.batch_size"), 1)
+	}
+
+	return DEFAULT_IMPORT_BATCH_SIZE
+}
+
+/*
+GetImportCheckpoint Return the checkpoint of the last full import that did not complete. Returns
+ErrNoImportStatus if there is no checkpoint
+*/
+func GetImportCheckpoint(ctx context.Context) (*ImportCheckpoint, error) {
+	var result *ImportCheckpoint
+
+	database, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "import_checkpoint", bson.M{"_id": importCheckpointId}, &result)
+	if errors.Is(err, server.ErrNotFound) {
+		return nil, ErrNoImportStatus
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+saveImportCheckpoint Store the progress of a full import, replacing any previous checkpoint
+*/
+func saveImportCheckpoint(ctx context.Context, checkpoint *ImportCheckpoint) error {
+	database, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	checkpoint.Id = importCheckpointId
+	checkpoint.UpdatedAt = util.CreateTimestampStr()
+
+	database.Delete(ctx, "import_checkpoint", bson.M{"_id": importCheckpointId})
+
+	_, err = database.Insert(ctx, "import_checkpoint", checkpoint)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCheckpointUpdateFailed, err)
+	}
+
+	return nil
+}
+
+/*
+ImportAll Import every set and its cards from the upstream MTGJSON source. Progress is checkpointed after
+every batch of cards, so an import that crashed or whose context was cancelled continues where it left off
+when called again, provided the upstream version has not changed. Only one import can run at a time across
+all API replicas, and ErrImportInProgress is returned if another import holds the import lock
+*/
+func ImportAll(ctx context.Context) (*ImportReport, error) {
+	ttl := DEFAULT_IMPORT_LOCK_TTL
+	if viper.IsSet("mtgjson.import.lock_ttl") {
+		ttl = viper.GetDuration("mtgjson.import.lock_ttl")
+	}
+
+	database, err := mtgContext.GetMongoDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := database.AcquireLock(ctx, importLockName, ttl)
+	if errors.Is(err, server.ErrLockHeld) {
+		return nil, ErrImportInProgress
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release(ctx)
+
+	setList, meta, err := upstream.FetchSetList()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Failed: []string{}}
+	if meta != nil {
+		report.Version = meta.Version
+	}
+
+	checkpoint, err := GetImportCheckpoint(ctx)
+	if err != nil || checkpoint.Version != report.Version {
+		checkpoint = &ImportCheckpoint{Version: report.Version, CompletedSets: []string{}}
+	} else {
+		mtgContext.GetLogger().Info("Resuming import from checkpoint", "version", checkpoint.Version, "completedSets", len(checkpoint.CompletedSets), "currentSet", checkpoint.CurrentSet, "offset", checkpoint.Offset)
+	}
+
+	var codes []string
+	for _, entry := range setList {
+		codes = append(codes, entry.Code)
+	}
+
+	slices.Sort(codes)
+
+	for _, code := range codes {
+		if slices.Contains(checkpoint.CompletedSets, code) {
+			continue
+		}
+
+		offset := 0
+		if checkpoint.CurrentSet == code {
+			offset = checkpoint.Offset
+		}
+
+		checkpoint.CurrentSet = code
+		save := func(offset int) error {
+			checkpoint.Offset = offset
+
+			err := saveImportCheckpoint(ctx, checkpoint)
+			if err != nil {
+				mtgContext.GetLogger().Error("Failed to save import checkpoint", "set", code, "offset", offset, "err", err)
+			}
+
+			err = lock.Renew(ctx, ttl)
+			if err != nil {
+				return ErrImportInProgress
+			}
+
+			return nil
+		}
+
+		setReport, err := importSetCards(ctx, code, offset, save)
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		if errors.Is(err, ErrImportInProgress) {
+			return report, err
+		}
+
+		if err != nil {
+			mtgContext.GetLogger().Error("Failed to import set", "set", code, "err", err)
+			report.Failed = append(report.Failed, code)
+		} else {
+			report.Imported += setReport.Imported
+			report.Skipped += setReport.Skipped
+		}
+
+		checkpoint.CompletedSets = append(checkpoint.CompletedSets, code)
+
+		err = save(0)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	database.Delete(ctx, "import_checkpoint", bson.M{"_id": importCheckpointId})
+	recordImport(ctx, report.Version)
+
+	return report, nil
+}
@@ -1,10 +1,17 @@
 package set
 
 import (
+	stdContext "context"
 	"errors"
+	"fmt"
+	"github.com/spf13/viper"
 	"github.com/stevezaluk/mtgjson-models/meta"
 	"github.com/stevezaluk/mtgjson-sdk/card"
 	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/server/tracing"
+	"github.com/stevezaluk/mtgjson-sdk/slug"
 	"github.com/stevezaluk/mtgjson-sdk/user"
 	"github.com/stevezaluk/mtgjson-sdk/util"
 	"slices"
@@ -12,51 +19,154 @@ import (
 	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
 	"github.com/stevezaluk/mtgjson-models/set"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 /*
-ReplaceSet Replace the entire set in the database with the model passed in the parameter.
-Returns ErrSetUpdateFailed if the set cannot be located
+repository Returns a typed repository for the set collection. Missing sets are reported as ErrNoSet
 */
-func ReplaceSet(set *set.Set) error {
-	var database = context.GetDatabase()
+func repository(ctx stdContext.Context) (*server.Repository[set.Set], error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := database.Replace("set", bson.M{"code": set.Code}, &set)
-	if !err {
-		return sdkErrors.ErrSetUpdateFailed
+	return server.NewRepository[set.Set](database, "set", sdkErrors.ErrNoSet), nil
+}
+
+/*
+ReplaceSet Replace the entire set in the database with the model passed in the parameter. The slug
+of a custom set is regenerated if it has been renamed. The set is only replaced if it has not been
+modified since the model was read, which is checked using the modified date of its API metadata, and
+the modified date is updated on success. Returns ErrSetUpdateFailed if the set cannot be located,
+wrapping server.ErrConflict if another writer modified the set first
+*/
+func ReplaceSet(ctx stdContext.Context, set *set.Set) error {
+	if set.MtgjsonApiMeta == nil {
+		return sdkErrors.ErrMissingMetaApi
 	}
 
-	return nil
+	repo, err := repository(ctx)
+	if err != nil {
+		return err
+	}
+
+	expected := set.MtgjsonApiMeta.ModifiedDate
+	set.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr()
+
+	err = repo.ReplaceVersion(ctx, bson.M{"code": set.Code}, server.VersionField, expected, set)
+	if err != nil {
+		set.MtgjsonApiMeta.ModifiedDate = expected
+		return fmt.Errorf("%w: %w", sdkErrors.ErrSetUpdateFailed, err)
+	}
+
+	invalidation.Publish(ctx, invalidation.KindSet, set.Code)
+
+	return assignSlug(ctx, set)
 }
 
 /*
 GetSet Takes a single string representing a set code and returns a set model for the set.
 Returns ErrNoSet if the set does not exist, or cannot be located
 */
-func GetSet(code string, owner string) (*set.Set, error) {
-	var ret *set.Set
-	var database = context.GetDatabase()
+func GetSet(ctx stdContext.Context, code string, owner string) (*set.Set, error) {
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	query := bson.M{"code": code}
 	if owner != "" {
 		query = bson.M{"code": code, "mtgjsonApiMeta.owner": owner}
 	}
 
-	err := database.Find("set", query, &ret)
-	if !err {
-		return ret, sdkErrors.ErrNoSet
+	return repo.FindOne(ctx, query)
+}
+
+/*
+prepareSet Fill the empty fields of a set model with their default values and assign its API metadata
+*/
+func prepareSet(set *set.Set, owner string) {
+	if set.ContentIds == nil || len(set.ContentIds) == 0 {
+		set.ContentIds = []string{}
 	}
 
-	return ret, nil
+	currentDate := util.CreateTimestampStr()
+	if set.ReleaseDate == "" {
+		set.ReleaseDate = currentDate
+	}
+
+	set.MtgjsonApiMeta = &meta.MTGJSONAPIMeta{
+		Owner:        owner,
+		Type:         "Set",
+		CreationDate: currentDate,
+		ModifiedDate: currentDate,
+	}
+}
+
+/*
+UpsertSet Create the set passed in the parameter, or replace it if a set with the same code already exists
+for the owner. This does not require the existence check performed by NewSet, so sync jobs can call it
+repeatedly with the same set. The API metadata of a replaced set is regenerated. Returns ErrSetUpdateFailed
+wrapping ErrSetDeleted if the set was soft deleted
+*/
+func UpsertSet(ctx stdContext.Context, set *set.Set, owner string) error {
+	if set.Name == "" || set.Code == "" {
+		return sdkErrors.ErrSetMissingId
+	}
+
+	if owner == "" {
+		owner = user.SystemUser
+	}
+
+	if owner != user.SystemUser {
+		_, err := user.GetUser(ctx, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	prepareSet(set, owner)
+
+	_, err = database.Upsert(ctx, "set", bson.M{"code": set.Code, "mtgjsonApiMeta.owner": owner}, set)
+	if errors.Is(err, server.ErrDeleted) {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrSetUpdateFailed, ErrSetDeleted)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindSet, set.Code)
+
+	return assignSlug(ctx, set)
 }
 
 /*
 NewSet Insert a new set in the form of a model into the MongoDB database. The set model must have a
 valid name and set code, additionally the set cannot already exist under the same set code. Owner is
 the email address of the owner you want to assign the deck to. If the string is empty (i.e. == ""), it
-will be assigned to the system user
+will be assigned to the system user. A soft deleted set still holds its code, in which case ErrSetAlreadyExists
+is returned wrapping ErrSetDeleted
+*/
+func NewSet(ctx stdContext.Context, set *set.Set, owner string) error {
+	ctx, span := tracing.Start(ctx, "set.NewSet", attribute.String("set.code", set.Code))
+
+	err := newSet(ctx, set, owner)
+	tracing.End(span, err)
+
+	return err
+}
+
+/*
+newSet Insert the set passed using the context passed for every database operation, see NewSet
 */
-func NewSet(set *set.Set, owner string) error {
+func newSet(ctx stdContext.Context, set *set.Set, owner string) error {
 	if set.Name == "" || set.Code == "" {
 		return sdkErrors.ErrSetMissingId
 	}
@@ -66,38 +176,43 @@ func NewSet(set *set.Set, owner string) error {
 	}
 
 	if owner != user.SystemUser {
-		_, err := user.GetUser(owner)
+		_, err := user.GetUser(ctx, owner)
 		if err != nil {
 			return err
 		}
 	}
 
-	var database = context.GetDatabase()
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
 
-	_, err := GetSet(set.Code, owner)
-	if !errors.Is(err, sdkErrors.ErrNoSet) {
+	_, err = GetSet(ctx, set.Code, owner)
+	if err == nil {
 		return sdkErrors.ErrSetAlreadyExists
 	}
 
-	if set.ContentIds == nil || len(set.ContentIds) == 0 {
-		set.ContentIds = []string{}
+	if !errors.Is(err, sdkErrors.ErrNoSet) {
+		return err
 	}
 
-	currentDate := util.CreateTimestampStr()
-	if set.ReleaseDate == "" {
-		set.ReleaseDate = currentDate
+	deleted, err := deletedSetExists(ctx, set.Code, owner)
+	if err != nil {
+		return err
 	}
 
-	set.MtgjsonApiMeta = &meta.MTGJSONAPIMeta{
-		Owner:        owner,
-		Type:         "Set",
-		CreationDate: currentDate,
-		ModifiedDate: currentDate,
+	if deleted {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrSetAlreadyExists, ErrSetDeleted)
 	}
 
-	database.Insert("set", &set)
+	prepareSet(set, owner)
 
-	return nil
+	_, err = database.Insert(ctx, "set", &set)
+	if err != nil {
+		return err
+	}
+
+	return assignSlug(ctx, set)
 }
 
 /*
@@ -105,7 +220,7 @@ AddCards Update the contentIds in the set model passed with new cards.
 This should probably perform card validation in the future. This should also be updated
 to allow multiples of cards to be added
 */
-func AddCards(set *set.Set, newCards []string) error {
+func AddCards(ctx stdContext.Context, set *set.Set, newCards []string) error {
 	if newCards == nil || len(newCards) == 0 {
 		return nil // no new cards to add. returning nil here to not consume a database call
 	}
@@ -116,9 +231,7 @@ func AddCards(set *set.Set, newCards []string) error {
 		return sdkErrors.ErrMissingMetaApi
 	}
 
-	set.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr() // need better error checking here
-
-	err := ReplaceSet(set)
+	err := ReplaceSet(ctx, set)
 	if err != nil {
 		return err
 	}
@@ -130,7 +243,7 @@ func AddCards(set *set.Set, newCards []string) error {
 RemoveCards Update the contentIds in the set model with the cards to be removed in the
 cards array. This should be updated to support removing multiples of one card at a time
 */
-func RemoveCards(set *set.Set, cards []string) error {
+func RemoveCards(ctx stdContext.Context, set *set.Set, cards []string) error {
 	if cards == nil || len(cards) == 0 {
 		return nil // no new cards to add. returning nil here to not consume a database call
 	}
@@ -147,9 +260,7 @@ func RemoveCards(set *set.Set, cards []string) error {
 		return sdkErrors.ErrMissingMetaApi
 	}
 
-	set.MtgjsonApiMeta.ModifiedDate = util.CreateTimestampStr()
-
-	err := ReplaceSet(set)
+	err := ReplaceSet(ctx, set)
 	if err != nil {
 		return err
 	}
@@ -160,14 +271,29 @@ func RemoveCards(set *set.Set, cards []string) error {
 /*
 GetSetContents Update the contents field of the set passed in the parameter using the GetCards
 function. Consumes a single database call. If the contentIds field is nil or has a length of 0,
-it will return nil and abort the call
+it will return nil and abort the call. When 'mtgjson.lazy_import' is enabled, an empty set owned
+by the system user will have its cards imported from the upstream MTGJSON source on first access
 */
-func GetSetContents(set *set.Set) error {
+func GetSetContents(ctx stdContext.Context, set *set.Set) error {
 	if set.ContentIds == nil || len(set.ContentIds) == 0 {
-		return nil // returning nil here to not consume a database call
+		if !viper.GetBool("mtgjson.lazy_import") || set.MtgjsonApiMeta == nil || set.MtgjsonApiMeta.Owner != user.SystemUser {
+			return nil // returning nil here to not consume a database call
+		}
+
+		_, err := ImportSetCards(ctx, set.Code)
+		if err != nil {
+			return err
+		}
+
+		imported, err := GetSet(ctx, set.Code, user.SystemUser)
+		if err != nil {
+			return err
+		}
+
+		set.ContentIds = imported.ContentIds
 	}
 
-	contents, err := card.GetCards(set.ContentIds)
+	contents, err := card.GetCards(ctx, set.ContentIds)
 	if err != nil {
 		return err
 	}
@@ -182,21 +308,35 @@ DeleteSet Remove a set from the MongoDB database using the code passed in the pa
 Returns ErrNoSet if the set does not exist. Returns ErrSetDeleteFailed if the deleted count
 does not equal 1
 */
-func DeleteSet(code string, owner string) error {
-	var database = context.GetDatabase()
+func DeleteSet(ctx stdContext.Context, code string, owner string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing, err := GetSet(ctx, code, owner)
+	if err != nil {
+		return err
+	}
 
 	query := bson.M{"code": code}
 	if owner != "" {
 		query = bson.M{"code": code, "mtgjsonApiMeta.owner": owner}
 	}
 
-	result, err := database.Delete("set", query)
-	if !err {
+	_, err = database.Delete(ctx, "set", query)
+	if errors.Is(err, server.ErrNotFound) {
 		return sdkErrors.ErrNoSet
 	}
 
-	if result.DeletedCount != 1 {
-		return sdkErrors.ErrSetDeleteFailed
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrSetDeleteFailed, err)
+	}
+
+	invalidation.Publish(ctx, invalidation.KindSet, code)
+
+	if existing.MtgjsonApiMeta != nil {
+		slug.Remove(ctx, slug.KindSet, code, existing.MtgjsonApiMeta.Owner)
 	}
 
 	return nil
@@ -205,16 +345,59 @@ func DeleteSet(code string, owner string) error {
 
 /*
 IndexSets Returns all sets in the database unmarshalled as card models. The limit parameter
-will be passed directly to the database query to limit the number of models returned
+will be passed directly to the database query to limit the number of models returned. Sort fields
+can be passed to order the sets before the limit is applied (e.g. "releaseDate"), see server.SortBy
 */
-func IndexSets(limit int64) ([]*set.Set, error) {
+func IndexSets(ctx stdContext.Context, limit int64, sort ...string) ([]*set.Set, error) {
 	var ret []*set.Set
-	var database = context.GetDatabase()
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	err := database.Index("set", limit, ret)
-	if !err {
-		return ret, sdkErrors.ErrNoSet
+	err = database.Index(ctx, "set", limit, &ret, sort...)
+	if err != nil {
+		return ret, err
 	}
 
 	return ret, nil
 }
+
+/*
+PageSets Returns a single page of sets from the database. Pass the NextCursor of the returned page in
+the options to fetch the next page. Large collections should be paged through this way rather than
+with IndexSets, which is limited to a single capped query
+*/
+func PageSets(ctx stdContext.Context, opts *server.PageOptions) ([]*set.Set, *server.Page, error) {
+	var result []*set.Set
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, err := database.Paginate(ctx, "set", bson.M{}, opts, &result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, page, nil
+}
+
+/*
+CountSets Returns the number of sets in the database owned by the user passed in the parameter, for use in
+pagination metadata. If owner is an empty string, the estimated size of the whole collection is returned
+instead, which does not require a collection scan
+*/
+func CountSets(ctx stdContext.Context, owner string) (int64, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if owner == "" {
+		return database.EstimatedCount(ctx, "set")
+	}
+
+	return database.Count(ctx, "set", bson.M{"mtgjsonApiMeta.owner": owner})
+}
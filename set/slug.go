@@ -0,0 +1,41 @@
+package set
+
+import (
+	"context"
+	"errors"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-models/set"
+	"github.com/stevezaluk/mtgjson-sdk/slug"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+)
+
+/*
+assignSlug Generate or update the slug of a custom set. Sets owned by the system user are addressed by
+their set code, so they are not given a slug
+*/
+func assignSlug(ctx context.Context, set *set.Set) error {
+	if set.MtgjsonApiMeta == nil || set.MtgjsonApiMeta.Owner == user.SystemUser {
+		return nil
+	}
+
+	_, err := slug.Assign(ctx, slug.KindSet, set.Name, set.Code, set.MtgjsonApiMeta.Owner)
+
+	return err
+}
+
+/*
+GetSetBySlug Fetch a custom set using its slug. Returns ErrNoSet if no set exists with the slug passed
+*/
+func GetSetBySlug(ctx context.Context, setSlug string) (*set.Set, error) {
+	entry, err := slug.Resolve(ctx, slug.KindSet, setSlug)
+	if errors.Is(err, slug.ErrNoSlug) {
+		return nil, sdkErrors.ErrNoSet
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return GetSet(ctx, entry.Code, entry.Owner)
+}
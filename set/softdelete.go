@@ -0,0 +1,81 @@
+package set
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"time"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/invalidation"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrSetDeleted = errors.New("set: Operation failed. A deleted set exists under the same code, restore it with RestoreSet or purge it first")
+
+/*
+deletedSetExists Returns true if a set matching the code and owner passed was soft deleted. Deleted sets are
+excluded from every other lookup, see server.SoftDeleteField
+*/
+func deletedSetExists(ctx stdContext.Context, code string, owner string) (bool, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := database.Count(ctx, "set", bson.M{"code": code, "mtgjsonApiMeta.owner": owner, server.SoftDeleteField: bson.M{"$exists": true}})
+	if err != nil {
+		return false, err
+	}
+
+	return count != 0, nil
+}
+
+/*
+RestoreSet Restore a set that was soft deleted with DeleteSet and re-assign its slug. Returns ErrNoSet if
+no deleted set matches the code and owner passed
+*/
+func RestoreSet(ctx stdContext.Context, code string, owner string) error {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"code": code}
+	if owner != "" {
+		query = bson.M{"code": code, "mtgjsonApiMeta.owner": owner}
+	}
+
+	_, err = database.Restore(ctx, "set", query)
+	if errors.Is(err, server.ErrNotFound) {
+		return sdkErrors.ErrNoSet
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrSetUpdateFailed, err)
+	}
+
+	restored, err := GetSet(ctx, code, owner)
+	if err != nil {
+		return err
+	}
+
+	invalidation.Publish(ctx, invalidation.KindSet, code)
+
+	return assignSlug(ctx, restored)
+}
+
+/*
+PurgeDeletedSets Permanently remove every set that was soft deleted before the time passed in the parameter.
+Returns the number of sets removed
+*/
+func PurgeDeletedSets(ctx stdContext.Context, before time.Time) (int64, error) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return database.PurgeDeleted(ctx, "set", before)
+}
@@ -0,0 +1,59 @@
+package set
+
+import (
+	stdContext "context"
+	"errors"
+	"testing"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	setModel "github.com/stevezaluk/mtgjson-models/set"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"github.com/stevezaluk/mtgjson-sdk/user"
+)
+
+func TestNewSetDeleted(t *testing.T) {
+	database := server.NewMemoryDatabase()
+	database.SoftDelete = true
+
+	ctx := context.WithDatabase(stdContext.Background(), database)
+
+	newSet := func() *setModel.Set {
+		return &setModel.Set{Code: "LEA", Name: "Limited Edition Alpha"}
+	}
+
+	err := NewSet(ctx, newSet(), user.SystemUser)
+	if err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+
+	err = DeleteSet(ctx, "LEA", user.SystemUser)
+	if err != nil {
+		t.Fatalf("DeleteSet() error = %v", err)
+	}
+
+	err = NewSet(ctx, newSet(), user.SystemUser)
+	if !errors.Is(err, sdkErrors.ErrSetAlreadyExists) || !errors.Is(err, ErrSetDeleted) {
+		t.Errorf("NewSet() error = %v, want %v", err, ErrSetDeleted)
+	}
+
+	err = UpsertSet(ctx, newSet(), user.SystemUser)
+	if !errors.Is(err, sdkErrors.ErrSetUpdateFailed) || !errors.Is(err, ErrSetDeleted) {
+		t.Errorf("UpsertSet() error = %v, want %v", err, ErrSetDeleted)
+	}
+
+	err = RestoreSet(ctx, "LEA", user.SystemUser)
+	if err != nil {
+		t.Fatalf("RestoreSet() error = %v", err)
+	}
+
+	err = UpsertSet(ctx, newSet(), user.SystemUser)
+	if err != nil {
+		t.Errorf("UpsertSet() error = %v", err)
+	}
+
+	count, err := database.Count(ctx, "set", nil)
+	if err != nil || count != 1 {
+		t.Errorf("Count() = %d, %v, want 1", count, err)
+	}
+}
@@ -0,0 +1,210 @@
+package slug
+
+import (
+	stdContext "context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+	"github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	DEFAULT_MAX_LENGTH = 64
+
+	KindDeck = "deck"
+	KindSet  = "set"
+)
+
+var ErrNoSlug = errors.New("slug: Failed to find an entity with the specified slug")
+var ErrSlugUpdateFailed = errors.New("slug: Operation failed. Failed to store slug")
+
+/*
+Entry Maps a slug to the entity it identifies. Codes are only unique per owner, so both are stored
+*/
+type Entry struct {
+	Kind  string `bson:"kind" json:"kind"`
+	Slug  string `bson:"slug" json:"slug"`
+	Code  string `bson:"code" json:"code"`
+	Owner string `bson:"owner" json:"owner"`
+}
+
+/*
+Slugify Convert a name into a lowercase, hyphenated, URL safe string. Accents are removed, any other
+character that is not a letter or digit is treated as a separator, and the result is truncated to the
+length configured with 'slug.max_length'
+*/
+func Slugify(name string) string {
+	maxLength := DEFAULT_MAX_LENGTH
+	if viper.IsSet("slug.max_length") {
+		maxLength = viper.GetInt("slug.max_length")
+	}
+
+	var builder strings.Builder
+	separator := false
+
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if separator && builder.Len() != 0 {
+				builder.WriteRune('-')
+			}
+
+			builder.WriteRune(r)
+			separator = false
+		default:
+			separator = true
+		}
+	}
+
+	ret := builder.String()
+	if len(ret) > maxLength {
+		ret = strings.TrimRight(ret[:maxLength], "-")
+	}
+
+	return ret
+}
+
+/*
+matchesBase Returns true if the slug was generated from the base passed, either as the base itself or
+the base with a numeric suffix used to keep it unique
+*/
+func matchesBase(slug string, base string) bool {
+	if slug == base {
+		return true
+	}
+
+	suffix, found := strings.CutPrefix(slug, base+"-")
+	if !found {
+		return false
+	}
+
+	_, err := strconv.Atoi(suffix)
+
+	return err == nil
+}
+
+/*
+GetSlug Return the slug entry of the entity passed. Returns ErrNoSlug if the entity does not have a slug
+*/
+func GetSlug(ctx stdContext.Context, kind string, code string, owner string) (*Entry, error) {
+	var result *Entry
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "slug", bson.M{"kind": kind, "code": code, "owner": owner}, &result)
+	if errors.Is(err, server.ErrNotFound) {
+		return nil, ErrNoSlug
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+Resolve Return the slug entry for the slug passed. Returns ErrNoSlug if no entity has the slug
+*/
+func Resolve(ctx stdContext.Context, kind string, slug string) (*Entry, error) {
+	var result *Entry
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Find(ctx, "slug", bson.M{"kind": kind, "slug": slug}, &result)
+	if errors.Is(err, server.ErrNotFound) {
+		return nil, ErrNoSlug
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+Assign Generate a unique slug for an entity from its name and store it. If the entity already has a slug
+that was generated from the same name it is kept, so slugs stay stable across updates. When the entity is
+renamed a new slug is generated, unless 'slug.keep_on_rename' is enabled. Slugs are unique per kind, with
+a numeric suffix appended when the name is already taken
+*/
+func Assign(ctx stdContext.Context, kind string, name string, code string, owner string) (string, error) {
+	base := Slugify(name)
+	if base == "" {
+		base = Slugify(code)
+	}
+
+	existing, err := GetSlug(ctx, kind, code, owner)
+	if err != nil && !errors.Is(err, ErrNoSlug) {
+		return "", err
+	}
+
+	if existing != nil && (matchesBase(existing.Slug, base) || viper.GetBool("slug.keep_on_rename")) {
+		return existing.Slug, nil
+	}
+
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	slug := base
+	for suffix := 2; ; suffix++ {
+		_, err = Resolve(ctx, kind, slug)
+		if errors.Is(err, ErrNoSlug) {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		slug = base + "-" + strconv.Itoa(suffix)
+	}
+
+	entry := &Entry{Kind: kind, Slug: slug, Code: code, Owner: owner}
+	if existing == nil {
+		_, err = database.Insert(ctx, "slug", entry)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrSlugUpdateFailed, err)
+		}
+
+		return slug, nil
+	}
+
+	_, err = database.Replace(ctx, "slug", bson.M{"kind": kind, "code": code, "owner": owner}, entry)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSlugUpdateFailed, err)
+	}
+
+	return slug, nil
+}
+
+/*
+Remove Delete the slug of an entity. Entities created before slugs were introduced will not have one,
+so a failed delete is not treated as an error
+*/
+func Remove(ctx stdContext.Context, kind string, code string, owner string) {
+	database, err := context.GetDatabase(ctx)
+	if err != nil {
+		return
+	}
+
+	database.Delete(ctx, "slug", bson.M{"kind": kind, "code": code, "owner": owner})
+}
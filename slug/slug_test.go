@@ -0,0 +1,54 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"spaces", "Lightning Bolt", "lightning-bolt"},
+		{"punctuation", "  Teferi's Protection!! ", "teferi-s-protection"},
+		{"digits", "Borrowing 100,000 Arrows", "borrowing-100-000-arrows"},
+		{"accents", "Jötun Grunt", "jotun-grunt"},
+		{"only separators", "--- ???", ""},
+		{"truncated without a trailing separator", strings.Repeat("a", DEFAULT_MAX_LENGTH-1) + " b", strings.Repeat("a", DEFAULT_MAX_LENGTH-1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Slugify(test.input); got != test.want {
+				t.Errorf("Slugify() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchesBase(t *testing.T) {
+	tests := []struct {
+		slug string
+		base string
+		want bool
+	}{
+		{"esper-control", "esper-control", true},
+		{"esper-control-2", "esper-control", true},
+		{"esper-control-12", "esper-control", true},
+		{"esper-control-", "esper-control", false},
+		{"esper-control-v2", "esper-control", false},
+		{"esper", "esper-control", false},
+		{"esper-controls", "esper-control", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.slug, func(t *testing.T) {
+			if got := matchesBase(test.slug, test.base); got != test.want {
+				t.Errorf("matchesBase(%q, %q) = %v, want %v", test.slug, test.base, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+LocalStorage A Storage backed by a directory on the local filesystem
+*/
+type LocalStorage struct {
+	Root string
+}
+
+/*
+NewLocalStorage Create a LocalStorage rooted at the directory passed in the parameter. The directory is
+created if it does not already exist
+*/
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	err := os.MkdirAll(root, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{Root: root}, nil
+}
+
+/*
+path Resolve an object key to a path within the storage root, rejecting keys that would escape it
+*/
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if key == "" || cleaned == "/" {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(s.Root, filepath.FromSlash(cleaned)), nil
+}
+
+/*
+Put Write the contents of the reader to the object key. The content type is not stored by this backend
+*/
+func (s *LocalStorage) Put(ctx context.Context, key string, reader io.Reader, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partially written object
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, reader)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+/*
+Get Open the object stored under the key for reading. Returns ErrObjectNotFound if the object does not exist
+*/
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+
+	return file, err
+}
+
+/*
+Delete Remove the object stored under the key. Returns ErrObjectNotFound if the object does not exist
+*/
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrObjectNotFound
+	}
+
+	return err
+}
+
+/*
+Exists Returns true if an object is stored under the key
+*/
+func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+/*
+List Return the keys of every object whose key starts with the prefix passed
+*/
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	ret := []string{}
+
+	err := filepath.WalkDir(s.Root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		relative, err := filepath.Rel(s.Root, path)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(relative)
+		if strings.HasPrefix(key, prefix) {
+			ret = append(ret, key)
+		}
+
+		return nil
+	})
+
+	return ret, err
+}
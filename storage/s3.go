@@ -0,0 +1,335 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	GCS_ENDPOINT = "https://storage.googleapis.com"
+)
+
+/*
+S3Storage A Storage backed by an S3 compatible object store. Requests are signed with AWS Signature Version 4
+and use path style addressing, so any S3 compatible service (AWS, MinIO, Ceph, Google Cloud Storage with HMAC
+keys) can be used
+*/
+type S3Storage struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	client *http.Client
+}
+
+/*
+NewS3Storage Create an S3Storage for the bucket passed. If the endpoint is empty, the AWS endpoint for the
+region is used
+*/
+func NewS3Storage(endpoint string, region string, bucket string, accessKey string, secretKey string) *S3Storage {
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+
+	return &S3Storage{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		client:    &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+/*
+NewGCSStorage Create a Storage for a Google Cloud Storage bucket. This uses the S3 compatible XML API of Google
+Cloud Storage, and requires an HMAC key created for a service account
+*/
+func NewGCSStorage(bucket string, accessKey string, secretKey string) *S3Storage {
+	return NewS3Storage(GCS_ENDPOINT, "auto", bucket, accessKey, secretKey)
+}
+
+/*
+encodePath URI encode each segment of an object path as required by Signature Version 4
+*/
+func encodePath(path string) string {
+	segments := strings.Split(path, "/")
+	for index, segment := range segments {
+		segments[index] = strings.ReplaceAll(url.PathEscape(segment), "+", "%2B")
+	}
+
+	return strings.Join(segments, "/")
+}
+
+/*
+hmacSHA256 Return the HMAC-SHA256 of the data using the key passed
+*/
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+/*
+sign Add the Signature Version 4 authorization headers to the request using the hash of the payload
+*/
+func (s *S3Storage) sign(req *http.Request, payloadHash string) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var canonicalQuery []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			canonicalQuery = append(canonicalQuery, url.QueryEscape(key)+"="+strings.ReplaceAll(url.QueryEscape(value), "+", "%20"))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders,
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := shortDate + "/" + s.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.SecretKey), shortDate)
+	signingKey = hmacSHA256(signingKey, s.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+signature)
+}
+
+/*
+do Build, sign and send a request for the object key passed. The query parameters are appended to the
+request URL, and the response is returned to the caller to be closed
+*/
+func (s *S3Storage) do(ctx context.Context, method string, key string, query url.Values, body []byte, contentType string) (*http.Response, error) {
+	rawURL := s.Endpoint + "/" + s.Bucket + "/" + encodePath(key)
+	if len(query) != 0 {
+		rawURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	payloadHash := sha256.Sum256(body)
+	s.sign(req, hex.EncodeToString(payloadHash[:]))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		slog.Error("Request to S3 storage failed", "method", method, "bucket", s.Bucket, "key", key, "err", err)
+		return nil, ErrRequestFailed
+	}
+
+	return resp, nil
+}
+
+/*
+checkResponse Convert an unsuccessful response into an error, closing its body
+*/
+func checkResponse(resp *http.Response, key string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrObjectNotFound
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	slog.Error("S3 storage returned an unexpected status", "key", key, "status", resp.StatusCode, "message", string(message))
+
+	return ErrRequestFailed
+}
+
+/*
+Put Upload the contents of the reader to the object key. The reader is buffered in memory so that the
+payload can be signed
+*/
+func (s *S3Storage) Put(ctx context.Context, key string, reader io.Reader, contentType string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, nil, body, contentType)
+	if err != nil {
+		return err
+	}
+
+	err = checkResponse(resp, key)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
+
+/*
+Get Open the object stored under the key for reading. Returns ErrObjectNotFound if the object does not exist
+*/
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkResponse(resp, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+/*
+Delete Remove the object stored under the key
+*/
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, "")
+	if err != nil {
+		return err
+	}
+
+	err = checkResponse(resp, key)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
+
+/*
+Exists Returns true if an object is stored under the key
+*/
+func (s *S3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, "")
+	if err != nil {
+		return false, err
+	}
+
+	err = checkResponse(resp, key)
+	if err == ErrObjectNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	resp.Body.Close()
+
+	return true, nil
+}
+
+/*
+listBucketResult The subset of the ListObjectsV2 response used by List
+*/
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+/*
+List Return the keys of every object whose key starts with the prefix passed. Results are paged through
+until the listing is complete
+*/
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	ret := []string{}
+	token := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, "")
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkResponse(resp, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, ErrRequestFailed
+		}
+
+		for _, object := range result.Contents {
+			ret = append(ret, object.Key)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return ret, nil
+		}
+
+		token = result.NextContinuationToken
+	}
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+	BackendGCS   = "gcs"
+)
+
+var ErrObjectNotFound = errors.New("storage: Failed to find object with the specified key")
+var ErrInvalidKey = errors.New("storage: Operation failed. Object key is invalid")
+var ErrUnknownBackend = errors.New("storage: Operation failed. Storage backend is not supported")
+var ErrRequestFailed = errors.New("storage: Operation failed. Request to the storage backend failed")
+
+/*
+Storage An abstraction over object storage used for large binary artifacts such as card images, backups
+and export bundles, so that they do not need to be stored in MongoDB. Keys are slash separated paths
+relative to the root of the storage backend
+*/
+type Storage interface {
+	Put(ctx context.Context, key string, reader io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+/*
+NewStorageFromConfig Create a Storage using the backend selected with the 'storage.backend' config key. The
+local backend reads 'storage.local.path', while the s3 and gcs backends read their bucket and credentials from
+'storage.s3.*' and 'storage.gcs.*' respectively
+*/
+func NewStorageFromConfig() (Storage, error) {
+	switch viper.GetString("storage.backend") {
+	case BackendLocal, "":
+		return NewLocalStorage(viper.GetString("storage.local.path"))
+	case BackendS3:
+		return NewS3Storage(
+			viper.GetString("storage.s3.endpoint"),
+			viper.GetString("storage.s3.region"),
+			viper.GetString("storage.s3.bucket"),
+			viper.GetString("storage.s3.access_key"),
+			viper.GetString("storage.s3.secret_key"),
+		), nil
+	case BackendGCS:
+		return NewGCSStorage(
+			viper.GetString("storage.gcs.bucket"),
+			viper.GetString("storage.gcs.access_key"),
+			viper.GetString("storage.gcs.secret_key"),
+		), nil
+	}
+
+	return nil, ErrUnknownBackend
+}
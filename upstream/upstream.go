@@ -0,0 +1,169 @@
+package upstream
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stevezaluk/mtgjson-models/card"
+)
+
+const (
+	DEFAULT_UPSTREAM_URL = "https://mtgjson.com/api/v5"
+)
+
+var ErrUpstreamRequestFailed = errors.New("upstream: Failed to fetch file from the upstream MTGJSON source")
+var ErrUpstreamDecodeFailed = errors.New("upstream: Failed to decode file from the upstream MTGJSON source")
+
+var client = &http.Client{Timeout: 5 * time.Minute}
+
+/*
+Meta The meta object included in every MTGJSON file, describing the build of the data
+*/
+type Meta struct {
+	Date    string `json:"date"`
+	Version string `json:"version"`
+}
+
+/*
+SetListEntry A single set as described by SetList.json. This contains only set level metadata, and
+no cards
+*/
+type SetListEntry struct {
+	Code             string `json:"code"`
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	ReleaseDate      string `json:"releaseDate"`
+	Block            string `json:"block"`
+	ParentCode       string `json:"parentCode"`
+	KeyruneCode      string `json:"keyruneCode"`
+	BaseSetSize      int64  `json:"baseSetSize"`
+	TotalSetSize     int64  `json:"totalSetSize"`
+	IsOnlineOnly     bool   `json:"isOnlineOnly"`
+	IsFoilOnly       bool   `json:"isFoilOnly"`
+	IsPartialPreview bool   `json:"isPartialPreview"`
+}
+
+/*
+GetUpstreamURL Return the base URL of the upstream MTGJSON source. This can be overridden with the
+'mtgjson.upstream' config key to point the SDK at a mirror
+*/
+func GetUpstreamURL() string {
+	url := viper.GetString("mtgjson.upstream")
+	if url == "" {
+		url = DEFAULT_UPSTREAM_URL
+	}
+
+	return strings.TrimSuffix(url, "/")
+}
+
+/*
+Open Start downloading a file from the upstream MTGJSON source and return its body, so that files too
+large to be decoded in full (e.g. AllPrices.json) can be streamed. The caller must close the body
+*/
+func Open(file string) (io.ReadCloser, error) {
+	url := GetUpstreamURL() + "/" + file
+
+	slog.Info("Fetching file from upstream", "url", url)
+	resp, err := client.Get(url)
+	if err != nil {
+		slog.Error("Failed to fetch file from upstream", "url", url, "err", err)
+		return nil, ErrUpstreamRequestFailed
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		slog.Error("Upstream returned an unexpected status", "url", url, "status", resp.StatusCode)
+		return nil, ErrUpstreamRequestFailed
+	}
+
+	return resp.Body, nil
+}
+
+/*
+Fetch Download a file from the upstream MTGJSON source and decode its data field into the interface
+passed in the 'model' parameter. The meta object of the file is returned
+*/
+func Fetch(file string, model interface{}) (*Meta, error) {
+	url := GetUpstreamURL() + "/" + file
+
+	body, err := Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	decoded := struct {
+		Meta *Meta       `json:"meta"`
+		Data interface{} `json:"data"`
+	}{Data: model}
+
+	err = json.NewDecoder(body).Decode(&decoded)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		// upstream occasionally changes the type of a field that the models don't use for lookups. The
+		// decoder still populates every other field, so log this instead of discarding the whole file
+		slog.Warn("Upstream file contains a field with an unexpected type", "url", url, "field", typeErr.Field, "err", err)
+		err = nil
+	}
+
+	if err != nil {
+		slog.Error("Failed to decode file from upstream", "url", url, "err", err)
+		return nil, ErrUpstreamDecodeFailed
+	}
+
+	return decoded.Meta, nil
+}
+
+/*
+SetFile A single set file (e.g. 10E.json) from the upstream MTGJSON source. Only the fields needed
+to import the set and its cards are decoded
+*/
+type SetFile struct {
+	Code         string          `json:"code"`
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	ReleaseDate  string          `json:"releaseDate"`
+	BaseSetSize  int64           `json:"baseSetSize"`
+	TotalSetSize int64           `json:"totalSetSize"`
+	Cards        []*card.CardSet `json:"cards"`
+}
+
+/*
+FetchSet Download the set file for the set code passed in the parameter from the upstream MTGJSON
+source. Set codes are upper case upstream, so the code is converted before the request is made
+*/
+func FetchSet(code string) (*SetFile, *Meta, error) {
+	var ret *SetFile
+
+	meta, err := Fetch(strings.ToUpper(code)+".json", &ret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ret == nil {
+		return nil, nil, ErrUpstreamDecodeFailed
+	}
+
+	return ret, meta, nil
+}
+
+/*
+FetchSetList Download SetList.json from the upstream MTGJSON source
+*/
+func FetchSetList() ([]*SetListEntry, *Meta, error) {
+	var ret []*SetListEntry
+
+	meta, err := Fetch("SetList.json", &ret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ret, meta, nil
+}
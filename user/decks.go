@@ -0,0 +1,94 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+AddOwnedDeck Add a deck code to the ownedDecks field of the user passed in the email parameter. This
+is called by the deck package when a deck is created, and is a no-op for the system user. The code is added with
+$addToSet, so adding a code that the user already owns (e.g. when a write is retried) leaves ownedDecks unchanged. The update runs with the
+context passed, so that it can take part in a transaction started with Database.WithTransaction
+*/
+func AddOwnedDeck(ctx context.Context, email string, code string) error {
+	if email == SystemUser {
+		return nil
+	}
+
+	mongoDatabase, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = mongoDatabase.Update(ctx, "user", bson.M{"email": email}, bson.M{"$addToSet": bson.M{"ownedDecks": code}})
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrUserUpdateFailed, err)
+	}
+
+	return nil
+}
+
+/*
+RemoveOwnedDeck Remove a deck code from the ownedDecks field of the user passed in the email parameter. This
+is called by the deck package when a deck is deleted, and is a no-op for the system user. The update runs with the
+context passed, so that it can take part in a transaction started with Database.WithTransaction
+*/
+func RemoveOwnedDeck(ctx context.Context, email string, code string) error {
+	if email == SystemUser {
+		return nil
+	}
+
+	mongoDatabase, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = mongoDatabase.PullField(ctx, "user", bson.M{"email": email}, bson.M{"ownedDecks": code})
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrUserUpdateFailed, err)
+	}
+
+	return nil
+}
+
+/*
+SyncOwnedDecks Rebuild the ownedDecks field of the user passed in the email parameter from the decks that
+they own in the deck collection. This repairs any drift between the two, and returns the rebuilt list of
+deck codes
+*/
+func SyncOwnedDecks(ctx context.Context, email string) ([]string, error) {
+	var decks []struct {
+		Code string `bson:"code"`
+	}
+
+	_, err := GetUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	mongoDatabase, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mongoDatabase.FindMany(ctx, "deck", bson.M{"mtgjsonApiMeta.owner": email}, &decks)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := []string{}
+	for _, deck := range decks {
+		codes = append(codes, deck.Code)
+	}
+
+	_, err = mongoDatabase.SetField(ctx, "user", bson.M{"email": email}, bson.M{"ownedDecks": codes})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", sdkErrors.ErrUserUpdateFailed, err)
+	}
+
+	return codes, nil
+}
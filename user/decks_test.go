@@ -0,0 +1,46 @@
+package user
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	userModel "github.com/stevezaluk/mtgjson-models/user"
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
+)
+
+func TestAddOwnedDeck(t *testing.T) {
+	ctx := mtgContext.WithDatabase(context.Background(), server.NewMemoryDatabase())
+
+	database, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		t.Fatalf("GetDatabase() error = %v", err)
+	}
+
+	_, err = database.Insert(ctx, "user", &userModel.User{Email: "player@example.com", OwnedDecks: []string{}})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	for _, code := range []string{"ESP", "ESP", "GRX"} {
+		err = AddOwnedDeck(ctx, "player@example.com", code)
+		if err != nil {
+			t.Fatalf("AddOwnedDeck(%q) error = %v", code, err)
+		}
+	}
+
+	err = RemoveOwnedDeck(ctx, "player@example.com", "GRX")
+	if err != nil {
+		t.Fatalf("RemoveOwnedDeck() error = %v", err)
+	}
+
+	user, err := GetUser(ctx, "player@example.com")
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+
+	if want := []string{"ESP"}; !reflect.DeepEqual(user.OwnedDecks, want) {
+		t.Errorf("ownedDecks = %v, want %v", user.OwnedDecks, want)
+	}
+}
@@ -0,0 +1,223 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/auth0/go-auth0"
+	"github.com/auth0/go-auth0/management"
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	userModel "github.com/stevezaluk/mtgjson-models/user"
+	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+)
+
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatJSON = "json"
+
+	CollisionSkip = "skip"
+	CollisionFail = "fail"
+)
+
+var ErrInvalidImportFormat = errors.New("user: Operation failed. Import format must be either csv or json")
+var ErrInvalidImportFile = errors.New("user: Operation failed. Failed to parse user import file")
+
+/*
+ImportedUser A single user record exported from another platform. Auth0Id is optional when the users are
+being provisioned in Auth0 as part of the import
+*/
+type ImportedUser struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Auth0Id  string `json:"auth0Id,omitempty"`
+}
+
+/*
+ImportFailure A user that could not be imported along with the reason it failed
+*/
+type ImportFailure struct {
+	Email  string `json:"email"`
+	Reason string `json:"reason"`
+}
+
+/*
+UserImportReport A summary of a bulk user import
+*/
+type UserImportReport struct {
+	Created     int64            `json:"created"`
+	Provisioned int64            `json:"provisioned"`
+	Skipped     int64            `json:"skipped"`
+	Failed      []*ImportFailure `json:"failed"`
+}
+
+/*
+parseImportedUsers Read a list of users from the reader passed in either CSV or JSON format. CSV files must
+have a header row containing at least the email and username columns, with an optional auth0Id column
+*/
+func parseImportedUsers(reader io.Reader, format string) ([]*ImportedUser, error) {
+	var ret []*ImportedUser
+
+	if format == ImportFormatJSON {
+		err := json.NewDecoder(reader).Decode(&ret)
+		if err != nil {
+			return nil, ErrInvalidImportFile
+		}
+
+		return ret, nil
+	}
+
+	if format != ImportFormatCSV {
+		return nil, ErrInvalidImportFormat
+	}
+
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, ErrInvalidImportFile
+	}
+
+	columns := map[string]int{}
+	for index, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+
+	emailColumn, hasEmail := columns["email"]
+	usernameColumn, hasUsername := columns["username"]
+	auth0Column, hasAuth0Id := columns["auth0id"]
+	if !hasEmail || !hasUsername {
+		return nil, ErrInvalidImportFile
+	}
+
+	for _, record := range records[1:] {
+		imported := &ImportedUser{
+			Email:    strings.TrimSpace(record[emailColumn]),
+			Username: strings.TrimSpace(record[usernameColumn]),
+		}
+
+		if hasAuth0Id {
+			imported.Auth0Id = strings.TrimSpace(record[auth0Column])
+		}
+
+		ret = append(ret, imported)
+	}
+
+	return ret, nil
+}
+
+/*
+provisionAuth0User Create the user passed in Auth0 using the Management API with a random password, and
+return their Auth0 id. The user is expected to set their own password using a password reset email
+*/
+func provisionAuth0User(ctx context.Context, managementAPI *management.Management, imported *ImportedUser) (string, error) {
+	password := make([]byte, 32)
+	_, err := rand.Read(password)
+	if err != nil {
+		return "", err
+	}
+
+	auth0User := &management.User{
+		Connection: auth0.String("Username-Password-Authentication"),
+		Email:      auth0.String(imported.Email),
+		Username:   auth0.String(imported.Username),
+		Password:   auth0.String(base64.RawURLEncoding.EncodeToString(password)),
+	}
+
+	err = managementAPI.User.Create(ctx, auth0User)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(auth0User.GetID(), "auth0|"), nil
+}
+
+/*
+ImportUsers Create users in bulk from a CSV or JSON export of another platform. If provision is true, any user
+without an Auth0 id is created in Auth0 using the Management API and sent a password reset email. The collision
+parameter controls what happens when a user already exists under the same email address: CollisionSkip counts
+them as skipped, while CollisionFail records them as a failure. A failure for one user does not stop the import
+*/
+func ImportUsers(ctx context.Context, reader io.Reader, format string, provision bool, collision string) (*UserImportReport, error) {
+	users, err := parseImportedUsers(reader, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var managementAPI *management.Management
+	if provision {
+		managementAPI, err = mtgContext.GetAuthManagementAPI()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := &UserImportReport{Failed: []*ImportFailure{}}
+	seen := map[string]bool{}
+
+	for _, imported := range users {
+		fail := func(reason string) {
+			report.Failed = append(report.Failed, &ImportFailure{Email: imported.Email, Reason: reason})
+		}
+
+		if !validateEmail(imported.Email) {
+			fail(sdkErrors.ErrInvalidEmail.Error())
+			continue
+		}
+
+		_, err = GetUser(ctx, imported.Email)
+		if err == nil || seen[strings.ToLower(imported.Email)] {
+			if collision == CollisionSkip {
+				report.Skipped++
+			} else {
+				fail(sdkErrors.ErrUserAlreadyExist.Error())
+			}
+
+			continue
+		}
+
+		seen[strings.ToLower(imported.Email)] = true
+
+		provisioned := false
+		if imported.Auth0Id == "" && provision {
+			imported.Auth0Id, err = provisionAuth0User(ctx, managementAPI, imported)
+			if err != nil {
+				slog.Error("Failed to provision imported user in Auth0", "email", imported.Email, "err", err)
+				fail(sdkErrors.ErrFailedToRegisterUser.Error())
+				continue
+			}
+
+			provisioned = true
+		}
+
+		err = NewUser(ctx, &userModel.User{
+			Username: imported.Username,
+			Email:    imported.Email,
+			Auth0Id:  imported.Auth0Id,
+			Stats:    &userModel.UserStatistics{},
+		})
+		if err != nil {
+			fail(err.Error())
+			continue
+		}
+
+		report.Created++
+
+		if provisioned {
+			report.Provisioned++
+
+			err = ResetUserPassword(ctx, imported.Email)
+			if err != nil {
+				slog.Error("Failed to send password reset to imported user", "email", imported.Email, "err", err)
+			}
+		}
+	}
+
+	slog.Info("Finished importing users", "created", report.Created, "provisioned", report.Provisioned, "skipped", report.Skipped, "failed", len(report.Failed))
+
+	return report, nil
+}
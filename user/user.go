@@ -2,6 +2,7 @@ package user
 
 import (
 	"errors"
+	"fmt"
 	"os/user"
 	"regexp"
 
@@ -11,6 +12,7 @@ import (
 	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
 	userModel "github.com/stevezaluk/mtgjson-models/user"
 	mtgContext "github.com/stevezaluk/mtgjson-sdk/context"
+	"github.com/stevezaluk/mtgjson-sdk/server"
 	"go.mongodb.org/mongo-driver/bson"
 
 	"context"
@@ -20,6 +22,18 @@ const (
 	SystemUser = "system"
 )
 
+/*
+repository Returns a typed repository for the user collection. Missing users are reported as ErrNoUser
+*/
+func repository(ctx context.Context) (*server.Repository[userModel.User], error) {
+	mongoDatabase, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.NewRepository[userModel.User](mongoDatabase, "user", sdkErrors.ErrNoUser), nil
+}
+
 /*
 Ensures that the passed string is a valid email address. If the email address is not valid then it returns false,
 true otherwise
@@ -38,9 +52,7 @@ func validateEmail(email string) bool {
 /*
 GetUser Fetch a user based on their username. Returns ErrNoUser if the user cannot be found
 */
-func GetUser(email string) (*userModel.User, error) {
-	var result *userModel.User
-
+func GetUser(ctx context.Context, email string) (*userModel.User, error) {
 	if email == "" {
 		return nil, sdkErrors.ErrUserMissingId
 	}
@@ -49,22 +61,22 @@ func GetUser(email string) (*userModel.User, error) {
 		return nil, sdkErrors.ErrInvalidEmail
 	}
 
-	var mongoDatabase = mtgContext.GetDatabase()
-
-	query := bson.M{"email": email}
-	err := mongoDatabase.Find("user", query, &result)
-	if !err {
-		return nil, sdkErrors.ErrNoUser
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return repo.FindOne(ctx, bson.M{"email": email})
 }
 
 /*
 GetEmailFromToken Fetch a users email from an authentication token passed to them
 */
 func GetEmailFromToken(token string) (string, error) {
-	var authApi = mtgContext.GetAuthAPI()
+	authApi, err := mtgContext.GetAuthAPI()
+	if err != nil {
+		return "", err
+	}
 
 	userInfo, err := authApi.UserInfo(context.Background(), token)
 	if err != nil {
@@ -78,7 +90,7 @@ func GetEmailFromToken(token string) (string, error) {
 NewUser Insert the contents of a User model in the MongoDB database. Returns ErrUserMissingId if the Username, or Email is not present
 Returns ErrUserAlreadyExist if a user already exists under this username
 */
-func NewUser(user *userModel.User) error {
+func NewUser(ctx context.Context, user *userModel.User) error {
 	if user.Username == "" || user.Email == "" || user.Auth0Id == "" {
 		return sdkErrors.ErrUserMissingId
 	}
@@ -87,11 +99,15 @@ func NewUser(user *userModel.User) error {
 		return sdkErrors.ErrInvalidEmail
 	}
 
-	_, err := GetUser(user.Email)
-	if !errors.Is(err, sdkErrors.ErrNoUser) {
+	_, err := GetUser(ctx, user.Email)
+	if err == nil {
 		return sdkErrors.ErrUserAlreadyExist
 	}
 
+	if !errors.Is(err, sdkErrors.ErrNoUser) {
+		return err
+	}
+
 	if len(user.OwnedCards) == 0 || user.OwnedCards == nil {
 		user.OwnedCards = []string{}
 	}
@@ -104,44 +120,78 @@ func NewUser(user *userModel.User) error {
 		user.OwnedDecks = []string{}
 	}
 
-	var mongoDatabase = mtgContext.GetDatabase()
-	mongoDatabase.Insert("user", &user)
+	mongoDatabase, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = mongoDatabase.Insert(ctx, "user", &user)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
 
 /*
 IndexUsers List all users from the database, and return them in a slice. A limit can be provided to ensure that too many objects
-don't get returned
+don't get returned. Sort fields can be passed to order the users (e.g. "username"), see server.SortBy
 */
-func IndexUsers(limit int64) ([]*user.User, error) {
+func IndexUsers(ctx context.Context, limit int64, sort ...string) ([]*user.User, error) {
 	var result []*user.User
 
-	var mongoDatabase = mtgContext.GetDatabase()
+	mongoDatabase, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	err := mongoDatabase.Index("user", limit, &result)
-	if !err {
-		return nil, sdkErrors.ErrNoUser
+	err = mongoDatabase.Index(ctx, "user", limit, &result, sort...)
+	if err != nil {
+		return nil, err
 	}
 
 	return result, nil
 }
 
+/*
+PageUsers Returns a single page of users from the database. Pass the NextCursor of the returned page in
+the options to fetch the next page. Large collections should be paged through this way rather than
+with IndexUsers, which is limited to a single capped query
+*/
+func PageUsers(ctx context.Context, opts *server.PageOptions) ([]*user.User, *server.Page, error) {
+	var result []*user.User
+
+	mongoDatabase, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, err := mongoDatabase.Paginate(ctx, "user", bson.M{}, opts, &result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, page, nil
+}
+
 /*
 DeleteUser Removes the requested users account from the MongoDB database. Does not remove there account from Auth0. Returns ErrUserMissingId if email is empty string,
 returns ErrInvalidEmail if the email address passed is not valid, returns ErrUserDeleteFailed if the DeletedCount is less than 1, and returns nil otherwise
 */
-func DeleteUser(email string) error {
-	_, err := GetUser(email)
+func DeleteUser(ctx context.Context, email string) error {
+	_, err := GetUser(ctx, email)
 	if err != nil {
 		return err
 	}
 
-	var mongoDatabase = mtgContext.GetDatabase()
+	mongoDatabase, err := mtgContext.GetDatabase(ctx)
+	if err != nil {
+		return err
+	}
 
-	_, valid := mongoDatabase.Delete("user", bson.M{"email": email})
-	if !valid {
-		return sdkErrors.ErrUserDeleteFailed
+	_, err = mongoDatabase.Delete(ctx, "user", bson.M{"email": email})
+	if err != nil {
+		return fmt.Errorf("%w: %w", sdkErrors.ErrUserDeleteFailed, err)
 	}
 
 	return nil
@@ -150,7 +200,7 @@ func DeleteUser(email string) error {
 /*
 RegisterUser Register a new user with Auth0 and store there user model within the MongoDB database
 */
-func RegisterUser(username string, email string, password string) (*userModel.User, error) {
+func RegisterUser(ctx context.Context, username string, email string, password string) (*userModel.User, error) {
 	ret := &userModel.User{
 		Username: username,
 		Email:    email,
@@ -172,16 +222,19 @@ func RegisterUser(username string, email string, password string) (*userModel.Us
 		Email:      ret.Email,
 	}
 
-	authAPI := mtgContext.GetAuthAPI()
+	authAPI, err := mtgContext.GetAuthAPI()
+	if err != nil {
+		return nil, err
+	}
 
-	userResp, err := authAPI.Database.Signup(context.Background(), userData)
+	userResp, err := authAPI.Database.Signup(ctx, userData)
 	if err != nil {
 		return ret, sdkErrors.ErrFailedToRegisterUser
 	}
 
 	ret.Auth0Id = userResp.ID
 
-	err = NewUser(ret)
+	err = NewUser(ctx, ret)
 	if err != nil {
 		return ret, err
 	}
@@ -192,13 +245,16 @@ func RegisterUser(username string, email string, password string) (*userModel.Us
 /*
 LoginUser Log a user in with there email address and password and return back an oauth.TokenSet
 */
-func LoginUser(email string, password string) (*oauth.TokenSet, error) {
-	_, err := GetUser(email)
+func LoginUser(ctx context.Context, email string, password string) (*oauth.TokenSet, error) {
+	_, err := GetUser(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
-	authAPI := mtgContext.GetAuthAPI()
+	authAPI, err := mtgContext.GetAuthAPI()
+	if err != nil {
+		return nil, err
+	}
 
 	userData := oauth.LoginWithPasswordRequest{
 		Username: email,
@@ -210,7 +266,7 @@ func LoginUser(email string, password string) (*oauth.TokenSet, error) {
 	validateOpts := oauth.IDTokenValidationOptions{}
 
 	token, err := authAPI.OAuth.LoginWithPassword(
-		context.Background(),
+		ctx,
 		userData,
 		validateOpts,
 	)
@@ -225,22 +281,25 @@ func LoginUser(email string, password string) (*oauth.TokenSet, error) {
 /*
 DeactivateUser Completely removes the requested user account, both from Auth0 and from MongoDB
 */
-func DeactivateUser(email string) error {
-	user, err := GetUser(email)
+func DeactivateUser(ctx context.Context, email string) error {
+	user, err := GetUser(ctx, email)
 	if err != nil {
 		return err
 	}
 
-	err = DeleteUser(email)
+	err = DeleteUser(ctx, email)
 	if err != nil {
 		return err
 	}
 
-	var managementAPI = mtgContext.GetAuthManagementAPI()
+	managementAPI, err := mtgContext.GetAuthManagementAPI()
+	if err != nil {
+		return err
+	}
 
 	userId := "auth0|" + user.Auth0Id
 
-	err = managementAPI.User.Delete(context.TODO(), userId)
+	err = managementAPI.User.Delete(ctx, userId)
 	if err != nil {
 		return err
 	}
@@ -251,13 +310,16 @@ func DeactivateUser(email string) error {
 /*
 ResetUserPassword Send a reset password email to a specified user account.
 */
-func ResetUserPassword(email string) error {
-	_, err := GetUser(email)
+func ResetUserPassword(ctx context.Context, email string) error {
+	_, err := GetUser(ctx, email)
 	if err != nil {
 		return err
 	}
 
-	var authAPI = mtgContext.GetAuthAPI()
+	authAPI, err := mtgContext.GetAuthAPI()
+	if err != nil {
+		return err
+	}
 
 	resetPwdRequest := database.ChangePasswordRequest{
 		Email:      email,
@@ -265,7 +327,7 @@ func ResetUserPassword(email string) error {
 	}
 
 	_, err = authAPI.Database.ChangePassword(
-		context.Background(),
+		ctx,
 		resetPwdRequest,
 	)
 
@@ -0,0 +1,203 @@
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	sdkErrors "github.com/stevezaluk/mtgjson-models/errors"
+	userModel "github.com/stevezaluk/mtgjson-models/user"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	Auth0EventSignup          = "ss"
+	Auth0EventDeletedUser     = "du"
+	Auth0EventUserDeletion    = "sdu"
+	Auth0EventBlockedAccount  = "limit_wc"
+	Auth0EventBlockedIP       = "limit_mu"
+	Auth0EventBreachedPass    = "pwd_leak"
+	Auth0EventActionCreated   = "user.created"
+	Auth0EventActionDeleted   = "user.deleted"
+	Auth0WebhookMaxBodyLength = 10 << 20
+)
+
+/*
+anomalyEvents Auth0 log event types that indicate suspicious activity on an account. These are logged
+as warnings so they are visible to operators, but do not change the user collection
+*/
+var anomalyEvents = []string{Auth0EventBlockedAccount, Auth0EventBlockedIP, Auth0EventBreachedPass}
+
+/*
+Auth0Event A single event delivered by an Auth0 Log Stream webhook. Events sent from an Auth0 Action
+should use the same shape, with the type set to user.created or user.deleted
+*/
+type Auth0Event struct {
+	LogId string `json:"log_id"`
+	Data  struct {
+		Type     string `json:"type"`
+		UserId   string `json:"user_id"`
+		UserName string `json:"user_name"`
+		Email    string `json:"email"`
+		Username string `json:"username"`
+	} `json:"data"`
+}
+
+/*
+ReconcileReport A summary of the changes made to the user collection while handling a batch of
+Auth0 events
+*/
+type ReconcileReport struct {
+	Created   int64 `json:"created"`
+	Deleted   int64 `json:"deleted"`
+	Anomalies int64 `json:"anomalies"`
+	Ignored   int64 `json:"ignored"`
+}
+
+/*
+GetUserByAuth0Id Fetch a user using their Auth0 id. The "auth0|" prefix is stripped before the lookup as
+user models store the id without it. Returns ErrNoUser if the user cannot be found
+*/
+func GetUserByAuth0Id(ctx context.Context, auth0Id string) (*userModel.User, error) {
+	if auth0Id == "" {
+		return nil, sdkErrors.ErrUserMissingId
+	}
+
+	repo, err := repository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.FindOne(ctx, bson.M{"auth0Id": strings.TrimPrefix(auth0Id, "auth0|")})
+}
+
+/*
+reconcileCreated Create a user in the user collection for an account that was registered in Auth0. Users that
+already exist are left untouched
+*/
+func reconcileCreated(ctx context.Context, event *Auth0Event) bool {
+	email := event.Data.Email
+	if email == "" {
+		email = event.Data.UserName
+	}
+
+	_, err := GetUser(ctx, email)
+	if err == nil {
+		return false
+	}
+
+	username := event.Data.Username
+	if username == "" {
+		username = strings.Split(email, "@")[0]
+	}
+
+	err = NewUser(ctx, &userModel.User{
+		Username: username,
+		Email:    email,
+		Auth0Id:  strings.TrimPrefix(event.Data.UserId, "auth0|"),
+		Stats:    &userModel.UserStatistics{},
+	})
+	if err != nil {
+		slog.Error("Failed to reconcile created Auth0 user", "email", email, "err", err)
+		return false
+	}
+
+	return true
+}
+
+/*
+reconcileDeleted Remove a user from the user collection for an account that was deleted in Auth0
+*/
+func reconcileDeleted(ctx context.Context, event *Auth0Event) bool {
+	user, err := GetUserByAuth0Id(ctx, event.Data.UserId)
+	if err != nil {
+		return false
+	}
+
+	err = DeleteUser(ctx, user.Email)
+	if err != nil {
+		slog.Error("Failed to reconcile deleted Auth0 user", "email", user.Email, "err", err)
+		return false
+	}
+
+	return true
+}
+
+/*
+HandleAuth0Events Reconcile the user collection with a batch of events delivered by an Auth0 Log Stream or Action.
+Successful signups create the user if they do not exist, deleted users are removed, and login anomalies are logged
+as warnings. Any other event type is ignored
+*/
+func HandleAuth0Events(ctx context.Context, events []*Auth0Event) *ReconcileReport {
+	report := &ReconcileReport{}
+
+	for _, event := range events {
+		eventType := event.Data.Type
+
+		switch {
+		case eventType == Auth0EventSignup || eventType == Auth0EventActionCreated:
+			if reconcileCreated(ctx, event) {
+				report.Created++
+			}
+		case eventType == Auth0EventDeletedUser || eventType == Auth0EventUserDeletion || eventType == Auth0EventActionDeleted:
+			if reconcileDeleted(ctx, event) {
+				report.Deleted++
+			}
+		case slices.Contains(anomalyEvents, eventType):
+			slog.Warn("Auth0 reported a login anomaly", "type", eventType, "userId", event.Data.UserId, "logId", event.LogId)
+			report.Anomalies++
+		default:
+			report.Ignored++
+		}
+	}
+
+	return report
+}
+
+/*
+NewAuth0WebhookHandler Return an http.Handler that consumes Auth0 Log Stream or Action webhooks and reconciles the
+user collection with them. Requests must carry the token passed in the parameter in their Authorization header, which
+is configured as the "Authorization Token" of the Auth0 log stream. The body may contain either a single event or an
+array of events
+*/
+func NewAuth0WebhookHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, Auth0WebhookMaxBodyLength))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var events []*Auth0Event
+		if err = json.Unmarshal(body, &events); err != nil {
+			var event *Auth0Event
+			if err = json.Unmarshal(body, &event); err != nil || event == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			events = []*Auth0Event{event}
+		}
+
+		report := HandleAuth0Events(r.Context(), events)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,149 @@
+package visibility
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/stevezaluk/mtgjson-models/meta"
+	"github.com/stevezaluk/mtgjson-models/user"
+)
+
+const (
+	TAG_NAME = "visibility"
+
+	// TagOwner marks a field that is only visible to the owner of the entity it belongs to
+	TagOwner = "owner"
+
+	// TagOwnerKey marks the field holding the email address of the entity's owner
+	TagOwnerKey = "ownerKey"
+)
+
+/*
+rule The visibility rules of a type that cannot carry struct tags, such as the generated models
+*/
+type rule struct {
+	ownerKey    string
+	ownerFields map[string]bool
+}
+
+/*
+rules The visibility rules of the generated models used by the SDK, keyed by type. Users are owned by
+their own email address, and their Auth0 ID and collection are only visible to themselves
+*/
+var (
+	rules = map[reflect.Type]*rule{
+		reflect.TypeOf((*user.User)(nil)).Elem(): {
+			ownerKey:    "Email",
+			ownerFields: map[string]bool{"Auth0Id": true, "OwnedCards": true},
+		},
+	}
+	rulesLock sync.RWMutex
+)
+
+/*
+RegisterModel Declare the visibility rules of a model type that cannot be given struct tags. OwnerKey is
+the name of the field holding the owner's email address, and ownerFields are the names of the fields
+that are only visible to the owner
+*/
+func RegisterModel(model interface{}, ownerKey string, ownerFields ...string) {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	r := &rule{ownerKey: ownerKey, ownerFields: map[string]bool{}}
+	for _, field := range ownerFields {
+		r.ownerFields[field] = true
+	}
+
+	rulesLock.Lock()
+	defer rulesLock.Unlock()
+
+	rules[t] = r
+}
+
+/*
+ownerOf Return the owner of the struct passed, and true if the struct declares an owner. The owner is
+read from the field tagged as the owner key, the registered owner key, or the mtgjsonApiMeta field
+*/
+func ownerOf(v reflect.Value, r *rule) (string, bool) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Tag.Get(TAG_NAME) == TagOwnerKey || (r != nil && r.ownerKey == field.Name) {
+			owner, ok := v.Field(i).Interface().(string)
+			return owner, ok
+		}
+
+		if apiMeta, ok := v.Field(i).Interface().(*meta.MTGJSONAPIMeta); ok && apiMeta != nil {
+			return apiMeta.Owner, true
+		}
+	}
+
+	return "", false
+}
+
+/*
+strip Walk the value passed, zeroing every owner only field of each struct that the viewer does not own.
+Structs that do not declare an owner inherit the ownership of the struct containing them
+*/
+func strip(v reflect.Value, viewer string, isOwner bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			strip(v.Elem(), viewer, isOwner)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			strip(v.Index(i), viewer, isOwner)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			strip(iter.Value(), viewer, isOwner)
+		}
+	case reflect.Struct:
+		t := v.Type()
+
+		rulesLock.RLock()
+		r := rules[t]
+		rulesLock.RUnlock()
+
+		owner, ok := ownerOf(v, r)
+		if ok {
+			isOwner = owner != "" && owner == viewer
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			ownerOnly := field.Tag.Get(TAG_NAME) == TagOwner || (r != nil && r.ownerFields[field.Name])
+			if ownerOnly && !isOwner {
+				if v.Field(i).CanSet() {
+					v.Field(i).Set(reflect.Zero(field.Type))
+				}
+
+				continue
+			}
+
+			strip(v.Field(i), viewer, isOwner)
+		}
+	}
+}
+
+/*
+Shape Remove the owner only fields from every entity in the model that is not owned by the viewer. The
+viewer is the email address of the user the response is for, and may be empty for anonymous requests.
+The model must be a pointer, or a slice or map of pointers, as it is modified in place
+*/
+func Shape(model interface{}, viewer string) {
+	strip(reflect.ValueOf(model), viewer, false)
+}